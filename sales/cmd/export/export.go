@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/internal/config"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/store"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+func main() {
+	var (
+		format   = flag.String("format", "ndjson", "export format, csv, ndjson or json")
+		output   = flag.String("o", "", "file to write the export to (default stdout)")
+		retailer = flag.String("retailer", "", "id of the retailer whose items are exported (required)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: export [flags]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *retailer == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.Config(ctx)
+	if err != nil {
+		log.Fatalf("failed to parse config: %v", err)
+	}
+
+	conn, err := store.Conn(ctx, cfg.DatabaseURL, "sales")
+	if err != nil {
+		log.Fatalf("failed to establish connection: %v", err)
+	}
+	defer conn.Close()
+
+	holder, err := keys.NewHolder(ctx, &store.Keys{Conn: conn})
+	if err != nil {
+		log.Fatalf("failed to setup key holder: %v", err)
+	}
+
+	id, err := holder.Decode(ctx, *retailer)
+	if err != nil {
+		log.Fatalf("failed to decode retailer id: %v", err)
+	}
+	if _, err := (&store.Retailers{Conn: conn}).GetRetailer(ctx, id); err != nil {
+		log.Fatalf("failed to get retailer: %v", err)
+	}
+	ctx = retailers.With(ctx, id)
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("failed to create export file: %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	buffered := bufio.NewWriter(w)
+	manager := items.ItemManager{Store: &store.Items{Conn: conn}}
+
+	err = manager.ExportItems(ctx, buffered, items.ExportFormat(strings.ToUpper(*format)), holder)
+	if err != nil {
+		log.Fatalf("failed to export items: %v", err)
+	}
+
+	if err := buffered.Flush(); err != nil {
+		log.Fatalf("failed to flush export: %v", err)
+	}
+}
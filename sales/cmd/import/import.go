@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/config"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/store"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+func main() {
+	var (
+		format   = flag.String("format", "", "import format, csv or ndjson (default inferred from file extension)")
+		dryRun   = flag.Bool("dry-run", false, "validate and report the outcome without keeping any changes")
+		by       = flag.String("actor", "import", "actor the imported changes are attributed to")
+		retailer = flag.String("retailer", "", "id of the retailer the items are imported into (required)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: import [flags] <file>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *retailer == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	ctx := actor.With(context.Background(), actor.Actor(*by))
+
+	cfg, err := config.Config(ctx)
+	if err != nil {
+		log.Fatalf("failed to parse config: %v", err)
+	}
+
+	conn, err := store.Conn(ctx, cfg.DatabaseURL, "sales")
+	if err != nil {
+		log.Fatalf("failed to establish connection: %v", err)
+	}
+	defer conn.Close()
+
+	holder, err := keys.NewHolder(ctx, &store.Keys{Conn: conn})
+	if err != nil {
+		log.Fatalf("failed to setup key holder: %v", err)
+	}
+
+	id, err := holder.Decode(ctx, *retailer)
+	if err != nil {
+		log.Fatalf("failed to decode retailer id: %v", err)
+	}
+	if _, err := (&store.Retailers{Conn: conn}).GetRetailer(ctx, id); err != nil {
+		log.Fatalf("failed to get retailer: %v", err)
+	}
+	ctx = retailers.With(ctx, id)
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("failed to open import file: %v", err)
+	}
+	defer file.Close()
+
+	rows, errs, err := items.ParseImport(file, items.ImportFormat(strings.ToUpper(*format)))
+	if err != nil {
+		log.Fatalf("failed to parse import file: %v", err)
+	}
+
+	result := &items.ImportResult{DryRun: *dryRun, Errors: errs}
+	if len(errs) == 0 {
+		manager := items.ItemManager{
+			Store:   &store.Items{Conn: conn},
+			History: &store.ItemHistory{Conn: conn},
+		}
+
+		result, err = manager.ImportItems(ctx, rows, *dryRun)
+		if err != nil {
+			log.Fatalf("failed to import items: %v", err)
+		}
+	}
+
+	for _, rowErr := range result.Errors {
+		fmt.Fprintln(os.Stderr, rowErr.Error())
+	}
+	if len(result.Errors) > 0 {
+		log.Fatalf("import rejected, %d row(s) with errors", len(result.Errors))
+	}
+
+	var prefix string
+	if result.DryRun {
+		prefix = "dry run: "
+	}
+	fmt.Printf("%s%d created, %d updated, %d unchanged\n", prefix, result.Created, result.Updated, result.Unchanged)
+}
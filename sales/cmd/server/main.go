@@ -4,16 +4,31 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
 
+	"github.com/suessflorian/pedlar/sales/internal/actor"
 	"github.com/suessflorian/pedlar/sales/internal/config"
+	"github.com/suessflorian/pedlar/sales/internal/customers"
 	"github.com/suessflorian/pedlar/sales/internal/graph"
 	"github.com/suessflorian/pedlar/sales/internal/graph/resolver"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
 	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/media"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/pricing"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/purchasing"
+	"github.com/suessflorian/pedlar/sales/internal/receipts"
+	"github.com/suessflorian/pedlar/sales/internal/reorder"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
 	"github.com/suessflorian/pedlar/sales/internal/store"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
 	"github.com/suessflorian/pedlar/sales/pkg/keys"
 )
 
@@ -35,10 +50,102 @@ func main() {
 		log.Fatalf("failed to setup key holder: %v", err)
 	}
 
+	prices := pricing.PriceManager{
+		Store: &store.Prices{Conn: conn},
+		Items: &store.Items{Conn: conn},
+	}
+
+	promos := promotions.PromotionManager{
+		Store: &store.Promotions{Conn: conn},
+	}
+
+	taxes := tax.TaxManager{
+		Store: &store.Taxes{Conn: conn},
+	}
+
+	tenancy := &retailers.Tenancy{
+		Store:    &store.Retailers{Conn: conn},
+		Codec:    holder,
+		Platform: strings.Split(cfg.PlatformHosts, ","),
+		Secret:   []byte(cfg.PlatformSecret),
+		TTL:      retailers.DefaultTTL,
+	}
+
+	var notifier reorder.Notifier = reorder.Log{}
+	if cfg.AlertWebhookURL != "" {
+		notifier = &reorder.Webhook{
+			URL:    cfg.AlertWebhookURL,
+			Secret: []byte(cfg.AlertWebhookSecret),
+			Codec:  holder,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
 	resolver := &resolver.Resolver{
-		ItemsManager: items.ItemManager{Store: &store.Items{Conn: conn}},
+		CustomerManager: customers.CustomerManager{
+			Store: &store.Customers{Conn: conn},
+			Sales: &store.Sales{Conn: conn},
+		},
+		InventoryManager: inventory.InventoryManager{
+			Store: &store.Inventory{Conn: conn},
+			Items: &store.Items{Conn: conn},
+		},
+		ItemsManager: items.ItemManager{
+			Store:   &store.Items{Conn: conn},
+			History: &store.ItemHistory{Conn: conn},
+		},
+		MediaManager: media.ImageManager{
+			Store: &store.Images{Conn: conn},
+			Items: &store.Items{Conn: conn},
+			Blobs: &media.LocalBlobStore{Root: cfg.MediaDir},
+			Signer: &media.Signer{
+				Secret:  []byte(cfg.MediaSigningSecret),
+				BaseURL: "/media",
+				TTL:     15 * time.Minute,
+			},
+		},
+		PricingManager:   prices,
+		PromotionManager: promos,
+		PurchasingManager: purchasing.PurchaseManager{
+			Store: &store.Purchasing{Conn: conn},
+			Items: &store.Items{Conn: conn},
+			Stock: &store.Inventory{Conn: conn},
+		},
+		ReceiptManager: receipts.ReceiptManager{
+			Sales:     &store.Sales{Conn: conn},
+			Items:     &store.Items{Conn: conn},
+			Retailers: &store.Retailers{Conn: conn},
+			Codec:     holder,
+		},
+		ReorderManager: reorder.ReorderManager{
+			Store:    &store.Reorder{Conn: conn},
+			Items:    &store.Items{Conn: conn},
+			Stock:    &store.Inventory{Conn: conn},
+			Notifier: notifier,
+		},
+		RetailerManager: retailers.RetailerManager{
+			Store:   &store.Retailers{Conn: conn},
+			Tenancy: tenancy,
+		},
+		SalesManager: sales.SaleManager{
+			Store:      &store.Sales{Conn: conn},
+			Items:      &store.Items{Conn: conn},
+			Prices:     &prices,
+			Promotions: &promos,
+			Taxes:      &taxes,
+			// TODO: charge through a real payment provider
+			Provider: &payments.Local{},
+		},
+		TaxManager: taxes,
 	}
 
+	watcher := &reorder.Watcher{
+		Manager:   &resolver.ReorderManager,
+		Retailers: &store.Retailers{Conn: conn},
+		Interval:  5 * time.Minute,
+	}
+	go watcher.Run(ctx)
+
 	srv := handler.NewDefaultServer(graph.NewExecutableSchema(
 		graph.Config{
 			Resolvers: resolver,
@@ -49,6 +156,10 @@ func main() {
 					if err != nil {
 						return nil, err
 					}
+					if id, ok := res.(keys.OpaqueID); ok {
+						// non-null ID arguments are passed by value and can't be set inplace
+						return *id.WithCodec(holder), nil
+					}
 					keys.SetCodec(res, holder)
 					return res, nil
 				},
@@ -57,7 +168,11 @@ func main() {
 	))
 
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	// retailers themselves are administered over /query by requests authenticated as the platform
+	http.Handle("/query", tenancy.Middleware(actor.Middleware(srv)))
+	http.Handle("/export", tenancy.Middleware(retailers.Require(resolver.ItemsManager.ExportHandler(holder))))
+	http.Handle("/media/", http.StripPrefix("/media", resolver.MediaManager.Handler()))
+	http.Handle("/receipts/", tenancy.Middleware(retailers.Require(http.StripPrefix("/receipts", resolver.ReceiptManager.Handler()))))
 
 	log.Printf("connect to http://localhost:%s/ for GraphQL playground", "8080")
 	log.Fatal(http.ListenAndServe(":"+"8080", nil))
@@ -0,0 +1,40 @@
+// Package actor propagates who is performing a request so that writes can be attributed to them.
+package actor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type Actor string
+
+// Anonymous is attributed to any request that does not identify itself.
+const Anonymous Actor = "anonymous"
+
+// Header is read by Middleware to identify the actor of a request.
+// TODO: derive from authenticated credentials once we have them.
+const Header = "X-Actor"
+
+type key struct{}
+
+func With(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, key{}, actor)
+}
+
+func From(ctx context.Context) Actor {
+	actor, ok := ctx.Value(key{}).(Actor)
+	if !ok || actor == "" {
+		return Anonymous
+	}
+	return actor
+}
+
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if actor := strings.TrimSpace(r.Header.Get(Header)); actor != "" {
+			r = r.WithContext(With(r.Context(), Actor(actor)))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
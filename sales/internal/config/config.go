@@ -5,12 +5,25 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
 
 	env "github.com/joho/godotenv"
 )
 
 type Cfg struct {
 	DatabaseURL string `env:"DATABASE_URL"`
+	// MediaDir is the directory the local blob store keeps attachments in.
+	MediaDir           string `env:"MEDIA_DIR"`
+	MediaSigningSecret string `env:"MEDIA_SIGNING_SECRET"`
+	// PlatformHosts are the comma separated hosts of pedlar itself rather than of a retailer.
+	PlatformHosts string `env:"PLATFORM_HOSTS"`
+	// PlatformSecret is the bearer token requests to a platform host authenticate as the platform
+	// with, only they administer retailers or name one with the X-Retailer header.
+	PlatformSecret string `env:"PLATFORM_SECRET"`
+	// AlertWebhookURL receives stock alerts as they are raised, they are only logged without one.
+	AlertWebhookURL string `env:"ALERT_WEBHOOK_URL,optional"`
+	// AlertWebhookSecret signs the stock alerts posted to the webhook, if given.
+	AlertWebhookSecret string `env:"ALERT_WEBHOOK_SECRET,optional"`
 }
 
 func Config(ctx context.Context) (Cfg, error) {
@@ -24,12 +37,14 @@ func Config(ctx context.Context) (Cfg, error) {
 	t := v.Type()
 
 	for i := 0; i < t.NumField(); i++ {
-		tag := t.Field(i).Tag.Get("env")
+		tag, option, _ := strings.Cut(t.Field(i).Tag.Get("env"), ",")
 		if tag == "" {
 			continue
 		}
 		val := os.Getenv(tag)
-		if val == "" {
+		if val == "" && option == "optional" {
+			continue
+		} else if val == "" {
 			return cfg, fmt.Errorf("missing configurable %q", tag)
 		}
 		v.Field(i).SetString(val)
@@ -0,0 +1,242 @@
+// Package customers manages the customers of retailers, and forgets them when asked to.
+package customers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+type store interface {
+	// CreateCustomer adds the customer to the retailer of the context with its id and timestamps
+	// assigned in place.
+	CreateCustomer(context.Context, *Customer) error
+	GetCustomer(context.Context, int) (*Customer, error)
+	// PageCustomers pages through the customers that match the filter in the order they were added.
+	PageCustomers(context.Context, Filter, paginate.Paginate) ([]*Customer, error)
+	UpdateCustomer(context.Context, *Customer) error
+	// EraseCustomer deletes the customer once none of their sales refer to them any more, returning
+	// how many sales did.
+	EraseCustomer(context.Context, int) (int, error)
+}
+
+// saleStore pages through the sales of a customer.
+type saleStore interface {
+	PageSales(context.Context, sales.SaleFilter, paginate.Paginate) ([]*sales.Sale, error)
+}
+
+type CustomerManager struct {
+	Store store
+	Sales saleStore
+}
+
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+	ErrNoNameCustomer   = errors.New("customer must have a name")
+	ErrEmail            = errors.New("email must be an address, ie someone@example.com")
+	ErrPhone            = errors.New("phone must be a number of 7 to 15 digits, ie +64 21 123 4567")
+	ErrDuplicateEmail   = errors.New("email belongs to another customer")
+	ErrDuplicatePhone   = errors.New("phone belongs to another customer")
+)
+
+var (
+	phoneFormatting = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+	phonePattern    = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+)
+
+// NormaliseEmail lower cases the address, ie Someone@Example.com is someone@example.com.
+func NormaliseEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", fmt.Errorf("%w: %q", ErrEmail, email)
+	}
+	return email, nil
+}
+
+// NormalisePhone strips the formatting from the number, ie +64 (21) 123-4567 is +64211234567.
+func NormalisePhone(phone string) (string, error) {
+	stripped := phoneFormatting.Replace(strings.TrimSpace(phone))
+	if !phonePattern.MatchString(stripped) {
+		return "", fmt.Errorf("%w: %q", ErrPhone, phone)
+	}
+	return stripped, nil
+}
+
+type NewCustomer struct {
+	Name  string
+	Email *string
+	Phone *string
+}
+
+// CustomerPatch changes the fields that are set, an empty email or phone removes it.
+type CustomerPatch struct {
+	Name  *string
+	Email *string
+	Phone *string
+}
+
+// Filter finds customers by their contact details, given in any format.
+type Filter struct {
+	Email *string
+	Phone *string
+}
+
+func (c *CustomerManager) CreateCustomer(ctx context.Context, input NewCustomer) (*Customer, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	customer := &Customer{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		Name:       strings.TrimSpace(input.Name),
+	}
+	if customer.Name == "" {
+		return nil, ErrNoNameCustomer
+	}
+	if customer.Email, err = contact(input.Email, NormaliseEmail); err != nil {
+		return nil, err
+	}
+	if customer.Phone, err = contact(input.Phone, NormalisePhone); err != nil {
+		return nil, err
+	}
+
+	if err := c.Store.CreateCustomer(ctx, customer); err != nil {
+		return nil, fmt.Errorf("failed to create customer: %w", err)
+	}
+	return customer, nil
+}
+
+func (c *CustomerManager) GetCustomer(ctx context.Context, externalID *keys.OpaqueID) (*Customer, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	return c.Store.GetCustomer(ctx, id)
+}
+
+// SearchCustomers looks customers up by their email or phone, every customer of the retailer
+// matches without a filter.
+func (c *CustomerManager) SearchCustomers(ctx context.Context, filter *Filter, page *paginate.Paginate) ([]*Customer, error) {
+	var normalised Filter
+	if filter != nil {
+		var err error
+		if normalised.Email, err = contact(filter.Email, NormaliseEmail); err != nil {
+			return nil, err
+		}
+		if normalised.Phone, err = contact(filter.Phone, NormalisePhone); err != nil {
+			return nil, err
+		}
+	}
+
+	decodedPage, err := paginate.Decode(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	customers, err := c.Store.PageCustomers(ctx, normalised, decodedPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to page through customers: %w", err)
+	}
+	return customers, nil
+}
+
+func (c *CustomerManager) UpdateCustomer(ctx context.Context, externalID *keys.OpaqueID, patch CustomerPatch) (*Customer, error) {
+	customer, err := c.GetCustomer(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Name != nil {
+		if customer.Name = strings.TrimSpace(*patch.Name); customer.Name == "" {
+			return nil, ErrNoNameCustomer
+		}
+	}
+	if patch.Email != nil {
+		if customer.Email, err = contact(patch.Email, NormaliseEmail); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Phone != nil {
+		if customer.Phone, err = contact(patch.Phone, NormalisePhone); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Store.UpdateCustomer(ctx, customer); err != nil {
+		return nil, fmt.Errorf("failed to update customer: %w", err)
+	}
+	return customer, nil
+}
+
+// SaleCustomer resolves the customer the sale was made to, walk-in sales have none.
+func (c *CustomerManager) SaleCustomer(ctx context.Context, sale *sales.Sale) (*Customer, error) {
+	if sale.CustomerID == nil {
+		return nil, nil
+	}
+	return c.Store.GetCustomer(ctx, sale.CustomerID.ID)
+}
+
+// exportPage is how many sales are read at a time when exporting a customer.
+const exportPage = 100
+
+// ExportCustomer gathers the customer along with every sale made to them.
+func (c *CustomerManager) ExportCustomer(ctx context.Context, externalID *keys.OpaqueID) (*Export, error) {
+	customer, err := c.GetCustomer(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		export = &Export{Customer: customer, Sales: []*sales.Sale{}, ExportedAt: time.Now()}
+		filter = sales.SaleFilter{Customer: &keys.OpaqueID{ID: customer.ID.ID}}
+		page   = paginate.Paginate{Limit: exportPage}
+	)
+	for {
+		found, err := c.Sales.PageSales(ctx, filter, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to page through sales of customer: %w", err)
+		}
+		export.Sales = append(export.Sales, found...)
+		if len(found) < page.Limit {
+			return export, nil
+		}
+		page.Cursor = &keys.OpaqueID{ID: found[len(found)-1].ID.ID}
+	}
+}
+
+// EraseCustomer forgets the customer, their sales stay on the books as walk-in sales.
+func (c *CustomerManager) EraseCustomer(ctx context.Context, externalID *keys.OpaqueID) (*Erasure, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	anonymised, err := c.Store.EraseCustomer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to erase customer: %w", err)
+	}
+	return &Erasure{Sales: anonymised, ErasedAt: time.Now()}, nil
+}
+
+// contact normalises optional contact details, empty details are none at all.
+func contact(given *string, normalise func(string) (string, error)) (*string, error) {
+	if given == nil || strings.TrimSpace(*given) == "" {
+		return nil, nil
+	}
+	normalised, err := normalise(*given)
+	if err != nil {
+		return nil, err
+	}
+	return &normalised, nil
+}
@@ -0,0 +1,149 @@
+package customers
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+func TestNormaliseEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+		err   error
+	}{
+		{email: " Ada@Example.com ", want: "ada@example.com"},
+		{email: "ada+receipts@example.co.nz", want: "ada+receipts@example.co.nz"},
+		{email: "", err: ErrEmail},
+		{email: "ada", err: ErrEmail},
+		{email: "ada@", err: ErrEmail},
+		{email: "Ada <ada@example.com>", err: ErrEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			got, err := NormaliseEmail(tt.email)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalisePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+		err   error
+	}{
+		{phone: "+64 (21) 123-4567", want: "+64211234567"},
+		{phone: "021 123 4567", want: "0211234567"},
+		{phone: "09.123.4567", want: "091234567"},
+		{phone: "", err: ErrPhone},
+		{phone: "12345", err: ErrPhone},
+		{phone: "+64 21 CALL-ADA", err: ErrPhone},
+		{phone: "64+211234567", err: ErrPhone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phone, func(t *testing.T) {
+			got, err := NormalisePhone(tt.phone)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// codec takes external ids to be internal ids written out.
+type codec struct{}
+
+func (codec) Encode(ctx context.Context, id int) (string, error) {
+	return strconv.Itoa(id), nil
+}
+
+func (codec) Decode(ctx context.Context, id string) (int, error) {
+	return strconv.Atoi(id)
+}
+
+// ledger holds one customer with the sales made to them, most recent sales first.
+type ledger struct {
+	sales []*sales.Sale
+	pages int
+}
+
+func (l *ledger) CreateCustomer(ctx context.Context, customer *Customer) error { return nil }
+
+func (l *ledger) GetCustomer(ctx context.Context, id int) (*Customer, error) {
+	if id != 1 {
+		return nil, ErrCustomerNotFound
+	}
+	return &Customer{ID: &keys.OpaqueID{ID: 1}, Name: "Ada"}, nil
+}
+
+func (l *ledger) PageCustomers(ctx context.Context, filter Filter, page paginate.Paginate) ([]*Customer, error) {
+	return nil, nil
+}
+
+func (l *ledger) UpdateCustomer(ctx context.Context, customer *Customer) error { return nil }
+
+func (l *ledger) EraseCustomer(ctx context.Context, id int) (int, error) {
+	return len(l.sales), nil
+}
+
+func (l *ledger) PageSales(ctx context.Context, filter sales.SaleFilter, page paginate.Paginate) ([]*sales.Sale, error) {
+	l.pages++
+	var found []*sales.Sale
+	for _, sale := range l.sales {
+		if page.Cursor != nil && sale.ID.ID >= page.Cursor.ID {
+			continue
+		}
+		if len(found) == page.Limit {
+			break
+		}
+		found = append(found, sale)
+	}
+	return found, nil
+}
+
+func TestExportCustomer(t *testing.T) {
+	var made = &ledger{}
+	for id := 2*exportPage + 1; id > 0; id-- {
+		made.sales = append(made.sales, &sales.Sale{ID: &keys.OpaqueID{ID: id}})
+	}
+	manager := &CustomerManager{Store: made, Sales: made}
+
+	id := &keys.OpaqueID{}
+	require.NoError(t, id.UnmarshalGQLContext(context.Background(), "1"))
+
+	export, err := manager.ExportCustomer(context.Background(), id.WithCodec(codec{}))
+	require.NoError(t, err)
+	require.Equal(t, "Ada", export.Customer.Name)
+	require.Len(t, export.Sales, 2*exportPage+1)
+	require.Equal(t, 3, made.pages)
+	require.Equal(t, 1, export.Sales[len(export.Sales)-1].ID.ID)
+}
+
+func TestSaleCustomerWalkIn(t *testing.T) {
+	manager := &CustomerManager{Store: &ledger{}}
+
+	customer, err := manager.SaleCustomer(context.Background(), &sales.Sale{})
+	require.NoError(t, err)
+	require.Nil(t, customer)
+
+	customer, err = manager.SaleCustomer(context.Background(), &sales.Sale{CustomerID: &keys.OpaqueID{ID: 1}})
+	require.NoError(t, err)
+	require.Equal(t, "Ada", customer.Name)
+}
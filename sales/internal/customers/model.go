@@ -0,0 +1,37 @@
+package customers
+
+import (
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// Customer is someone a retailer knows by name, sales made to anyone else are walk-in sales and
+// have no customer.
+type Customer struct {
+	ID         *keys.OpaqueID
+	RetailerID *keys.OpaqueID
+	Name       string
+	// Email is held lower cased, no two customers of a retailer share one.
+	Email *string
+	// Phone is held without formatting, ie +64211234567, no two customers of a retailer share one.
+	Phone     *string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Export is everything held about a customer, as owed to them on request.
+type Export struct {
+	Customer   *Customer
+	Sales      []*sales.Sale
+	ExportedAt time.Time
+}
+
+// Erasure is what is left of a customer once they've been forgotten, their sales are kept without
+// them.
+type Erasure struct {
+	// Sales is how many sales no longer refer to the customer.
+	Sales    int
+	ErasedAt time.Time
+}
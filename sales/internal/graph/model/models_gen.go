@@ -3,9 +3,19 @@
 package model
 
 import (
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
 )
 
+type BulkImportItems struct {
+	Format items.ImportFormat `json:"format"`
+	Data   string             `json:"data"`
+	DryRun *bool              `json:"dry_run,omitempty"`
+}
+
 type ConfirmCreateItem struct {
 	Similar []*items.Item  `json:"similar"`
 	Details *items.Details `json:"details"`
@@ -15,5 +25,25 @@ type ConfirmCreateItem struct {
 type Mutation struct {
 }
 
+type NewVariant struct {
+	Options       []*items.Option `json:"options"`
+	Sku           *string         `json:"sku,omitempty"`
+	Barcode       *string         `json:"barcode,omitempty"`
+	PriceOverride *money.Input    `json:"price_override,omitempty"`
+}
+
 type Query struct {
 }
+
+type SignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type UpdateItem struct {
+	Version     int                                 `json:"version"`
+	Name        graphql.Omittable[*string]          `json:"name,omitempty"`
+	Description graphql.Omittable[*string]          `json:"description,omitempty"`
+	UnitScale   graphql.Omittable[*items.UnitScale] `json:"unit_scale,omitempty"`
+	Sku         graphql.Omittable[*string]          `json:"sku,omitempty"`
+}
@@ -0,0 +1,60 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+	"errors"
+
+	"github.com/suessflorian/pedlar/sales/internal/customers"
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+// Sales is the resolver for the sales field.
+func (r *customerResolver) Sales(ctx context.Context, obj *customers.Customer, paginate *paginate.Paginate) ([]*sales.Sale, error) {
+	return r.SalesManager.CustomerSales(ctx, obj.ID.ID, paginate)
+}
+
+// CreateCustomer is the resolver for the createCustomer field.
+func (r *mutationResolver) CreateCustomer(ctx context.Context, input customers.NewCustomer) (*customers.Customer, error) {
+	return r.CustomerManager.CreateCustomer(ctx, input)
+}
+
+// UpdateCustomer is the resolver for the updateCustomer field.
+func (r *mutationResolver) UpdateCustomer(ctx context.Context, id keys.OpaqueID, patch customers.CustomerPatch) (*customers.Customer, error) {
+	return r.CustomerManager.UpdateCustomer(ctx, &id, patch)
+}
+
+// EraseCustomer is the resolver for the eraseCustomer field.
+func (r *mutationResolver) EraseCustomer(ctx context.Context, id keys.OpaqueID) (*customers.Erasure, error) {
+	return r.CustomerManager.EraseCustomer(ctx, &id)
+}
+
+// Customer is the resolver for the customer field.
+func (r *queryResolver) Customer(ctx context.Context, id keys.OpaqueID) (*customers.Customer, error) {
+	customer, err := r.CustomerManager.GetCustomer(ctx, &id)
+	if errors.Is(err, customers.ErrCustomerNotFound) {
+		return nil, nil
+	}
+	return customer, err
+}
+
+// Customers is the resolver for the customers field.
+func (r *queryResolver) Customers(ctx context.Context, filter *customers.Filter, paginate *paginate.Paginate) ([]*customers.Customer, error) {
+	return r.CustomerManager.SearchCustomers(ctx, filter, paginate)
+}
+
+// ExportCustomer is the resolver for the exportCustomer field.
+func (r *queryResolver) ExportCustomer(ctx context.Context, id keys.OpaqueID) (*customers.Export, error) {
+	return r.CustomerManager.ExportCustomer(ctx, &id)
+}
+
+// Customer returns graph.CustomerResolver implementation.
+func (r *Resolver) Customer() graph.CustomerResolver { return &customerResolver{r} }
+
+type customerResolver struct{ *Resolver }
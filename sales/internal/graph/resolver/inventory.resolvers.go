@@ -0,0 +1,143 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+// Stock is the resolver for the stock field.
+func (r *itemResolver) Stock(ctx context.Context, obj *items.Item) ([]*inventory.Level, error) {
+	return r.InventoryManager.ItemStock(ctx, obj)
+}
+
+// Components is the resolver for the components field.
+func (r *itemResolver) Components(ctx context.Context, obj *items.Item) ([]*inventory.Component, error) {
+	return r.InventoryManager.Components(ctx, obj)
+}
+
+// AvailableToSell is the resolver for the available_to_sell field.
+func (r *itemResolver) AvailableToSell(ctx context.Context, obj *items.Item, location *keys.OpaqueID) (string, error) {
+	available, err := r.InventoryManager.AvailableToSell(ctx, obj, location)
+	if err != nil {
+		return "", err
+	}
+	return inventory.FormatQuantity(available), nil
+}
+
+// Item is the resolver for the item field.
+func (r *itemComponentResolver) Item(ctx context.Context, obj *inventory.Component) (*items.Item, error) {
+	return r.InventoryManager.ItemOf(ctx, obj.ItemID)
+}
+
+// Quantity is the resolver for the quantity field.
+func (r *itemComponentResolver) Quantity(ctx context.Context, obj *inventory.Component) (string, error) {
+	return inventory.FormatQuantity(obj.Quantity), nil
+}
+
+// CreateStockLocation is the resolver for the createStockLocation field.
+func (r *mutationResolver) CreateStockLocation(ctx context.Context, input inventory.NewLocation) (*inventory.Location, error) {
+	return r.InventoryManager.CreateLocation(ctx, input)
+}
+
+// UpdateStockLocation is the resolver for the updateStockLocation field.
+func (r *mutationResolver) UpdateStockLocation(ctx context.Context, id keys.OpaqueID, patch inventory.LocationPatch) (*inventory.Location, error) {
+	return r.InventoryManager.UpdateLocation(ctx, &id, patch)
+}
+
+// ReceiveStock is the resolver for the receiveStock field.
+func (r *mutationResolver) ReceiveStock(ctx context.Context, input inventory.NewMovement) (*inventory.Movement, error) {
+	return r.InventoryManager.ReceiveStock(ctx, input)
+}
+
+// AdjustStock is the resolver for the adjustStock field.
+func (r *mutationResolver) AdjustStock(ctx context.Context, input inventory.NewMovement) (*inventory.Movement, error) {
+	return r.InventoryManager.AdjustStock(ctx, input)
+}
+
+// TransferStock is the resolver for the transferStock field.
+func (r *mutationResolver) TransferStock(ctx context.Context, input inventory.NewTransfer) ([]*inventory.Movement, error) {
+	return r.InventoryManager.TransferStock(ctx, input)
+}
+
+// StockLocations is the resolver for the stockLocations field.
+func (r *queryResolver) StockLocations(ctx context.Context) ([]*inventory.Location, error) {
+	return r.InventoryManager.GetLocations(ctx)
+}
+
+// StockLevels is the resolver for the stockLevels field.
+func (r *queryResolver) StockLevels(ctx context.Context, filter *inventory.Filter) ([]*inventory.Level, error) {
+	return r.InventoryManager.StockLevels(ctx, filter)
+}
+
+// StockMovements is the resolver for the stockMovements field.
+func (r *queryResolver) StockMovements(ctx context.Context, filter *inventory.Filter, paginate *paginate.Paginate) ([]*inventory.Movement, error) {
+	return r.InventoryManager.SearchMovements(ctx, filter, paginate)
+}
+
+// Location is the resolver for the location field.
+func (r *saleResolver) Location(ctx context.Context, obj *sales.Sale) (*inventory.Location, error) {
+	return r.InventoryManager.SaleLocation(ctx, obj)
+}
+
+// Item is the resolver for the item field.
+func (r *stockLevelResolver) Item(ctx context.Context, obj *inventory.Level) (*items.Item, error) {
+	return r.InventoryManager.ItemOf(ctx, obj.ItemID)
+}
+
+// Location is the resolver for the location field.
+func (r *stockLevelResolver) Location(ctx context.Context, obj *inventory.Level) (*inventory.Location, error) {
+	return r.InventoryManager.LocationOf(ctx, obj.LocationID)
+}
+
+// OnHand is the resolver for the on_hand field.
+func (r *stockLevelResolver) OnHand(ctx context.Context, obj *inventory.Level) (string, error) {
+	return inventory.FormatQuantity(obj.OnHand), nil
+}
+
+// Item is the resolver for the item field.
+func (r *stockMovementResolver) Item(ctx context.Context, obj *inventory.Movement) (*items.Item, error) {
+	return r.InventoryManager.ItemOf(ctx, obj.ItemID)
+}
+
+// Location is the resolver for the location field.
+func (r *stockMovementResolver) Location(ctx context.Context, obj *inventory.Movement) (*inventory.Location, error) {
+	return r.InventoryManager.LocationOf(ctx, obj.LocationID)
+}
+
+// Quantity is the resolver for the quantity field.
+func (r *stockMovementResolver) Quantity(ctx context.Context, obj *inventory.Movement) (string, error) {
+	return inventory.FormatQuantity(obj.Quantity), nil
+}
+
+// TransferLocation is the resolver for the transfer_location field.
+func (r *stockMovementResolver) TransferLocation(ctx context.Context, obj *inventory.Movement) (*inventory.Location, error) {
+	return r.InventoryManager.LocationOf(ctx, obj.TransferLocationID)
+}
+
+// Actor is the resolver for the actor field.
+func (r *stockMovementResolver) Actor(ctx context.Context, obj *inventory.Movement) (string, error) {
+	return string(obj.Actor), nil
+}
+
+// ItemComponent returns graph.ItemComponentResolver implementation.
+func (r *Resolver) ItemComponent() graph.ItemComponentResolver { return &itemComponentResolver{r} }
+
+// StockLevel returns graph.StockLevelResolver implementation.
+func (r *Resolver) StockLevel() graph.StockLevelResolver { return &stockLevelResolver{r} }
+
+// StockMovement returns graph.StockMovementResolver implementation.
+func (r *Resolver) StockMovement() graph.StockMovementResolver { return &stockMovementResolver{r} }
+
+type itemComponentResolver struct{ *Resolver }
+type stockLevelResolver struct{ *Resolver }
+type stockMovementResolver struct{ *Resolver }
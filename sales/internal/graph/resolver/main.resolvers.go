@@ -6,7 +6,10 @@ package resolver
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/suessflorian/pedlar/sales/internal/graph"
 	"github.com/suessflorian/pedlar/sales/internal/graph/model"
@@ -15,11 +18,90 @@ import (
 	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
 )
 
+// Parent is the resolver for the parent field.
+func (r *categoryResolver) Parent(ctx context.Context, obj *items.Category) (*items.Category, error) {
+	return r.ItemsManager.CategoryParent(ctx, obj)
+}
+
+// Children is the resolver for the children field.
+func (r *categoryResolver) Children(ctx context.Context, obj *items.Category) ([]*items.Category, error) {
+	return r.ItemsManager.CategoryChildren(ctx, obj)
+}
+
+// Items is the resolver for the items field.
+func (r *categoryResolver) Items(ctx context.Context, obj *items.Category, paginate *paginate.Paginate) ([]*items.Item, error) {
+	return r.ItemsManager.CategoryItems(ctx, obj, paginate)
+}
+
+// Report is the resolver for the report field.
+func (r *categoryResolver) Report(ctx context.Context, obj *items.Category, from *time.Time, to *time.Time) (*items.CategoryReport, error) {
+	return r.ItemsManager.CategoryReport(ctx, obj, from, to)
+}
+
 // Confirm is the resolver for the confirm field.
 func (r *confirmCreateItemResolver) Confirm(ctx context.Context, obj *model.ConfirmCreateItem) (*items.Item, error) {
 	return r.ItemsManager.CreateItem(ctx, *obj.Details)
 }
 
+// Children is the resolver for the children field.
+func (r *itemResolver) Children(ctx context.Context, obj *items.Item) ([]*items.Item, error) {
+	return r.ItemsManager.GetChildren(ctx, obj)
+}
+
+// History is the resolver for the history field.
+func (r *itemResolver) History(ctx context.Context, obj *items.Item, paginate *paginate.Paginate) ([]*items.Change, error) {
+	return r.ItemsManager.ItemHistory(ctx, obj, paginate)
+}
+
+// Barcodes is the resolver for the barcodes field.
+func (r *itemResolver) Barcodes(ctx context.Context, obj *items.Item) ([]string, error) {
+	return r.ItemsManager.GetBarcodes(ctx, obj)
+}
+
+// Variants is the resolver for the variants field.
+func (r *itemResolver) Variants(ctx context.Context, obj *items.Item) ([]*items.Variant, error) {
+	return r.ItemsManager.GetVariants(ctx, obj)
+}
+
+// Variant is the resolver for the variant field.
+func (r *itemResolver) Variant(ctx context.Context, obj *items.Item, options []*items.Option) (*items.Variant, error) {
+	variant, err := r.ItemsManager.FindVariant(ctx, obj, derefOptions(options))
+	if errors.Is(err, items.ErrVariantNotFound) {
+		return nil, nil
+	}
+	return variant, err
+}
+
+// Categories is the resolver for the categories field.
+func (r *itemResolver) Categories(ctx context.Context, obj *items.Item) ([]*items.Category, error) {
+	return r.ItemsManager.GetItemCategories(ctx, obj)
+}
+
+// Tags is the resolver for the tags field.
+func (r *itemResolver) Tags(ctx context.Context, obj *items.Item) ([]string, error) {
+	return r.ItemsManager.GetTags(ctx, obj)
+}
+
+// Item is the resolver for the item field.
+func (r *itemChangeResolver) Item(ctx context.Context, obj *items.Change) (*items.Item, error) {
+	return r.ItemsManager.ChangedItem(ctx, obj)
+}
+
+// Related is the resolver for the related field.
+func (r *itemChangeResolver) Related(ctx context.Context, obj *items.Change) (*items.Item, error) {
+	return r.ItemsManager.RelatedItem(ctx, obj)
+}
+
+// Actor is the resolver for the actor field.
+func (r *itemChangeResolver) Actor(ctx context.Context, obj *items.Change) (string, error) {
+	return string(obj.Actor), nil
+}
+
+// Item is the resolver for the item field.
+func (r *itemVariantResolver) Item(ctx context.Context, obj *items.Variant) (*items.Item, error) {
+	return r.ItemsManager.VariantItem(ctx, obj)
+}
+
 // CreateItem is the resolver for the createItem field.
 func (r *mutationResolver) CreateItem(ctx context.Context, input items.Details) (*model.ConfirmCreateItem, error) {
 	if input.UnitScale == "" {
@@ -37,15 +119,158 @@ func (r *mutationResolver) CreateItem(ctx context.Context, input items.Details)
 			Name:        input.Name,
 			Description: input.Description,
 			UnitScale:   input.UnitScale,
+			SKU:         input.SKU,
 		},
 	}, nil
 }
 
+// UpdateItem is the resolver for the updateItem field.
+func (r *mutationResolver) UpdateItem(ctx context.Context, id keys.OpaqueID, input model.UpdateItem) (*items.Item, error) {
+	var patch items.Patch
+	if name, ok := input.Name.ValueOK(); ok {
+		if name == nil {
+			name = new(string)
+		}
+		patch.Name = name
+	}
+	if description, ok := input.Description.ValueOK(); ok {
+		if description == nil {
+			description = new(string)
+		}
+		patch.Description = description
+	}
+	if scale, ok := input.UnitScale.ValueOK(); ok {
+		if scale == nil {
+			unit := items.Unit
+			scale = &unit
+		}
+		patch.UnitScale = scale
+	}
+	if sku, ok := input.Sku.ValueOK(); ok {
+		if sku == nil {
+			sku = new(string)
+		}
+		patch.SKU = sku
+	}
+
+	return r.ItemsManager.UpdateItem(ctx, &id, input.Version, patch)
+}
+
+// DeleteItem is the resolver for the deleteItem field.
+func (r *mutationResolver) DeleteItem(ctx context.Context, id keys.OpaqueID, version int) (*items.Item, error) {
+	return r.ItemsManager.ArchiveItem(ctx, &id, version)
+}
+
+// RestoreItem is the resolver for the restoreItem field.
+func (r *mutationResolver) RestoreItem(ctx context.Context, id keys.OpaqueID, version int) (*items.Item, error) {
+	return r.ItemsManager.RestoreItem(ctx, &id, version)
+}
+
+// AddItemChild is the resolver for the addItemChild field.
+func (r *mutationResolver) AddItemChild(ctx context.Context, parent keys.OpaqueID, child keys.OpaqueID, quantity *string) (*items.Item, error) {
+	return r.ItemsManager.AddChild(ctx, &parent, &child, quantity)
+}
+
+// RemoveItemChild is the resolver for the removeItemChild field.
+func (r *mutationResolver) RemoveItemChild(ctx context.Context, parent keys.OpaqueID, child keys.OpaqueID) (*items.Item, error) {
+	return r.ItemsManager.RemoveChild(ctx, &parent, &child)
+}
+
+// AddItemBarcode is the resolver for the addItemBarcode field.
+func (r *mutationResolver) AddItemBarcode(ctx context.Context, id keys.OpaqueID, code string) (*items.Item, error) {
+	return r.ItemsManager.AddBarcode(ctx, &id, code)
+}
+
+// RemoveItemBarcode is the resolver for the removeItemBarcode field.
+func (r *mutationResolver) RemoveItemBarcode(ctx context.Context, id keys.OpaqueID, code string) (*items.Item, error) {
+	return r.ItemsManager.RemoveBarcode(ctx, &id, code)
+}
+
+// SetItemOptionAxes is the resolver for the setItemOptionAxes field.
+func (r *mutationResolver) SetItemOptionAxes(ctx context.Context, id keys.OpaqueID, version int, axes []string) (*items.Item, error) {
+	return r.ItemsManager.SetOptionAxes(ctx, &id, version, axes)
+}
+
+// CreateItemVariant is the resolver for the createItemVariant field.
+func (r *mutationResolver) CreateItemVariant(ctx context.Context, item keys.OpaqueID, input model.NewVariant) (*items.Variant, error) {
+	deets := items.VariantDetails{
+		Options: derefOptions(input.Options),
+		SKU:     input.Sku,
+		Barcode: input.Barcode,
+	}
+	if input.PriceOverride != nil {
+		price, err := input.PriceOverride.Money()
+		if err != nil {
+			return nil, fmt.Errorf("invalid price override: %w", err)
+		}
+		deets.PriceOverride = &price
+	}
+
+	return r.ItemsManager.CreateVariant(ctx, &item, deets)
+}
+
+// BulkImportItems is the resolver for the bulkImportItems field.
+func (r *mutationResolver) BulkImportItems(ctx context.Context, input model.BulkImportItems) (*items.ImportResult, error) {
+	rows, errs, err := items.ParseImport(strings.NewReader(input.Data), input.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	var dryRun = input.DryRun != nil && *input.DryRun
+	if len(errs) > 0 {
+		return &items.ImportResult{DryRun: dryRun, Errors: errs}, nil
+	}
+
+	return r.ItemsManager.ImportItems(ctx, rows, dryRun)
+}
+
+// CreateCategory is the resolver for the createCategory field.
+func (r *mutationResolver) CreateCategory(ctx context.Context, name string, parent *keys.OpaqueID) (*items.Category, error) {
+	return r.ItemsManager.CreateCategory(ctx, name, parent)
+}
+
+// RenameCategory is the resolver for the renameCategory field.
+func (r *mutationResolver) RenameCategory(ctx context.Context, id keys.OpaqueID, name string) (*items.Category, error) {
+	return r.ItemsManager.RenameCategory(ctx, &id, name)
+}
+
+// MoveCategory is the resolver for the moveCategory field.
+func (r *mutationResolver) MoveCategory(ctx context.Context, id keys.OpaqueID, parent *keys.OpaqueID) (*items.Category, error) {
+	return r.ItemsManager.MoveCategory(ctx, &id, parent)
+}
+
+// DeleteCategory is the resolver for the deleteCategory field.
+func (r *mutationResolver) DeleteCategory(ctx context.Context, id keys.OpaqueID) (*items.Category, error) {
+	return r.ItemsManager.DeleteCategory(ctx, &id)
+}
+
+// CategoriseItem is the resolver for the categoriseItem field.
+func (r *mutationResolver) CategoriseItem(ctx context.Context, item keys.OpaqueID, category keys.OpaqueID) (*items.Item, error) {
+	return r.ItemsManager.CategoriseItem(ctx, &item, &category)
+}
+
+// UncategoriseItem is the resolver for the uncategoriseItem field.
+func (r *mutationResolver) UncategoriseItem(ctx context.Context, item keys.OpaqueID, category keys.OpaqueID) (*items.Item, error) {
+	return r.ItemsManager.UncategoriseItem(ctx, &item, &category)
+}
+
+// TagItem is the resolver for the tagItem field.
+func (r *mutationResolver) TagItem(ctx context.Context, item keys.OpaqueID, tag string) (*items.Item, error) {
+	return r.ItemsManager.TagItem(ctx, &item, tag)
+}
+
+// UntagItem is the resolver for the untagItem field.
+func (r *mutationResolver) UntagItem(ctx context.Context, item keys.OpaqueID, tag string) (*items.Item, error) {
+	return r.ItemsManager.UntagItem(ctx, &item, tag)
+}
+
 // Items is the resolver for the items field.
-func (r *queryResolver) Items(ctx context.Context, paginate *paginate.Paginate) ([]*items.Item, error) {
-	return r.ItemsManager.SearchItems(ctx, &items.ItemSearch{
-		Page: paginate,
-	})
+func (r *queryResolver) Items(ctx context.Context, paginate *paginate.Paginate, filter *items.ItemFilter) ([]*items.Item, error) {
+	var search = &items.ItemSearch{Page: paginate}
+	if filter != nil {
+		search.Filter = *filter
+	}
+	return r.ItemsManager.SearchItems(ctx, search)
 }
 
 // Item is the resolver for the item field.
@@ -53,17 +278,79 @@ func (r *queryResolver) Item(ctx context.Context, id *keys.OpaqueID) (*items.Ite
 	return r.ItemsManager.GetItem(ctx, id)
 }
 
+// Categories is the resolver for the categories field.
+func (r *queryResolver) Categories(ctx context.Context, parent *keys.OpaqueID) ([]*items.Category, error) {
+	return r.ItemsManager.GetCategories(ctx, parent)
+}
+
+// Category is the resolver for the category field.
+func (r *queryResolver) Category(ctx context.Context, id keys.OpaqueID) (*items.Category, error) {
+	category, err := r.ItemsManager.GetCategory(ctx, &id)
+	if errors.Is(err, items.ErrCategoryNotFound) {
+		return nil, nil
+	}
+	return category, err
+}
+
+// ItemHistory is the resolver for the itemHistory field.
+func (r *queryResolver) ItemHistory(ctx context.Context, actor string, paginate *paginate.Paginate) ([]*items.Change, error) {
+	return r.ItemsManager.ActorHistory(ctx, actor, paginate)
+}
+
+// Variant is the resolver for the variant field.
+func (r *queryResolver) Variant(ctx context.Context, id keys.OpaqueID) (*items.Variant, error) {
+	variant, err := r.ItemsManager.GetVariant(ctx, &id)
+	if errors.Is(err, items.ErrVariantNotFound) {
+		return nil, nil
+	}
+	return variant, err
+}
+
+// ItemBySku is the resolver for the itemBySku field.
+func (r *queryResolver) ItemBySku(ctx context.Context, sku string) (*items.Item, error) {
+	item, err := r.ItemsManager.GetItemBySKU(ctx, sku)
+	if errors.Is(err, items.ErrItemNotFound) {
+		return nil, nil
+	}
+	return item, err
+}
+
+// ItemByBarcode is the resolver for the itemByBarcode field.
+func (r *queryResolver) ItemByBarcode(ctx context.Context, code string) (*items.Item, error) {
+	item, err := r.ItemsManager.GetItemByBarcode(ctx, code)
+	if errors.Is(err, items.ErrItemNotFound) {
+		return nil, nil
+	}
+	return item, err
+}
+
+// Category returns graph.CategoryResolver implementation.
+func (r *Resolver) Category() graph.CategoryResolver { return &categoryResolver{r} }
+
 // ConfirmCreateItem returns graph.ConfirmCreateItemResolver implementation.
 func (r *Resolver) ConfirmCreateItem() graph.ConfirmCreateItemResolver {
 	return &confirmCreateItemResolver{r}
 }
 
+// Item returns graph.ItemResolver implementation.
+func (r *Resolver) Item() graph.ItemResolver { return &itemResolver{r} }
+
+// ItemChange returns graph.ItemChangeResolver implementation.
+func (r *Resolver) ItemChange() graph.ItemChangeResolver { return &itemChangeResolver{r} }
+
+// ItemVariant returns graph.ItemVariantResolver implementation.
+func (r *Resolver) ItemVariant() graph.ItemVariantResolver { return &itemVariantResolver{r} }
+
 // Mutation returns graph.MutationResolver implementation.
 func (r *Resolver) Mutation() graph.MutationResolver { return &mutationResolver{r} }
 
 // Query returns graph.QueryResolver implementation.
 func (r *Resolver) Query() graph.QueryResolver { return &queryResolver{r} }
 
+type categoryResolver struct{ *Resolver }
 type confirmCreateItemResolver struct{ *Resolver }
+type itemResolver struct{ *Resolver }
+type itemChangeResolver struct{ *Resolver }
+type itemVariantResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
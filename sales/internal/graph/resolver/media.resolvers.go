@@ -0,0 +1,52 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/graph/model"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/media"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// Images is the resolver for the images field.
+func (r *itemResolver) Images(ctx context.Context, obj *items.Item) ([]*media.Image, error) {
+	return r.MediaManager.GetImages(ctx, obj)
+}
+
+// Item is the resolver for the item field.
+func (r *itemImageResolver) Item(ctx context.Context, obj *media.Image) (*items.Item, error) {
+	return r.MediaManager.ImageItem(ctx, obj)
+}
+
+// URL is the resolver for the url field.
+func (r *itemImageResolver) URL(ctx context.Context, obj *media.Image) (*model.SignedURL, error) {
+	url, expires := r.MediaManager.URL(ctx, obj)
+	return &model.SignedURL{URL: url, ExpiresAt: expires}, nil
+}
+
+// AttachItemImage is the resolver for the attachItemImage field.
+func (r *mutationResolver) AttachItemImage(ctx context.Context, item keys.OpaqueID, file graphql.Upload) (*media.Image, error) {
+	return r.MediaManager.AttachImage(ctx, &item, file.File)
+}
+
+// RemoveItemImage is the resolver for the removeItemImage field.
+func (r *mutationResolver) RemoveItemImage(ctx context.Context, id keys.OpaqueID) (*media.Image, error) {
+	return r.MediaManager.RemoveImage(ctx, &id)
+}
+
+// ReorderItemImages is the resolver for the reorderItemImages field.
+func (r *mutationResolver) ReorderItemImages(ctx context.Context, item keys.OpaqueID, images []*keys.OpaqueID) ([]*media.Image, error) {
+	return r.MediaManager.ReorderImages(ctx, &item, images)
+}
+
+// ItemImage returns graph.ItemImageResolver implementation.
+func (r *Resolver) ItemImage() graph.ItemImageResolver { return &itemImageResolver{r} }
+
+type itemImageResolver struct{ *Resolver }
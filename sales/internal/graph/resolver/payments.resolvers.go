@@ -0,0 +1,47 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// PaySale is the resolver for the paySale field.
+func (r *mutationResolver) PaySale(ctx context.Context, input sales.NewPayment) (*sales.Sale, error) {
+	return r.SalesManager.PaySale(ctx, input)
+}
+
+// Actor is the resolver for the actor field.
+func (r *paymentResolver) Actor(ctx context.Context, obj *payments.Payment) (string, error) {
+	return string(obj.Actor), nil
+}
+
+// Paid is the resolver for the paid field.
+func (r *saleResolver) Paid(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	paid, err := obj.AmountPaid()
+	if err != nil {
+		return nil, err
+	}
+	return &paid, nil
+}
+
+// Due is the resolver for the due field.
+func (r *saleResolver) Due(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	due, err := obj.Due()
+	if err != nil {
+		return nil, err
+	}
+	return &due, nil
+}
+
+// Payment returns graph.PaymentResolver implementation.
+func (r *Resolver) Payment() graph.PaymentResolver { return &paymentResolver{r} }
+
+type paymentResolver struct{ *Resolver }
@@ -0,0 +1,65 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/pricing"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Price is the resolver for the price field.
+func (r *itemResolver) Price(ctx context.Context, obj *items.Item, list keys.OpaqueID, at *time.Time) (*money.Money, error) {
+	price, err := r.PricingManager.ItemPrice(ctx, obj, &list, at)
+	if errors.Is(err, pricing.ErrNoPrice) {
+		return nil, nil
+	}
+	return price, err
+}
+
+// Prices is the resolver for the prices field.
+func (r *itemResolver) Prices(ctx context.Context, obj *items.Item, list keys.OpaqueID) ([]*pricing.Price, error) {
+	return r.PricingManager.ItemPrices(ctx, obj, &list)
+}
+
+// PriceList is the resolver for the price_list field.
+func (r *itemPriceResolver) PriceList(ctx context.Context, obj *pricing.Price) (*pricing.PriceList, error) {
+	return r.PricingManager.PriceList(ctx, obj)
+}
+
+// CreatePriceList is the resolver for the createPriceList field.
+func (r *mutationResolver) CreatePriceList(ctx context.Context, input pricing.NewPriceList) (*pricing.PriceList, error) {
+	return r.PricingManager.CreatePriceList(ctx, input)
+}
+
+// SetItemPrice is the resolver for the setItemPrice field.
+func (r *mutationResolver) SetItemPrice(ctx context.Context, input pricing.NewPrice) (*pricing.Price, error) {
+	return r.PricingManager.SetItemPrice(ctx, input)
+}
+
+// PriceList is the resolver for the priceList field.
+func (r *queryResolver) PriceList(ctx context.Context, id keys.OpaqueID) (*pricing.PriceList, error) {
+	list, err := r.PricingManager.GetPriceList(ctx, &id)
+	if errors.Is(err, pricing.ErrPriceListNotFound) {
+		return nil, nil
+	}
+	return list, err
+}
+
+// PriceLists is the resolver for the priceLists field.
+func (r *queryResolver) PriceLists(ctx context.Context, retailer keys.OpaqueID) ([]*pricing.PriceList, error) {
+	return r.PricingManager.GetPriceLists(ctx, &retailer)
+}
+
+// ItemPrice returns graph.ItemPriceResolver implementation.
+func (r *Resolver) ItemPrice() graph.ItemPriceResolver { return &itemPriceResolver{r} }
+
+type itemPriceResolver struct{ *Resolver }
@@ -0,0 +1,169 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+	"errors"
+
+	"github.com/suessflorian/pedlar/sales/internal/customers"
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/pricing"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Promotion is the resolver for the promotion field.
+func (r *discountResolver) Promotion(ctx context.Context, obj *promotions.Discount) (*promotions.Promotion, error) {
+	return r.SalesManager.DiscountPromotion(ctx, obj)
+}
+
+// Discount is the resolver for the discount field.
+func (r *lineItemResolver) Discount(ctx context.Context, obj *sales.LineItem) (*money.Money, error) {
+	discount, err := obj.Discount()
+	if err != nil {
+		return nil, err
+	}
+	return &discount, nil
+}
+
+// Net is the resolver for the net field.
+func (r *lineItemResolver) Net(ctx context.Context, obj *sales.LineItem) (*money.Money, error) {
+	net, err := obj.Net()
+	if err != nil {
+		return nil, err
+	}
+	return &net, nil
+}
+
+// Item is the resolver for the item field.
+func (r *lineItemPreviewResolver) Item(ctx context.Context, obj *sales.LineItem) (*items.Item, error) {
+	return r.SalesManager.LineItemItem(ctx, obj)
+}
+
+// Quantity is the resolver for the quantity field.
+func (r *lineItemPreviewResolver) Quantity(ctx context.Context, obj *sales.LineItem) (string, error) {
+	return inventory.FormatQuantity(obj.Quantity), nil
+}
+
+// Total is the resolver for the total field.
+func (r *lineItemPreviewResolver) Total(ctx context.Context, obj *sales.LineItem) (*money.Money, error) {
+	total, err := obj.Total()
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// Discount is the resolver for the discount field.
+func (r *lineItemPreviewResolver) Discount(ctx context.Context, obj *sales.LineItem) (*money.Money, error) {
+	return r.LineItem().Discount(ctx, obj)
+}
+
+// Net is the resolver for the net field.
+func (r *lineItemPreviewResolver) Net(ctx context.Context, obj *sales.LineItem) (*money.Money, error) {
+	return r.LineItem().Net(ctx, obj)
+}
+
+// CreatePromotion is the resolver for the createPromotion field.
+func (r *mutationResolver) CreatePromotion(ctx context.Context, input promotions.NewPromotion) (*promotions.Promotion, error) {
+	return r.PromotionManager.CreatePromotion(ctx, input)
+}
+
+// EndPromotion is the resolver for the endPromotion field.
+func (r *mutationResolver) EndPromotion(ctx context.Context, id keys.OpaqueID) (*promotions.Promotion, error) {
+	return r.PromotionManager.EndPromotion(ctx, &id)
+}
+
+// Percent is the resolver for the percent field.
+func (r *promotionResolver) Percent(ctx context.Context, obj *promotions.Promotion) (*string, error) {
+	if obj.Percent == nil {
+		return nil, nil
+	}
+	percent := tax.FormatRate(obj.Percent)
+	return &percent, nil
+}
+
+// Promotion is the resolver for the promotion field.
+func (r *queryResolver) Promotion(ctx context.Context, id keys.OpaqueID) (*promotions.Promotion, error) {
+	promotion, err := r.PromotionManager.GetPromotion(ctx, &id)
+	if errors.Is(err, promotions.ErrPromotionNotFound) {
+		return nil, nil
+	}
+	return promotion, err
+}
+
+// Promotions is the resolver for the promotions field.
+func (r *queryResolver) Promotions(ctx context.Context, retailer keys.OpaqueID) ([]*promotions.Promotion, error) {
+	return r.PromotionManager.GetPromotions(ctx, &retailer)
+}
+
+// PreviewSale is the resolver for the previewSale field.
+func (r *queryResolver) PreviewSale(ctx context.Context, input sales.NewSale) (*sales.Sale, error) {
+	return r.SalesManager.PreviewSale(ctx, input)
+}
+
+// Discount is the resolver for the discount field.
+func (r *saleResolver) Discount(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	discount, err := obj.Discount()
+	if err != nil {
+		return nil, err
+	}
+	return &discount, nil
+}
+
+// Customer is the resolver for the customer field.
+func (r *salePreviewResolver) Customer(ctx context.Context, obj *sales.Sale) (*customers.Customer, error) {
+	return r.CustomerManager.SaleCustomer(ctx, obj)
+}
+
+// PriceList is the resolver for the price_list field.
+func (r *salePreviewResolver) PriceList(ctx context.Context, obj *sales.Sale) (*pricing.PriceList, error) {
+	return r.SalesManager.SalePriceList(ctx, obj)
+}
+
+// Subtotal is the resolver for the subtotal field.
+func (r *salePreviewResolver) Subtotal(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	return r.Sale().Subtotal(ctx, obj)
+}
+
+// Discount is the resolver for the discount field.
+func (r *salePreviewResolver) Discount(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	return r.Sale().Discount(ctx, obj)
+}
+
+// Tax is the resolver for the tax field.
+func (r *salePreviewResolver) Tax(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	return r.Sale().Tax(ctx, obj)
+}
+
+// Total is the resolver for the total field.
+func (r *salePreviewResolver) Total(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	return r.Sale().Total(ctx, obj)
+}
+
+// Discount returns graph.DiscountResolver implementation.
+func (r *Resolver) Discount() graph.DiscountResolver { return &discountResolver{r} }
+
+// LineItemPreview returns graph.LineItemPreviewResolver implementation.
+func (r *Resolver) LineItemPreview() graph.LineItemPreviewResolver {
+	return &lineItemPreviewResolver{r}
+}
+
+// Promotion returns graph.PromotionResolver implementation.
+func (r *Resolver) Promotion() graph.PromotionResolver { return &promotionResolver{r} }
+
+// SalePreview returns graph.SalePreviewResolver implementation.
+func (r *Resolver) SalePreview() graph.SalePreviewResolver { return &salePreviewResolver{r} }
+
+type discountResolver struct{ *Resolver }
+type lineItemPreviewResolver struct{ *Resolver }
+type promotionResolver struct{ *Resolver }
+type salePreviewResolver struct{ *Resolver }
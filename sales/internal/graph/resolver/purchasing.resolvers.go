@@ -0,0 +1,183 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+	"errors"
+
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/purchasing"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Suppliers is the resolver for the suppliers field.
+func (r *itemResolver) Suppliers(ctx context.Context, obj *items.Item) ([]*purchasing.SupplierItem, error) {
+	return r.PurchasingManager.ItemSuppliers(ctx, obj)
+}
+
+// CreateSupplier is the resolver for the createSupplier field.
+func (r *mutationResolver) CreateSupplier(ctx context.Context, input purchasing.NewSupplier) (*purchasing.Supplier, error) {
+	return r.PurchasingManager.CreateSupplier(ctx, input)
+}
+
+// UpdateSupplier is the resolver for the updateSupplier field.
+func (r *mutationResolver) UpdateSupplier(ctx context.Context, id keys.OpaqueID, patch purchasing.SupplierPatch) (*purchasing.Supplier, error) {
+	return r.PurchasingManager.UpdateSupplier(ctx, &id, patch)
+}
+
+// SetSupplierItem is the resolver for the setSupplierItem field.
+func (r *mutationResolver) SetSupplierItem(ctx context.Context, input purchasing.NewSupplierItem) (*purchasing.SupplierItem, error) {
+	return r.PurchasingManager.SetSupplierItem(ctx, input)
+}
+
+// RemoveSupplierItem is the resolver for the removeSupplierItem field.
+func (r *mutationResolver) RemoveSupplierItem(ctx context.Context, supplier keys.OpaqueID, item keys.OpaqueID) (*purchasing.SupplierItem, error) {
+	return r.PurchasingManager.RemoveSupplierItem(ctx, &supplier, &item)
+}
+
+// CreatePurchaseOrder is the resolver for the createPurchaseOrder field.
+func (r *mutationResolver) CreatePurchaseOrder(ctx context.Context, input purchasing.NewPurchaseOrder) (*purchasing.PurchaseOrder, error) {
+	return r.PurchasingManager.CreatePurchaseOrder(ctx, input)
+}
+
+// PlacePurchaseOrder is the resolver for the placePurchaseOrder field.
+func (r *mutationResolver) PlacePurchaseOrder(ctx context.Context, id keys.OpaqueID) (*purchasing.PurchaseOrder, error) {
+	return r.PurchasingManager.PlacePurchaseOrder(ctx, &id)
+}
+
+// CancelPurchaseOrder is the resolver for the cancelPurchaseOrder field.
+func (r *mutationResolver) CancelPurchaseOrder(ctx context.Context, id keys.OpaqueID) (*purchasing.PurchaseOrder, error) {
+	return r.PurchasingManager.CancelPurchaseOrder(ctx, &id)
+}
+
+// ReceivePurchaseOrder is the resolver for the receivePurchaseOrder field.
+func (r *mutationResolver) ReceivePurchaseOrder(ctx context.Context, id keys.OpaqueID, input purchasing.NewReceipt) (*purchasing.PurchaseOrder, error) {
+	return r.PurchasingManager.ReceivePurchaseOrder(ctx, &id, input)
+}
+
+// Supplier is the resolver for the supplier field.
+func (r *purchaseOrderResolver) Supplier(ctx context.Context, obj *purchasing.PurchaseOrder) (*purchasing.Supplier, error) {
+	return r.PurchasingManager.SupplierOf(ctx, obj.SupplierID)
+}
+
+// Location is the resolver for the location field.
+func (r *purchaseOrderResolver) Location(ctx context.Context, obj *purchasing.PurchaseOrder) (*inventory.Location, error) {
+	return r.InventoryManager.LocationOf(ctx, obj.LocationID)
+}
+
+// Total is the resolver for the total field.
+func (r *purchaseOrderResolver) Total(ctx context.Context, obj *purchasing.PurchaseOrder) (*money.Money, error) {
+	total, err := obj.Total()
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// Receipts is the resolver for the receipts field.
+func (r *purchaseOrderResolver) Receipts(ctx context.Context, obj *purchasing.PurchaseOrder) ([]*inventory.Movement, error) {
+	return r.PurchasingManager.Receipts(ctx, obj)
+}
+
+// CreatedBy is the resolver for the created_by field.
+func (r *purchaseOrderResolver) CreatedBy(ctx context.Context, obj *purchasing.PurchaseOrder) (string, error) {
+	return string(obj.CreatedBy), nil
+}
+
+// Item is the resolver for the item field.
+func (r *purchaseOrderLineResolver) Item(ctx context.Context, obj *purchasing.Line) (*items.Item, error) {
+	return r.InventoryManager.ItemOf(ctx, obj.ItemID)
+}
+
+// Quantity is the resolver for the quantity field.
+func (r *purchaseOrderLineResolver) Quantity(ctx context.Context, obj *purchasing.Line) (string, error) {
+	return inventory.FormatQuantity(obj.Quantity), nil
+}
+
+// Received is the resolver for the received field.
+func (r *purchaseOrderLineResolver) Received(ctx context.Context, obj *purchasing.Line) (string, error) {
+	return inventory.FormatQuantity(obj.Received), nil
+}
+
+// Outstanding is the resolver for the outstanding field.
+func (r *purchaseOrderLineResolver) Outstanding(ctx context.Context, obj *purchasing.Line) (string, error) {
+	return inventory.FormatQuantity(obj.Outstanding()), nil
+}
+
+// Cost is the resolver for the cost field.
+func (r *purchaseOrderLineResolver) Cost(ctx context.Context, obj *purchasing.Line) (*money.Money, error) {
+	cost, err := obj.Cost()
+	if err != nil {
+		return nil, err
+	}
+	return &cost, nil
+}
+
+// Supplier is the resolver for the supplier field.
+func (r *queryResolver) Supplier(ctx context.Context, id keys.OpaqueID) (*purchasing.Supplier, error) {
+	supplier, err := r.PurchasingManager.GetSupplier(ctx, &id)
+	if errors.Is(err, purchasing.ErrSupplierNotFound) {
+		return nil, nil
+	}
+	return supplier, err
+}
+
+// Suppliers is the resolver for the suppliers field.
+func (r *queryResolver) Suppliers(ctx context.Context) ([]*purchasing.Supplier, error) {
+	return r.PurchasingManager.GetSuppliers(ctx)
+}
+
+// PurchaseOrder is the resolver for the purchaseOrder field.
+func (r *queryResolver) PurchaseOrder(ctx context.Context, id keys.OpaqueID) (*purchasing.PurchaseOrder, error) {
+	order, err := r.PurchasingManager.GetPurchaseOrder(ctx, &id)
+	if errors.Is(err, purchasing.ErrOrderNotFound) {
+		return nil, nil
+	}
+	return order, err
+}
+
+// PurchaseOrders is the resolver for the purchaseOrders field.
+func (r *queryResolver) PurchaseOrders(ctx context.Context, filter *purchasing.OrderFilter, paginate *paginate.Paginate) ([]*purchasing.PurchaseOrder, error) {
+	return r.PurchasingManager.SearchPurchaseOrders(ctx, filter, paginate)
+}
+
+// Items is the resolver for the items field.
+func (r *supplierResolver) Items(ctx context.Context, obj *purchasing.Supplier) ([]*purchasing.SupplierItem, error) {
+	return r.PurchasingManager.SupplierItems(ctx, obj)
+}
+
+// Supplier is the resolver for the supplier field.
+func (r *supplierItemResolver) Supplier(ctx context.Context, obj *purchasing.SupplierItem) (*purchasing.Supplier, error) {
+	return r.PurchasingManager.SupplierOf(ctx, obj.SupplierID)
+}
+
+// Item is the resolver for the item field.
+func (r *supplierItemResolver) Item(ctx context.Context, obj *purchasing.SupplierItem) (*items.Item, error) {
+	return r.InventoryManager.ItemOf(ctx, obj.ItemID)
+}
+
+// PurchaseOrder returns graph.PurchaseOrderResolver implementation.
+func (r *Resolver) PurchaseOrder() graph.PurchaseOrderResolver { return &purchaseOrderResolver{r} }
+
+// PurchaseOrderLine returns graph.PurchaseOrderLineResolver implementation.
+func (r *Resolver) PurchaseOrderLine() graph.PurchaseOrderLineResolver {
+	return &purchaseOrderLineResolver{r}
+}
+
+// Supplier returns graph.SupplierResolver implementation.
+func (r *Resolver) Supplier() graph.SupplierResolver { return &supplierResolver{r} }
+
+// SupplierItem returns graph.SupplierItemResolver implementation.
+func (r *Resolver) SupplierItem() graph.SupplierItemResolver { return &supplierItemResolver{r} }
+
+type purchaseOrderResolver struct{ *Resolver }
+type purchaseOrderLineResolver struct{ *Resolver }
+type supplierResolver struct{ *Resolver }
+type supplierItemResolver struct{ *Resolver }
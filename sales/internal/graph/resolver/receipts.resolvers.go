@@ -0,0 +1,26 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+
+	"github.com/suessflorian/pedlar/sales/internal/receipts"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+)
+
+// Receipt is the resolver for the receipt field.
+func (r *saleResolver) Receipt(ctx context.Context, obj *sales.Sale, format receipts.Format) (string, error) {
+	var receipt bytes.Buffer
+	if err := r.ReceiptManager.Render(ctx, &receipt, obj, format); err != nil {
+		return "", err
+	}
+	if format.Binary() {
+		return base64.StdEncoding.EncodeToString(receipt.Bytes()), nil
+	}
+	return receipt.String(), nil
+}
@@ -0,0 +1,85 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// RefundSale is the resolver for the refundSale field.
+func (r *mutationResolver) RefundSale(ctx context.Context, input sales.NewRefund) (*sales.Refund, error) {
+	return r.SalesManager.RefundSale(ctx, input)
+}
+
+// VoidSale is the resolver for the voidSale field.
+func (r *mutationResolver) VoidSale(ctx context.Context, id keys.OpaqueID, reason string) (*sales.Sale, error) {
+	return r.SalesManager.VoidSale(ctx, &id, reason)
+}
+
+// RetryReversals is the resolver for the retryReversals field.
+func (r *mutationResolver) RetryReversals(ctx context.Context, id keys.OpaqueID) (*sales.Sale, error) {
+	return r.SalesManager.RetryReversals(ctx, &id)
+}
+
+// Actor is the resolver for the actor field.
+func (r *refundResolver) Actor(ctx context.Context, obj *sales.Refund) (string, error) {
+	return string(obj.Actor), nil
+}
+
+// Amount is the resolver for the amount field.
+func (r *refundResolver) Amount(ctx context.Context, obj *sales.Refund) (*money.Money, error) {
+	amount, err := obj.Amount()
+	if err != nil {
+		return nil, err
+	}
+	return &amount, nil
+}
+
+// Quantity is the resolver for the quantity field.
+func (r *refundLineResolver) Quantity(ctx context.Context, obj *sales.RefundLine) (string, error) {
+	return inventory.FormatQuantity(obj.Quantity), nil
+}
+
+// Refunded is the resolver for the refunded field.
+func (r *saleResolver) Refunded(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	refunded, err := obj.Refunded()
+	if err != nil {
+		return nil, err
+	}
+	return &refunded, nil
+}
+
+// NetTotal is the resolver for the net_total field.
+func (r *saleResolver) NetTotal(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	total, err := obj.NetTotal()
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// Actor is the resolver for the actor field.
+func (r *saleVoidResolver) Actor(ctx context.Context, obj *sales.Void) (string, error) {
+	return string(obj.Actor), nil
+}
+
+// Refund returns graph.RefundResolver implementation.
+func (r *Resolver) Refund() graph.RefundResolver { return &refundResolver{r} }
+
+// RefundLine returns graph.RefundLineResolver implementation.
+func (r *Resolver) RefundLine() graph.RefundLineResolver { return &refundLineResolver{r} }
+
+// SaleVoid returns graph.SaleVoidResolver implementation.
+func (r *Resolver) SaleVoid() graph.SaleVoidResolver { return &saleVoidResolver{r} }
+
+type refundResolver struct{ *Resolver }
+type refundLineResolver struct{ *Resolver }
+type saleVoidResolver struct{ *Resolver }
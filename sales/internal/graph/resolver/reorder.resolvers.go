@@ -0,0 +1,121 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/reorder"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+// SetReorderPoint is the resolver for the setReorderPoint field.
+func (r *mutationResolver) SetReorderPoint(ctx context.Context, input reorder.NewPoint) (*reorder.Point, error) {
+	return r.ReorderManager.SetReorderPoint(ctx, input)
+}
+
+// RemoveReorderPoint is the resolver for the removeReorderPoint field.
+func (r *mutationResolver) RemoveReorderPoint(ctx context.Context, item keys.OpaqueID, location keys.OpaqueID) (*reorder.Point, error) {
+	return r.ReorderManager.RemoveReorderPoint(ctx, &item, &location)
+}
+
+// ReorderPoints is the resolver for the reorderPoints field.
+func (r *queryResolver) ReorderPoints(ctx context.Context, filter *inventory.Filter) ([]*reorder.Point, error) {
+	return r.ReorderManager.ReorderPoints(ctx, filter)
+}
+
+// StockAlerts is the resolver for the stockAlerts field.
+func (r *queryResolver) StockAlerts(ctx context.Context, filter *reorder.AlertFilter, paginate *paginate.Paginate) ([]*reorder.Alert, error) {
+	return r.ReorderManager.SearchAlerts(ctx, filter, paginate)
+}
+
+// ReorderSuggestions is the resolver for the reorderSuggestions field.
+func (r *queryResolver) ReorderSuggestions(ctx context.Context, location *keys.OpaqueID) ([]*reorder.Suggestion, error) {
+	return r.ReorderManager.Suggestions(ctx, location)
+}
+
+// Item is the resolver for the item field.
+func (r *reorderPointResolver) Item(ctx context.Context, obj *reorder.Point) (*items.Item, error) {
+	return r.InventoryManager.ItemOf(ctx, obj.ItemID)
+}
+
+// Location is the resolver for the location field.
+func (r *reorderPointResolver) Location(ctx context.Context, obj *reorder.Point) (*inventory.Location, error) {
+	return r.InventoryManager.LocationOf(ctx, obj.LocationID)
+}
+
+// Threshold is the resolver for the threshold field.
+func (r *reorderPointResolver) Threshold(ctx context.Context, obj *reorder.Point) (string, error) {
+	return inventory.FormatQuantity(obj.Threshold), nil
+}
+
+// Item is the resolver for the item field.
+func (r *reorderSuggestionResolver) Item(ctx context.Context, obj *reorder.Suggestion) (*items.Item, error) {
+	return r.InventoryManager.ItemOf(ctx, obj.ItemID)
+}
+
+// Location is the resolver for the location field.
+func (r *reorderSuggestionResolver) Location(ctx context.Context, obj *reorder.Suggestion) (*inventory.Location, error) {
+	return r.InventoryManager.LocationOf(ctx, obj.LocationID)
+}
+
+// OnHand is the resolver for the on_hand field.
+func (r *reorderSuggestionResolver) OnHand(ctx context.Context, obj *reorder.Suggestion) (string, error) {
+	return inventory.FormatQuantity(obj.OnHand), nil
+}
+
+// Threshold is the resolver for the threshold field.
+func (r *reorderSuggestionResolver) Threshold(ctx context.Context, obj *reorder.Suggestion) (string, error) {
+	return inventory.FormatQuantity(obj.Threshold), nil
+}
+
+// DailySales is the resolver for the daily_sales field.
+func (r *reorderSuggestionResolver) DailySales(ctx context.Context, obj *reorder.Suggestion) (string, error) {
+	return inventory.FormatQuantity(obj.DailySales), nil
+}
+
+// Quantity is the resolver for the quantity field.
+func (r *reorderSuggestionResolver) Quantity(ctx context.Context, obj *reorder.Suggestion) (string, error) {
+	return inventory.FormatQuantity(obj.Quantity), nil
+}
+
+// Item is the resolver for the item field.
+func (r *stockAlertResolver) Item(ctx context.Context, obj *reorder.Alert) (*items.Item, error) {
+	return r.InventoryManager.ItemOf(ctx, obj.ItemID)
+}
+
+// Location is the resolver for the location field.
+func (r *stockAlertResolver) Location(ctx context.Context, obj *reorder.Alert) (*inventory.Location, error) {
+	return r.InventoryManager.LocationOf(ctx, obj.LocationID)
+}
+
+// OnHand is the resolver for the on_hand field.
+func (r *stockAlertResolver) OnHand(ctx context.Context, obj *reorder.Alert) (string, error) {
+	return inventory.FormatQuantity(obj.OnHand), nil
+}
+
+// Threshold is the resolver for the threshold field.
+func (r *stockAlertResolver) Threshold(ctx context.Context, obj *reorder.Alert) (string, error) {
+	return inventory.FormatQuantity(obj.Threshold), nil
+}
+
+// ReorderPoint returns graph.ReorderPointResolver implementation.
+func (r *Resolver) ReorderPoint() graph.ReorderPointResolver { return &reorderPointResolver{r} }
+
+// ReorderSuggestion returns graph.ReorderSuggestionResolver implementation.
+func (r *Resolver) ReorderSuggestion() graph.ReorderSuggestionResolver {
+	return &reorderSuggestionResolver{r}
+}
+
+// StockAlert returns graph.StockAlertResolver implementation.
+func (r *Resolver) StockAlert() graph.StockAlertResolver { return &stockAlertResolver{r} }
+
+type reorderPointResolver struct{ *Resolver }
+type reorderSuggestionResolver struct{ *Resolver }
+type stockAlertResolver struct{ *Resolver }
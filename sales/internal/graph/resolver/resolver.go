@@ -1,7 +1,39 @@
 package resolver
 
-import "github.com/suessflorian/pedlar/sales/internal/items"
+import (
+	"github.com/suessflorian/pedlar/sales/internal/customers"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/media"
+	"github.com/suessflorian/pedlar/sales/internal/pricing"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/purchasing"
+	"github.com/suessflorian/pedlar/sales/internal/receipts"
+	"github.com/suessflorian/pedlar/sales/internal/reorder"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+)
 
 type Resolver struct {
-	ItemsManager items.ItemManager
+	CustomerManager   customers.CustomerManager
+	InventoryManager  inventory.InventoryManager
+	ItemsManager      items.ItemManager
+	MediaManager      media.ImageManager
+	PricingManager    pricing.PriceManager
+	PromotionManager  promotions.PromotionManager
+	PurchasingManager purchasing.PurchaseManager
+	ReceiptManager    receipts.ReceiptManager
+	ReorderManager    reorder.ReorderManager
+	RetailerManager   retailers.RetailerManager
+	SalesManager      sales.SaleManager
+	TaxManager        tax.TaxManager
+}
+
+func derefOptions(options []*items.Option) []items.Option {
+	var deref = make([]items.Option, 0, len(options))
+	for _, option := range options {
+		deref = append(deref, *option)
+	}
+	return deref
 }
@@ -0,0 +1,61 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// CreateRetailer is the resolver for the createRetailer field.
+func (r *mutationResolver) CreateRetailer(ctx context.Context, input retailers.NewRetailer) (*retailers.Retailer, error) {
+	return r.RetailerManager.CreateRetailer(ctx, input)
+}
+
+// UpdateRetailer is the resolver for the updateRetailer field.
+func (r *mutationResolver) UpdateRetailer(ctx context.Context, id keys.OpaqueID, patch retailers.RetailerPatch) (*retailers.Retailer, error) {
+	return r.RetailerManager.UpdateRetailer(ctx, &id, patch)
+}
+
+// DeleteRetailer is the resolver for the deleteRetailer field.
+func (r *mutationResolver) DeleteRetailer(ctx context.Context, id keys.OpaqueID) (*retailers.Retailer, error) {
+	return r.RetailerManager.DeleteRetailer(ctx, &id)
+}
+
+// AddRetailerDomain is the resolver for the addRetailerDomain field.
+func (r *mutationResolver) AddRetailerDomain(ctx context.Context, id keys.OpaqueID, domain string) (*retailers.Retailer, error) {
+	return r.RetailerManager.AddDomain(ctx, &id, domain)
+}
+
+// RemoveRetailerDomain is the resolver for the removeRetailerDomain field.
+func (r *mutationResolver) RemoveRetailerDomain(ctx context.Context, id keys.OpaqueID, domain string) (*retailers.Retailer, error) {
+	return r.RetailerManager.RemoveDomain(ctx, &id, domain)
+}
+
+// Retailer is the resolver for the retailer field.
+func (r *queryResolver) Retailer(ctx context.Context, id *keys.OpaqueID) (*retailers.Retailer, error) {
+	if id == nil {
+		return r.RetailerManager.CurrentRetailer(ctx)
+	}
+	return r.RetailerManager.GetRetailer(ctx, id)
+}
+
+// Retailers is the resolver for the retailers field.
+func (r *queryResolver) Retailers(ctx context.Context) ([]*retailers.Retailer, error) {
+	return r.RetailerManager.GetRetailers(ctx)
+}
+
+// Domains is the resolver for the domains field.
+func (r *retailerResolver) Domains(ctx context.Context, obj *retailers.Retailer) ([]string, error) {
+	return r.RetailerManager.GetDomains(ctx, obj)
+}
+
+// Retailer returns graph.RetailerResolver implementation.
+func (r *Resolver) Retailer() graph.RetailerResolver { return &retailerResolver{r} }
+
+type retailerResolver struct{ *Resolver }
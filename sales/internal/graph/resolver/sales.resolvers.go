@@ -0,0 +1,95 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+	"errors"
+
+	"github.com/suessflorian/pedlar/sales/internal/customers"
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/pricing"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Item is the resolver for the item field.
+func (r *lineItemResolver) Item(ctx context.Context, obj *sales.LineItem) (*items.Item, error) {
+	return r.SalesManager.LineItemItem(ctx, obj)
+}
+
+// Quantity is the resolver for the quantity field.
+func (r *lineItemResolver) Quantity(ctx context.Context, obj *sales.LineItem) (string, error) {
+	return inventory.FormatQuantity(obj.Quantity), nil
+}
+
+// Total is the resolver for the total field.
+func (r *lineItemResolver) Total(ctx context.Context, obj *sales.LineItem) (*money.Money, error) {
+	total, err := obj.Total()
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// RecordSale is the resolver for the recordSale field.
+func (r *mutationResolver) RecordSale(ctx context.Context, input sales.NewSale) (*sales.Sale, error) {
+	return r.SalesManager.RecordSale(ctx, input)
+}
+
+// Actor is the resolver for the actor field.
+func (r *priceOverrideResolver) Actor(ctx context.Context, obj *sales.PriceOverride) (string, error) {
+	return string(obj.Actor), nil
+}
+
+// Sale is the resolver for the sale field.
+func (r *queryResolver) Sale(ctx context.Context, id keys.OpaqueID) (*sales.Sale, error) {
+	sale, err := r.SalesManager.GetSale(ctx, &id)
+	if errors.Is(err, sales.ErrSaleNotFound) {
+		return nil, nil
+	}
+	return sale, err
+}
+
+// Sales is the resolver for the sales field.
+func (r *queryResolver) Sales(ctx context.Context, filter *sales.SaleFilter, paginate *paginate.Paginate) ([]*sales.Sale, error) {
+	return r.SalesManager.SearchSales(ctx, filter, paginate)
+}
+
+// Customer is the resolver for the customer field.
+func (r *saleResolver) Customer(ctx context.Context, obj *sales.Sale) (*customers.Customer, error) {
+	return r.CustomerManager.SaleCustomer(ctx, obj)
+}
+
+// PriceList is the resolver for the price_list field.
+func (r *saleResolver) PriceList(ctx context.Context, obj *sales.Sale) (*pricing.PriceList, error) {
+	return r.SalesManager.SalePriceList(ctx, obj)
+}
+
+// Total is the resolver for the total field.
+func (r *saleResolver) Total(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	total, err := obj.Total()
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// LineItem returns graph.LineItemResolver implementation.
+func (r *Resolver) LineItem() graph.LineItemResolver { return &lineItemResolver{r} }
+
+// PriceOverride returns graph.PriceOverrideResolver implementation.
+func (r *Resolver) PriceOverride() graph.PriceOverrideResolver { return &priceOverrideResolver{r} }
+
+// Sale returns graph.SaleResolver implementation.
+func (r *Resolver) Sale() graph.SaleResolver { return &saleResolver{r} }
+
+type lineItemResolver struct{ *Resolver }
+type priceOverrideResolver struct{ *Resolver }
+type saleResolver struct{ *Resolver }
@@ -0,0 +1,89 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/graph"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// CreateTaxRate is the resolver for the createTaxRate field.
+func (r *mutationResolver) CreateTaxRate(ctx context.Context, input tax.NewRate) (*tax.Rate, error) {
+	return r.TaxManager.CreateRate(ctx, input)
+}
+
+// SetTaxSettings is the resolver for the setTaxSettings field.
+func (r *mutationResolver) SetTaxSettings(ctx context.Context, input tax.NewSettings) (*tax.Settings, error) {
+	return r.TaxManager.SetSettings(ctx, input)
+}
+
+// SetItemTaxClass is the resolver for the setItemTaxClass field.
+func (r *mutationResolver) SetItemTaxClass(ctx context.Context, id keys.OpaqueID, version int, taxClass string) (*items.Item, error) {
+	return r.ItemsManager.SetTaxClass(ctx, &id, version, taxClass)
+}
+
+// TaxRates is the resolver for the taxRates field.
+func (r *queryResolver) TaxRates(ctx context.Context, jurisdiction string, at *time.Time) ([]*tax.Rate, error) {
+	return r.TaxManager.Rates(ctx, jurisdiction, at)
+}
+
+// TaxSettings is the resolver for the taxSettings field.
+func (r *queryResolver) TaxSettings(ctx context.Context, retailer keys.OpaqueID) (*tax.Settings, error) {
+	settings, err := r.TaxManager.GetSettings(ctx, &retailer)
+	if errors.Is(err, tax.ErrNoSettings) {
+		return nil, nil
+	}
+	return settings, err
+}
+
+// Subtotal is the resolver for the subtotal field.
+func (r *saleResolver) Subtotal(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	subtotal, err := obj.Subtotal()
+	if err != nil {
+		return nil, err
+	}
+	return &subtotal, nil
+}
+
+// Tax is the resolver for the tax field.
+func (r *saleResolver) Tax(ctx context.Context, obj *sales.Sale) (*money.Money, error) {
+	charged, err := obj.Tax()
+	if err != nil {
+		return nil, err
+	}
+	return &charged, nil
+}
+
+// TaxRate is the resolver for the tax_rate field.
+func (r *saleTaxResolver) TaxRate(ctx context.Context, obj *tax.Line) (*tax.Rate, error) {
+	return r.TaxManager.RateByID(ctx, obj.RateID.ID)
+}
+
+// Rate is the resolver for the rate field.
+func (r *saleTaxResolver) Rate(ctx context.Context, obj *tax.Line) (string, error) {
+	return tax.FormatRate(obj.Rate), nil
+}
+
+// Rate is the resolver for the rate field.
+func (r *taxRateResolver) Rate(ctx context.Context, obj *tax.Rate) (string, error) {
+	return tax.FormatRate(obj.Rate), nil
+}
+
+// SaleTax returns graph.SaleTaxResolver implementation.
+func (r *Resolver) SaleTax() graph.SaleTaxResolver { return &saleTaxResolver{r} }
+
+// TaxRate returns graph.TaxRateResolver implementation.
+func (r *Resolver) TaxRate() graph.TaxRateResolver { return &taxRateResolver{r} }
+
+type saleTaxResolver struct{ *Resolver }
+type taxRateResolver struct{ *Resolver }
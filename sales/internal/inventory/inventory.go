@@ -0,0 +1,453 @@
+// Package inventory keeps a ledger of every movement of stock, and the stock on hand it adds up to.
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+type store interface {
+	// CreateLocation adds the location to the retailer of the context with its id assigned in
+	// place, the first location of a retailer is its default.
+	CreateLocation(context.Context, *Location) error
+	GetLocation(context.Context, int) (*Location, error)
+	GetLocations(context.Context) ([]*Location, error)
+	// UpdateLocation takes the default away from any other location when the location is made
+	// the default.
+	UpdateLocation(context.Context, *Location) error
+
+	// PostMovements appends the movements to the ledger all at once, with their ids and times
+	// assigned in place.
+	PostMovements(context.Context, []*Movement) error
+	// PageMovements pages through the movements that match the filter, most recent first.
+	PageMovements(context.Context, Filter, paginate.Paginate) ([]*Movement, error)
+	// GetLevels is the stock on hand that matches the filter, ordered by item then location.
+	GetLevels(context.Context, Filter) ([]*Level, error)
+	// GetComponents is what one of the item is made of down to the items without children, none
+	// when the item isn't a kit.
+	GetComponents(ctx context.Context, item int) ([]*Component, error)
+}
+
+// itemStore resolves the items that stock is of.
+type itemStore interface {
+	GetItem(context.Context, int) (*items.Item, error)
+}
+
+type InventoryManager struct {
+	Store store
+	Items itemStore
+}
+
+var (
+	ErrLocationNotFound  = errors.New("stock location not found")
+	ErrNoNameLocation    = errors.New("stock location must have a name")
+	ErrDuplicateLocation = errors.New("stock location with this name already exists")
+	// ErrDefaultLocation is returned when unsetting the default location, another location is
+	// made the default instead.
+	ErrDefaultLocation = errors.New("default stock location can only be replaced by another")
+	ErrQuantity        = errors.New("quantity must be a decimal number, ie 2.5")
+	ErrQuantityPlaces  = errors.New("quantity must be a whole number of ten thousandths of the unit scale of the item")
+	ErrNotPositive     = errors.New("quantity must be positive")
+	ErrNoChange        = errors.New("adjustment must change the stock on hand")
+	ErrNoNote          = errors.New("adjustment must give a reason in its note")
+	ErrSameLocation    = errors.New("stock must be transferred between different locations")
+	// ErrKitStock is returned when moving stock of a kit, kits hold no stock of their own and
+	// their components are moved instead.
+	ErrKitStock = errors.New("kit holds no stock of its own, move stock of its components")
+)
+
+var quantityPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+type NewLocation struct {
+	Name    string
+	Default bool
+}
+
+// LocationPatch changes the fields that are set.
+type LocationPatch struct {
+	Name    *string
+	Default *bool
+}
+
+// NewMovement receives or adjusts stock of an item at a location, the quantity is in the unit
+// scale of the item unless another unit scale of the same dimension is given.
+type NewMovement struct {
+	Item      *keys.OpaqueID
+	Location  *keys.OpaqueID
+	Quantity  string
+	UnitScale *items.UnitScale
+	Note      *string
+}
+
+type NewTransfer struct {
+	Item      *keys.OpaqueID
+	From      *keys.OpaqueID
+	To        *keys.OpaqueID
+	Quantity  string
+	UnitScale *items.UnitScale
+	Note      *string
+}
+
+// Filter narrows stock down to an item, a location, or both.
+type Filter struct {
+	Item     *keys.OpaqueID
+	Location *keys.OpaqueID
+}
+
+func (m *InventoryManager) CreateLocation(ctx context.Context, input NewLocation) (*Location, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	location := &Location{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		Name:       strings.TrimSpace(input.Name),
+		Default:    input.Default,
+	}
+	if location.Name == "" {
+		return nil, ErrNoNameLocation
+	}
+
+	if err := m.Store.CreateLocation(ctx, location); err != nil {
+		return nil, fmt.Errorf("failed to create stock location: %w", err)
+	}
+	return location, nil
+}
+
+func (m *InventoryManager) GetLocation(ctx context.Context, externalID *keys.OpaqueID) (*Location, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	return m.Store.GetLocation(ctx, id)
+}
+
+func (m *InventoryManager) GetLocations(ctx context.Context) ([]*Location, error) {
+	return m.Store.GetLocations(ctx)
+}
+
+func (m *InventoryManager) UpdateLocation(ctx context.Context, externalID *keys.OpaqueID, patch LocationPatch) (*Location, error) {
+	location, err := m.GetLocation(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Name != nil {
+		if location.Name = strings.TrimSpace(*patch.Name); location.Name == "" {
+			return nil, ErrNoNameLocation
+		}
+	}
+	if patch.Default != nil {
+		if location.Default && !*patch.Default {
+			return nil, ErrDefaultLocation
+		}
+		location.Default = *patch.Default
+	}
+
+	if err := m.Store.UpdateLocation(ctx, location); err != nil {
+		return nil, fmt.Errorf("failed to update stock location: %w", err)
+	}
+	return location, nil
+}
+
+// ReceiveStock brings stock of the item into the location, ie when a delivery arrives.
+func (m *InventoryManager) ReceiveStock(ctx context.Context, input NewMovement) (*Movement, error) {
+	movement, err := m.movement(ctx, input.Item, input.Location, input.Quantity, input.UnitScale)
+	if err != nil {
+		return nil, err
+	}
+	if movement.Quantity.Sign() <= 0 {
+		return nil, ErrNotPositive
+	}
+	movement.Reason, movement.Note = Receipt, input.Note
+
+	if err := m.Store.PostMovements(ctx, []*Movement{movement}); err != nil {
+		return nil, fmt.Errorf("failed to post stock receipt: %w", err)
+	}
+	return movement, nil
+}
+
+// AdjustStock corrects the stock on hand of the item at the location by the quantity, ie after a
+// stocktake or when stock is damaged. Adjustments must say why.
+func (m *InventoryManager) AdjustStock(ctx context.Context, input NewMovement) (*Movement, error) {
+	if input.Note == nil || strings.TrimSpace(*input.Note) == "" {
+		return nil, ErrNoNote
+	}
+
+	movement, err := m.movement(ctx, input.Item, input.Location, input.Quantity, input.UnitScale)
+	if err != nil {
+		return nil, err
+	}
+	if movement.Quantity.Sign() == 0 {
+		return nil, ErrNoChange
+	}
+	movement.Reason, movement.Note = Adjustment, input.Note
+
+	if err := m.Store.PostMovements(ctx, []*Movement{movement}); err != nil {
+		return nil, fmt.Errorf("failed to post stock adjustment: %w", err)
+	}
+	return movement, nil
+}
+
+// TransferStock moves stock of the item from one location to another, the stock leaving and
+// arriving are posted together.
+func (m *InventoryManager) TransferStock(ctx context.Context, input NewTransfer) ([]*Movement, error) {
+	out, err := m.movement(ctx, input.Item, input.From, input.Quantity, input.UnitScale)
+	if err != nil {
+		return nil, err
+	}
+	if out.Quantity.Sign() <= 0 {
+		return nil, ErrNotPositive
+	}
+
+	to, err := input.To.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode location id: %w", err)
+	}
+	if to == out.LocationID.ID {
+		return nil, ErrSameLocation
+	}
+
+	in := &Movement{
+		ItemID:             out.ItemID,
+		LocationID:         &keys.OpaqueID{ID: to},
+		Quantity:           new(big.Rat).Set(out.Quantity),
+		Reason:             Transfer,
+		TransferLocationID: out.LocationID,
+		Note:               input.Note,
+		Actor:              out.Actor,
+	}
+	out.Quantity.Neg(out.Quantity)
+	out.Reason, out.TransferLocationID, out.Note = Transfer, in.LocationID, input.Note
+
+	var movements = []*Movement{out, in}
+	if err := m.Store.PostMovements(ctx, movements); err != nil {
+		return nil, fmt.Errorf("failed to post stock transfer: %w", err)
+	}
+	return movements, nil
+}
+
+// movement starts a movement of the item at the location, the quantity is converted into the unit
+// scale of the item.
+func (m *InventoryManager) movement(ctx context.Context, itemID, locationID *keys.OpaqueID, quantity string, scale *items.UnitScale) (*Movement, error) {
+	id, err := itemID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode item id: %w", err)
+	}
+	location, err := locationID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode location id: %w", err)
+	}
+
+	item, err := m.Items.GetItem(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item of stock: %w", err)
+	}
+
+	components, err := m.Store.GetComponents(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get components of item: %w", err)
+	}
+	if len(components) > 0 {
+		return nil, ErrKitStock
+	}
+
+	amount, err := ParseQuantity(quantity, scale, item.UnitScale)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Movement{
+		ItemID:     &keys.OpaqueID{ID: id},
+		LocationID: &keys.OpaqueID{ID: location},
+		Quantity:   amount,
+		Actor:      actor.From(ctx),
+	}, nil
+}
+
+// ParseQuantity reads the decimal quantity given in the unit scale and converts it into the unit
+// scale of the item, ie 250 in g is 0.25 for an item sold by the kg. Quantities in no unit scale
+// are in the unit scale of the item already.
+func ParseQuantity(quantity string, given *items.UnitScale, scale items.UnitScale) (*big.Rat, error) {
+	quantity = strings.TrimSpace(quantity)
+	if !quantityPattern.MatchString(quantity) {
+		return nil, fmt.Errorf("%w: %q", ErrQuantity, quantity)
+	}
+	amount, ok := new(big.Rat).SetString(quantity)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrQuantity, quantity)
+	}
+
+	if given != nil {
+		converted, err := items.Quantity{Amount: amount, Scale: *given}.In(scale)
+		if err != nil {
+			return nil, err
+		}
+		amount = converted.Amount
+	}
+
+	var shifted = new(big.Rat).Mul(amount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(places), nil)))
+	if !shifted.IsInt() {
+		return nil, fmt.Errorf("%w: %s%s", ErrQuantityPlaces, quantity, string(scale))
+	}
+	return amount, nil
+}
+
+func (m *InventoryManager) SearchMovements(ctx context.Context, filter *Filter, page *paginate.Paginate) ([]*Movement, error) {
+	decoded, err := decodeFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	decodedPage, err := paginate.Decode(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	movements, err := m.Store.PageMovements(ctx, decoded, decodedPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to page through stock movements: %w", err)
+	}
+	return movements, nil
+}
+
+// StockLevels is the stock on hand of every item at every location it has been, narrowed down by
+// the filter.
+func (m *InventoryManager) StockLevels(ctx context.Context, filter *Filter) ([]*Level, error) {
+	decoded, err := decodeFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return m.Store.GetLevels(ctx, decoded)
+}
+
+// ItemStock is the stock on hand of the item at every location it has been.
+func (m *InventoryManager) ItemStock(ctx context.Context, item *items.Item) ([]*Level, error) {
+	return m.Store.GetLevels(ctx, Filter{Item: &keys.OpaqueID{ID: item.ID.ID}})
+}
+
+// Components is what one of the item is made of down to the items that hold stock, none when the
+// item isn't a kit.
+func (m *InventoryManager) Components(ctx context.Context, item *items.Item) ([]*Component, error) {
+	return m.Store.GetComponents(ctx, item.ID.ID)
+}
+
+// AvailableToSell is how much of the item can be sold from the stock on hand at the location, or
+// at every location when none is given. Kits hold no stock of their own, as many whole kits can be
+// sold as can be built from the stock of their components at each location, or as much of them
+// when they are sold by measure.
+func (m *InventoryManager) AvailableToSell(ctx context.Context, item *items.Item, locationID *keys.OpaqueID) (*big.Rat, error) {
+	location, err := keys.DecodeOptional(ctx, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode location id: %w", err)
+	}
+
+	components, err := m.Store.GetComponents(ctx, item.ID.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get components of item: %w", err)
+	}
+	var kit = len(components) > 0
+	if !kit {
+		components = []*Component{{ItemID: &keys.OpaqueID{ID: item.ID.ID}, Quantity: big.NewRat(1, 1)}}
+	}
+
+	var levels []*Level
+	for _, component := range components {
+		found, err := m.Store.GetLevels(ctx, Filter{Item: component.ItemID, Location: location})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stock levels of component: %w", err)
+		}
+		levels = append(levels, found...)
+	}
+	return Buildable(components, levels, kit && item.UnitScale == items.Unit), nil
+}
+
+// Buildable is how much can be built from the components with the stock on hand, summed over the
+// locations as stock at one location can't build with stock at another. Whole counts only whole
+// builds, ie kits, otherwise builds are counted down to the places the ledger holds. Stock below
+// zero builds nothing.
+func Buildable(components []*Component, levels []*Level, whole bool) *big.Rat {
+	var onHand = map[int]map[int]*big.Rat{}
+	for _, level := range levels {
+		if onHand[level.LocationID.ID] == nil {
+			onHand[level.LocationID.ID] = map[int]*big.Rat{}
+		}
+		onHand[level.LocationID.ID][level.ItemID.ID] = level.OnHand
+	}
+
+	var (
+		total = new(big.Rat)
+		unit  = new(big.Int).Exp(big.NewInt(10), big.NewInt(places), nil)
+	)
+	if whole {
+		unit = big.NewInt(1)
+	}
+	for _, stock := range onHand {
+		var least *big.Rat
+		for _, component := range components {
+			var built = new(big.Rat)
+			if held, ok := stock[component.ItemID.ID]; ok && held.Sign() > 0 {
+				built.Quo(held, component.Quantity)
+			}
+			if least == nil || built.Cmp(least) < 0 {
+				least = built
+			}
+		}
+
+		// round down to what can be built
+		var scaled = new(big.Int).Mul(least.Num(), unit)
+		scaled.Quo(scaled, least.Denom())
+		total.Add(total, new(big.Rat).SetFrac(scaled, unit))
+	}
+	return total
+}
+
+// SaleLocation resolves the location the sale took its stock from, sales where stock isn't
+// tracked have none.
+func (m *InventoryManager) SaleLocation(ctx context.Context, sale *sales.Sale) (*Location, error) {
+	if sale.LocationID == nil {
+		return nil, nil
+	}
+	return m.Store.GetLocation(ctx, sale.LocationID.ID)
+}
+
+// LocationOf resolves the location of a movement or stock level, or the other side of a transfer.
+func (m *InventoryManager) LocationOf(ctx context.Context, id *keys.OpaqueID) (*Location, error) {
+	if id == nil {
+		return nil, nil
+	}
+	return m.Store.GetLocation(ctx, id.ID)
+}
+
+// ItemOf resolves the item of a movement or stock level.
+func (m *InventoryManager) ItemOf(ctx context.Context, id *keys.OpaqueID) (*items.Item, error) {
+	return m.Items.GetItem(ctx, id.ID)
+}
+
+func decodeFilter(ctx context.Context, filter *Filter) (Filter, error) {
+	var decoded Filter
+	if filter == nil {
+		return decoded, nil
+	}
+
+	var err error
+	if decoded.Item, err = keys.DecodeOptional(ctx, filter.Item); err != nil {
+		return decoded, fmt.Errorf("failed to decode item id: %w", err)
+	}
+	if decoded.Location, err = keys.DecodeOptional(ctx, filter.Location); err != nil {
+		return decoded, fmt.Errorf("failed to decode location id: %w", err)
+	}
+	return decoded, nil
+}
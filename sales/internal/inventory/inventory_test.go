@@ -0,0 +1,229 @@
+package inventory
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+func TestParseQuantity(t *testing.T) {
+	var (
+		grams = items.Gram
+		litre = items.Litre
+	)
+	tests := []struct {
+		name     string
+		quantity string
+		given    *items.UnitScale
+		scale    items.UnitScale
+		want     string
+		err      error
+	}{
+		{name: "units", quantity: "3", scale: items.Unit, want: "3"},
+		{name: "negative", quantity: "-1.5", scale: items.Kilogram, want: "-1.5"},
+		{name: "converted", quantity: "250", given: &grams, scale: items.Kilogram, want: "0.25"},
+		{name: "smallest", quantity: "0.1", given: &grams, scale: items.Kilogram, want: "0.0001"},
+		{name: "too fine", quantity: "0.01", given: &grams, scale: items.Kilogram, err: ErrQuantityPlaces},
+		{name: "incompatible", quantity: "1", given: &litre, scale: items.Kilogram, err: items.ErrIncompatibleUnitScale},
+		{name: "fraction", quantity: "1/3", scale: items.Unit, err: ErrQuantity},
+		{name: "exponent", quantity: "1e3", scale: items.Unit, err: ErrQuantity},
+		{name: "empty", quantity: "", scale: items.Unit, err: ErrQuantity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuantity(tt.quantity, tt.given, tt.scale)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, FormatQuantity(got))
+		})
+	}
+}
+
+func TestFormatQuantity(t *testing.T) {
+	assert.Equal(t, "10", FormatQuantity(big.NewRat(10, 1)))
+	assert.Equal(t, "0", FormatQuantity(new(big.Rat)))
+	assert.Equal(t, "-2.5", FormatQuantity(big.NewRat(-5, 2)))
+	assert.Equal(t, "0.0001", FormatQuantity(big.NewRat(1, 10000)))
+}
+
+// codec takes external ids to be internal ids written out.
+type codec struct{}
+
+func (codec) Encode(ctx context.Context, id int) (string, error) {
+	return strconv.Itoa(id), nil
+}
+
+func (codec) Decode(ctx context.Context, id string) (int, error) {
+	return strconv.Atoi(id)
+}
+
+func opaque(t *testing.T, id int) *keys.OpaqueID {
+	t.Helper()
+
+	var external = &keys.OpaqueID{}
+	require.NoError(t, external.UnmarshalGQLContext(context.Background(), strconv.Itoa(id)))
+	return external.WithCodec(codec{})
+}
+
+// ledger remembers what was posted to it, every item is sold by the kg and item 9 is a kit.
+type ledger struct {
+	posted []*Movement
+}
+
+func (l *ledger) CreateLocation(ctx context.Context, location *Location) error { return nil }
+
+func (l *ledger) GetLocation(ctx context.Context, id int) (*Location, error) {
+	return &Location{ID: &keys.OpaqueID{ID: id}}, nil
+}
+
+func (l *ledger) GetLocations(ctx context.Context) ([]*Location, error) { return nil, nil }
+
+func (l *ledger) UpdateLocation(ctx context.Context, location *Location) error { return nil }
+
+func (l *ledger) PostMovements(ctx context.Context, movements []*Movement) error {
+	l.posted = append(l.posted, movements...)
+	return nil
+}
+
+func (l *ledger) PageMovements(ctx context.Context, filter Filter, page paginate.Paginate) ([]*Movement, error) {
+	return nil, nil
+}
+
+func (l *ledger) GetLevels(ctx context.Context, filter Filter) ([]*Level, error) { return nil, nil }
+
+func (l *ledger) GetComponents(ctx context.Context, item int) ([]*Component, error) {
+	if item != 9 {
+		return []*Component{}, nil
+	}
+	return []*Component{{ItemID: &keys.OpaqueID{ID: 1}, Quantity: big.NewRat(1, 4)}}, nil
+}
+
+func (l *ledger) GetItem(ctx context.Context, id int) (*items.Item, error) {
+	return &items.Item{ID: &keys.OpaqueID{ID: id}, Details: items.Details{Name: "beans", UnitScale: items.Kilogram}}, nil
+}
+
+func TestTransferStock(t *testing.T) {
+	var (
+		posted  = &ledger{}
+		manager = &InventoryManager{Store: posted, Items: posted}
+		grams   = items.Gram
+	)
+
+	movements, err := manager.TransferStock(context.Background(), NewTransfer{
+		Item:      opaque(t, 1),
+		From:      opaque(t, 2),
+		To:        opaque(t, 3),
+		Quantity:  "500",
+		UnitScale: &grams,
+	})
+	require.NoError(t, err)
+	require.Len(t, movements, 2)
+	require.Equal(t, posted.posted, movements)
+
+	out, in := movements[0], movements[1]
+	assert.Equal(t, Transfer, out.Reason)
+	assert.Equal(t, "-0.5", FormatQuantity(out.Quantity))
+	assert.Equal(t, 2, out.LocationID.ID)
+	assert.Equal(t, 3, out.TransferLocationID.ID)
+	assert.Equal(t, "0.5", FormatQuantity(in.Quantity))
+	assert.Equal(t, 3, in.LocationID.ID)
+	assert.Equal(t, 2, in.TransferLocationID.ID)
+
+	_, err = manager.TransferStock(context.Background(), NewTransfer{Item: opaque(t, 1), From: opaque(t, 2), To: opaque(t, 2), Quantity: "1"})
+	require.ErrorIs(t, err, ErrSameLocation)
+
+	_, err = manager.TransferStock(context.Background(), NewTransfer{Item: opaque(t, 1), From: opaque(t, 2), To: opaque(t, 3), Quantity: "-1"})
+	require.ErrorIs(t, err, ErrNotPositive)
+}
+
+func TestAdjustStock(t *testing.T) {
+	var (
+		posted  = &ledger{}
+		manager = &InventoryManager{Store: posted, Items: posted}
+		note    = "stocktake"
+	)
+
+	_, err := manager.AdjustStock(context.Background(), NewMovement{Item: opaque(t, 1), Location: opaque(t, 2), Quantity: "-0.25"})
+	require.ErrorIs(t, err, ErrNoNote)
+
+	_, err = manager.AdjustStock(context.Background(), NewMovement{Item: opaque(t, 1), Location: opaque(t, 2), Quantity: "0", Note: &note})
+	require.ErrorIs(t, err, ErrNoChange)
+
+	movement, err := manager.AdjustStock(context.Background(), NewMovement{Item: opaque(t, 1), Location: opaque(t, 2), Quantity: "-0.25", Note: &note})
+	require.NoError(t, err)
+	assert.Equal(t, Adjustment, movement.Reason)
+	assert.Equal(t, "-0.25", FormatQuantity(movement.Quantity))
+
+	_, err = manager.ReceiveStock(context.Background(), NewMovement{Item: opaque(t, 1), Location: opaque(t, 2), Quantity: "-1"})
+	require.ErrorIs(t, err, ErrNotPositive)
+
+	_, err = manager.ReceiveStock(context.Background(), NewMovement{Item: opaque(t, 9), Location: opaque(t, 2), Quantity: "1"})
+	require.ErrorIs(t, err, ErrKitStock)
+	require.Len(t, posted.posted, 1)
+}
+
+func TestBuildable(t *testing.T) {
+	var level = func(item, location int, onHand *big.Rat) *Level {
+		return &Level{ItemID: &keys.OpaqueID{ID: item}, LocationID: &keys.OpaqueID{ID: location}, OnHand: onHand}
+	}
+	// a gift box is two candles and a quarter kg of tea
+	var box = []*Component{
+		{ItemID: &keys.OpaqueID{ID: 1}, Quantity: big.NewRat(2, 1)},
+		{ItemID: &keys.OpaqueID{ID: 2}, Quantity: big.NewRat(1, 4)},
+	}
+
+	tests := []struct {
+		name   string
+		levels []*Level
+		whole  bool
+		want   string
+	}{
+		{name: "no stock", want: "0"},
+		{
+			name:   "least component",
+			levels: []*Level{level(1, 1, big.NewRat(7, 1)), level(2, 1, big.NewRat(2, 1))},
+			whole:  true,
+			want:   "3",
+		},
+		{
+			name:   "missing component",
+			levels: []*Level{level(1, 1, big.NewRat(8, 1))},
+			whole:  true,
+			want:   "0",
+		},
+		{
+			name:   "locations don't build together",
+			levels: []*Level{level(1, 1, big.NewRat(2, 1)), level(2, 2, big.NewRat(1, 1)), level(1, 3, big.NewRat(5, 1)), level(2, 3, big.NewRat(1, 2))},
+			whole:  true,
+			want:   "2",
+		},
+		{
+			name:   "below zero",
+			levels: []*Level{level(1, 1, big.NewRat(-4, 1)), level(2, 1, big.NewRat(1, 1)), level(1, 2, big.NewRat(2, 1)), level(2, 2, big.NewRat(1, 1))},
+			whole:  true,
+			want:   "1",
+		},
+		{
+			name:   "by measure",
+			levels: []*Level{level(1, 1, big.NewRat(7, 1)), level(2, 1, big.NewRat(1, 3))},
+			want:   "1.3333",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatQuantity(Buildable(box, tt.levels, tt.whole)))
+		})
+	}
+}
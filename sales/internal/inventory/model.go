@@ -0,0 +1,81 @@
+package inventory
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// Location is somewhere a retailer holds stock, ie a shop floor or a back room.
+type Location struct {
+	ID         *keys.OpaqueID
+	RetailerID *keys.OpaqueID
+	Name       string
+	// Default is where sales take stock from unless the till says otherwise, a retailer has at
+	// most one default location.
+	Default   bool
+	CreatedAt time.Time
+}
+
+// Reason is why stock moved.
+type Reason string
+
+const (
+	Receipt Reason = "RECEIPT"
+	// Sale movements take stock sold out of a location, refunds and voids put back what they took.
+	Sale       Reason = "SALE"
+	Refund     Reason = "REFUND"
+	Void       Reason = "VOID"
+	Adjustment Reason = "ADJUSTMENT"
+	// Transfer movements come in pairs, stock leaving one location and arriving at another.
+	Transfer Reason = "TRANSFER"
+)
+
+// Movement is an entry on the stock ledger, the ledger is only ever appended to.
+type Movement struct {
+	ID         *keys.OpaqueID
+	ItemID     *keys.OpaqueID
+	LocationID *keys.OpaqueID
+	// Quantity is in the unit scale of the item, negative when stock leaves the location.
+	Quantity *big.Rat
+	Reason   Reason
+	// SaleID is the sale that sold, refunded or voided the stock.
+	SaleID *keys.OpaqueID
+	// PurchaseOrderID is the purchase order the stock was received against.
+	PurchaseOrderID *keys.OpaqueID
+	// TransferLocationID is the location on the other side of a transfer.
+	TransferLocationID *keys.OpaqueID
+	Note               *string
+	Actor              actor.Actor
+	MovedAt            time.Time
+}
+
+// Level is the stock of an item on hand at a location, it can fall below zero when more is sold
+// than was known to be there.
+type Level struct {
+	ItemID     *keys.OpaqueID
+	LocationID *keys.OpaqueID
+	// OnHand is in the unit scale of the item.
+	OnHand    *big.Rat
+	UpdatedAt time.Time
+}
+
+// Component is an item a kit is made of that holds stock of its own, with how much of it goes into
+// one of the kit. Components of kits within the kit are counted through to theirs.
+type Component struct {
+	ItemID *keys.OpaqueID
+	// Quantity is in the unit scale of the component.
+	Quantity *big.Rat
+}
+
+// places is how many decimal places of the unit scale of an item the ledger holds.
+const places = 4
+
+// FormatQuantity writes out the quantity with as few decimal places as it needs, ie 2.5.
+func FormatQuantity(quantity *big.Rat) string {
+	formatted := quantity.FloatString(places)
+	return strings.TrimSuffix(strings.TrimRight(formatted, "0"), ".")
+}
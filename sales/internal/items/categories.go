@@ -0,0 +1,294 @@
+package items
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Category classifies items independently of what they're composed of, categories form a tree
+// ie Beverages > Coffee > Beans. An item can be in any number of categories.
+type Category struct {
+	ID       *keys.OpaqueID
+	Name     string
+	ParentID *keys.OpaqueID
+}
+
+// CategoryReport aggregates a category along with all of its descendants.
+type CategoryReport struct {
+	// Categories counts the descendants of the category.
+	Categories int
+	// Items counts the distinct items that aren't archived in the category or its descendants.
+	Items int
+	// UnitsSold and Revenue leave out voided sales and refunded units, UnitsSold only counts items
+	// sold by the unit.
+	UnitsSold int
+	// Revenue holds the revenue of each currency that items were sold in, ordered by currency.
+	Revenue []money.Money
+}
+
+// ItemFilter narrows down pages of items, a category includes all of its descendants and items
+// must carry every one of the tags.
+type ItemFilter struct {
+	Category *keys.OpaqueID `json:"category"`
+	Tags     []string       `json:"tags"`
+}
+
+var (
+	ErrCategoryNotFound  = errors.New("category not found")
+	ErrNoNameCategory    = errors.New("category must have a name")
+	ErrDuplicateCategory = errors.New("category with this name already exists under the same parent")
+	ErrCategoryNotEmpty  = errors.New("category cannot be deleted while it has subcategories")
+	ErrCategoryPromoted  = errors.New("category cannot be deleted while promotions target it")
+	ErrCyclicCategory    = errors.New("category cannot be a descendant of itself")
+	ErrInvalidTag        = errors.New("tags must be between 1 and 64 characters")
+)
+
+func (i *ItemManager) CreateCategory(ctx context.Context, name string, parentID *keys.OpaqueID) (*Category, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrNoNameCategory
+	}
+
+	parent, err := keys.DecodeOptional(ctx, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode parent id: %w", err)
+	}
+
+	return i.Store.CreateCategory(ctx, name, parent)
+}
+
+func (i *ItemManager) RenameCategory(ctx context.Context, externalID *keys.OpaqueID, name string) (*Category, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrNoNameCategory
+	}
+
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	category, err := i.Store.GetCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.Store.UpdateCategory(ctx, id, name, category.ParentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename category: %w", err)
+	}
+	return i.Store.GetCategory(ctx, id)
+}
+
+// MoveCategory reparents the category along with all of its descendants, a nil parent makes it a
+// root category.
+func (i *ItemManager) MoveCategory(ctx context.Context, externalID, parentID *keys.OpaqueID) (*Category, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	parent, err := keys.DecodeOptional(ctx, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode parent id: %w", err)
+	}
+	if parent != nil && parent.ID == id {
+		return nil, ErrCyclicCategory
+	}
+
+	category, err := i.Store.GetCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.Store.UpdateCategory(ctx, id, category.Name, parent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move category: %w", err)
+	}
+	return i.Store.GetCategory(ctx, id)
+}
+
+// DeleteCategory removes an empty category, items in it are uncategorised rather than deleted.
+func (i *ItemManager) DeleteCategory(ctx context.Context, externalID *keys.OpaqueID) (*Category, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	category, err := i.Store.GetCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.Store.DeleteCategory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete category: %w", err)
+	}
+	return category, nil
+}
+
+func (i *ItemManager) GetCategory(ctx context.Context, externalID *keys.OpaqueID) (*Category, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	return i.Store.GetCategory(ctx, id)
+}
+
+// GetCategories lists the subcategories of the parent, or the root categories without one.
+func (i *ItemManager) GetCategories(ctx context.Context, parentID *keys.OpaqueID) ([]*Category, error) {
+	parent, err := keys.DecodeOptional(ctx, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode parent id: %w", err)
+	}
+	return i.Store.GetCategories(ctx, parent)
+}
+
+func (i *ItemManager) CategoryParent(ctx context.Context, category *Category) (*Category, error) {
+	if category.ParentID == nil {
+		return nil, nil
+	}
+	return i.Store.GetCategory(ctx, category.ParentID.ID)
+}
+
+func (i *ItemManager) CategoryChildren(ctx context.Context, category *Category) ([]*Category, error) {
+	return i.Store.GetCategories(ctx, category.ID)
+}
+
+// CategoryItems pages through the items of the category and all of its descendants.
+func (i *ItemManager) CategoryItems(ctx context.Context, category *Category, page *paginate.Paginate) ([]*Item, error) {
+	return i.searchItems(ctx, page, ItemFilter{Category: &keys.OpaqueID{ID: category.ID.ID}})
+}
+
+// CategoryReport aggregates the category and its descendants, sales are limited to those made
+// within [from, to) when given.
+func (i *ItemManager) CategoryReport(ctx context.Context, category *Category, from, to *time.Time) (*CategoryReport, error) {
+	report, err := i.Store.CategoryReport(ctx, category.ID.ID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate category: %w", err)
+	}
+	return report, nil
+}
+
+func (i *ItemManager) GetItemCategories(ctx context.Context, item *Item) ([]*Category, error) {
+	return i.Store.GetItemCategories(ctx, item.ID.ID)
+}
+
+func (i *ItemManager) CategoriseItem(ctx context.Context, itemID, categoryID *keys.OpaqueID) (*Item, error) {
+	return i.categorise(ctx, itemID, categoryID, Categorised)
+}
+
+func (i *ItemManager) UncategoriseItem(ctx context.Context, itemID, categoryID *keys.OpaqueID) (*Item, error) {
+	return i.categorise(ctx, itemID, categoryID, Uncategorised)
+}
+
+func (i *ItemManager) categorise(ctx context.Context, itemID, categoryID *keys.OpaqueID, action Action) (*Item, error) {
+	id, err := i.owned(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryInternalID, err := categoryID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode category id: %w", err)
+	}
+
+	category, err := i.Store.GetCategory(ctx, categoryInternalID)
+	if err != nil {
+		return nil, err
+	}
+
+	var field = FieldChange{Field: "category"}
+	if action == Categorised {
+		field.After = &category.Name
+	} else {
+		field.Before = &category.Name
+	}
+	err = i.History.Record(ctx, func(ctx context.Context) ([]Change, error) {
+		var (
+			changed bool
+			err     error
+		)
+		if action == Categorised {
+			changed, err = i.Store.CategoriseItem(ctx, id, categoryInternalID)
+		} else {
+			changed, err = i.Store.UncategoriseItem(ctx, id, categoryInternalID)
+		}
+		if err != nil || !changed {
+			return nil, err
+		}
+		return []Change{change(ctx, id, action, nil, []FieldChange{field})}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update item categories: %w", err)
+	}
+
+	return i.Store.GetItem(ctx, id)
+}
+
+func (i *ItemManager) GetTags(ctx context.Context, item *Item) ([]string, error) {
+	return i.Store.GetTags(ctx, item.ID.ID)
+}
+
+func (i *ItemManager) TagItem(ctx context.Context, itemID *keys.OpaqueID, tag string) (*Item, error) {
+	return i.tag(ctx, itemID, tag, Tagged)
+}
+
+func (i *ItemManager) UntagItem(ctx context.Context, itemID *keys.OpaqueID, tag string) (*Item, error) {
+	return i.tag(ctx, itemID, tag, Untagged)
+}
+
+func (i *ItemManager) tag(ctx context.Context, itemID *keys.OpaqueID, tag string, action Action) (*Item, error) {
+	id, err := i.owned(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err = normaliseTag(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	var field = FieldChange{Field: "tag"}
+	if action == Tagged {
+		field.After = &tag
+	} else {
+		field.Before = &tag
+	}
+	err = i.History.Record(ctx, func(ctx context.Context) ([]Change, error) {
+		var (
+			changed bool
+			err     error
+		)
+		if action == Tagged {
+			changed, err = i.Store.TagItem(ctx, id, tag)
+		} else {
+			changed, err = i.Store.UntagItem(ctx, id, tag)
+		}
+		if err != nil || !changed {
+			return nil, err
+		}
+		return []Change{change(ctx, id, action, nil, []FieldChange{field})}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update item tags: %w", err)
+	}
+
+	return i.Store.GetItem(ctx, id)
+}
+
+// normaliseTag folds tags to lower case so that "Organic" and "organic" are the same tag.
+func normaliseTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len(tag) > 64 {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
@@ -0,0 +1,267 @@
+package items
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+type ExportFormat string
+
+var ErrExportFormat = errors.New("unsupported export format")
+
+const (
+	ExportCSV    ExportFormat = "CSV"
+	ExportNDJSON ExportFormat = "NDJSON"
+	ExportJSON   ExportFormat = "JSON"
+)
+
+// CatalogueItem is an item along with everything that hangs off of it, as streamed out of the
+// store for exports. IDs are internal and must be encoded before leaving the system.
+type CatalogueItem struct {
+	ID int
+	Details
+
+	Barcodes   []string
+	ParentIDs  []int
+	ParentSKUs []string
+	ChildIDs   []int
+	Variants   []CatalogueVariant
+}
+
+type CatalogueVariant struct {
+	ID                    int               `json:"id"`
+	Options               map[string]string `json:"options"`
+	SKU                   *string           `json:"sku"`
+	Barcode               *string           `json:"barcode"`
+	PriceOverride         *string           `json:"price_override"`
+	PriceOverrideCurrency *string           `json:"price_override_currency"`
+}
+
+type exported struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	UnitScale   UnitScale         `json:"unit_scale"`
+	SKU         *string           `json:"sku"`
+	Barcodes    []string          `json:"barcodes"`
+	Parents     []string          `json:"parents"`
+	ParentSKUs  []string          `json:"parent_skus"`
+	Children    []string          `json:"children"`
+	Variants    []exportedVariant `json:"variants"`
+}
+
+type exportedVariant struct {
+	ID                    string            `json:"id"`
+	Options               map[string]string `json:"options"`
+	SKU                   *string           `json:"sku"`
+	Barcode               *string           `json:"barcode"`
+	PriceOverride         *string           `json:"price_override"`
+	PriceOverrideCurrency *string           `json:"price_override_currency"`
+}
+
+// csvExportHeader is a superset of the import header so that exports can be imported again.
+var csvExportHeader = []string{
+	"id", "name", "description", "unit_scale", "sku", "parent_skus", "barcodes", "parents", "children",
+	"variant_id", "variant_options", "variant_sku", "variant_barcode", "variant_price_override",
+	"variant_price_override_currency",
+}
+
+// ExportItems streams every item that isn't archived to the writer, external ids are encoded with
+// the codec. Items are written as they are read so that exports never hold the whole catalogue.
+func (i *ItemManager) ExportItems(ctx context.Context, w io.Writer, format ExportFormat, codec keys.EncoderDecoder) error {
+	var encode = func(ids ...int) ([]string, error) {
+		var encoded = make([]string, 0, len(ids))
+		for _, id := range ids {
+			external, err := codec.Encode(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode id: %w", err)
+			}
+			encoded = append(encoded, external)
+		}
+		return encoded, nil
+	}
+
+	var convert = func(item *CatalogueItem) (*exported, error) {
+		ids, err := encode(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		parents, err := encode(item.ParentIDs...)
+		if err != nil {
+			return nil, err
+		}
+		children, err := encode(item.ChildIDs...)
+		if err != nil {
+			return nil, err
+		}
+
+		out := &exported{
+			ID:          ids[0],
+			Name:        item.Name,
+			Description: item.Description,
+			UnitScale:   item.UnitScale,
+			SKU:         item.SKU,
+			Barcodes:    nonNil(item.Barcodes),
+			Parents:     parents,
+			ParentSKUs:  nonNil(item.ParentSKUs),
+			Children:    children,
+			Variants:    make([]exportedVariant, 0, len(item.Variants)),
+		}
+		for _, variant := range item.Variants {
+			id, err := encode(variant.ID)
+			if err != nil {
+				return nil, err
+			}
+			out.Variants = append(out.Variants, exportedVariant{
+				ID:                    id[0],
+				Options:               variant.Options,
+				SKU:                   variant.SKU,
+				Barcode:               variant.Barcode,
+				PriceOverride:         variant.PriceOverride,
+				PriceOverrideCurrency: variant.PriceOverrideCurrency,
+			})
+		}
+		return out, nil
+	}
+
+	switch format {
+	case ExportNDJSON:
+		encoder := json.NewEncoder(w)
+		return i.Store.StreamItems(ctx, func(item *CatalogueItem) error {
+			out, err := convert(item)
+			if err != nil {
+				return err
+			}
+			return encoder.Encode(out)
+		})
+
+	case ExportJSON:
+		if _, err := io.WriteString(w, `{"items":[`); err != nil {
+			return err
+		}
+		var first = true
+		err := i.Store.StreamItems(ctx, func(item *CatalogueItem) error {
+			out, err := convert(item)
+			if err != nil {
+				return err
+			}
+			encoded, err := json.Marshal(out)
+			if err != nil {
+				return fmt.Errorf("failed to marshal item: %w", err)
+			}
+			if !first {
+				encoded = append([]byte{','}, encoded...)
+			}
+			first = false
+			_, err = w.Write(encoded)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "]}\n")
+		return err
+
+	case ExportCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvExportHeader); err != nil {
+			return err
+		}
+		err := i.Store.StreamItems(ctx, func(item *CatalogueItem) error {
+			out, err := convert(item)
+			if err != nil {
+				return err
+			}
+
+			row := []string{
+				out.ID, out.Name, out.Description, string(out.UnitScale), deref(out.SKU),
+				strings.Join(out.ParentSKUs, "|"), strings.Join(out.Barcodes, "|"),
+				strings.Join(out.Parents, "|"), strings.Join(out.Children, "|"),
+			}
+			if err := writer.Write(append(row, "", "", "", "", "", "")); err != nil {
+				return err
+			}
+
+			// variants follow the item they belong to as rows of their own
+			for _, variant := range out.Variants {
+				var options = make([]string, 0, len(variant.Options))
+				for axis, value := range variant.Options {
+					options = append(options, axis+"="+value)
+				}
+				sort.Strings(options)
+
+				variantRow := append(append([]string{}, row...), variant.ID, strings.Join(options, "|"), deref(variant.SKU), deref(variant.Barcode), deref(variant.PriceOverride), deref(variant.PriceOverrideCurrency))
+				if err := writer.Write(variantRow); err != nil {
+					return err
+				}
+			}
+
+			writer.Flush()
+			return writer.Error()
+		})
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+
+	default:
+		return fmt.Errorf("%w: %q", ErrExportFormat, format)
+	}
+}
+
+// ExportHandler serves the catalogue export, the format is chosen with the format query parameter.
+func (i *ItemManager) ExportHandler(codec keys.EncoderDecoder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := ExportFormat(strings.ToUpper(r.URL.Query().Get("format")))
+		if format == "" {
+			format = ExportNDJSON
+		}
+
+		var contentType, extension string
+		switch format {
+		case ExportCSV:
+			contentType, extension = "text/csv", "csv"
+		case ExportNDJSON:
+			contentType, extension = "application/x-ndjson", "ndjson"
+		case ExportJSON:
+			contentType, extension = "application/json", "json"
+		default:
+			http.Error(w, fmt.Sprintf("unsupported export format %q", format), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"catalogue.%s\"", extension))
+
+		if err := i.ExportItems(r.Context(), w, format, codec); err != nil {
+			slog.Error(fmt.Sprintf("failed to export catalogue: %v", err))
+			// headers are long gone by now, all we can do is cut the stream short
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package items
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type catalogueStore struct {
+	store
+	catalogue []*CatalogueItem
+}
+
+func (c *catalogueStore) StreamItems(ctx context.Context, fn func(*CatalogueItem) error) error {
+	for _, item := range c.catalogue {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type prefixCodec struct{}
+
+func (prefixCodec) Encode(ctx context.Context, id int) (string, error) {
+	return fmt.Sprintf("ext-%d", id), nil
+}
+
+func (prefixCodec) Decode(ctx context.Context, id string) (int, error) {
+	var internal int
+	_, err := fmt.Sscanf(id, "ext-%d", &internal)
+	return internal, err
+}
+
+func TestExportItems(t *testing.T) {
+	sku, price, currency := "KIT-1", "4.50", "NZD"
+	manager := ItemManager{Store: &catalogueStore{catalogue: []*CatalogueItem{
+		{ID: 1, Details: Details{Name: "beans", UnitScale: Kilogram}, ParentIDs: []int{2}, ParentSKUs: []string{"KIT-1"}},
+		{ID: 2, Details: Details{Name: "kit", UnitScale: Unit, SKU: &sku}, ChildIDs: []int{1}, Variants: []CatalogueVariant{
+			{ID: 7, Options: map[string]string{"size": "L", "colour": "red"}, PriceOverride: &price, PriceOverrideCurrency: &currency},
+		}},
+	}}}
+
+	ctx := context.Background()
+
+	t.Run("ndjson", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, manager.ExportItems(ctx, &buf, ExportNDJSON, prefixCodec{}))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+
+		var kit exported
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &kit))
+		assert.Equal(t, "ext-2", kit.ID)
+		assert.Equal(t, []string{"ext-1"}, kit.Children)
+		require.Len(t, kit.Variants, 1)
+		assert.Equal(t, "ext-7", kit.Variants[0].ID)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, manager.ExportItems(ctx, &buf, ExportJSON, prefixCodec{}))
+
+		var document struct {
+			Items []exported `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &document))
+		require.Len(t, document.Items, 2)
+		assert.Equal(t, []string{"ext-2"}, document.Items[0].Parents)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, manager.ExportItems(ctx, &buf, ExportCSV, prefixCodec{}))
+
+		exportedCSV := buf.String()
+		records, err := csv.NewReader(strings.NewReader(exportedCSV)).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+
+		assert.Equal(t, csvExportHeader, records[0])
+		assert.Equal(t, []string{"ext-1", "beans", "", "kg", "", "KIT-1", "", "ext-2", "", "", "", "", "", "", ""}, records[1])
+		assert.Equal(t, []string{"ext-7", "colour=red|size=L", "", "", "4.50", "NZD"}, records[3][9:])
+
+		// exports can be fed back into imports
+		rows, errs, err := ParseImport(strings.NewReader(exportedCSV), CSV)
+		require.NoError(t, err)
+		require.Empty(t, errs)
+		assert.Equal(t, []string{"KIT-1"}, rows[0].ParentSKUs)
+	})
+}
@@ -0,0 +1,142 @@
+package items
+
+import (
+	"context"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+type Action string
+
+const (
+	Created      Action = "CREATED"
+	Updated      Action = "UPDATED"
+	Archived     Action = "ARCHIVED"
+	Restored     Action = "RESTORED"
+	ChildAdded   Action = "CHILD_ADDED"
+	ChildRemoved Action = "CHILD_REMOVED"
+	VariantAdded Action = "VARIANT_ADDED"
+
+	Categorised   Action = "CATEGORISED"
+	Uncategorised Action = "UNCATEGORISED"
+	Tagged        Action = "TAGGED"
+	Untagged      Action = "UNTAGGED"
+)
+
+// Change is an entry of the append only history of an item.
+type Change struct {
+	ID     *keys.OpaqueID
+	ItemID *keys.OpaqueID
+	// Related is the other item involved in a relationship change.
+	Related *keys.OpaqueID
+
+	Actor      actor.Actor
+	Action     Action
+	Diff       []FieldChange
+	RecordedAt time.Time
+}
+
+type FieldChange struct {
+	Field  string  `json:"field"`
+	Before *string `json:"before"`
+	After  *string `json:"after"`
+}
+
+type history interface {
+	// Record makes the write as a single unit of work, the changes it describes are appended to the
+	// history of their items as part of it so that neither is kept without the other. Everything
+	// the store does with the context handed to write is part of the unit of work.
+	Record(ctx context.Context, write func(ctx context.Context) ([]Change, error)) error
+
+	PageItemHistory(ctx context.Context, id int, page paginate.Paginate) ([]*Change, error)
+	PageActorHistory(ctx context.Context, actor actor.Actor, page paginate.Paginate) ([]*Change, error)
+}
+
+// change describes a change to the item attributed to the actor of the context.
+func change(ctx context.Context, item int, action Action, related *keys.OpaqueID, diff []FieldChange) Change {
+	return Change{
+		ItemID:  &keys.OpaqueID{ID: item},
+		Related: related,
+		Actor:   actor.From(ctx),
+		Action:  action,
+		Diff:    diff,
+	}
+}
+
+// record makes the write and appends the change describing it to the history of the item as a
+// single unit of work, every write to an item goes through here or History.Record.
+func (i *ItemManager) record(ctx context.Context, described Change, write func(ctx context.Context) error) error {
+	return i.History.Record(ctx, func(ctx context.Context) ([]Change, error) {
+		if err := write(ctx); err != nil {
+			return nil, err
+		}
+		return []Change{described}, nil
+	})
+}
+
+// diff lists the fields that differ between two versions of details, a nil side represents
+// the item not existing (yet).
+func diff(before, after *Details) []FieldChange {
+	var fields = func(deets *Details) map[string]*string {
+		if deets == nil {
+			return map[string]*string{}
+		}
+		scale := string(deets.UnitScale)
+		return map[string]*string{
+			"name":        &deets.Name,
+			"description": &deets.Description,
+			"unit_scale":  &scale,
+			"sku":         deets.SKU,
+		}
+	}
+
+	b, a := fields(before), fields(after)
+
+	var changes []FieldChange
+	for _, field := range []string{"name", "description", "unit_scale", "sku"} {
+		if b[field] != nil && a[field] != nil && *b[field] == *a[field] {
+			continue
+		}
+		if b[field] == nil && a[field] == nil {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: field, Before: b[field], After: a[field]})
+	}
+	return changes
+}
+
+// ItemHistory pages through the history of an item, most recent changes first.
+func (i *ItemManager) ItemHistory(ctx context.Context, item *Item, page *paginate.Paginate) ([]*Change, error) {
+	decoded, err := paginate.Decode(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.History.PageItemHistory(ctx, item.ID.ID, decoded)
+}
+
+// ActorHistory pages through changes made by an actor to any item, most recent changes first.
+func (i *ItemManager) ActorHistory(ctx context.Context, name string, page *paginate.Paginate) ([]*Change, error) {
+	decoded, err := paginate.Decode(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.History.PageActorHistory(ctx, actor.Actor(name), decoded)
+}
+
+// ChangedItem resolves the item a change was made to.
+func (i *ItemManager) ChangedItem(ctx context.Context, change *Change) (*Item, error) {
+	return i.Store.GetItem(ctx, change.ItemID.ID)
+}
+
+// RelatedItem resolves the other item involved in a relationship change, if any.
+func (i *ItemManager) RelatedItem(ctx context.Context, change *Change) (*Item, error) {
+	if change.Related == nil {
+		return nil, nil
+	}
+	return i.Store.GetItem(ctx, change.Related.ID)
+}
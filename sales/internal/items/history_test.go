@@ -0,0 +1,116 @@
+package items
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+func TestDiff(t *testing.T) {
+	var str = func(s string) *string { return &s }
+
+	before := &Details{Name: "flat white", Description: "", UnitScale: Unit}
+
+	t.Run("created", func(t *testing.T) {
+		changes := diff(nil, before)
+		assert.Equal(t, []FieldChange{
+			{Field: "name", After: str("flat white")},
+			{Field: "description", After: str("")},
+			{Field: "unit_scale", After: str("unit")},
+		}, changes)
+	})
+
+	t.Run("only changed fields", func(t *testing.T) {
+		after := *before
+		after.Name = "long black"
+
+		changes := diff(before, &after)
+		assert.Equal(t, []FieldChange{
+			{Field: "name", Before: str("flat white"), After: str("long black")},
+		}, changes)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		assert.Empty(t, diff(before, before))
+		assert.Empty(t, diff(nil, nil))
+	})
+}
+
+// relatingStore holds how much of each child goes into its parent.
+type relatingStore struct {
+	store
+	quantities map[[2]int]*big.Rat
+}
+
+func (r *relatingStore) AddChild(ctx context.Context, parent int, child int, quantity *big.Rat) (*big.Rat, error) {
+	before := r.quantities[[2]int{parent, child}]
+	r.quantities[[2]int{parent, child}] = quantity
+	return before, nil
+}
+
+func (r *relatingStore) RemoveChild(ctx context.Context, parent int, child int) (*big.Rat, error) {
+	before := r.quantities[[2]int{parent, child}]
+	delete(r.quantities, [2]int{parent, child})
+	return before, nil
+}
+
+func (r *relatingStore) GetItem(ctx context.Context, id int) (*Item, error) {
+	return &Item{ID: &keys.OpaqueID{ID: id}}, nil
+}
+
+// recordingHistory keeps the changes of every write that went through.
+type recordingHistory struct {
+	history
+	changes []Change
+}
+
+func (r *recordingHistory) Record(ctx context.Context, write func(ctx context.Context) ([]Change, error)) error {
+	changes, err := write(ctx)
+	if err != nil {
+		return err
+	}
+	r.changes = append(r.changes, changes...)
+	return nil
+}
+
+func TestRelateHistory(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		history = &recordingHistory{}
+		manager = ItemManager{Store: &relatingStore{quantities: map[[2]int]*big.Rat{}}, History: history}
+		str     = func(s string) *string { return &s }
+	)
+
+	var external = func(id string) *keys.OpaqueID {
+		var opaque keys.OpaqueID
+		require.NoError(t, opaque.UnmarshalGQLContext(ctx, id))
+		return opaque.WithCodec(prefixCodec{})
+	}
+
+	for _, quantity := range []string{"2", "0.25", "0.25"} {
+		_, err := manager.AddChild(ctx, external("ext-1"), external("ext-2"), &quantity)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err := manager.RemoveChild(ctx, external("ext-1"), external("ext-2"))
+		require.NoError(t, err)
+	}
+
+	// relating the items again keeps what the quantity was, nothing changes what is already so
+	var diffs [][]FieldChange
+	for _, change := range history.changes {
+		assert.Equal(t, 1, change.ItemID.ID)
+		assert.Equal(t, 2, change.Related.ID)
+		diffs = append(diffs, change.Diff)
+	}
+	assert.Equal(t, [][]FieldChange{
+		{{Field: "quantity", After: str("2")}},
+		{{Field: "quantity", Before: str("2"), After: str("0.25")}},
+		{{Field: "quantity", Before: str("0.25")}},
+	}, diffs)
+	assert.Equal(t, ChildRemoved, history.changes[2].Action)
+}
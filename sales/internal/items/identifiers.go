@@ -0,0 +1,102 @@
+package items
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/pkg/gtin"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+var (
+	ErrDuplicateSKU     = errors.New("sku is already in use")
+	ErrDuplicateBarcode = errors.New("barcode is already in use")
+	ErrBarcodeNotFound  = errors.New("barcode not found")
+)
+
+// normaliseSKU trims the SKU, an empty SKU is no SKU at all.
+func normaliseSKU(sku *string) *string {
+	if sku == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*sku)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// GetItemBySKU finds the item with the SKU, or the item of the variant with the SKU.
+func (i *ItemManager) GetItemBySKU(ctx context.Context, sku string) (*Item, error) {
+	trimmed := normaliseSKU(&sku)
+	if trimmed == nil {
+		return nil, ErrItemNotFound
+	}
+
+	id, err := i.Store.GetItemBySKU(ctx, *trimmed)
+	if err != nil {
+		return nil, err
+	}
+	return i.Store.GetItem(ctx, id)
+}
+
+// GetItemByBarcode finds the item, or the item of the variant, registered under the barcode.
+func (i *ItemManager) GetItemByBarcode(ctx context.Context, code string) (*Item, error) {
+	normalised, err := gtin.Normalise(code)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := i.Store.GetItemByBarcode(ctx, normalised)
+	if err != nil {
+		return nil, err
+	}
+	return i.Store.GetItem(ctx, id)
+}
+
+func (i *ItemManager) GetBarcodes(ctx context.Context, item *Item) ([]string, error) {
+	return i.Store.GetBarcodes(ctx, item.ID.ID)
+}
+
+// AddBarcode registers another barcode for the item, an item can be known by many barcodes but
+// a barcode only ever identifies one item.
+func (i *ItemManager) AddBarcode(ctx context.Context, externalID *keys.OpaqueID, code string) (*Item, error) {
+	return i.barcode(ctx, externalID, code, true)
+}
+
+func (i *ItemManager) RemoveBarcode(ctx context.Context, externalID *keys.OpaqueID, code string) (*Item, error) {
+	return i.barcode(ctx, externalID, code, false)
+}
+
+func (i *ItemManager) barcode(ctx context.Context, externalID *keys.OpaqueID, code string, add bool) (*Item, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	code = strings.TrimSpace(code)
+	normalised, err := gtin.Normalise(code)
+	if err != nil {
+		return nil, err
+	}
+
+	var field = FieldChange{Field: "barcode"}
+	if add {
+		field.After = &code
+	} else {
+		field.Before = &code
+	}
+	err = i.record(ctx, change(ctx, id, Updated, nil, []FieldChange{field}), func(ctx context.Context) error {
+		if add {
+			return i.Store.AddBarcode(ctx, id, normalised, code)
+		}
+		return i.Store.RemoveBarcode(ctx, id, normalised)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update barcodes of item: %w", err)
+	}
+
+	return i.Store.GetItem(ctx, id)
+}
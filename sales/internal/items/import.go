@@ -0,0 +1,296 @@
+package items
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+type ImportFormat string
+
+const (
+	CSV    ImportFormat = "CSV"
+	NDJSON ImportFormat = "NDJSON"
+)
+
+// ImportRow is a single item of a bulk import. Rows are keyed by SKU, a row with the SKU of an
+// existing item updates it, otherwise a new item is created.
+type ImportRow struct {
+	Line int `json:"-"`
+
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	UnitScale   UnitScale `json:"unit_scale"`
+	SKU         string    `json:"sku"`
+	// ParentSKUs compose this item into the items with these SKUs, they can refer to items
+	// in the same import or to existing items.
+	ParentSKUs []string `json:"parent_skus"`
+}
+
+// RowError describes why a line of an import was rejected.
+type RowError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e RowError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Message)
+}
+
+// ImportOutcome is what happened to a single row of an import in the store.
+type ImportOutcome struct {
+	Line    int
+	ID      int
+	Created bool
+	// Before holds the details of an updated item prior to the import.
+	Before *Details
+	After  Details
+}
+
+// ImportedRelationship is a relationship that didn't exist prior to the import.
+type ImportedRelationship struct {
+	Parent int
+	Child  int
+}
+
+type ImportResult struct {
+	DryRun    bool
+	Created   int
+	Updated   int
+	Unchanged int
+	Errors    []RowError
+}
+
+var ErrImportFormat = errors.New("unsupported import format")
+
+// csvHeader is the expected header of csv imports, parent_skus are separated by "|".
+var csvHeader = []string{"name", "description", "unit_scale", "sku", "parent_skus"}
+
+// ParseImport reads rows out of the import, lines that can't be read are reported as row errors
+// rather than failing the whole import.
+func ParseImport(r io.Reader, format ImportFormat) ([]ImportRow, []RowError, error) {
+	switch format {
+	case CSV:
+		return parseCSV(r)
+	case NDJSON:
+		return parseNDJSON(r)
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrImportFormat, format)
+	}
+}
+
+func parseCSV(r io.Reader) ([]ImportRow, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	var columns = make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, nil, fmt.Errorf("csv header must contain at least name, expected columns %s", strings.Join(csvHeader, ","))
+	}
+
+	var (
+		rows   []ImportRow
+		errs   []RowError
+		record []string
+	)
+	for line := 2; ; line++ {
+		record, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			errs = append(errs, RowError{Line: line, Message: err.Error()})
+			continue
+		}
+
+		var column = func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := ImportRow{
+			Line:        line,
+			Name:        column("name"),
+			Description: column("description"),
+			UnitScale:   UnitScale(column("unit_scale")),
+			SKU:         column("sku"),
+		}
+		if parents := column("parent_skus"); parents != "" {
+			row.ParentSKUs = strings.Split(parents, "|")
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, errs, nil
+}
+
+func parseNDJSON(r io.Reader) ([]ImportRow, []RowError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var (
+		rows []ImportRow
+		errs []RowError
+	)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var row ImportRow
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			errs = append(errs, RowError{Line: line, Message: fmt.Sprintf("invalid json: %v", err)})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read ndjson: %w", err)
+	}
+
+	return rows, errs, nil
+}
+
+// validate normalises every row in place and reports every problem found with them.
+func validate(rows []ImportRow) []RowError {
+	var (
+		errs []RowError
+		seen = make(map[string]int, len(rows))
+	)
+	for i := range rows {
+		row := &rows[i]
+
+		row.Name = strings.TrimSpace(row.Name)
+		if row.Name == "" {
+			errs = append(errs, RowError{Line: row.Line, Field: "name", Message: ErrNoNameItem.Error()})
+		}
+
+		row.SKU = strings.TrimSpace(row.SKU)
+		if row.SKU == "" {
+			errs = append(errs, RowError{Line: row.Line, Field: "sku", Message: "imported items must have a sku"})
+		} else if first, ok := seen[row.SKU]; ok {
+			errs = append(errs, RowError{Line: row.Line, Field: "sku", Message: fmt.Sprintf("duplicate of line %d", first)})
+		} else {
+			seen[row.SKU] = row.Line
+		}
+
+		if row.UnitScale == "" {
+			row.UnitScale = Unit
+		}
+		scale, err := ParseUnitScale(string(row.UnitScale))
+		if err != nil {
+			errs = append(errs, RowError{Line: row.Line, Field: "unit_scale", Message: err.Error()})
+		}
+		row.UnitScale = scale
+
+		var parents = make([]string, 0, len(row.ParentSKUs))
+		for _, parent := range row.ParentSKUs {
+			parent = strings.TrimSpace(parent)
+			if parent == "" {
+				continue
+			}
+			if parent == row.SKU {
+				errs = append(errs, RowError{Line: row.Line, Field: "parent_skus", Message: ErrCyclicRelationship.Error()})
+				continue
+			}
+			parents = append(parents, parent)
+		}
+		row.ParentSKUs = parents
+	}
+	return errs
+}
+
+// ImportItems validates and upserts all rows in a single transaction, nothing is imported if any
+// row is rejected. On a dry run the result reports what would have happened without any changes
+// being kept.
+func (i *ItemManager) ImportItems(ctx context.Context, rows []ImportRow, dryRun bool) (*ImportResult, error) {
+	var result = &ImportResult{DryRun: dryRun}
+
+	result.Errors = validate(rows)
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	var (
+		outcomes []ImportOutcome
+		errs     []RowError
+	)
+	err := i.History.Record(ctx, func(ctx context.Context) ([]Change, error) {
+		var (
+			added []ImportedRelationship
+			err   error
+		)
+		outcomes, added, errs, err = i.Store.ImportItems(ctx, rows, dryRun)
+		if err != nil || dryRun || len(errs) > 0 {
+			return nil, err
+		}
+		return imported(actor.From(ctx), outcomes, added), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import items: %w", err)
+	}
+	if len(errs) > 0 {
+		result.Errors = errs
+		return result, nil
+	}
+
+	for _, outcome := range outcomes {
+		switch {
+		case outcome.Created:
+			result.Created++
+		case outcome.Before != nil:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+
+	return result, nil
+}
+
+// imported describes the history of an import, items left as they were have none.
+func imported(by actor.Actor, outcomes []ImportOutcome, relationships []ImportedRelationship) []Change {
+	var changes = make([]Change, 0, len(outcomes)+len(relationships))
+	for _, outcome := range outcomes {
+		switch {
+		case outcome.Created:
+			changes = append(changes, Change{Actor: by, Action: Created, ItemID: &keys.OpaqueID{ID: outcome.ID}, Diff: diff(nil, &outcome.After)})
+		case outcome.Before != nil:
+			changes = append(changes, Change{Actor: by, Action: Updated, ItemID: &keys.OpaqueID{ID: outcome.ID}, Diff: diff(outcome.Before, &outcome.After)})
+		}
+	}
+	for _, relationship := range relationships {
+		changes = append(changes, Change{
+			Actor:   by,
+			Action:  ChildAdded,
+			ItemID:  &keys.OpaqueID{ID: relationship.Parent},
+			Related: &keys.OpaqueID{ID: relationship.Child},
+		})
+	}
+	return changes
+}
@@ -0,0 +1,90 @@
+package items
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImport(t *testing.T) {
+	t.Run("csv", func(t *testing.T) {
+		rows, errs, err := ParseImport(strings.NewReader(`name,description,unit_scale,sku,parent_skus
+beans,single origin,kg,BEAN-1,
+"flat white",,unit,FW-1,BEAN-1|MILK-1
+`), CSV)
+		require.NoError(t, err)
+		require.Empty(t, errs)
+
+		assert.Equal(t, []ImportRow{
+			{Line: 2, Name: "beans", Description: "single origin", UnitScale: "kg", SKU: "BEAN-1"},
+			{Line: 3, Name: "flat white", UnitScale: "unit", SKU: "FW-1", ParentSKUs: []string{"BEAN-1", "MILK-1"}},
+		}, rows)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		rows, errs, err := ParseImport(strings.NewReader(`{"name": "beans", "unit_scale": "kg", "sku": "BEAN-1"}
+
+{"name": "flat white", "sku": "FW-1", "parent_skus": ["BEAN-1"]}
+{"name": broken}
+`), NDJSON)
+		require.NoError(t, err)
+
+		require.Len(t, errs, 1)
+		assert.Equal(t, 4, errs[0].Line)
+
+		assert.Equal(t, []ImportRow{
+			{Line: 1, Name: "beans", UnitScale: "kg", SKU: "BEAN-1"},
+			{Line: 3, Name: "flat white", SKU: "FW-1", ParentSKUs: []string{"BEAN-1"}},
+		}, rows)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, _, err := ParseImport(strings.NewReader(""), ImportFormat("XML"))
+		require.ErrorIs(t, err, ErrImportFormat)
+	})
+}
+
+func TestValidateImport(t *testing.T) {
+	rows := []ImportRow{
+		{Line: 1, Name: " beans ", UnitScale: "kilogram", SKU: "BEAN-1"},
+		{Line: 2, Name: "", SKU: "X-1"},
+		{Line: 3, Name: "milk", UnitScale: "gallon", SKU: "MILK-1"},
+		{Line: 4, Name: "more beans", SKU: "BEAN-1"},
+		{Line: 5, Name: "loop", SKU: "LOOP-1", ParentSKUs: []string{"LOOP-1", " "}},
+		{Line: 6, Name: "no sku"},
+	}
+
+	errs := validate(rows)
+	assert.Equal(t, []RowError{
+		{Line: 2, Field: "name", Message: ErrNoNameItem.Error()},
+		{Line: 3, Field: "unit_scale", Message: `unknown unit scale: "gallon"`},
+		{Line: 4, Field: "sku", Message: "duplicate of line 1"},
+		{Line: 5, Field: "parent_skus", Message: ErrCyclicRelationship.Error()},
+		{Line: 6, Field: "sku", Message: "imported items must have a sku"},
+	}, errs)
+
+	assert.Equal(t, "beans", rows[0].Name)
+	assert.Equal(t, Kilogram, rows[0].UnitScale)
+	assert.Equal(t, Unit, rows[1].UnitScale)
+	assert.Empty(t, rows[4].ParentSKUs)
+}
+
+func TestImportedHistory(t *testing.T) {
+	var sku = "BEAN-1"
+	changes := imported("importer", []ImportOutcome{
+		{ID: 1, Created: true, After: Details{Name: "beans", UnitScale: Kilogram, SKU: &sku}},
+		{ID: 2, Before: &Details{Name: "milk", UnitScale: Litre}, After: Details{Name: "oat milk", UnitScale: Litre}},
+		{ID: 3, After: Details{Name: "cup", UnitScale: Unit}},
+	}, []ImportedRelationship{{Parent: 2, Child: 1}})
+
+	var described []string
+	for _, change := range changes {
+		assert.Equal(t, "importer", string(change.Actor))
+		described = append(described, fmt.Sprintf("%s %d", change.Action, change.ItemID.ID))
+	}
+	assert.Equal(t, []string{"CREATED 1", "UPDATED 2", "CHILD_ADDED 2"}, described, "items left as they were have no history")
+	assert.Equal(t, 1, changes[2].Related.ID)
+}
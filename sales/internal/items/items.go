@@ -4,25 +4,76 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/suessflorian/pedlar/sales/pkg/keys"
 	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
 )
 
+// store writes to items, the manager records the history of each write through History.Record so
+// that it is part of the same transaction.
 type store interface {
 	CreateItem(context.Context, Details) (*Item, error)
-	UpdateItemDetails(context.Context, *keys.OpaqueID, Details) error
+	UpdateItemDetails(ctx context.Context, id int, version int, deets Details) error
+	SetItemArchived(ctx context.Context, id int, version int, archived bool) error
 
 	GetItem(context.Context, int) (*Item, error)
 	GetItems(context.Context, ...int) ([]*Item, error)
+	GetChildren(context.Context, int) ([]*Item, error)
 
-	PageItems(context.Context, paginate.Paginate) ([]*keys.OpaqueID, error)
+	// AddChild relates the items with the quantity of the child that goes into one of the parent,
+	// relating them again changes the quantity. The quantity it replaced is returned, nil when
+	// the items weren't related.
+	AddChild(ctx context.Context, parent int, child int, quantity *big.Rat) (*big.Rat, error)
+	// RemoveChild returns the quantity of the relationship it removed, nil when the items weren't
+	// related.
+	RemoveChild(ctx context.Context, parent int, child int) (*big.Rat, error)
+
+	GetItemBySKU(ctx context.Context, sku string) (int, error)
+	GetItemByBarcode(ctx context.Context, gtin string) (int, error)
+	GetBarcodes(ctx context.Context, item int) ([]string, error)
+	AddBarcode(ctx context.Context, item int, gtin string, code string) error
+	RemoveBarcode(ctx context.Context, item int, gtin string) error
+
+	// ImportItems upserts the rows keyed by SKU along with their parent relationships, rows that
+	// reference unknown parents are reported back as row errors. Nothing is kept of a dry run.
+	ImportItems(ctx context.Context, rows []ImportRow, dryRun bool) ([]ImportOutcome, []ImportedRelationship, []RowError, error)
+
+	// StreamItems calls back with every item that isn't archived without holding them all at once.
+	StreamItems(ctx context.Context, fn func(*CatalogueItem) error) error
+
+	SetOptionAxes(ctx context.Context, id int, version int, axes []string) error
+	SetTaxClass(ctx context.Context, id int, version int, class string) error
+	CreateVariant(ctx context.Context, item int, deets VariantDetails) (*Variant, error)
+	GetVariant(context.Context, int) (*Variant, error)
+	GetVariants(ctx context.Context, item int) ([]*Variant, error)
+
+	CreateCategory(ctx context.Context, name string, parent *keys.OpaqueID) (*Category, error)
+	UpdateCategory(ctx context.Context, id int, name string, parent *keys.OpaqueID) error
+	DeleteCategory(ctx context.Context, id int) error
+	GetCategory(context.Context, int) (*Category, error)
+	GetCategories(ctx context.Context, parent *keys.OpaqueID) ([]*Category, error)
+	CategoryReport(ctx context.Context, id int, from, to *time.Time) (*CategoryReport, error)
+
+	// CategoriseItem, UncategoriseItem, TagItem and UntagItem report whether anything changed.
+	CategoriseItem(ctx context.Context, item int, category int) (bool, error)
+	UncategoriseItem(ctx context.Context, item int, category int) (bool, error)
+	GetItemCategories(ctx context.Context, item int) ([]*Category, error)
+	TagItem(ctx context.Context, item int, tag string) (bool, error)
+	UntagItem(ctx context.Context, item int, tag string) (bool, error)
+	GetTags(ctx context.Context, item int) ([]string, error)
+
+	PageItems(context.Context, ItemFilter, paginate.Paginate) ([]*keys.OpaqueID, error)
 	// TODO: SearchItems(context.Context, search) ([]*Item, error)
 }
 
 type ItemManager struct {
-	Store store
+	Store   store
+	History history
 }
 
 func (i *ItemManager) GetItem(ctx context.Context, externalID *keys.OpaqueID) (*Item, error) {
@@ -34,6 +85,20 @@ func (i *ItemManager) GetItem(ctx context.Context, externalID *keys.OpaqueID) (*
 	return i.Store.GetItem(ctx, id)
 }
 
+// owned decodes the id of an item of the retailer of the request, tags don't hold the retailer so
+// items are checked before they're categorised or tagged.
+func (i *ItemManager) owned(ctx context.Context, externalID *keys.OpaqueID) (int, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return -1, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	if _, err := i.Store.GetItem(ctx, id); err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
 func (i *ItemManager) GetItems(ctx context.Context, externalIDs ...*keys.OpaqueID) ([]*Item, error) {
 	var ids = make([]int, 0, len(externalIDs))
 	for _, externalID := range externalIDs {
@@ -47,38 +112,92 @@ func (i *ItemManager) GetItems(ctx context.Context, externalIDs ...*keys.OpaqueI
 }
 
 type ItemSearch struct {
-	Page *paginate.Paginate
+	Page   *paginate.Paginate
+	Filter ItemFilter
 	// TODO: embed a search type
 }
 
 func (i *ItemManager) SearchItems(ctx context.Context, search *ItemSearch) ([]*Item, error) {
 	// TODO: if search pattern found, route request to SearchItems instead
 
-	if search.Page == nil {
-		search.Page = &paginate.Paginate{
-			Cursor: nil,
-			Limit:  20,
+	var filter = ItemFilter{Tags: make([]string, 0, len(search.Filter.Tags))}
+	if search.Filter.Category != nil {
+		category, err := search.Filter.Category.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode category id: %w", err)
+		}
+		filter.Category = &keys.OpaqueID{ID: category}
+	}
+	for _, tag := range search.Filter.Tags {
+		tag, err := normaliseTag(tag)
+		if err != nil {
+			return nil, err
 		}
+		filter.Tags = append(filter.Tags, tag)
 	}
 
-	items, err := i.Store.PageItems(ctx, *search.Page)
+	return i.searchItems(ctx, search.Page, filter)
+}
+
+// searchItems pages through items matching a filter that refers to internal ids.
+func (i *ItemManager) searchItems(ctx context.Context, cursor *paginate.Paginate, filter ItemFilter) ([]*Item, error) {
+	page, err := paginate.Decode(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := i.Store.PageItems(ctx, filter, page)
 	if err != nil {
 		return nil, fmt.Errorf("failed to page through items: %w", err)
 	}
 
-	return i.GetItems(ctx, items...)
+	var ids = make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return i.Store.GetItems(ctx, ids...)
 }
 
 var (
 	ErrNoNameItem = errors.New("item must have a name")
+
+	ErrItemNotFound    = errors.New("item not found")
+	ErrItemArchived    = errors.New("item is archived")
+	ErrItemNotArchived = errors.New("item is not archived")
+
+	// ErrVersionConflict is returned when the version presented by a writer is no longer the
+	// current version of the item, ie someone else has written to the item in the meantime.
+	ErrVersionConflict = errors.New("item has been modified since it was read")
+
+	ErrCyclicRelationship = errors.New("item cannot be a descendant of itself")
+	ErrChildQuantity      = errors.New("quantity of a child item must be positive with at most 4 decimal places")
+
+	// ErrUnitScaleStocked is returned when changing the unit scale of an item that has moved
+	// stock, the stock ledger holds quantities in the unit scale of the item.
+	ErrUnitScaleStocked = errors.New("unit scale of an item with stock cannot change")
 )
 
 func (i *ItemManager) CreateItem(ctx context.Context, deets Details) (*Item, error) {
 	if strings.TrimSpace(deets.Name) == "" {
 		return nil, ErrNoNameItem
 	}
+	if deets.UnitScale == "" {
+		deets.UnitScale = Unit
+	}
+	if _, err := deets.UnitScale.Dimension(); err != nil {
+		return nil, err
+	}
+	deets.SKU = normaliseSKU(deets.SKU)
 
-	item, err := i.Store.CreateItem(ctx, deets)
+	var item *Item
+	err := i.History.Record(ctx, func(ctx context.Context) ([]Change, error) {
+		created, err := i.Store.CreateItem(ctx, deets)
+		if err != nil {
+			return nil, err
+		}
+		item = created
+		return []Change{change(ctx, created.ID.ID, Created, nil, diff(nil, &deets))}, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new item from details provided: %w", err)
 	}
@@ -86,15 +205,194 @@ func (i *ItemManager) CreateItem(ctx context.Context, deets Details) (*Item, err
 	return item, nil
 }
 
-func (i *ItemManager) UpdateItemDetails(ctx context.Context, id *keys.OpaqueID, deets Details) (*Item, error) {
+func (i *ItemManager) UpdateItemDetails(ctx context.Context, externalID *keys.OpaqueID, version int, deets Details) (*Item, error) {
+	var sku = new(string)
+	if deets.SKU != nil {
+		sku = deets.SKU
+	}
+	return i.UpdateItem(ctx, externalID, version, Patch{
+		Name:        &deets.Name,
+		Description: &deets.Description,
+		UnitScale:   &deets.UnitScale,
+		SKU:         sku,
+	})
+}
+
+// UpdateItem applies the patch on top of the current details of the item. The version must match
+// the current version of the item, otherwise ErrVersionConflict is returned.
+func (i *ItemManager) UpdateItem(ctx context.Context, externalID *keys.OpaqueID, version int, patch Patch) (*Item, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	item, err := i.Store.GetItem(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	if item.ArchivedAt != nil {
+		return nil, ErrItemArchived
+	}
+	if item.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	deets := patch.apply(item.Details)
 	if strings.TrimSpace(deets.Name) == "" {
 		return nil, ErrNoNameItem
 	}
+	if deets.UnitScale == "" {
+		deets.UnitScale = Unit
+	}
+	if _, err := deets.UnitScale.Dimension(); err != nil {
+		return nil, err
+	}
+	deets.SKU = normaliseSKU(deets.SKU)
 
-	err := i.Store.UpdateItemDetails(ctx, id, deets)
+	err = i.record(ctx, change(ctx, id, Updated, nil, diff(&item.Details, &deets)), func(ctx context.Context) error {
+		return i.Store.UpdateItemDetails(ctx, id, version, deets)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create new item from details provided: %w", err)
+		return nil, fmt.Errorf("failed to update item details: %w", err)
+	}
+
+	return i.Store.GetItem(ctx, id)
+}
+
+// ArchiveItem soft deletes the item, archived items are excluded from pages of items but
+// remain retrievable by id.
+func (i *ItemManager) ArchiveItem(ctx context.Context, externalID *keys.OpaqueID, version int) (*Item, error) {
+	return i.setArchived(ctx, externalID, version, true)
+}
+
+// RestoreItem reverses an ArchiveItem.
+func (i *ItemManager) RestoreItem(ctx context.Context, externalID *keys.OpaqueID, version int) (*Item, error) {
+	return i.setArchived(ctx, externalID, version, false)
+}
+
+func (i *ItemManager) setArchived(ctx context.Context, externalID *keys.OpaqueID, version int, archived bool) (*Item, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	item, err := i.Store.GetItem(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	if archived && item.ArchivedAt != nil {
+		return nil, ErrItemArchived
+	}
+	if !archived && item.ArchivedAt == nil {
+		return nil, ErrItemNotArchived
+	}
+	if item.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	var action = Archived
+	if !archived {
+		action = Restored
+	}
+	before, after := strconv.FormatBool(!archived), strconv.FormatBool(archived)
+	err = i.record(ctx, change(ctx, id, action, nil, []FieldChange{{Field: "archived", Before: &before, After: &after}}), func(ctx context.Context) error {
+		return i.Store.SetItemArchived(ctx, id, version, archived)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set item archived status: %w", err)
+	}
+
+	return i.Store.GetItem(ctx, id)
+}
+
+func (i *ItemManager) GetChildren(ctx context.Context, item *Item) ([]*Item, error) {
+	return i.Store.GetChildren(ctx, item.ID.ID)
+}
+
+// AddChild composes the child item into the parent item, relationships must stay acyclic. The
+// quantity is how much of the child, in its unit scale, goes into one of the parent, 1 unless
+// given.
+func (i *ItemManager) AddChild(ctx context.Context, parentID, childID *keys.OpaqueID, quantity *string) (*Item, error) {
+	var amount = big.NewRat(1, 1)
+	if quantity != nil {
+		var err error
+		if amount, err = ParseChildQuantity(*quantity); err != nil {
+			return nil, err
+		}
+	}
+	return i.relate(ctx, parentID, childID, ChildAdded, amount)
+}
+
+var childQuantityPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,4})?$`)
+
+// ParseChildQuantity reads the decimal quantity of a child item that goes into its parent, ie 0.25.
+func ParseChildQuantity(quantity string) (*big.Rat, error) {
+	quantity = strings.TrimSpace(quantity)
+	if !childQuantityPattern.MatchString(quantity) {
+		return nil, fmt.Errorf("%w: %q", ErrChildQuantity, quantity)
+	}
+	amount, ok := new(big.Rat).SetString(quantity)
+	if !ok || amount.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrChildQuantity, quantity)
+	}
+	return amount, nil
+}
+
+// formatChildQuantity writes the quantity of a child item like it is read, nil stays nil.
+func formatChildQuantity(quantity *big.Rat) *string {
+	if quantity == nil {
+		return nil
+	}
+	formatted := strings.TrimSuffix(strings.TrimRight(quantity.FloatString(4), "0"), ".")
+	return &formatted
+}
+
+func (i *ItemManager) RemoveChild(ctx context.Context, parentID, childID *keys.OpaqueID) (*Item, error) {
+	return i.relate(ctx, parentID, childID, ChildRemoved, nil)
+}
+
+func (i *ItemManager) relate(ctx context.Context, parentID, childID *keys.OpaqueID, action Action, quantity *big.Rat) (*Item, error) {
+	parent, err := parentID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode parent id: %w", err)
+	}
+
+	child, err := childID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode child id: %w", err)
+	}
+
+	if parent == child {
+		return nil, ErrCyclicRelationship
+	}
+
+	// relating the items again only changes the quantity, the history keeps what it was before
+	err = i.History.Record(ctx, func(ctx context.Context) ([]Change, error) {
+		var (
+			before, after *big.Rat
+			err           error
+		)
+		if action == ChildAdded {
+			before, err = i.Store.AddChild(ctx, parent, child, quantity)
+			after = quantity
+		} else {
+			before, err = i.Store.RemoveChild(ctx, parent, child)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if before == nil && after == nil || before != nil && after != nil && before.Cmp(after) == 0 {
+			return nil, nil
+		}
+
+		field := FieldChange{Field: "quantity", Before: formatChildQuantity(before), After: formatChildQuantity(after)}
+		return []Change{change(ctx, parent, action, &keys.OpaqueID{ID: child}, []FieldChange{field})}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update item relationship: %w", err)
 	}
 
-	return i.GetItem(ctx, id)
+	return i.Store.GetItem(ctx, parent)
 }
@@ -1,11 +1,27 @@
 package items
 
-import "github.com/suessflorian/pedlar/sales/pkg/keys"
+import (
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
 
 type Item struct {
 	ID *keys.OpaqueID
+	// RetailerID is the retailer that owns the item.
+	RetailerID *keys.OpaqueID
 	Details
 
+	// Version is bumped on every write, writers must present the version they read
+	// so that concurrent edits are detected rather than silently overwritten.
+	Version    int
+	ArchivedAt *time.Time
+
+	// OptionAxes are the axes variants of this item are configured along, ie size and colour.
+	OptionAxes []string
+	// TaxClass decides which tax rates apply to sales of the item, ie STANDARD or ZERO_RATED.
+	TaxClass string
+
 	Children []*Item
 }
 
@@ -13,6 +29,33 @@ type Details struct {
 	Name        string
 	Description string
 	UnitScale   UnitScale
+	// SKU is the optional stock keeping unit the retailer uses to refer to the item.
+	SKU *string
+}
+
+// Patch describes a partial update of an items details, nil fields are left untouched.
+type Patch struct {
+	Name        *string
+	Description *string
+	UnitScale   *UnitScale
+	// SKU set to an empty string clears the SKU of the item.
+	SKU *string
+}
+
+func (p Patch) apply(deets Details) Details {
+	if p.Name != nil {
+		deets.Name = *p.Name
+	}
+	if p.Description != nil {
+		deets.Description = *p.Description
+	}
+	if p.UnitScale != nil {
+		deets.UnitScale = *p.UnitScale
+	}
+	if p.SKU != nil {
+		deets.SKU = p.SKU
+	}
+	return deets
 }
 
 type UnitScale string
@@ -0,0 +1,61 @@
+package items
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// DefaultTaxClass is the tax class of every created item, it is taxed at the standard rate of the
+// jurisdiction of whoever sells it.
+const DefaultTaxClass = "STANDARD"
+
+var ErrTaxClass = errors.New("tax class must be 1 to 32 letters, digits or underscores")
+
+var taxClassPattern = regexp.MustCompile(`^[A-Z0-9_]{1,32}$`)
+
+// NormaliseTaxClass upper cases the tax class, ie zero_rated is ZERO_RATED.
+func NormaliseTaxClass(class string) (string, error) {
+	class = strings.ToUpper(strings.TrimSpace(class))
+	if !taxClassPattern.MatchString(class) {
+		return "", fmt.Errorf("%w: %q", ErrTaxClass, class)
+	}
+	return class, nil
+}
+
+// SetTaxClass decides which tax rates apply to sales of the item.
+func (i *ItemManager) SetTaxClass(ctx context.Context, externalID *keys.OpaqueID, version int, class string) (*Item, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	class, err = NormaliseTaxClass(class)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := i.Store.GetItem(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if item.ArchivedAt != nil {
+		return nil, ErrItemArchived
+	}
+	if item.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	err = i.record(ctx, change(ctx, id, Updated, nil, []FieldChange{{Field: "tax_class", Before: &item.TaxClass, After: &class}}), func(ctx context.Context) error {
+		return i.Store.SetTaxClass(ctx, id, version, class)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set tax class: %w", err)
+	}
+
+	return i.Store.GetItem(ctx, id)
+}
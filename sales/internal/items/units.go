@@ -0,0 +1,155 @@
+package items
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Dimension is what a unit scale measures, only unit scales of the same dimension
+// can be converted between.
+type Dimension string
+
+const (
+	Count  Dimension = "count"
+	Mass   Dimension = "mass"
+	Volume Dimension = "volume"
+	Length Dimension = "length"
+)
+
+const (
+	Kilogram   UnitScale = "kg"
+	Gram       UnitScale = "g"
+	Litre      UnitScale = "l"
+	Millilitre UnitScale = "ml"
+	Metre      UnitScale = "m"
+)
+
+var (
+	ErrUnknownUnitScale      = errors.New("unknown unit scale")
+	ErrIncompatibleUnitScale = errors.New("unit scales measure different dimensions")
+)
+
+type unit struct {
+	dimension Dimension
+	// factor is how many of the dimensions base unit make up one of this unit.
+	factor *big.Rat
+}
+
+// registry holds every supported unit scale, the base unit of each dimension has factor 1.
+var registry = map[UnitScale]unit{
+	Unit:       {dimension: Count, factor: big.NewRat(1, 1)},
+	Gram:       {dimension: Mass, factor: big.NewRat(1, 1)},
+	Kilogram:   {dimension: Mass, factor: big.NewRat(1000, 1)},
+	Millilitre: {dimension: Volume, factor: big.NewRat(1, 1)},
+	Litre:      {dimension: Volume, factor: big.NewRat(1000, 1)},
+	Metre:      {dimension: Length, factor: big.NewRat(1, 1)},
+}
+
+// aliases are accepted spellings of unit scales that are normalised on parse.
+var aliases = map[string]UnitScale{
+	"each":        Unit,
+	"units":       Unit,
+	"kilogram":    Kilogram,
+	"kilograms":   Kilogram,
+	"gram":        Gram,
+	"grams":       Gram,
+	"litre":       Litre,
+	"litres":      Litre,
+	"liter":       Litre,
+	"liters":      Litre,
+	"millilitre":  Millilitre,
+	"millilitres": Millilitre,
+	"milliliter":  Millilitre,
+	"milliliters": Millilitre,
+	"metre":       Metre,
+	"metres":      Metre,
+	"meter":       Metre,
+	"meters":      Metre,
+}
+
+// ParseUnitScale normalises the given unit scale, returning ErrUnknownUnitScale for anything
+// that isn't in the registry.
+func ParseUnitScale(s string) (UnitScale, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if alias, ok := aliases[s]; ok {
+		return alias, nil
+	}
+	if _, ok := registry[UnitScale(s)]; ok {
+		return UnitScale(s), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownUnitScale, s)
+}
+
+func (s UnitScale) Dimension() (Dimension, error) {
+	u, ok := registry[s]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownUnitScale, string(s))
+	}
+	return u.dimension, nil
+}
+
+// Factor returns how many of the target unit scale make up one of this unit scale.
+func (s UnitScale) Factor(to UnitScale) (*big.Rat, error) {
+	from, ok := registry[s]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownUnitScale, string(s))
+	}
+	target, ok := registry[to]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownUnitScale, string(to))
+	}
+	if from.dimension != target.dimension {
+		return nil, fmt.Errorf("%w: %q is %s, %q is %s", ErrIncompatibleUnitScale, string(s), from.dimension, string(to), target.dimension)
+	}
+	return new(big.Rat).Quo(from.factor, target.factor), nil
+}
+
+func (s UnitScale) Compatible(other UnitScale) bool {
+	_, err := s.Factor(other)
+	return err == nil
+}
+
+func (s *UnitScale) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("must be a string")
+	}
+
+	scale, err := ParseUnitScale(str)
+	if err != nil {
+		return err
+	}
+
+	*s = scale
+	return nil
+}
+
+func (s UnitScale) MarshalGQL(w io.Writer) {
+	_, _ = io.WriteString(w, strconv.Quote(string(s)))
+}
+
+// Quantity is an exact amount measured in a unit scale.
+type Quantity struct {
+	Amount *big.Rat
+	Scale  UnitScale
+}
+
+// In converts the quantity into the target unit scale, ie 250g in kg is 1/4kg.
+func (q Quantity) In(to UnitScale) (Quantity, error) {
+	factor, err := q.Scale.Factor(to)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{
+		Amount: new(big.Rat).Mul(q.Amount, factor),
+		Scale:  to,
+	}, nil
+}
+
+func (q Quantity) String() string {
+	return q.Amount.FloatString(3) + string(q.Scale)
+}
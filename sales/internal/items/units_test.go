@@ -0,0 +1,76 @@
+package items
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnitScale(t *testing.T) {
+	for input, expected := range map[string]UnitScale{
+		"unit":   Unit,
+		"each":   Unit,
+		"KG":     Kilogram,
+		"gram":   Gram,
+		" litre": Litre,
+		"liter":  Litre,
+		"ml":     Millilitre,
+		"metre":  Metre,
+	} {
+		scale, err := ParseUnitScale(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, scale, input)
+	}
+
+	_, err := ParseUnitScale("furlong")
+	require.ErrorIs(t, err, ErrUnknownUnitScale)
+}
+
+func TestQuantityIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity Quantity
+		to       UnitScale
+		expected *big.Rat
+	}{
+		{"grams to kilograms", Quantity{big.NewRat(250, 1), Gram}, Kilogram, big.NewRat(1, 4)},
+		{"kilograms to grams", Quantity{big.NewRat(3, 2), Kilogram}, Gram, big.NewRat(1500, 1)},
+		{"millilitres to litres", Quantity{big.NewRat(330, 1), Millilitre}, Litre, big.NewRat(33, 100)},
+		{"same scale", Quantity{big.NewRat(2, 1), Unit}, Unit, big.NewRat(2, 1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			converted, err := test.quantity.In(test.to)
+			require.NoError(t, err)
+			assert.Equal(t, test.to, converted.Scale)
+			assert.Zero(t, test.expected.Cmp(converted.Amount), "expected %s, got %s", test.expected, converted.Amount)
+		})
+	}
+
+	_, err := Quantity{big.NewRat(1, 1), Kilogram}.In(Litre)
+	require.ErrorIs(t, err, ErrIncompatibleUnitScale)
+
+	_, err = Quantity{big.NewRat(1, 1), UnitScale("furlong")}.In(Metre)
+	require.ErrorIs(t, err, ErrUnknownUnitScale)
+}
+
+func TestParseChildQuantity(t *testing.T) {
+	for input, expected := range map[string]*big.Rat{
+		"1":      big.NewRat(1, 1),
+		" 2 ":    big.NewRat(2, 1),
+		"0.25":   big.NewRat(1, 4),
+		"0.0001": big.NewRat(1, 10000),
+	} {
+		quantity, err := ParseChildQuantity(input)
+		require.NoError(t, err, input)
+		assert.Zero(t, expected.Cmp(quantity), "expected %s, got %s", expected, quantity)
+	}
+
+	for _, input := range []string{"0", "0.0000", "-1", "0.00001", "1/4", ""} {
+		_, err := ParseChildQuantity(input)
+		require.ErrorIs(t, err, ErrChildQuantity, input)
+	}
+}
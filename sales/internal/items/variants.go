@@ -0,0 +1,207 @@
+package items
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/pkg/gtin"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Variant is a purchasable configuration of an item along its option axes, ie the large red
+// shirt of a shirt that declares the axes size and colour.
+type Variant struct {
+	ID     *keys.OpaqueID
+	ItemID *keys.OpaqueID
+	VariantDetails
+}
+
+type VariantDetails struct {
+	Options []Option
+	SKU     *string
+	Barcode *string
+	// PriceOverride replaces the price of the item for this variant when set.
+	PriceOverride *money.Money
+}
+
+type Option struct {
+	Axis  string `json:"axis"`
+	Value string `json:"value"`
+}
+
+var (
+	ErrVariantNotFound  = errors.New("variant not found")
+	ErrDuplicateVariant = errors.New("variant with these options already exists")
+	ErrVariantOptions   = errors.New("variant options must give exactly one value for every option axis of the item")
+	ErrOptionAxes       = errors.New("option axes must be distinct and named")
+	ErrVariantsExist    = errors.New("option axes cannot change once an item has variants")
+	ErrPriceOverride    = errors.New("variant price override must not be negative")
+)
+
+// SetOptionAxes declares the axes that variants of the item are configured along.
+func (i *ItemManager) SetOptionAxes(ctx context.Context, externalID *keys.OpaqueID, version int, axes []string) (*Item, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	var normalised = make([]string, 0, len(axes))
+	for _, axis := range axes {
+		axis = strings.ToLower(strings.TrimSpace(axis))
+		if axis == "" || slices.Contains(normalised, axis) {
+			return nil, ErrOptionAxes
+		}
+		normalised = append(normalised, axis)
+	}
+
+	item, err := i.Store.GetItem(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if item.ArchivedAt != nil {
+		return nil, ErrItemArchived
+	}
+	if item.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	variants, err := i.Store.GetVariants(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variants of item: %w", err)
+	}
+	if len(variants) > 0 {
+		return nil, ErrVariantsExist
+	}
+
+	before, after := strings.Join(item.OptionAxes, ","), strings.Join(normalised, ",")
+	err = i.record(ctx, change(ctx, id, Updated, nil, []FieldChange{{Field: "option_axes", Before: &before, After: &after}}), func(ctx context.Context) error {
+		return i.Store.SetOptionAxes(ctx, id, version, normalised)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set option axes: %w", err)
+	}
+
+	return i.Store.GetItem(ctx, id)
+}
+
+func (i *ItemManager) CreateVariant(ctx context.Context, itemID *keys.OpaqueID, deets VariantDetails) (*Variant, error) {
+	id, err := itemID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	item, err := i.Store.GetItem(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if item.ArchivedAt != nil {
+		return nil, ErrItemArchived
+	}
+
+	deets.Options, err = matchAxes(item.OptionAxes, deets.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	if deets.PriceOverride != nil && deets.PriceOverride.Sign() < 0 {
+		return nil, ErrPriceOverride
+	}
+
+	deets.SKU = normaliseSKU(deets.SKU)
+	if deets.Barcode != nil {
+		code := strings.TrimSpace(*deets.Barcode)
+		if err := gtin.Validate(code); err != nil {
+			return nil, err
+		}
+		deets.Barcode = &code
+	}
+
+	var (
+		options = describe(deets.Options)
+		variant *Variant
+	)
+	err = i.record(ctx, change(ctx, id, VariantAdded, nil, []FieldChange{{Field: "variant", After: &options}}), func(ctx context.Context) error {
+		created, err := i.Store.CreateVariant(ctx, id, deets)
+		variant = created
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create variant: %w", err)
+	}
+
+	return variant, nil
+}
+
+func (i *ItemManager) GetVariant(ctx context.Context, externalID *keys.OpaqueID) (*Variant, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	return i.Store.GetVariant(ctx, id)
+}
+
+func (i *ItemManager) GetVariants(ctx context.Context, item *Item) ([]*Variant, error) {
+	return i.Store.GetVariants(ctx, item.ID.ID)
+}
+
+// FindVariant looks up the variant of the item configured with exactly the options given.
+func (i *ItemManager) FindVariant(ctx context.Context, item *Item, options []Option) (*Variant, error) {
+	options, err := matchAxes(item.OptionAxes, options)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := i.Store.GetVariants(ctx, item.ID.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variants of item: %w", err)
+	}
+
+	for _, variant := range variants {
+		if slices.Equal(variant.Options, options) {
+			return variant, nil
+		}
+	}
+	return nil, ErrVariantNotFound
+}
+
+func (i *ItemManager) VariantItem(ctx context.Context, variant *Variant) (*Item, error) {
+	return i.Store.GetItem(ctx, variant.ItemID.ID)
+}
+
+// matchAxes checks that the options give a value for every axis, returning them normalised and
+// sorted by axis so that they can be compared.
+func matchAxes(axes []string, options []Option) ([]Option, error) {
+	if len(axes) == 0 || len(options) != len(axes) {
+		return nil, ErrVariantOptions
+	}
+
+	var matched = make([]Option, 0, len(options))
+	for _, option := range options {
+		option.Axis = strings.ToLower(strings.TrimSpace(option.Axis))
+		option.Value = strings.TrimSpace(option.Value)
+		if !slices.Contains(axes, option.Axis) || option.Value == "" {
+			return nil, ErrVariantOptions
+		}
+		if slices.ContainsFunc(matched, func(o Option) bool { return o.Axis == option.Axis }) {
+			return nil, ErrVariantOptions
+		}
+		matched = append(matched, option)
+	}
+
+	sort.Slice(matched, func(a, b int) bool { return matched[a].Axis < matched[b].Axis })
+	return matched, nil
+}
+
+func describe(options []Option) string {
+	var parts = make([]string, 0, len(options))
+	for _, option := range options {
+		parts = append(parts, option.Axis+"="+option.Value)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,35 @@
+package items
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchAxes(t *testing.T) {
+	axes := []string{"size", "colour"}
+
+	t.Run("normalised and sorted", func(t *testing.T) {
+		matched, err := matchAxes(axes, []Option{{Axis: "Size ", Value: " L"}, {Axis: "colour", Value: "red"}})
+		require.NoError(t, err)
+		assert.Equal(t, []Option{{Axis: "colour", Value: "red"}, {Axis: "size", Value: "L"}}, matched)
+	})
+
+	for name, options := range map[string][]Option{
+		"missing axis":  {{Axis: "size", Value: "L"}},
+		"unknown axis":  {{Axis: "size", Value: "L"}, {Axis: "fit", Value: "slim"}},
+		"repeated axis": {{Axis: "size", Value: "L"}, {Axis: "size", Value: "M"}},
+		"empty value":   {{Axis: "size", Value: "L"}, {Axis: "colour", Value: " "}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := matchAxes(axes, options)
+			require.ErrorIs(t, err, ErrVariantOptions)
+		})
+	}
+
+	t.Run("item without axes", func(t *testing.T) {
+		_, err := matchAxes(nil, nil)
+		require.ErrorIs(t, err, ErrVariantOptions)
+	})
+}
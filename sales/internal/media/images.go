@@ -0,0 +1,252 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// MaxImageSize is the largest image in bytes that can be attached to an item.
+const MaxImageSize = 10 << 20
+
+// Image is an image attached to an item, images of an item are ordered by position.
+type Image struct {
+	ID     *keys.OpaqueID
+	ItemID *keys.OpaqueID
+	Metadata
+
+	// Key addresses the bytes of the image in the blob store.
+	Key       string
+	Position  int
+	CreatedAt time.Time
+}
+
+// Metadata is read from the uploaded bytes themselves, whatever the uploader claims is ignored.
+type Metadata struct {
+	ContentType string
+	Width       int
+	Height      int
+	Size        int64
+	// Checksum is the hex encoded SHA-256 of the image.
+	Checksum string
+}
+
+var (
+	ErrImageNotFound    = errors.New("image not found")
+	ErrUnsupportedImage = errors.New("image must be a png, jpeg or gif")
+	ErrImageTooLarge    = fmt.Errorf("image must be at most %d bytes", MaxImageSize)
+	ErrDuplicateImage   = errors.New("image is already attached to the item")
+	ErrImageOrder       = errors.New("order must list every image of the item exactly once")
+)
+
+var extensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+type store interface {
+	CreateImage(ctx context.Context, item int, key string, meta Metadata) (*Image, error)
+	GetImage(context.Context, int) (*Image, error)
+	GetImages(ctx context.Context, item int) ([]*Image, error)
+	DeleteImage(context.Context, int) error
+	// ReorderImages assigns positions in the order of the ids given.
+	ReorderImages(ctx context.Context, item int, ids []int) error
+}
+
+// itemStore resolves the items that images are attached to.
+type itemStore interface {
+	GetItem(context.Context, int) (*items.Item, error)
+}
+
+type ImageManager struct {
+	Store  store
+	Items  itemStore
+	Blobs  BlobStore
+	Signer *Signer
+}
+
+// inspect reads the metadata of an image, only formats with a registered decoder are accepted.
+func inspect(data []byte) (Metadata, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		return Metadata{}, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Metadata{}, ErrUnsupportedImage
+	}
+
+	checksum := sha256.Sum256(data)
+	return Metadata{
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		Size:        int64(len(data)),
+		Checksum:    hex.EncodeToString(checksum[:]),
+	}, nil
+}
+
+// AttachImage stores the image and appends it to the images of the item.
+func (m *ImageManager) AttachImage(ctx context.Context, itemID *keys.OpaqueID, r io.Reader) (*Image, error) {
+	item, err := m.item(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > MaxImageSize {
+		return nil, ErrImageTooLarge
+	}
+
+	meta, err := inspect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key := "images/" + uuid.NewString() + extensions[meta.ContentType]
+	if err := m.Blobs.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+
+	img, err := m.Store.CreateImage(ctx, item.ID.ID, key, meta)
+	if err != nil {
+		if err := m.Blobs.Delete(ctx, key); err != nil {
+			slog.Error(fmt.Sprintf("failed to clean up image blob %q: %v", key, err))
+		}
+		return nil, fmt.Errorf("failed to create image: %w", err)
+	}
+	return img, nil
+}
+
+func (m *ImageManager) GetImages(ctx context.Context, item *items.Item) ([]*Image, error) {
+	return m.Store.GetImages(ctx, item.ID.ID)
+}
+
+func (m *ImageManager) ImageItem(ctx context.Context, img *Image) (*items.Item, error) {
+	return m.Items.GetItem(ctx, img.ItemID.ID)
+}
+
+// RemoveImage detaches the image from its item and discards the stored bytes.
+func (m *ImageManager) RemoveImage(ctx context.Context, externalID *keys.OpaqueID) (*Image, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	img, err := m.Store.GetImage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.Items.GetItem(ctx, img.ItemID.ID); err != nil {
+		return nil, fmt.Errorf("failed to get item of image: %w", err)
+	}
+
+	if err := m.Store.DeleteImage(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to delete image: %w", err)
+	}
+
+	err = m.Blobs.Delete(ctx, img.Key)
+	if err != nil && !errors.Is(err, ErrBlobNotFound) {
+		return nil, fmt.Errorf("failed to delete image blob: %w", err)
+	}
+	return img, nil
+}
+
+// ReorderImages orders the images of the item as given, every image of the item must be listed.
+func (m *ImageManager) ReorderImages(ctx context.Context, itemID *keys.OpaqueID, imageIDs []*keys.OpaqueID) ([]*Image, error) {
+	item, err := m.item(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids = make([]int, 0, len(imageIDs))
+	for _, imageID := range imageIDs {
+		id, err := imageID.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := m.Store.ReorderImages(ctx, item.ID.ID, ids); err != nil {
+		return nil, fmt.Errorf("failed to reorder images: %w", err)
+	}
+	return m.Store.GetImages(ctx, item.ID.ID)
+}
+
+// item resolves the item images are attached to, items of other retailers are never found.
+func (m *ImageManager) item(ctx context.Context, itemID *keys.OpaqueID) (*items.Item, error) {
+	id, err := itemID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode item id: %w", err)
+	}
+
+	item, err := m.Items.GetItem(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	return item, nil
+}
+
+// URL signs a URL to the image that expires after the TTL of the signer.
+func (m *ImageManager) URL(ctx context.Context, img *Image) (string, time.Time) {
+	return m.Signer.Sign(img.Key, time.Now())
+}
+
+// Handler serves blobs to holders of a URL issued by URL, it expects to be mounted under the base
+// URL of the signer with that prefix stripped.
+func (m *ImageManager) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+
+		err := m.Signer.Verify(key, r.URL.Query(), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		blob, err := m.Blobs.Open(r.Context(), key)
+		if errors.Is(err, ErrBlobNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			slog.Error(fmt.Sprintf("failed to open blob %q: %v", key, err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer blob.Close()
+
+		if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+
+		// keys are never reused, so the blob can be cached for as long as the url is valid
+		expires, _ := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, immutable, max-age=%d", expires-time.Now().Unix()))
+		http.ServeContent(w, r, "", time.Time{}, blob)
+	})
+}
@@ -0,0 +1,180 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	data := encodePNG(t, 3, 2)
+
+	meta, err := inspect(data)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", meta.ContentType)
+	assert.Equal(t, 3, meta.Width)
+	assert.Equal(t, 2, meta.Height)
+	assert.Equal(t, int64(len(data)), meta.Size)
+	assert.Len(t, meta.Checksum, 64)
+
+	_, err = inspect([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+
+	// a png signature followed by garbage is not an image
+	_, err = inspect(append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), "garbage"...))
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	blobs := &LocalBlobStore{Root: t.TempDir()}
+
+	require.NoError(t, blobs.Put(ctx, "images/a.png", bytes.NewReader([]byte("a"))))
+
+	blob, err := blobs.Open(ctx, "images/a.png")
+	require.NoError(t, err)
+	content, err := io.ReadAll(blob)
+	require.NoError(t, err)
+	require.NoError(t, blob.Close())
+	assert.Equal(t, []byte("a"), content)
+
+	for _, key := range []string{"../a.png", "images/../../a.png", "/images/a.png", ""} {
+		_, err := blobs.Open(ctx, key)
+		assert.Error(t, err, key)
+		assert.NotErrorIs(t, err, ErrBlobNotFound, key)
+	}
+
+	require.NoError(t, blobs.Delete(ctx, "images/a.png"))
+	_, err = blobs.Open(ctx, "images/a.png")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+}
+
+func TestImageHandler(t *testing.T) {
+	ctx := context.Background()
+	data := encodePNG(t, 1, 1)
+
+	manager := &ImageManager{
+		Blobs:  &LocalBlobStore{Root: t.TempDir()},
+		Signer: &Signer{Secret: []byte("secret"), BaseURL: "/media", TTL: time.Minute},
+	}
+	require.NoError(t, manager.Blobs.Put(ctx, "images/a.png", bytes.NewReader(data)))
+
+	server := httptest.NewServer(http.StripPrefix("/media", manager.Handler()))
+	defer server.Close()
+
+	signed, _ := manager.URL(ctx, &Image{Key: "images/a.png"})
+
+	res, err := http.Get(server.URL + signed)
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "image/png", res.Header.Get("Content-Type"))
+	assert.Equal(t, data, body)
+
+	res, err = http.Get(server.URL + "/media/images/a.png")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
+
+// gallery holds images of item 1, the only item of the retailer, and of item 2 of another retailer
+// that a scoped store would never hand back.
+type gallery struct{ images map[int]*Image }
+
+func (g *gallery) CreateImage(ctx context.Context, item int, key string, meta Metadata) (*Image, error) {
+	img := &Image{ID: &keys.OpaqueID{ID: len(g.images) + 1}, ItemID: &keys.OpaqueID{ID: item}, Metadata: meta, Key: key}
+	g.images[img.ID.ID] = img
+	return img, nil
+}
+
+func (g *gallery) GetImage(ctx context.Context, id int) (*Image, error) {
+	img, ok := g.images[id]
+	if !ok {
+		return nil, ErrImageNotFound
+	}
+	return img, nil
+}
+
+func (g *gallery) GetImages(ctx context.Context, item int) ([]*Image, error) { return nil, nil }
+
+func (g *gallery) DeleteImage(ctx context.Context, id int) error {
+	delete(g.images, id)
+	return nil
+}
+
+func (g *gallery) ReorderImages(ctx context.Context, item int, ids []int) error { return nil }
+
+func (g *gallery) GetItem(ctx context.Context, id int) (*items.Item, error) {
+	if id != 1 {
+		return nil, items.ErrItemNotFound
+	}
+	return &items.Item{ID: &keys.OpaqueID{ID: id}}, nil
+}
+
+// codec takes external ids to be internal ids written out.
+type codec struct{}
+
+func (codec) Encode(ctx context.Context, id int) (string, error) {
+	return strconv.Itoa(id), nil
+}
+
+func (codec) Decode(ctx context.Context, id string) (int, error) {
+	return strconv.Atoi(id)
+}
+
+func opaque(t *testing.T, id int) *keys.OpaqueID {
+	t.Helper()
+
+	var external = &keys.OpaqueID{}
+	require.NoError(t, external.UnmarshalGQLContext(context.Background(), strconv.Itoa(id)))
+	return external.WithCodec(codec{})
+}
+
+func TestImagesOfOtherRetailers(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		store   = &gallery{images: map[int]*Image{}}
+		blobs   = &LocalBlobStore{Root: t.TempDir()}
+		manager = &ImageManager{Store: store, Items: store, Blobs: blobs}
+	)
+
+	_, err := manager.AttachImage(ctx, opaque(t, 2), bytes.NewReader(encodePNG(t, 1, 1)))
+	require.ErrorIs(t, err, items.ErrItemNotFound)
+	assert.Empty(t, store.images, "nothing is stored for items that aren't there")
+
+	_, err = manager.ReorderImages(ctx, opaque(t, 2), nil)
+	require.ErrorIs(t, err, items.ErrItemNotFound)
+
+	theirs := &Image{ID: &keys.OpaqueID{ID: 9}, ItemID: &keys.OpaqueID{ID: 2}, Key: "images/theirs.png"}
+	store.images[9] = theirs
+	_, err = manager.RemoveImage(ctx, opaque(t, 9))
+	require.ErrorIs(t, err, items.ErrItemNotFound)
+	assert.Contains(t, store.images, 9)
+
+	mine, err := manager.AttachImage(ctx, opaque(t, 1), bytes.NewReader(encodePNG(t, 1, 1)))
+	require.NoError(t, err)
+	_, err = manager.RemoveImage(ctx, opaque(t, mine.ID.ID))
+	require.NoError(t, err)
+	assert.NotContains(t, store.images, mine.ID.ID)
+}
@@ -0,0 +1,60 @@
+package media
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrURLExpired   = errors.New("url has expired")
+	ErrURLSignature = errors.New("url signature is invalid")
+)
+
+// Signer issues expiring URLs to blobs, anyone holding the URL can fetch the blob until it
+// expires without any further authorisation.
+type Signer struct {
+	Secret []byte
+	// BaseURL is where the blob handler is mounted, ie "/media".
+	BaseURL string
+	TTL     time.Duration
+}
+
+func (s *Signer) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns the URL to the blob along with when it stops being valid.
+func (s *Signer) Sign(key string, now time.Time) (string, time.Time) {
+	expires := now.Add(s.TTL).Truncate(time.Second)
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", s.signature(key, expires.Unix()))
+
+	return fmt.Sprintf("%s/%s?%s", strings.TrimSuffix(s.BaseURL, "/"), key, query.Encode()), expires
+}
+
+// Verify checks the expires and signature query parameters issued by Sign for the key.
+func (s *Signer) Verify(key string, query url.Values, now time.Time) error {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return ErrURLSignature
+	}
+
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(s.signature(key, expires))) {
+		return ErrURLSignature
+	}
+	if now.Unix() > expires {
+		return ErrURLExpired
+	}
+	return nil
+}
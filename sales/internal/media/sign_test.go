@@ -0,0 +1,51 @@
+package media
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	signer := &Signer{Secret: []byte("secret"), BaseURL: "/media/", TTL: time.Minute}
+	now := time.Unix(1700000000, 0)
+
+	signed, expires := signer.Sign("images/a.png", now)
+	assert.Equal(t, now.Add(time.Minute), expires)
+
+	parsed, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/media/images/a.png", parsed.Path)
+
+	tests := []struct {
+		name  string
+		key   string
+		query url.Values
+		at    time.Time
+		err   error
+	}{
+		{name: "valid", key: "images/a.png", query: parsed.Query(), at: now},
+		{name: "valid until expiry", key: "images/a.png", query: parsed.Query(), at: expires},
+		{name: "expired", key: "images/a.png", query: parsed.Query(), at: expires.Add(time.Second), err: ErrURLExpired},
+		{name: "other key", key: "images/b.png", query: parsed.Query(), at: now, err: ErrURLSignature},
+		{name: "extended expiry", key: "images/a.png", query: url.Values{
+			"expires":   {"1900000000"},
+			"signature": {parsed.Query().Get("signature")},
+		}, at: now, err: ErrURLSignature},
+		{name: "unsigned", key: "images/a.png", query: url.Values{}, at: now, err: ErrURLSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signer.Verify(tt.key, tt.query, tt.at)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
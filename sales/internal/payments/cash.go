@@ -0,0 +1,21 @@
+package payments
+
+import "github.com/suessflorian/pedlar/sales/pkg/money"
+
+// Change applies cash tendered to the amount due, it returns what the cash pays off and the change
+// given back. Cash short of the amount due pays off what it can without change.
+func Change(due, tendered money.Money) (applied, change money.Money, err error) {
+	over, err := tendered.Cmp(due)
+	if err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	if over <= 0 {
+		return tendered, money.Zero(tendered.Currency()), nil
+	}
+
+	change, err = tendered.Sub(due)
+	if err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	return due, change, nil
+}
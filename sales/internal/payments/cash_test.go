@@ -0,0 +1,40 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		due      string
+		tendered string
+		applied  string
+		change   string
+	}{
+		{name: "exact", due: "17.83", tendered: "17.83", applied: "17.83", change: "0.00"},
+		{name: "over", due: "17.83", tendered: "20.00", applied: "17.83", change: "2.17"},
+		{name: "short", due: "17.83", tendered: "10.00", applied: "10.00", change: "0.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, err := money.Parse(tt.due, "NZD")
+			require.NoError(t, err)
+			tendered, err := money.Parse(tt.tendered, "NZD")
+			require.NoError(t, err)
+
+			applied, change, err := Change(due, tendered)
+			require.NoError(t, err)
+			assert.Equal(t, tt.applied, applied.Decimal())
+			assert.Equal(t, tt.change, change.Decimal())
+		})
+	}
+
+	_, _, err := Change(money.Zero("NZD"), money.Zero("AUD"))
+	require.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
@@ -0,0 +1,47 @@
+package payments
+
+import (
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Tender is how a customer pays.
+type Tender string
+
+const (
+	Cash        Tender = "CASH"
+	Card        Tender = "CARD"
+	Voucher     Tender = "VOUCHER"
+	StoreCredit Tender = "STORE_CREDIT"
+)
+
+// Payment pays off part or all of a sale by a single tender, a sale split across tenders has a
+// payment for each of them.
+type Payment struct {
+	ID     *keys.OpaqueID
+	SaleID *keys.OpaqueID
+	Tender Tender
+	// Amount is what the payment pays off the sale.
+	Amount money.Money
+	// Tendered is the cash handed over and Change what was given back of it, both are only set
+	// for cash payments.
+	Tendered *money.Money
+	Change   *money.Money
+	// Reference identifies the charge with the payment provider, cash has none.
+	Reference *string
+	Actor     actor.Actor
+	PaidAt    time.Time
+	// ReversedAt is set once the charge was undone by voiding the sale, ReversalFailure says why
+	// the charge couldn't be undone while it is left to retry.
+	ReversedAt      *time.Time
+	ReversalFailure *string
+}
+
+// ThroughProvider is whether the tender is charged by the payment provider, cash changes hands
+// at the till.
+func (t Tender) ThroughProvider() bool {
+	return t != Cash
+}
@@ -0,0 +1,72 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+var (
+	ErrDeclined = errors.New("payment declined")
+	ErrNoCharge = errors.New("charge not found")
+	ErrReversed = errors.New("charge has been reversed already")
+)
+
+// PaymentProvider charges every tender but cash, ie a card terminal or the system that holds
+// voucher and store credit balances.
+type PaymentProvider interface {
+	// Charge takes the amount by the tender from what the token identifies, ie a card or a voucher
+	// code, returning the reference of the charge.
+	Charge(ctx context.Context, tender Tender, amount money.Money, token string) (string, error)
+	// Reverse undoes the charge in full.
+	Reverse(ctx context.Context, reference string) error
+}
+
+// Local approves every charge without moving any money, tokens starting with "decline" are
+// declined. It is meant for development and tests rather than taking real payments.
+type Local struct {
+	mu       sync.Mutex
+	charges  map[string]money.Money
+	reversed map[string]bool
+}
+
+func (l *Local) Charge(ctx context.Context, tender Tender, amount money.Money, token string) (string, error) {
+	if strings.HasPrefix(token, "decline") {
+		return "", fmt.Errorf("%w: %s of %s", ErrDeclined, tender, amount)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.charges == nil {
+		l.charges, l.reversed = make(map[string]money.Money), make(map[string]bool)
+	}
+	reference := fmt.Sprintf("local-%d", len(l.charges)+1)
+	l.charges[reference] = amount
+	return reference, nil
+}
+
+func (l *Local) Reverse(ctx context.Context, reference string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.charges[reference]; !ok {
+		return ErrNoCharge
+	}
+	if l.reversed[reference] {
+		return ErrReversed
+	}
+	l.reversed[reference] = true
+	return nil
+}
+
+// Reversed tells whether the charge has been reversed.
+func (l *Local) Reversed(reference string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.reversed[reference]
+}
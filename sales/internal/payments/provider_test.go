@@ -0,0 +1,27 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	amount, err := money.Parse("5.00", "NZD")
+	require.NoError(t, err)
+
+	var local Local
+	_, err = local.Charge(ctx, Card, amount, "decline-insufficient-funds")
+	require.ErrorIs(t, err, ErrDeclined)
+
+	reference, err := local.Charge(ctx, Card, amount, "4242")
+	require.NoError(t, err)
+	require.NoError(t, local.Reverse(ctx, reference))
+	assert.True(t, local.Reversed(reference))
+	require.ErrorIs(t, local.Reverse(ctx, reference), ErrReversed)
+	require.ErrorIs(t, local.Reverse(ctx, "unknown"), ErrNoCharge)
+}
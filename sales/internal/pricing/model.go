@@ -0,0 +1,51 @@
+package pricing
+
+import (
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Kind is who a price list is meant for.
+type Kind string
+
+const (
+	Retail    Kind = "RETAIL"
+	Wholesale Kind = "WHOLESALE"
+	Member    Kind = "MEMBER"
+)
+
+// PriceList is a set of item prices of a retailer, all in the currency of the list. Sales are
+// priced from the retail list of their retailer and currency unless told otherwise.
+type PriceList struct {
+	ID         *keys.OpaqueID
+	RetailerID *keys.OpaqueID
+	Name       string
+	Kind       Kind
+	Currency   money.Currency
+}
+
+// Price is the price of an item on a price list within [EffectiveFrom, EffectiveTo), an open
+// ended price has no EffectiveTo. The amount is the price of one of the unit scale, which may be
+// any unit scale compatible with the unit scale of the item, ie 45.00 per kg for an item sold in g.
+type Price struct {
+	ID            *keys.OpaqueID
+	PriceListID   *keys.OpaqueID
+	ItemID        *keys.OpaqueID
+	Amount        money.Money
+	UnitScale     items.UnitScale
+	EffectiveFrom time.Time
+	EffectiveTo   *time.Time
+}
+
+// Per converts the price into the price of one of the given unit scale, rounding half to even
+// where the conversion is finer than the minor unit of the currency.
+func (p *Price) Per(scale items.UnitScale) (money.Money, error) {
+	factor, err := scale.Factor(p.UnitScale)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return p.Amount.Mul(factor, money.HalfEven)
+}
@@ -0,0 +1,44 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestPricePer(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount string
+		per    items.UnitScale
+		scale  items.UnitScale
+		want   string
+		err    error
+	}{
+		{name: "same unit scale", amount: "4.50", per: items.Unit, scale: items.Unit, want: "4.50"},
+		{name: "per kg sold in kg", amount: "45.00", per: items.Kilogram, scale: items.Kilogram, want: "45.00"},
+		{name: "per kg sold in g", amount: "45.00", per: items.Kilogram, scale: items.Gram, want: "0.04"},
+		{name: "per kg sold in g rounds half to even", amount: "25.00", per: items.Kilogram, scale: items.Gram, want: "0.02"},
+		{name: "per ml sold in l", amount: "0.01", per: items.Millilitre, scale: items.Litre, want: "10.00"},
+		{name: "incompatible", amount: "1.00", per: items.Kilogram, scale: items.Litre, err: items.ErrIncompatibleUnitScale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := money.Parse(tt.amount, "NZD")
+			require.NoError(t, err)
+
+			price := &Price{Amount: amount, UnitScale: tt.per}
+			got, err := price.Per(tt.scale)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Decimal())
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type store interface {
+	CreatePriceList(ctx context.Context, retailer int, name string, kind Kind, currency money.Currency) (*PriceList, error)
+	GetPriceList(context.Context, int) (*PriceList, error)
+	GetPriceLists(ctx context.Context, retailer int) ([]*PriceList, error)
+	// DefaultPriceList is the retail price list of the retailer in the currency.
+	DefaultPriceList(ctx context.Context, retailer int, currency money.Currency) (*PriceList, error)
+	// CreatePrice adds the price with its id assigned in place. An open ended price of the item that
+	// starts before the new price is ended where the new price starts, any other overlap in
+	// effective dates is refused with ErrPriceOverlap.
+	CreatePrice(context.Context, *Price) error
+	// ActivePrice is the price of the item on the price list effective at the time.
+	ActivePrice(ctx context.Context, list int, item int, at time.Time) (*Price, error)
+	// GetPrices lists every price the item has had on the price list, latest first.
+	GetPrices(ctx context.Context, list int, item int) ([]*Price, error)
+}
+
+// itemStore resolves the items that prices are for.
+type itemStore interface {
+	GetItem(context.Context, int) (*items.Item, error)
+}
+
+type PriceManager struct {
+	Store store
+	Items itemStore
+}
+
+var (
+	ErrPriceListNotFound  = errors.New("price list not found")
+	ErrNoNamePriceList    = errors.New("price list must have a name")
+	ErrDuplicatePriceList = errors.New("price list with this name already exists for the retailer")
+	// ErrDuplicateRetailList is returned for a second retail price list of a retailer in the same
+	// currency, the retail list is what sales are priced from by default.
+	ErrDuplicateRetailList = errors.New("retailer already has a retail price list in this currency")
+	ErrPriceListKind       = errors.New("unknown price list kind")
+	ErrRetailerNotFound    = errors.New("retailer not found")
+	ErrNoPrice             = errors.New("item has no price on the price list at this time")
+	ErrPriceOverlap        = errors.New("price overlaps the effective dates of another price of the item")
+	ErrEffectiveDates      = errors.New("price must be effective to a time after it is effective from")
+	ErrNegativePrice       = errors.New("price must not be negative")
+	ErrPriceCurrency       = errors.New("price must be in the currency of its price list")
+)
+
+type NewPriceList struct {
+	Retailer *keys.OpaqueID
+	Name     string
+	Kind     Kind
+	Currency money.Currency
+}
+
+// NewPrice prices an item on a price list, the unit scale defaults to the unit scale of the item
+// and prices are effective from now unless told otherwise.
+type NewPrice struct {
+	PriceList     *keys.OpaqueID
+	Item          *keys.OpaqueID
+	Amount        money.Input
+	UnitScale     *items.UnitScale
+	EffectiveFrom *time.Time
+	EffectiveTo   *time.Time
+}
+
+func (p *PriceManager) CreatePriceList(ctx context.Context, input NewPriceList) (*PriceList, error) {
+	retailer, err := scope(ctx, input.Retailer)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, ErrNoNamePriceList
+	}
+
+	switch input.Kind {
+	case Retail, Wholesale, Member:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrPriceListKind, input.Kind)
+	}
+
+	if _, err := money.ParseCurrency(string(input.Currency)); err != nil {
+		return nil, err
+	}
+
+	list, err := p.Store.CreatePriceList(ctx, retailer, name, input.Kind, input.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create price list: %w", err)
+	}
+	return list, nil
+}
+
+func (p *PriceManager) GetPriceList(ctx context.Context, externalID *keys.OpaqueID) (*PriceList, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	return p.Store.GetPriceList(ctx, id)
+}
+
+func (p *PriceManager) GetPriceLists(ctx context.Context, retailerID *keys.OpaqueID) ([]*PriceList, error) {
+	retailer, err := scope(ctx, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	return p.Store.GetPriceLists(ctx, retailer)
+}
+
+// scope is the retailer the request is scoped to, a retailer named by the request must be that one.
+func scope(ctx context.Context, named *keys.OpaqueID) (int, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	if named != nil {
+		id, err := named.Decode(ctx)
+		if err != nil {
+			return -1, fmt.Errorf("failed to decode retailer id: %w", err)
+		}
+		if id != retailer {
+			return -1, ErrRetailerNotFound
+		}
+	}
+	return retailer, nil
+}
+
+// PriceListByID gets the price list by its internal id, for records that reference price lists.
+func (p *PriceManager) PriceListByID(ctx context.Context, id int) (*PriceList, error) {
+	return p.Store.GetPriceList(ctx, id)
+}
+
+// PriceList resolves the price list of a price.
+func (p *PriceManager) PriceList(ctx context.Context, price *Price) (*PriceList, error) {
+	return p.Store.GetPriceList(ctx, price.PriceListID.ID)
+}
+
+func (p *PriceManager) SetItemPrice(ctx context.Context, input NewPrice) (*Price, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listID, err := input.PriceList.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode price list id: %w", err)
+	}
+	itemID, err := input.Item.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode item id: %w", err)
+	}
+
+	list, err := p.Store.GetPriceList(ctx, listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price list: %w", err)
+	}
+	// price lists of other retailers are as good as not there
+	if list.RetailerID.ID != retailer {
+		return nil, ErrPriceListNotFound
+	}
+
+	item, err := p.Items.GetItem(ctx, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	amount, err := input.Amount.Money()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse price: %w", err)
+	}
+	if amount.Sign() < 0 {
+		return nil, ErrNegativePrice
+	}
+	if amount.Currency() != list.Currency {
+		return nil, fmt.Errorf("%w: %s on a price list in %s", ErrPriceCurrency, amount.Currency(), list.Currency)
+	}
+
+	var scale = item.UnitScale
+	if input.UnitScale != nil {
+		scale = *input.UnitScale
+	}
+	if _, err := item.UnitScale.Factor(scale); err != nil {
+		return nil, err
+	}
+
+	var from = time.Now()
+	if input.EffectiveFrom != nil {
+		from = *input.EffectiveFrom
+	}
+	if input.EffectiveTo != nil && !input.EffectiveTo.After(from) {
+		return nil, ErrEffectiveDates
+	}
+
+	var price = &Price{
+		PriceListID:   list.ID,
+		ItemID:        item.ID,
+		Amount:        amount,
+		UnitScale:     scale,
+		EffectiveFrom: from,
+		EffectiveTo:   input.EffectiveTo,
+	}
+	if err := p.Store.CreatePrice(ctx, price); err != nil {
+		return nil, fmt.Errorf("failed to create price: %w", err)
+	}
+	return price, nil
+}
+
+// ItemPrice is the price of one of the unit scale of the item on the price list at the time.
+func (p *PriceManager) ItemPrice(ctx context.Context, item *items.Item, listID *keys.OpaqueID, at *time.Time) (*money.Money, error) {
+	id, err := listID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode price list id: %w", err)
+	}
+
+	var when = time.Now()
+	if at != nil {
+		when = *at
+	}
+
+	quoted, err := p.quote(ctx, id, item, when)
+	if err != nil {
+		return nil, err
+	}
+	return &quoted, nil
+}
+
+// ItemPrices lists every price the item has had on the price list, latest first.
+func (p *PriceManager) ItemPrices(ctx context.Context, item *items.Item, listID *keys.OpaqueID) ([]*Price, error) {
+	id, err := listID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode price list id: %w", err)
+	}
+	return p.Store.GetPrices(ctx, id, item.ID.ID)
+}
+
+// SaleList picks the price list a sale of the retailer is priced from, the given price list or
+// otherwise the retail list of the retailer in the currency of the sale. Retailers without a
+// retail list have no default and nil is returned.
+func (p *PriceManager) SaleList(ctx context.Context, retailer int, listID *keys.OpaqueID, currency money.Currency) (*PriceList, error) {
+	if listID == nil {
+		list, err := p.Store.DefaultPriceList(ctx, retailer, currency)
+		if errors.Is(err, ErrPriceListNotFound) {
+			return nil, nil
+		}
+		return list, err
+	}
+
+	id, err := listID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode price list id: %w", err)
+	}
+
+	list, err := p.Store.GetPriceList(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// price lists of other retailers are as good as not there
+	if list.RetailerID.ID != retailer {
+		return nil, ErrPriceListNotFound
+	}
+	if list.Currency != currency {
+		return nil, fmt.Errorf("%w: price list in %s for a sale in %s", money.ErrCurrencyMismatch, list.Currency, currency)
+	}
+	return list, nil
+}
+
+// Quote is the price of one of the unit scale of the item on the price list at the time.
+func (p *PriceManager) Quote(ctx context.Context, list *PriceList, itemID int, at time.Time) (money.Money, error) {
+	item, err := p.Items.GetItem(ctx, itemID)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to get item: %w", err)
+	}
+	return p.quote(ctx, list.ID.ID, item, at)
+}
+
+func (p *PriceManager) quote(ctx context.Context, list int, item *items.Item, at time.Time) (money.Money, error) {
+	price, err := p.Store.ActivePrice(ctx, list, item.ID.ID, at)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	quoted, err := price.Per(item.UnitScale)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to convert price into unit scale of item: %w", err)
+	}
+	return quoted, nil
+}
@@ -0,0 +1,93 @@
+package pricing
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// codec takes external ids to be internal ids written out.
+type codec struct{}
+
+func (codec) Encode(ctx context.Context, id int) (string, error) {
+	return strconv.Itoa(id), nil
+}
+
+func (codec) Decode(ctx context.Context, id string) (int, error) {
+	return strconv.Atoi(id)
+}
+
+func opaque(t *testing.T, id int) *keys.OpaqueID {
+	t.Helper()
+
+	var external = &keys.OpaqueID{}
+	require.NoError(t, external.UnmarshalGQLContext(context.Background(), strconv.Itoa(id)))
+	return external.WithCodec(codec{})
+}
+
+// lists holds price list 1 of retailer 1 and price list 2 of retailer 2, both in NZD, and hands
+// back any price it's given.
+type lists struct{ created []*Price }
+
+func (l *lists) CreatePriceList(ctx context.Context, retailer int, name string, kind Kind, currency money.Currency) (*PriceList, error) {
+	return nil, nil
+}
+
+func (l *lists) GetPriceList(ctx context.Context, id int) (*PriceList, error) {
+	if id != 1 && id != 2 {
+		return nil, ErrPriceListNotFound
+	}
+	return &PriceList{ID: &keys.OpaqueID{ID: id}, RetailerID: &keys.OpaqueID{ID: id}, Name: "retail", Kind: Retail, Currency: "NZD"}, nil
+}
+
+func (l *lists) GetPriceLists(ctx context.Context, retailer int) ([]*PriceList, error) {
+	return nil, nil
+}
+
+func (l *lists) DefaultPriceList(ctx context.Context, retailer int, currency money.Currency) (*PriceList, error) {
+	return nil, ErrPriceListNotFound
+}
+
+func (l *lists) CreatePrice(ctx context.Context, price *Price) error {
+	l.created = append(l.created, price)
+	return nil
+}
+
+func (l *lists) ActivePrice(ctx context.Context, list int, item int, at time.Time) (*Price, error) {
+	return nil, ErrNoPrice
+}
+
+func (l *lists) GetPrices(ctx context.Context, list int, item int) ([]*Price, error) { return nil, nil }
+
+func (l *lists) GetItem(ctx context.Context, id int) (*items.Item, error) {
+	return &items.Item{ID: &keys.OpaqueID{ID: id}, Details: items.Details{Name: "mug", UnitScale: items.Unit}}, nil
+}
+
+func TestSetItemPrice(t *testing.T) {
+	var (
+		ctx     = retailers.With(context.Background(), 1)
+		store   = &lists{}
+		manager = &PriceManager{Store: store, Items: store}
+	)
+	var price = func(list int) error {
+		_, err := manager.SetItemPrice(ctx, NewPrice{PriceList: opaque(t, list), Item: opaque(t, 7), Amount: money.Input{Amount: "4.50", Currency: "NZD"}})
+		return err
+	}
+
+	require.NoError(t, price(1))
+	require.Len(t, store.created, 1)
+
+	// price lists of other retailers are never priced on
+	require.ErrorIs(t, price(2), ErrPriceListNotFound)
+	require.Len(t, store.created, 1)
+
+	_, err := manager.SetItemPrice(context.Background(), NewPrice{PriceList: opaque(t, 1), Item: opaque(t, 7), Amount: money.Input{Amount: "4.50", Currency: "NZD"}})
+	require.ErrorIs(t, err, retailers.ErrNoRetailer)
+}
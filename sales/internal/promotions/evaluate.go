@@ -0,0 +1,192 @@
+package promotions
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Evaluate applies the promotions to the basket and returns the discounts of every line of the
+// basket by index. The outcome depends on the basket and the promotions alone, not on the order
+// the promotions are given in.
+func Evaluate(basket Basket, promotions []*Promotion) ([][]*Discount, error) {
+	var ordered = slices.Clone(promotions)
+	slices.SortFunc(ordered, func(a, b *Promotion) int {
+		if a.Priority != b.Priority {
+			return cmp.Compare(b.Priority, a.Priority)
+		}
+		return cmp.Compare(a.ID.ID, b.ID.ID)
+	})
+
+	var (
+		discounts = make([][]*Discount, len(basket.Lines))
+		totals    = make([]money.Money, len(basket.Lines))
+		remaining = make([]money.Money, len(basket.Lines))
+		// locked lines were discounted by an exclusive promotion
+		locked = make([]bool, len(basket.Lines))
+	)
+	for i, line := range basket.Lines {
+		if line.UnitPrice.Currency() != basket.Currency {
+			return nil, fmt.Errorf("%w: line priced in %s on a basket in %s", money.ErrCurrencyMismatch, line.UnitPrice.Currency(), basket.Currency)
+		}
+		total, err := line.UnitPrice.Mul(line.Quantity, money.HalfEven)
+		if err != nil {
+			return nil, err
+		}
+		discounts[i], totals[i], remaining[i] = []*Discount{}, total, total
+	}
+
+	for _, promotion := range ordered {
+		if !promotion.applies(basket) {
+			continue
+		}
+
+		var targets []int
+		for i, line := range basket.Lines {
+			if locked[i] || remaining[i].Sign() <= 0 || !promotion.targets(line) {
+				continue
+			}
+			if promotion.Exclusive && len(discounts[i]) > 0 {
+				continue
+			}
+			targets = append(targets, i)
+		}
+		if len(targets) == 0 {
+			continue
+		}
+
+		if promotion.MinSpend != nil {
+			var spend = money.Zero(basket.Currency)
+			for _, i := range targets {
+				spend, _ = spend.Add(totals[i])
+			}
+			if below, err := spend.Cmp(*promotion.MinSpend); err != nil || below < 0 {
+				continue
+			}
+		}
+
+		amounts, err := promotion.discount(basket, targets, remaining)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply promotion %d: %w", promotion.ID.ID, err)
+		}
+
+		for n, i := range targets {
+			var amount = amounts[n]
+			if amount.Sign() <= 0 {
+				continue
+			}
+			if over, _ := amount.Cmp(remaining[i]); over > 0 {
+				amount = remaining[i]
+			}
+
+			discounts[i] = append(discounts[i], &Discount{PromotionID: promotion.ID, Name: promotion.Name, Amount: amount})
+			remaining[i], _ = remaining[i].Sub(amount)
+			if promotion.Exclusive {
+				locked[i] = true
+			}
+		}
+	}
+	return discounts, nil
+}
+
+// applies decides whether the promotion runs for the basket at all.
+func (p *Promotion) applies(basket Basket) bool {
+	if basket.At.Before(p.StartsAt) || (p.EndsAt != nil && !basket.At.Before(*p.EndsAt)) {
+		return false
+	}
+	if currency, ok := p.Currency(); ok && currency != basket.Currency {
+		return false
+	}
+	return p.Coupon == nil || slices.Contains(basket.Coupons, *p.Coupon)
+}
+
+func (p *Promotion) targets(line Line) bool {
+	switch {
+	case p.ItemID != nil:
+		return line.Item == p.ItemID.ID
+	case p.CategoryID != nil:
+		return slices.Contains(line.Categories, p.CategoryID.ID)
+	default:
+		return true
+	}
+}
+
+// discount works out the amount taken off each of the targeted lines.
+func (p *Promotion) discount(basket Basket, targets []int, remaining []money.Money) ([]money.Money, error) {
+	var amounts = make([]money.Money, len(targets))
+	for n := range amounts {
+		amounts[n] = money.Zero(basket.Currency)
+	}
+
+	switch p.Kind {
+	case PercentOff:
+		for n, i := range targets {
+			off, err := remaining[i].Mul(p.Percent, money.HalfEven)
+			if err != nil {
+				return nil, err
+			}
+			amounts[n] = off
+		}
+
+	case AmountOff:
+		var (
+			total   = money.Zero(basket.Currency)
+			weights = make([]int64, len(targets))
+		)
+		for n, i := range targets {
+			total, _ = total.Add(remaining[i])
+			weights[n] = remaining[i].Minor()
+		}
+
+		var off = *p.Amount
+		if over, _ := off.Cmp(total); over > 0 {
+			off = total
+		}
+		return off.Allocate(weights...)
+
+	case BuyXGetY:
+		// units are taken dearest first so that the cheapest units of every group are given away,
+		// lines of the same price in the order of the basket. Only whole units count, ie 2 of 2.5kg.
+		var (
+			order = make([]int, len(targets))
+			units = make([]int64, len(targets))
+			total int64
+		)
+		for n, i := range targets {
+			quantity := basket.Lines[i].Quantity
+			order[n], units[n] = n, new(big.Int).Quo(quantity.Num(), quantity.Denom()).Int64()
+			total += units[n]
+		}
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Compare(basket.Lines[targets[b]].UnitPrice.Minor(), basket.Lines[targets[a]].UnitPrice.Minor())
+		})
+
+		var (
+			size   = int64(p.Buy + p.Free)
+			groups = total / size
+			// given counts the units given away among the first of the units, only complete groups
+			// give any away
+			given = func(units int64) int64 {
+				units = min(units, groups*size)
+				return units/size*int64(p.Free) + max(0, units%size-int64(p.Buy))
+			}
+			start int64
+		)
+		for _, n := range order {
+			var line = basket.Lines[targets[n]]
+			end := start + units[n]
+			off, err := line.UnitPrice.Times(given(end) - given(start))
+			if err != nil {
+				return nil, err
+			}
+			amounts[n], start = off, end
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrPromotionKind, p.Kind)
+	}
+	return amounts, nil
+}
@@ -0,0 +1,287 @@
+package promotions
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestEvaluate(t *testing.T) {
+	var (
+		now       = time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+		lastWeek  = now.AddDate(0, 0, -7)
+		yesterday = now.AddDate(0, 0, -1)
+		tomorrow  = now.AddDate(0, 0, 1)
+	)
+
+	var nzd = func(amount string) *money.Money {
+		m, err := money.Parse(amount, "NZD")
+		require.NoError(t, err)
+		return &m
+	}
+	var coupon = func(code string) *string {
+		return &code
+	}
+
+	// promotion builds a running promotion, the options fill in the rest
+	var promotion = func(id int, name string, kind Kind, options func(*Promotion)) *Promotion {
+		p := &Promotion{ID: &keys.OpaqueID{ID: id}, Name: name, Kind: kind, StartsAt: lastWeek}
+		if options != nil {
+			options(p)
+		}
+		return p
+	}
+
+	var (
+		threeForTwo = promotion(1, "3 for 2", BuyXGetY, func(p *Promotion) { p.Buy, p.Free = 2, 1 })
+		tenOff      = promotion(2, "10% off coffee", PercentOff, func(p *Promotion) {
+			p.Percent, p.CategoryID = big.NewRat(1, 10), &keys.OpaqueID{ID: 50}
+		})
+		fiveOff = promotion(3, "$5 off", AmountOff, func(p *Promotion) { p.Amount = nzd("5.00") })
+	)
+
+	type line struct {
+		item       int
+		categories []int
+		quantity   string
+		price      string
+	}
+
+	tests := []struct {
+		name       string
+		lines      []line
+		coupons    []string
+		currency   money.Currency
+		promotions []*Promotion
+		// want holds the discounts of every line as "name amount"
+		want [][]string
+	}{
+		{
+			name:       "buy two get one free",
+			lines:      []line{{item: 1, quantity: "3", price: "2.00"}},
+			promotions: []*Promotion{threeForTwo},
+			want:       [][]string{{"3 for 2 2.00"}},
+		},
+		{
+			name:       "buy two get one free gives the cheapest unit away",
+			lines:      []line{{item: 1, quantity: "2", price: "3.00"}, {item: 2, quantity: "1", price: "1.00"}},
+			promotions: []*Promotion{threeForTwo},
+			want:       [][]string{{}, {"3 for 2 1.00"}},
+		},
+		{
+			name:       "buy two get one free only counts complete groups",
+			lines:      []line{{item: 1, quantity: "5", price: "2.00"}},
+			promotions: []*Promotion{threeForTwo},
+			want:       [][]string{{"3 for 2 2.00"}},
+		},
+		{
+			name:       "buy two get one free across lines of the same price",
+			lines:      []line{{item: 1, quantity: "2", price: "2.00"}, {item: 2, quantity: "4", price: "1.00"}, {item: 3, quantity: "3", price: "1.00"}},
+			promotions: []*Promotion{threeForTwo},
+			want:       [][]string{{}, {"3 for 2 2.00"}, {"3 for 2 1.00"}},
+		},
+		{
+			name:       "buy two get one free of many units",
+			lines:      []line{{item: 1, quantity: "100000", price: "1.00"}},
+			promotions: []*Promotion{threeForTwo},
+			want:       [][]string{{"3 for 2 33333.00"}},
+		},
+		{
+			name:       "buy two get one free without enough units",
+			lines:      []line{{item: 1, quantity: "2", price: "2.00"}},
+			promotions: []*Promotion{threeForTwo},
+			want:       [][]string{{}},
+		},
+		{
+			name:  "buy two get one free of an item",
+			lines: []line{{item: 1, quantity: "2", price: "2.00"}, {item: 2, quantity: "1", price: "2.00"}},
+			promotions: []*Promotion{promotion(4, "3 for 2 beans", BuyXGetY, func(p *Promotion) {
+				p.Buy, p.Free, p.ItemID = 2, 1, &keys.OpaqueID{ID: 2}
+			})},
+			want: [][]string{{}, {}},
+		},
+		{
+			name:       "buy two get one free only counts whole units",
+			lines:      []line{{item: 1, quantity: "2.5", price: "2.00"}, {item: 2, quantity: "0.75", price: "1.00"}},
+			promotions: []*Promotion{threeForTwo},
+			want:       [][]string{{}, {}},
+		},
+		{
+			name:       "percent off a weighed line",
+			lines:      []line{{item: 1, categories: []int{50}, quantity: "0.25", price: "30.10"}},
+			promotions: []*Promotion{tenOff},
+			want:       [][]string{{"10% off coffee 0.75"}},
+		},
+		{
+			name:       "percent off a category rounds half to even",
+			lines:      []line{{item: 1, categories: []int{50}, quantity: "1", price: "4.55"}, {item: 2, categories: []int{60}, quantity: "1", price: "4.55"}},
+			promotions: []*Promotion{tenOff},
+			want:       [][]string{{"10% off coffee 0.46"}, {}},
+		},
+		{
+			name:       "amount off is split by what line items cost",
+			lines:      []line{{item: 1, quantity: "2", price: "3.00"}, {item: 2, quantity: "1", price: "4.00"}},
+			promotions: []*Promotion{fiveOff},
+			want:       [][]string{{"$5 off 3.00"}, {"$5 off 2.00"}},
+		},
+		{
+			name:       "amount off never exceeds the basket",
+			lines:      []line{{item: 1, quantity: "1", price: "3.00"}},
+			promotions: []*Promotion{fiveOff},
+			want:       [][]string{{"$5 off 3.00"}},
+		},
+		{
+			name:       "amount off in another currency",
+			lines:      []line{{item: 1, quantity: "1", price: "30.00"}},
+			currency:   "AUD",
+			promotions: []*Promotion{fiveOff},
+			want:       [][]string{{}},
+		},
+		{
+			name:  "coupon is required",
+			lines: []line{{item: 1, quantity: "1", price: "10.00"}},
+			promotions: []*Promotion{promotion(5, "welcome", PercentOff, func(p *Promotion) {
+				p.Percent, p.Coupon = big.NewRat(1, 5), coupon("WELCOME")
+			})},
+			want: [][]string{{}},
+		},
+		{
+			name:    "coupon is presented",
+			lines:   []line{{item: 1, quantity: "1", price: "10.00"}},
+			coupons: []string{"WELCOME"},
+			promotions: []*Promotion{promotion(5, "welcome", PercentOff, func(p *Promotion) {
+				p.Percent, p.Coupon = big.NewRat(1, 5), coupon("WELCOME")
+			})},
+			want: [][]string{{"welcome 2.00"}},
+		},
+		{
+			name:  "outside of the date window",
+			lines: []line{{item: 1, quantity: "3", price: "2.00"}},
+			promotions: []*Promotion{
+				promotion(6, "ended", BuyXGetY, func(p *Promotion) { p.Buy, p.Free, p.EndsAt = 2, 1, &now }),
+				promotion(7, "upcoming", BuyXGetY, func(p *Promotion) { p.Buy, p.Free, p.StartsAt = 2, 1, tomorrow }),
+			},
+			want: [][]string{{}},
+		},
+		{
+			name:  "within the date window",
+			lines: []line{{item: 1, quantity: "3", price: "2.00"}},
+			promotions: []*Promotion{
+				promotion(8, "this week", BuyXGetY, func(p *Promotion) { p.Buy, p.Free, p.StartsAt, p.EndsAt = 2, 1, yesterday, &tomorrow }),
+			},
+			want: [][]string{{"this week 2.00"}},
+		},
+		{
+			name:  "minimum spend not met",
+			lines: []line{{item: 1, quantity: "1", price: "49.99"}},
+			promotions: []*Promotion{promotion(9, "spend 50", AmountOff, func(p *Promotion) {
+				p.Amount, p.MinSpend = nzd("10.00"), nzd("50.00")
+			})},
+			want: [][]string{{}},
+		},
+		{
+			name:  "minimum spend met",
+			lines: []line{{item: 1, quantity: "2", price: "25.00"}},
+			promotions: []*Promotion{promotion(9, "spend 50", AmountOff, func(p *Promotion) {
+				p.Amount, p.MinSpend = nzd("10.00"), nzd("50.00")
+			})},
+			want: [][]string{{"spend 50 10.00"}},
+		},
+		{
+			name:  "promotions stack by priority",
+			lines: []line{{item: 1, categories: []int{50}, quantity: "1", price: "10.00"}},
+			promotions: []*Promotion{
+				fiveOff,
+				promotion(10, "10% off coffee first", PercentOff, func(p *Promotion) {
+					p.Percent, p.CategoryID, p.Priority = big.NewRat(1, 10), &keys.OpaqueID{ID: 50}, 1
+				}),
+			},
+			want: [][]string{{"10% off coffee first 1.00", "$5 off 5.00"}},
+		},
+		{
+			name:  "exclusive promotion stops later promotions",
+			lines: []line{{item: 1, categories: []int{50}, quantity: "3", price: "2.00"}, {item: 2, quantity: "1", price: "4.00"}},
+			promotions: []*Promotion{
+				tenOff,
+				promotion(11, "3 for 2 exclusive", BuyXGetY, func(p *Promotion) {
+					p.Buy, p.Free, p.ItemID, p.Exclusive, p.Priority = 2, 1, &keys.OpaqueID{ID: 1}, true, 5
+				}),
+			},
+			want: [][]string{{"3 for 2 exclusive 2.00"}, {}},
+		},
+		{
+			name:  "exclusive promotion skips discounted line items",
+			lines: []line{{item: 1, categories: []int{50}, quantity: "1", price: "10.00"}, {item: 2, quantity: "1", price: "10.00"}},
+			promotions: []*Promotion{
+				tenOff,
+				promotion(12, "half price exclusive", PercentOff, func(p *Promotion) {
+					p.Percent, p.Exclusive, p.Priority = big.NewRat(1, 2), true, -1
+				}),
+			},
+			want: [][]string{{"10% off coffee 1.00"}, {"half price exclusive 5.00"}},
+		},
+		{
+			name:  "ties in priority go by id",
+			lines: []line{{item: 1, quantity: "1", price: "10.00"}},
+			promotions: []*Promotion{
+				promotion(14, "second", PercentOff, func(p *Promotion) { p.Percent = big.NewRat(1, 2) }),
+				promotion(13, "first", AmountOff, func(p *Promotion) { p.Amount = nzd("2.00") }),
+			},
+			want: [][]string{{"first 2.00", "second 4.00"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var currency money.Currency = "NZD"
+			if tt.currency != "" {
+				currency = tt.currency
+			}
+
+			var basket = Basket{Currency: currency, At: now, Coupons: tt.coupons}
+			for _, l := range tt.lines {
+				price, err := money.Parse(l.price, currency)
+				require.NoError(t, err)
+				quantity, ok := new(big.Rat).SetString(l.quantity)
+				require.True(t, ok)
+				basket.Lines = append(basket.Lines, Line{Item: l.item, Categories: l.categories, Quantity: quantity, UnitPrice: price})
+			}
+
+			// evaluation mustn't depend on the order promotions are listed in
+			var reversed = slices.Clone(tt.promotions)
+			slices.Reverse(reversed)
+
+			for _, promotions := range [][]*Promotion{tt.promotions, reversed} {
+				discounts, err := Evaluate(basket, promotions)
+				require.NoError(t, err)
+
+				var got = make([][]string, 0, len(discounts))
+				for _, line := range discounts {
+					var described = []string{}
+					for _, discount := range line {
+						described = append(described, discount.Name+" "+discount.Amount.Decimal())
+					}
+					got = append(got, described)
+				}
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNormaliseCoupon(t *testing.T) {
+	got, err := NormaliseCoupon(" summer-10 ")
+	require.NoError(t, err)
+	assert.Equal(t, "SUMMER-10", got)
+
+	_, err = NormaliseCoupon("")
+	require.ErrorIs(t, err, ErrCoupon)
+	_, err = NormaliseCoupon("10% off")
+	require.ErrorIs(t, err, ErrCoupon)
+}
@@ -0,0 +1,94 @@
+package promotions
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Kind is how a promotion discounts the line items it targets.
+type Kind string
+
+const (
+	// BuyXGetY gives Free units away for every Buy units bought, the cheapest units of every group
+	// are the ones given away.
+	BuyXGetY Kind = "BUY_X_GET_Y"
+	// PercentOff takes Percent off every targeted line item.
+	PercentOff Kind = "PERCENT_OFF"
+	// AmountOff takes Amount off the targeted line items, split between them by what they cost.
+	AmountOff Kind = "AMOUNT_OFF"
+)
+
+// Promotion is a rule of a retailer discounting sales within [StartsAt, EndsAt), an open ended
+// promotion has no EndsAt. A promotion targets line items of its item or of its category and any
+// descendant of it, or every line item when it has neither.
+//
+// Promotions are applied by descending priority and then by id. Exclusive promotions don't
+// combine, they skip line items that were already discounted and the line items they discount
+// are skipped by every later promotion.
+type Promotion struct {
+	ID         *keys.OpaqueID
+	RetailerID *keys.OpaqueID
+	Name       string
+	Kind       Kind
+	Priority   int
+	Exclusive  bool
+
+	ItemID     *keys.OpaqueID
+	CategoryID *keys.OpaqueID
+	// MinSpend is what the targeted line items must add up to before any discount for the
+	// promotion to apply.
+	MinSpend *money.Money
+	// Coupon is the code the sale must present for the promotion to apply, promotions without a
+	// coupon apply automatically.
+	Coupon *string
+
+	Buy  int
+	Free int
+	// Percent is the fraction taken off, ie 0.1 for 10% off.
+	Percent *big.Rat
+	Amount  *money.Money
+
+	StartsAt time.Time
+	EndsAt   *time.Time
+}
+
+// Currency is the currency a promotion is restricted to by its amounts, promotions without
+// amounts apply in any currency.
+func (p *Promotion) Currency() (money.Currency, bool) {
+	if p.Amount != nil {
+		return p.Amount.Currency(), true
+	}
+	if p.MinSpend != nil {
+		return p.MinSpend.Currency(), true
+	}
+	return "", false
+}
+
+// Discount is money taken off a line item by a promotion, Name is the name of the promotion at
+// the time of sale.
+type Discount struct {
+	PromotionID *keys.OpaqueID
+	Name        string
+	Amount      money.Money
+}
+
+// Line is a line item of a basket being evaluated, Categories holds every category the item is in
+// along with their ancestors. Quantity is in the unit scale of the item, which the unit price is
+// the price of one of.
+type Line struct {
+	Item       int
+	Categories []int
+	Quantity   *big.Rat
+	UnitPrice  money.Money
+}
+
+// Basket is a sale being evaluated against promotions.
+type Basket struct {
+	Currency money.Currency
+	At       time.Time
+	Coupons  []string
+	Lines    []Line
+}
@@ -0,0 +1,304 @@
+package promotions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type store interface {
+	// CreatePromotion adds the promotion with its id assigned in place.
+	CreatePromotion(context.Context, *Promotion) error
+	GetPromotion(context.Context, int) (*Promotion, error)
+	// GetPromotions lists every promotion of the retailer, latest first.
+	GetPromotions(ctx context.Context, retailer int) ([]*Promotion, error)
+	// ActivePromotions are the promotions of the retailer running at the time.
+	ActivePromotions(ctx context.Context, retailer int, at time.Time) ([]*Promotion, error)
+	// EndPromotion ends the promotion at the time unless it already ends before then.
+	EndPromotion(ctx context.Context, id int, at time.Time) error
+	// ItemCategories holds the categories of each of the items along with their ancestors.
+	ItemCategories(ctx context.Context, items []int) (map[int][]int, error)
+}
+
+type PromotionManager struct {
+	Store store
+}
+
+var (
+	ErrPromotionNotFound = errors.New("promotion not found")
+	ErrRetailerNotFound  = errors.New("retailer not found")
+	ErrNoNamePromotion   = errors.New("promotion must have a name")
+	ErrPromotionKind     = errors.New("unknown promotion kind")
+	ErrTarget            = errors.New("promotion can target an item or a category but not both")
+	ErrBuyXGetY          = errors.New("buy x get y promotion must buy and give away at least one unit")
+	ErrPercent           = errors.New("percent off must be a decimal fraction above 0 and up to 1 of at most 6 decimal places, ie 0.1")
+	ErrAmountOff         = errors.New("amount off must be positive")
+	ErrMinSpend          = errors.New("minimum spend must not be negative")
+	ErrCoupon            = errors.New("coupon must be 1 to 32 letters, digits, dashes or underscores")
+	ErrWindow            = errors.New("promotion must end after it starts")
+	// ErrPromotionCurrency is returned for promotions with amounts in more than one currency.
+	ErrPromotionCurrency = errors.New("amounts of a promotion must all be in the same currency")
+	// ErrUnknownCoupon is returned for coupons presented on a sale that no running promotion of the
+	// retailer accepts.
+	ErrUnknownCoupon = errors.New("coupon is not accepted by any running promotion")
+)
+
+// NewPromotion is a promotion as presented by a client, only the fields of its kind are read.
+// Promotions start now unless told otherwise.
+type NewPromotion struct {
+	Retailer  *keys.OpaqueID
+	Name      string
+	Kind      Kind
+	Priority  *int
+	Exclusive *bool
+	Item      *keys.OpaqueID
+	Category  *keys.OpaqueID
+	MinSpend  *money.Input
+	Coupon    *string
+	Buy       *int
+	Free      *int
+	Percent   *string
+	Amount    *money.Input
+	StartsAt  *time.Time
+	EndsAt    *time.Time
+}
+
+// NormaliseCoupon upper cases the coupon, ie summer-10 is SUMMER-10.
+func NormaliseCoupon(coupon string) (string, error) {
+	coupon = strings.ToUpper(strings.TrimSpace(coupon))
+	if len(coupon) == 0 || len(coupon) > 32 || strings.IndexFunc(coupon, func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) >= 0 {
+		return "", fmt.Errorf("%w: %q", ErrCoupon, coupon)
+	}
+	return coupon, nil
+}
+
+func (p *PromotionManager) CreatePromotion(ctx context.Context, input NewPromotion) (*Promotion, error) {
+	retailer, err := scope(ctx, input.Retailer)
+	if err != nil {
+		return nil, err
+	}
+
+	var promotion = &Promotion{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		Name:       strings.TrimSpace(input.Name),
+		Kind:       input.Kind,
+		StartsAt:   time.Now(),
+		EndsAt:     input.EndsAt,
+	}
+	if promotion.Name == "" {
+		return nil, ErrNoNamePromotion
+	}
+	if input.Priority != nil {
+		promotion.Priority = *input.Priority
+	}
+	if input.Exclusive != nil {
+		promotion.Exclusive = *input.Exclusive
+	}
+	if input.StartsAt != nil {
+		promotion.StartsAt = *input.StartsAt
+	}
+	if promotion.EndsAt != nil && !promotion.EndsAt.After(promotion.StartsAt) {
+		return nil, ErrWindow
+	}
+
+	if input.Item != nil && input.Category != nil {
+		return nil, ErrTarget
+	}
+	if input.Item != nil {
+		item, err := input.Item.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode item id: %w", err)
+		}
+		promotion.ItemID = &keys.OpaqueID{ID: item}
+	}
+	if input.Category != nil {
+		category, err := input.Category.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode category id: %w", err)
+		}
+		promotion.CategoryID = &keys.OpaqueID{ID: category}
+	}
+
+	if input.Coupon != nil {
+		coupon, err := NormaliseCoupon(*input.Coupon)
+		if err != nil {
+			return nil, err
+		}
+		promotion.Coupon = &coupon
+	}
+
+	if input.MinSpend != nil {
+		spend, err := input.MinSpend.Money()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse minimum spend: %w", err)
+		}
+		if spend.Sign() < 0 {
+			return nil, ErrMinSpend
+		}
+		promotion.MinSpend = &spend
+	}
+
+	switch input.Kind {
+	case BuyXGetY:
+		if input.Buy == nil || input.Free == nil || *input.Buy < 1 || *input.Free < 1 {
+			return nil, ErrBuyXGetY
+		}
+		promotion.Buy, promotion.Free = *input.Buy, *input.Free
+
+	case PercentOff:
+		if input.Percent == nil {
+			return nil, ErrPercent
+		}
+		percent, ok := new(big.Rat).SetString(strings.TrimSpace(*input.Percent))
+		if !ok || percent.Sign() <= 0 || percent.Cmp(big.NewRat(1, 1)) > 0 || !new(big.Rat).Mul(percent, big.NewRat(1_000_000, 1)).IsInt() {
+			return nil, fmt.Errorf("%w: %q", ErrPercent, *input.Percent)
+		}
+		promotion.Percent = percent
+
+	case AmountOff:
+		if input.Amount == nil {
+			return nil, ErrAmountOff
+		}
+		amount, err := input.Amount.Money()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse amount off: %w", err)
+		}
+		if amount.Sign() <= 0 {
+			return nil, ErrAmountOff
+		}
+		if promotion.MinSpend != nil && promotion.MinSpend.Currency() != amount.Currency() {
+			return nil, fmt.Errorf("%w: %s off with a minimum spend in %s", ErrPromotionCurrency, amount.Currency(), promotion.MinSpend.Currency())
+		}
+		promotion.Amount = &amount
+
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrPromotionKind, input.Kind)
+	}
+
+	if err := p.Store.CreatePromotion(ctx, promotion); err != nil {
+		return nil, fmt.Errorf("failed to create promotion: %w", err)
+	}
+	return promotion, nil
+}
+
+func (p *PromotionManager) GetPromotion(ctx context.Context, externalID *keys.OpaqueID) (*Promotion, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	return p.Store.GetPromotion(ctx, id)
+}
+
+func (p *PromotionManager) GetPromotions(ctx context.Context, retailerID *keys.OpaqueID) ([]*Promotion, error) {
+	retailer, err := scope(ctx, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	return p.Store.GetPromotions(ctx, retailer)
+}
+
+// scope is the retailer the request is scoped to, a retailer named by the request must be that one.
+func scope(ctx context.Context, named *keys.OpaqueID) (int, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	if named != nil {
+		id, err := named.Decode(ctx)
+		if err != nil {
+			return -1, fmt.Errorf("failed to decode retailer id: %w", err)
+		}
+		if id != retailer {
+			return -1, ErrRetailerNotFound
+		}
+	}
+	return retailer, nil
+}
+
+// PromotionByID gets the promotion by its internal id, for records that reference promotions.
+func (p *PromotionManager) PromotionByID(ctx context.Context, id int) (*Promotion, error) {
+	return p.Store.GetPromotion(ctx, id)
+}
+
+// EndPromotion stops the promotion from applying to sales from now on.
+func (p *PromotionManager) EndPromotion(ctx context.Context, externalID *keys.OpaqueID) (*Promotion, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+
+	if err := p.Store.EndPromotion(ctx, id, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to end promotion: %w", err)
+	}
+	return p.Store.GetPromotion(ctx, id)
+}
+
+// Apply evaluates the running promotions of the retailer against the basket, returning the
+// discounts of every line of the basket by index.
+func (p *PromotionManager) Apply(ctx context.Context, retailer int, basket Basket) ([][]*Discount, error) {
+	var coupons = make([]string, 0, len(basket.Coupons))
+	for _, coupon := range basket.Coupons {
+		normalised, err := NormaliseCoupon(coupon)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, normalised)
+	}
+	basket.Coupons = coupons
+
+	running, err := p.Store.ActivePromotions(ctx, retailer, basket.At)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running promotions: %w", err)
+	}
+
+	for _, coupon := range basket.Coupons {
+		if !accepted(running, coupon, basket.Currency) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCoupon, coupon)
+		}
+	}
+
+	var categorised bool
+	for _, promotion := range running {
+		categorised = categorised || promotion.CategoryID != nil
+	}
+	if categorised {
+		var ids = make([]int, 0, len(basket.Lines))
+		for _, line := range basket.Lines {
+			ids = append(ids, line.Item)
+		}
+
+		categories, err := p.Store.ItemCategories(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get categories of items: %w", err)
+		}
+
+		basket.Lines = append([]Line(nil), basket.Lines...)
+		for i := range basket.Lines {
+			basket.Lines[i].Categories = categories[basket.Lines[i].Item]
+		}
+	}
+
+	return Evaluate(basket, running)
+}
+
+func accepted(running []*Promotion, coupon string, currency money.Currency) bool {
+	for _, promotion := range running {
+		if promotion.Coupon == nil || *promotion.Coupon != coupon {
+			continue
+		}
+		if promoted, ok := promotion.Currency(); !ok || promoted == currency {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package purchasing
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Supplier is who a retailer buys stock from.
+type Supplier struct {
+	ID         *keys.OpaqueID
+	RetailerID *keys.OpaqueID
+	Name       string
+	Email      *string
+	Phone      *string
+	// Currency is what the supplier is paid in, the costs of its items and purchase orders with it
+	// are all in this currency.
+	Currency  money.Currency
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SupplierItem is an item a supplier sells, with what it costs from them.
+type SupplierItem struct {
+	SupplierID *keys.OpaqueID
+	ItemID     *keys.OpaqueID
+	// Code is what the supplier calls the item, ie on their price list or invoices.
+	Code *string
+	// Cost is of one of the unit scale of the item, in the currency of the supplier.
+	Cost      money.Money
+	UpdatedAt time.Time
+}
+
+// Status is how far along a purchase order is.
+type Status string
+
+const (
+	// Draft orders haven't been sent to the supplier yet.
+	Draft             Status = "DRAFT"
+	Ordered           Status = "ORDERED"
+	PartiallyReceived Status = "PARTIALLY_RECEIVED"
+	Received          Status = "RECEIVED"
+	// Cancelled orders won't be received any further, stock received before they were cancelled
+	// stays received.
+	Cancelled Status = "CANCELLED"
+)
+
+// PurchaseOrder is stock ordered from a supplier to be delivered to a location.
+type PurchaseOrder struct {
+	ID         *keys.OpaqueID
+	RetailerID *keys.OpaqueID
+	SupplierID *keys.OpaqueID
+	LocationID *keys.OpaqueID
+	Status     Status
+	// Currency is the currency of the supplier when the order was made.
+	Currency  money.Currency
+	Note      *string
+	Lines     []*Line
+	CreatedBy actor.Actor
+	CreatedAt time.Time
+	OrderedAt *time.Time
+	// ClosedAt is when the order was received in full or cancelled.
+	ClosedAt *time.Time
+}
+
+// Line is an item ordered on a purchase order.
+type Line struct {
+	ID     *keys.OpaqueID
+	ItemID *keys.OpaqueID
+	// Quantity and Received are in the unit scale of the item, no more is received than ordered.
+	Quantity *big.Rat
+	Received *big.Rat
+	// UnitCost is of one of the unit scale of the item, in the currency of the order.
+	UnitCost money.Money
+}
+
+// Outstanding is how much of the line is still to be received.
+func (l *Line) Outstanding() *big.Rat {
+	return new(big.Rat).Sub(l.Quantity, l.Received)
+}
+
+// Cost is the quantity of the line at its unit cost, rounded half to even to the minor unit.
+func (l *Line) Cost() (money.Money, error) {
+	return l.UnitCost.Mul(l.Quantity, money.HalfEven)
+}
+
+// Total sums the costs of every line of the order.
+func (o *PurchaseOrder) Total() (money.Money, error) {
+	var costs = make([]money.Money, 0, len(o.Lines))
+	for _, line := range o.Lines {
+		cost, err := line.Cost()
+		if err != nil {
+			return money.Money{}, err
+		}
+		costs = append(costs, cost)
+	}
+	return money.Sum(o.Currency, costs...)
+}
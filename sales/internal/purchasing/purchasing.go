@@ -0,0 +1,579 @@
+// Package purchasing keeps the suppliers of retailers, what their items cost from them, and the
+// purchase orders stock is received against.
+package purchasing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/customers"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type store interface {
+	// CreateSupplier adds the supplier to the retailer of the context with its id and timestamps
+	// assigned in place.
+	CreateSupplier(context.Context, *Supplier) error
+	GetSupplier(context.Context, int) (*Supplier, error)
+	// GetSuppliers is every supplier of the retailer of the context, ordered by name.
+	GetSuppliers(context.Context) ([]*Supplier, error)
+	UpdateSupplier(context.Context, *Supplier) error
+
+	// SetSupplierItem adds the item to those the supplier sells, replacing its code and cost if the
+	// supplier sells it already.
+	SetSupplierItem(context.Context, *SupplierItem) error
+	RemoveSupplierItem(ctx context.Context, supplier int, item int) error
+	// GetSupplierItems is the items of suppliers that match the filter, ordered by supplier then
+	// item.
+	GetSupplierItems(context.Context, SupplierItemFilter) ([]*SupplierItem, error)
+
+	// CreateOrder adds the order along with its lines, with their ids and times assigned in place.
+	CreateOrder(context.Context, *PurchaseOrder) error
+	GetOrder(context.Context, int) (*PurchaseOrder, error)
+	// PageOrders pages through the orders that match the filter, most recent first.
+	PageOrders(context.Context, OrderFilter, paginate.Paginate) ([]*PurchaseOrder, error)
+	// TransitionOrder moves the order on to the status from any of the statuses given, updating it
+	// in place. Orders in none of them are refused with ErrOrderStatus.
+	TransitionOrder(ctx context.Context, order *PurchaseOrder, to Status, from ...Status) error
+	// ReceiveOrder adds the stock of the movements to what has been received of the lines of
+	// their items and posts them to the ledger, all at once. The order is then received, or
+	// partially received while any line is still outstanding. Orders that aren't ordered or
+	// partially received are refused with ErrOrderStatus.
+	ReceiveOrder(ctx context.Context, order *PurchaseOrder, movements []*inventory.Movement) error
+	// GetReceipts is the stock received against the order, in the order it was received.
+	GetReceipts(ctx context.Context, order int) ([]*inventory.Movement, error)
+}
+
+// itemStore resolves the items that are supplied and ordered.
+type itemStore interface {
+	GetItem(context.Context, int) (*items.Item, error)
+}
+
+// stockStore tells kits apart, kits hold no stock of their own to order.
+type stockStore interface {
+	GetComponents(ctx context.Context, item int) ([]*inventory.Component, error)
+}
+
+type PurchaseManager struct {
+	Store store
+	Items itemStore
+	Stock stockStore
+}
+
+var (
+	ErrSupplierNotFound     = errors.New("supplier not found")
+	ErrNoNameSupplier       = errors.New("supplier must have a name")
+	ErrDuplicateSupplier    = errors.New("supplier with this name already exists")
+	ErrSupplierItemNotFound = errors.New("supplier does not sell this item")
+	ErrDuplicateCode        = errors.New("supplier already uses this code for another item")
+	ErrNegativeCost         = errors.New("cost must not be negative")
+	ErrCostCurrency         = errors.New("cost must be in the currency of the supplier")
+	ErrOrderNotFound        = errors.New("purchase order not found")
+	ErrNoLines              = errors.New("purchase order must order at least one item")
+	ErrDuplicateItem        = errors.New("item is ordered on more than one line")
+	// ErrNoCost is returned for lines without a unit cost of items the supplier has no cost for.
+	ErrNoCost        = errors.New("line must give a unit cost, the supplier has none for the item")
+	ErrOrderStatus   = errors.New("purchase order is not in a status that allows this")
+	ErrLineNotFound  = errors.New("purchase order line not found")
+	ErrNoReceipt     = errors.New("receipt must receive at least one line")
+	ErrDuplicateLine = errors.New("line is received more than once in the same receipt")
+	ErrOverReceipt   = errors.New("more received than is outstanding on the line")
+)
+
+type NewSupplier struct {
+	Name     string
+	Email    *string
+	Phone    *string
+	Currency money.Currency
+}
+
+// SupplierPatch changes the fields that are set, an empty email or phone removes it. The currency
+// of a supplier doesn't change as its costs and orders are in it.
+type SupplierPatch struct {
+	Name  *string
+	Email *string
+	Phone *string
+}
+
+// NewSupplierItem sets what the item costs from the supplier, the cost is of one of the unit scale
+// of the item.
+type NewSupplierItem struct {
+	Supplier *keys.OpaqueID
+	Item     *keys.OpaqueID
+	Code     *string
+	Cost     money.Input
+}
+
+// SupplierItemFilter narrows supplier items down to those of a supplier, of an item, or both.
+type SupplierItemFilter struct {
+	Supplier *keys.OpaqueID
+	Item     *keys.OpaqueID
+}
+
+// NewPurchaseOrder orders stock from a supplier into a location, orders start out as drafts.
+type NewPurchaseOrder struct {
+	Supplier *keys.OpaqueID
+	Location *keys.OpaqueID
+	Note     *string
+	Lines    []*NewLine
+}
+
+// NewLine orders a quantity of an item, given in the unit scale of the item unless another unit
+// scale of the same dimension is given. The unit cost is of one of the unit scale of the item and
+// defaults to what the item costs from the supplier.
+type NewLine struct {
+	Item      *keys.OpaqueID
+	Quantity  string
+	UnitScale *items.UnitScale
+	UnitCost  *money.Input
+}
+
+// NewReceipt receives stock of lines of an order.
+type NewReceipt struct {
+	Lines []*NewReceiptLine
+	Note  *string
+}
+
+// NewReceiptLine receives a quantity of the item of a line, given in the unit scale of the item
+// unless another unit scale of the same dimension is given.
+type NewReceiptLine struct {
+	Line      *keys.OpaqueID
+	Quantity  string
+	UnitScale *items.UnitScale
+}
+
+// OrderFilter narrows orders down to those with a supplier, into a location, in a status, or any
+// of them together.
+type OrderFilter struct {
+	Supplier *keys.OpaqueID
+	Location *keys.OpaqueID
+	Status   *Status
+}
+
+func (m *PurchaseManager) CreateSupplier(ctx context.Context, input NewSupplier) (*Supplier, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	supplier := &Supplier{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		Name:       strings.TrimSpace(input.Name),
+	}
+	if supplier.Name == "" {
+		return nil, ErrNoNameSupplier
+	}
+	if supplier.Email, err = contact(input.Email, customers.NormaliseEmail); err != nil {
+		return nil, err
+	}
+	if supplier.Phone, err = contact(input.Phone, customers.NormalisePhone); err != nil {
+		return nil, err
+	}
+	if supplier.Currency, err = money.ParseCurrency(string(input.Currency)); err != nil {
+		return nil, err
+	}
+
+	if err := m.Store.CreateSupplier(ctx, supplier); err != nil {
+		return nil, fmt.Errorf("failed to create supplier: %w", err)
+	}
+	return supplier, nil
+}
+
+func (m *PurchaseManager) GetSupplier(ctx context.Context, externalID *keys.OpaqueID) (*Supplier, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	return m.Store.GetSupplier(ctx, id)
+}
+
+func (m *PurchaseManager) GetSuppliers(ctx context.Context) ([]*Supplier, error) {
+	return m.Store.GetSuppliers(ctx)
+}
+
+func (m *PurchaseManager) UpdateSupplier(ctx context.Context, externalID *keys.OpaqueID, patch SupplierPatch) (*Supplier, error) {
+	supplier, err := m.GetSupplier(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Name != nil {
+		if supplier.Name = strings.TrimSpace(*patch.Name); supplier.Name == "" {
+			return nil, ErrNoNameSupplier
+		}
+	}
+	if patch.Email != nil {
+		if supplier.Email, err = contact(patch.Email, customers.NormaliseEmail); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Phone != nil {
+		if supplier.Phone, err = contact(patch.Phone, customers.NormalisePhone); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.Store.UpdateSupplier(ctx, supplier); err != nil {
+		return nil, fmt.Errorf("failed to update supplier: %w", err)
+	}
+	return supplier, nil
+}
+
+// SetSupplierItem sets the code and cost of an item the supplier sells. Kits hold no stock of their
+// own and can't be supplied, their components are instead.
+func (m *PurchaseManager) SetSupplierItem(ctx context.Context, input NewSupplierItem) (*SupplierItem, error) {
+	supplier, err := m.GetSupplier(ctx, input.Supplier)
+	if err != nil {
+		return nil, err
+	}
+	item, err := m.stocked(ctx, input.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	cost, err := m.cost(supplier.Currency, input.Cost)
+	if err != nil {
+		return nil, err
+	}
+
+	var code *string
+	if input.Code != nil {
+		if trimmed := strings.TrimSpace(*input.Code); trimmed != "" {
+			code = &trimmed
+		}
+	}
+
+	supplied := &SupplierItem{
+		SupplierID: &keys.OpaqueID{ID: supplier.ID.ID},
+		ItemID:     &keys.OpaqueID{ID: item.ID.ID},
+		Code:       code,
+		Cost:       cost,
+	}
+	if err := m.Store.SetSupplierItem(ctx, supplied); err != nil {
+		return nil, fmt.Errorf("failed to set supplier item: %w", err)
+	}
+	return supplied, nil
+}
+
+// RemoveSupplierItem stops the supplier selling the item, orders of it from the supplier keep the
+// cost they were made at.
+func (m *PurchaseManager) RemoveSupplierItem(ctx context.Context, supplierID, itemID *keys.OpaqueID) (*SupplierItem, error) {
+	filter, err := decodeSupplierItemFilter(ctx, &SupplierItemFilter{Supplier: supplierID, Item: itemID})
+	if err != nil {
+		return nil, err
+	}
+
+	supplied, err := m.Store.GetSupplierItems(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier item: %w", err)
+	}
+	if len(supplied) == 0 {
+		return nil, ErrSupplierItemNotFound
+	}
+
+	if err := m.Store.RemoveSupplierItem(ctx, filter.Supplier.ID, filter.Item.ID); err != nil {
+		return nil, fmt.Errorf("failed to remove supplier item: %w", err)
+	}
+	return supplied[0], nil
+}
+
+// SupplierItems is every item the supplier sells.
+func (m *PurchaseManager) SupplierItems(ctx context.Context, supplier *Supplier) ([]*SupplierItem, error) {
+	return m.Store.GetSupplierItems(ctx, SupplierItemFilter{Supplier: &keys.OpaqueID{ID: supplier.ID.ID}})
+}
+
+// ItemSuppliers is what the item costs from every supplier that sells it.
+func (m *PurchaseManager) ItemSuppliers(ctx context.Context, item *items.Item) ([]*SupplierItem, error) {
+	return m.Store.GetSupplierItems(ctx, SupplierItemFilter{Item: &keys.OpaqueID{ID: item.ID.ID}})
+}
+
+// CreatePurchaseOrder drafts an order of stock from the supplier into the location. Lines without
+// a unit cost are costed at what their item costs from the supplier.
+func (m *PurchaseManager) CreatePurchaseOrder(ctx context.Context, input NewPurchaseOrder) (*PurchaseOrder, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	supplier, err := m.GetSupplier(ctx, input.Supplier)
+	if err != nil {
+		return nil, err
+	}
+	location, err := input.Location.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode location id: %w", err)
+	}
+
+	if len(input.Lines) == 0 {
+		return nil, ErrNoLines
+	}
+
+	order := &PurchaseOrder{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		SupplierID: &keys.OpaqueID{ID: supplier.ID.ID},
+		LocationID: &keys.OpaqueID{ID: location},
+		Status:     Draft,
+		Currency:   supplier.Currency,
+		Note:       input.Note,
+		CreatedBy:  actor.From(ctx),
+	}
+
+	var ordered = map[int]bool{}
+	for _, given := range input.Lines {
+		item, err := m.stocked(ctx, given.Item)
+		if err != nil {
+			return nil, err
+		}
+		if ordered[item.ID.ID] {
+			return nil, ErrDuplicateItem
+		}
+		ordered[item.ID.ID] = true
+
+		quantity, err := inventory.ParseQuantity(given.Quantity, given.UnitScale, item.UnitScale)
+		if err != nil {
+			return nil, err
+		}
+		if quantity.Sign() <= 0 {
+			return nil, inventory.ErrNotPositive
+		}
+
+		line := &Line{
+			ItemID:   &keys.OpaqueID{ID: item.ID.ID},
+			Quantity: quantity,
+			Received: new(big.Rat),
+		}
+		if given.UnitCost != nil {
+			if line.UnitCost, err = m.cost(supplier.Currency, *given.UnitCost); err != nil {
+				return nil, err
+			}
+		} else {
+			supplied, err := m.Store.GetSupplierItems(ctx, SupplierItemFilter{Supplier: order.SupplierID, Item: line.ItemID})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cost of item from supplier: %w", err)
+			}
+			if len(supplied) == 0 {
+				return nil, fmt.Errorf("%w: %q", ErrNoCost, item.Name)
+			}
+			line.UnitCost = supplied[0].Cost
+		}
+		order.Lines = append(order.Lines, line)
+	}
+
+	if err := m.Store.CreateOrder(ctx, order); err != nil {
+		return nil, fmt.Errorf("failed to create purchase order: %w", err)
+	}
+	return order, nil
+}
+
+func (m *PurchaseManager) GetPurchaseOrder(ctx context.Context, externalID *keys.OpaqueID) (*PurchaseOrder, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	return m.Store.GetOrder(ctx, id)
+}
+
+func (m *PurchaseManager) SearchPurchaseOrders(ctx context.Context, filter *OrderFilter, page *paginate.Paginate) ([]*PurchaseOrder, error) {
+	var decoded OrderFilter
+	if filter != nil {
+		var err error
+		if decoded.Supplier, err = keys.DecodeOptional(ctx, filter.Supplier); err != nil {
+			return nil, fmt.Errorf("failed to decode supplier id: %w", err)
+		}
+		if decoded.Location, err = keys.DecodeOptional(ctx, filter.Location); err != nil {
+			return nil, fmt.Errorf("failed to decode location id: %w", err)
+		}
+		decoded.Status = filter.Status
+	}
+
+	decodedPage, err := paginate.Decode(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := m.Store.PageOrders(ctx, decoded, decodedPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to page through purchase orders: %w", err)
+	}
+	return orders, nil
+}
+
+// PlacePurchaseOrder marks the draft as sent to the supplier, stock can be received against it
+// from then on.
+func (m *PurchaseManager) PlacePurchaseOrder(ctx context.Context, externalID *keys.OpaqueID) (*PurchaseOrder, error) {
+	order, err := m.GetPurchaseOrder(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.Store.TransitionOrder(ctx, order, Ordered, Draft); err != nil {
+		return nil, fmt.Errorf("failed to place purchase order: %w", err)
+	}
+	return order, nil
+}
+
+// CancelPurchaseOrder closes the order before it is received in full, stock already received
+// against it stays received.
+func (m *PurchaseManager) CancelPurchaseOrder(ctx context.Context, externalID *keys.OpaqueID) (*PurchaseOrder, error) {
+	order, err := m.GetPurchaseOrder(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.Store.TransitionOrder(ctx, order, Cancelled, Draft, Ordered, PartiallyReceived); err != nil {
+		return nil, fmt.Errorf("failed to cancel purchase order: %w", err)
+	}
+	return order, nil
+}
+
+// ReceivePurchaseOrder brings the stock of the lines received into the location of the order,
+// any part of what is outstanding on a line can be received at a time.
+func (m *PurchaseManager) ReceivePurchaseOrder(ctx context.Context, externalID *keys.OpaqueID, input NewReceipt) (*PurchaseOrder, error) {
+	order, err := m.GetPurchaseOrder(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != Ordered && order.Status != PartiallyReceived {
+		return nil, fmt.Errorf("%w: %s", ErrOrderStatus, order.Status)
+	}
+
+	if len(input.Lines) == 0 {
+		return nil, ErrNoReceipt
+	}
+
+	var (
+		lines     = map[int]*Line{}
+		received  = map[int]bool{}
+		movements = make([]*inventory.Movement, 0, len(input.Lines))
+	)
+	for _, line := range order.Lines {
+		lines[line.ID.ID] = line
+	}
+	for _, given := range input.Lines {
+		id, err := given.Line.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode line id: %w", err)
+		}
+		line, ok := lines[id]
+		if !ok {
+			return nil, ErrLineNotFound
+		}
+		if received[id] {
+			return nil, ErrDuplicateLine
+		}
+		received[id] = true
+
+		item, err := m.Items.GetItem(ctx, line.ItemID.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get item of line: %w", err)
+		}
+		quantity, err := inventory.ParseQuantity(given.Quantity, given.UnitScale, item.UnitScale)
+		if err != nil {
+			return nil, err
+		}
+		if quantity.Sign() <= 0 {
+			return nil, inventory.ErrNotPositive
+		}
+		if quantity.Cmp(line.Outstanding()) > 0 {
+			return nil, fmt.Errorf("%w: %s of %q outstanding", ErrOverReceipt, inventory.FormatQuantity(line.Outstanding()), item.Name)
+		}
+
+		movements = append(movements, &inventory.Movement{
+			ItemID:          &keys.OpaqueID{ID: line.ItemID.ID},
+			LocationID:      &keys.OpaqueID{ID: order.LocationID.ID},
+			Quantity:        quantity,
+			Reason:          inventory.Receipt,
+			PurchaseOrderID: &keys.OpaqueID{ID: order.ID.ID},
+			Note:            input.Note,
+			Actor:           actor.From(ctx),
+		})
+	}
+
+	if err := m.Store.ReceiveOrder(ctx, order, movements); err != nil {
+		return nil, fmt.Errorf("failed to receive purchase order: %w", err)
+	}
+	return m.Store.GetOrder(ctx, order.ID.ID)
+}
+
+// Receipts is the stock received against the order.
+func (m *PurchaseManager) Receipts(ctx context.Context, order *PurchaseOrder) ([]*inventory.Movement, error) {
+	return m.Store.GetReceipts(ctx, order.ID.ID)
+}
+
+// SupplierOf resolves the supplier of a supplier item or purchase order.
+func (m *PurchaseManager) SupplierOf(ctx context.Context, id *keys.OpaqueID) (*Supplier, error) {
+	return m.Store.GetSupplier(ctx, id.ID)
+}
+
+// stocked resolves the item, refusing kits as they hold no stock of their own.
+func (m *PurchaseManager) stocked(ctx context.Context, externalID *keys.OpaqueID) (*items.Item, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode item id: %w", err)
+	}
+
+	item, err := m.Items.GetItem(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	components, err := m.Stock.GetComponents(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get components of item: %w", err)
+	}
+	if len(components) > 0 {
+		return nil, inventory.ErrKitStock
+	}
+	return item, nil
+}
+
+// cost reads a cost given in the currency of the supplier.
+func (m *PurchaseManager) cost(currency money.Currency, input money.Input) (money.Money, error) {
+	cost, err := input.Money()
+	if err != nil {
+		return money.Money{}, err
+	}
+	if cost.Currency() != currency {
+		return money.Money{}, fmt.Errorf("%w: %s", ErrCostCurrency, currency)
+	}
+	if cost.Sign() < 0 {
+		return money.Money{}, ErrNegativeCost
+	}
+	return cost, nil
+}
+
+func contact(given *string, normalise func(string) (string, error)) (*string, error) {
+	if given == nil || strings.TrimSpace(*given) == "" {
+		return nil, nil
+	}
+	normalised, err := normalise(*given)
+	if err != nil {
+		return nil, err
+	}
+	return &normalised, nil
+}
+
+func decodeSupplierItemFilter(ctx context.Context, filter *SupplierItemFilter) (SupplierItemFilter, error) {
+	var decoded SupplierItemFilter
+	if filter == nil {
+		return decoded, nil
+	}
+
+	var err error
+	if decoded.Supplier, err = keys.DecodeOptional(ctx, filter.Supplier); err != nil {
+		return decoded, fmt.Errorf("failed to decode supplier id: %w", err)
+	}
+	if decoded.Item, err = keys.DecodeOptional(ctx, filter.Item); err != nil {
+		return decoded, fmt.Errorf("failed to decode item id: %w", err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,241 @@
+package purchasing
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func nzd(t *testing.T, amount string) money.Money {
+	t.Helper()
+
+	parsed, err := money.Parse(amount, "NZD")
+	require.NoError(t, err)
+	return parsed
+}
+
+func TestTotal(t *testing.T) {
+	order := &PurchaseOrder{
+		Currency: "NZD",
+		Lines: []*Line{
+			// 2.475 rounds half to even
+			{Quantity: big.NewRat(5, 2), Received: new(big.Rat), UnitCost: nzd(t, "0.99")},
+			{Quantity: big.NewRat(3, 1), Received: big.NewRat(1, 1), UnitCost: nzd(t, "4.00")},
+		},
+	}
+
+	cost, err := order.Lines[0].Cost()
+	require.NoError(t, err)
+	assert.Equal(t, "2.48", cost.Decimal())
+	assert.Equal(t, "2", inventory.FormatQuantity(order.Lines[1].Outstanding()))
+
+	total, err := order.Total()
+	require.NoError(t, err)
+	assert.Equal(t, "14.48", total.Decimal())
+}
+
+// codec takes external ids to be internal ids written out.
+type codec struct{}
+
+func (codec) Encode(ctx context.Context, id int) (string, error) {
+	return strconv.Itoa(id), nil
+}
+
+func (codec) Decode(ctx context.Context, id string) (int, error) {
+	return strconv.Atoi(id)
+}
+
+func opaque(t *testing.T, id int) *keys.OpaqueID {
+	t.Helper()
+
+	var external = &keys.OpaqueID{}
+	require.NoError(t, external.UnmarshalGQLContext(context.Background(), strconv.Itoa(id)))
+	return external.WithCodec(codec{})
+}
+
+// books holds a single supplier 1 in NZD that sells item 1 for 2.50, item 1 is sold by the unit,
+// item 2 by the kg, and item 9 is a kit.
+type books struct {
+	orders   map[int]*PurchaseOrder
+	received []*inventory.Movement
+}
+
+func (b *books) CreateSupplier(ctx context.Context, supplier *Supplier) error { return nil }
+
+func (b *books) GetSupplier(ctx context.Context, id int) (*Supplier, error) {
+	if id != 1 {
+		return nil, ErrSupplierNotFound
+	}
+	return &Supplier{ID: &keys.OpaqueID{ID: 1}, Name: "wholesaler", Currency: "NZD"}, nil
+}
+
+func (b *books) GetSuppliers(ctx context.Context) ([]*Supplier, error) { return nil, nil }
+
+func (b *books) UpdateSupplier(ctx context.Context, supplier *Supplier) error { return nil }
+
+func (b *books) SetSupplierItem(ctx context.Context, supplied *SupplierItem) error { return nil }
+
+func (b *books) RemoveSupplierItem(ctx context.Context, supplier int, item int) error { return nil }
+
+func (b *books) GetSupplierItems(ctx context.Context, filter SupplierItemFilter) ([]*SupplierItem, error) {
+	if filter.Item.ID != 1 {
+		return nil, nil
+	}
+	cost, err := money.Parse("2.50", "NZD")
+	return []*SupplierItem{{SupplierID: filter.Supplier, ItemID: filter.Item, Cost: cost}}, err
+}
+
+func (b *books) CreateOrder(ctx context.Context, order *PurchaseOrder) error {
+	order.ID = &keys.OpaqueID{ID: len(b.orders) + 1}
+	for n, line := range order.Lines {
+		line.ID = &keys.OpaqueID{ID: 10*order.ID.ID + n}
+	}
+	b.orders[order.ID.ID] = order
+	return nil
+}
+
+func (b *books) GetOrder(ctx context.Context, id int) (*PurchaseOrder, error) {
+	order, ok := b.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (b *books) PageOrders(ctx context.Context, filter OrderFilter, page paginate.Paginate) ([]*PurchaseOrder, error) {
+	return nil, nil
+}
+
+func (b *books) TransitionOrder(ctx context.Context, order *PurchaseOrder, to Status, from ...Status) error {
+	for _, status := range from {
+		if order.Status == status {
+			order.Status = to
+			return nil
+		}
+	}
+	return ErrOrderStatus
+}
+
+func (b *books) ReceiveOrder(ctx context.Context, order *PurchaseOrder, movements []*inventory.Movement) error {
+	for _, movement := range movements {
+		for _, line := range order.Lines {
+			if line.ItemID.ID == movement.ItemID.ID {
+				line.Received.Add(line.Received, movement.Quantity)
+			}
+		}
+	}
+	b.received = append(b.received, movements...)
+	return nil
+}
+
+func (b *books) GetReceipts(ctx context.Context, order int) ([]*inventory.Movement, error) {
+	return b.received, nil
+}
+
+func (b *books) GetItem(ctx context.Context, id int) (*items.Item, error) {
+	var scale = items.Unit
+	if id == 2 {
+		scale = items.Kilogram
+	}
+	return &items.Item{ID: &keys.OpaqueID{ID: id}, Details: items.Details{Name: "tea", UnitScale: scale}}, nil
+}
+
+func (b *books) GetComponents(ctx context.Context, item int) ([]*inventory.Component, error) {
+	if item != 9 {
+		return nil, nil
+	}
+	return []*inventory.Component{{ItemID: &keys.OpaqueID{ID: 1}, Quantity: big.NewRat(1, 1)}}, nil
+}
+
+func TestCreatePurchaseOrder(t *testing.T) {
+	var (
+		ctx     = retailers.With(context.Background(), 1)
+		stock   = &books{orders: map[int]*PurchaseOrder{}}
+		manager = &PurchaseManager{Store: stock, Items: stock, Stock: stock}
+		grams   = items.Gram
+	)
+	var order = func(lines ...*NewLine) (*PurchaseOrder, error) {
+		return manager.CreatePurchaseOrder(ctx, NewPurchaseOrder{Supplier: opaque(t, 1), Location: opaque(t, 3), Lines: lines})
+	}
+
+	created, err := order(
+		&NewLine{Item: opaque(t, 1), Quantity: "12"},
+		&NewLine{Item: opaque(t, 2), Quantity: "500", UnitScale: &grams, UnitCost: &money.Input{Amount: "30.00", Currency: "NZD"}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, Draft, created.Status)
+	assert.Equal(t, money.Currency("NZD"), created.Currency)
+	assert.Equal(t, "2.50", created.Lines[0].UnitCost.Decimal())
+	assert.Equal(t, "0.5", inventory.FormatQuantity(created.Lines[1].Quantity))
+
+	total, err := created.Total()
+	require.NoError(t, err)
+	assert.Equal(t, "45.00", total.Decimal())
+
+	_, err = order()
+	require.ErrorIs(t, err, ErrNoLines)
+	_, err = order(&NewLine{Item: opaque(t, 2), Quantity: "1"})
+	require.ErrorIs(t, err, ErrNoCost)
+	_, err = order(&NewLine{Item: opaque(t, 1), Quantity: "1"}, &NewLine{Item: opaque(t, 1), Quantity: "2"})
+	require.ErrorIs(t, err, ErrDuplicateItem)
+	_, err = order(&NewLine{Item: opaque(t, 1), Quantity: "0"})
+	require.ErrorIs(t, err, inventory.ErrNotPositive)
+	_, err = order(&NewLine{Item: opaque(t, 1), Quantity: "1", UnitCost: &money.Input{Amount: "1.00", Currency: "AUD"}})
+	require.ErrorIs(t, err, ErrCostCurrency)
+	_, err = order(&NewLine{Item: opaque(t, 9), Quantity: "1"})
+	require.ErrorIs(t, err, inventory.ErrKitStock)
+}
+
+func TestReceivePurchaseOrder(t *testing.T) {
+	var (
+		ctx     = retailers.With(context.Background(), 1)
+		stock   = &books{orders: map[int]*PurchaseOrder{}}
+		manager = &PurchaseManager{Store: stock, Items: stock, Stock: stock}
+	)
+
+	order, err := manager.CreatePurchaseOrder(ctx, NewPurchaseOrder{
+		Supplier: opaque(t, 1),
+		Location: opaque(t, 3),
+		Lines:    []*NewLine{{Item: opaque(t, 1), Quantity: "12"}},
+	})
+	require.NoError(t, err)
+	line := opaque(t, order.Lines[0].ID.ID)
+
+	var receive = func(quantity string) error {
+		_, err := manager.ReceivePurchaseOrder(ctx, opaque(t, order.ID.ID), NewReceipt{Lines: []*NewReceiptLine{{Line: line, Quantity: quantity}}})
+		return err
+	}
+
+	require.ErrorIs(t, receive("1"), ErrOrderStatus)
+	_, err = manager.PlacePurchaseOrder(ctx, opaque(t, order.ID.ID))
+	require.NoError(t, err)
+	_, err = manager.PlacePurchaseOrder(ctx, opaque(t, order.ID.ID))
+	require.ErrorIs(t, err, ErrOrderStatus)
+
+	require.NoError(t, receive("5"))
+	require.ErrorIs(t, receive("8"), ErrOverReceipt)
+	require.ErrorIs(t, receive("0"), inventory.ErrNotPositive)
+
+	_, err = manager.ReceivePurchaseOrder(ctx, opaque(t, order.ID.ID), NewReceipt{Lines: []*NewReceiptLine{{Line: line, Quantity: "1"}, {Line: line, Quantity: "1"}}})
+	require.ErrorIs(t, err, ErrDuplicateLine)
+	_, err = manager.ReceivePurchaseOrder(ctx, opaque(t, order.ID.ID), NewReceipt{Lines: []*NewReceiptLine{{Line: opaque(t, 99), Quantity: "1"}}})
+	require.ErrorIs(t, err, ErrLineNotFound)
+
+	require.Len(t, stock.received, 1)
+	movement := stock.received[0]
+	assert.Equal(t, inventory.Receipt, movement.Reason)
+	assert.Equal(t, order.ID.ID, movement.PurchaseOrderID.ID)
+	assert.Equal(t, 3, movement.LocationID.ID)
+	assert.Equal(t, "5", inventory.FormatQuantity(movement.Quantity))
+	assert.Equal(t, "7", inventory.FormatQuantity(order.Lines[0].Outstanding()))
+}
@@ -0,0 +1,91 @@
+package receipts
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+)
+
+// receiptHTML is a standalone page with inline styles, so that it reads the same when emailed.
+var receiptHTML = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"tender": func(tender payments.Tender) string {
+		return strings.ReplaceAll(string(tender), "_", " ")
+	},
+	"open": func(r *Receipt) bool {
+		return r.Void == nil && r.Status == sales.Open
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Receipt {{.SaleID}}</title>
+</head>
+<body style="font-family: sans-serif; max-width: 24em; margin: 0 auto;">
+<header style="text-align: center;">
+<h1 style="font-size: 1.2em;">{{.Retailer}}</h1>
+<p>Sale {{.SaleID}}<br><time datetime="{{.SaleDate.Format "2006-01-02T15:04:05Z07:00"}}">{{.SaleDate.Format "2006-01-02 15:04"}}</time></p>
+{{- with .Void}}
+<p><strong>VOIDED</strong><br>{{.Reason}}</p>
+{{- end}}
+</header>
+<table style="width: 100%; border-collapse: collapse;">
+<tbody>
+{{- range .Lines}}
+<tr><td colspan="2">{{.Name}}</td></tr>
+<tr><td style="padding-left: 1em;">{{.Quantity}} x {{.UnitPrice.Decimal}}</td><td style="text-align: right;">{{.Total.Decimal}}</td></tr>
+{{- range .Discounts}}
+<tr><td style="padding-left: 1em;">{{.Name}}</td><td style="text-align: right;">{{.Amount.Neg.Decimal}}</td></tr>
+{{- end}}
+{{- end}}
+</tbody>
+<tbody style="border-top: 1px solid;">
+<tr><td>Subtotal</td><td style="text-align: right;">{{.Subtotal.Decimal}}</td></tr>
+{{- if not .TaxIncluded}}
+{{- range .Taxes}}
+<tr><td>{{.Name}} {{.Rate}}</td><td style="text-align: right;">{{.Amount.Decimal}}</td></tr>
+{{- end}}
+{{- end}}
+<tr><th style="text-align: left;">Total {{.Currency}}</th><th style="text-align: right;">{{.Total.Decimal}}</th></tr>
+{{- if .TaxIncluded}}
+{{- range .Taxes}}
+<tr><td>Includes {{.Name}} {{.Rate}}</td><td style="text-align: right;">{{.Amount.Decimal}}</td></tr>
+{{- end}}
+{{- end}}
+</tbody>
+{{- if .Payments}}
+<tbody style="border-top: 1px solid;">
+{{- range .Payments}}
+<tr><td>{{tender .Tender}}{{if .ReversedAt}} (reversed){{end}}</td><td style="text-align: right;">{{.Amount.Decimal}}</td></tr>
+{{- if and .Tendered .Change}}
+<tr><td style="padding-left: 1em;">Tendered</td><td style="text-align: right;">{{.Tendered.Decimal}}</td></tr>
+<tr><td style="padding-left: 1em;">Change</td><td style="text-align: right;">{{.Change.Decimal}}</td></tr>
+{{- end}}
+{{- with .Reference}}
+<tr><td colspan="2" style="padding-left: 1em;">Ref {{.}}</td></tr>
+{{- end}}
+{{- end}}
+</tbody>
+{{- end}}
+{{- if open .}}
+<tbody style="border-top: 1px solid;">
+<tr><th style="text-align: left;">Due</th><th style="text-align: right;">{{.Due.Decimal}}</th></tr>
+</tbody>
+{{- end}}
+{{- if not .Refunded.IsZero}}
+<tbody style="border-top: 1px solid;">
+<tr><td>Refunded</td><td style="text-align: right;">{{.Refunded.Neg.Decimal}}</td></tr>
+</tbody>
+{{- end}}
+</table>
+<footer style="text-align: center;"><p>Thank you</p></footer>
+</body>
+</html>
+`))
+
+// writeHTML renders the receipt as a page of HTML.
+func writeHTML(w io.Writer, r *Receipt) error {
+	return receiptHTML.Execute(w, r)
+}
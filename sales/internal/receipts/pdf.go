@@ -0,0 +1,83 @@
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Receipts are typeset on a page of 80mm thermal paper in the monospaced fonts every PDF reader
+// has, so nothing needs embedding.
+const (
+	pdfWidth   = 227 // 80mm in points
+	pdfMargin  = 12
+	pdfSize    = 7
+	pdfLeading = 9
+)
+
+// writePDF renders the receipt as a single page PDF as long as the receipt is. Anything outside of
+// Latin-1 is printed as a question mark.
+func writePDF(w io.Writer, r *Receipt) error {
+	rows := layout(r, Text80mm.columns())
+	height := 2*pdfMargin + len(rows)*pdfLeading
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n%d TL\n%d %d Td\n", pdfLeading, pdfMargin, height-pdfMargin-pdfSize)
+	for _, row := range rows {
+		font := "F1"
+		if row.bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "/%s %d Tf (%s) Tj T*\n", font, pdfSize, pdfString(row.text))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> /Contents 4 0 R >>", pdfWidth, height),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+	}
+
+	var (
+		document bytes.Buffer
+		offsets  = make([]int, 0, len(objects))
+	)
+	document.WriteString("%PDF-1.4\n")
+	for i, object := range objects {
+		offsets = append(offsets, document.Len())
+		fmt.Fprintf(&document, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	// the cross reference table is how readers find objects, every entry is exactly 20 bytes
+	xref := document.Len()
+	fmt.Fprintf(&document, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&document, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&document, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := document.WriteTo(w)
+	return err
+}
+
+// pdfString escapes text for a PDF literal string in WinAnsiEncoding, which agrees with Latin-1
+// on every printable character of it.
+func pdfString(text string) string {
+	var escaped strings.Builder
+	for _, c := range text {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			escaped.WriteByte('\\')
+			escaped.WriteRune(c)
+		case c < ' ' || c > 0xff || (c >= 0x7f && c < 0xa0):
+			escaped.WriteByte('?')
+		default:
+			escaped.WriteByte(byte(c))
+		}
+	}
+	return escaped.String()
+}
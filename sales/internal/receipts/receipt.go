@@ -0,0 +1,177 @@
+// Package receipts renders recorded sales into receipts to print or email.
+package receipts
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type Format string
+
+const (
+	// Text58mm and Text80mm lay receipts out as plain text in as many columns as fit on 58mm and
+	// 80mm thermal paper.
+	Text58mm Format = "TEXT_58MM"
+	Text80mm Format = "TEXT_80MM"
+	// ESCPOS58mm and ESCPOS80mm lay receipts out as the plain text formats do, wrapped in the
+	// ESC/POS commands thermal printers take.
+	ESCPOS58mm Format = "ESCPOS_58MM"
+	ESCPOS80mm Format = "ESCPOS_80MM"
+	HTML       Format = "HTML"
+	PDF        Format = "PDF"
+)
+
+// columns is how many characters of the default font fit across the paper of the format.
+func (f Format) columns() int {
+	switch f {
+	case Text58mm, ESCPOS58mm:
+		return 32
+	default:
+		return 48
+	}
+}
+
+// ContentType is the media type of receipts rendered in the format.
+func (f Format) ContentType() string {
+	switch f {
+	case Text58mm, Text80mm:
+		return "text/plain; charset=utf-8"
+	case ESCPOS58mm, ESCPOS80mm:
+		return "application/octet-stream"
+	case HTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "application/pdf"
+	}
+}
+
+// Extension is the file extension of receipts rendered in the format.
+func (f Format) Extension() string {
+	switch f {
+	case Text58mm, Text80mm:
+		return "txt"
+	case ESCPOS58mm, ESCPOS80mm:
+		return "bin"
+	case HTML:
+		return "html"
+	default:
+		return "pdf"
+	}
+}
+
+// Binary is whether receipts rendered in the format aren't text.
+func (f Format) Binary() bool {
+	switch f {
+	case ESCPOS58mm, ESCPOS80mm, PDF:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f Format) valid() error {
+	switch f {
+	case Text58mm, Text80mm, ESCPOS58mm, ESCPOS80mm, HTML, PDF:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrFormat, f)
+	}
+}
+
+// Receipt is a sale as printed, every amount is in the currency of the sale.
+type Receipt struct {
+	// Retailer heads the receipt.
+	Retailer string
+	// SaleID is the external id of the sale, which is what the sale is looked up by.
+	SaleID   string
+	SaleDate time.Time
+	Currency money.Currency
+	Status   sales.Status
+
+	Lines    []Line
+	Subtotal money.Money
+	Taxes    []Tax
+	// TaxIncluded is whether the taxes are included in prices rather than added on top of them.
+	TaxIncluded bool
+	Total       money.Money
+
+	Payments []*payments.Payment
+	Due      money.Money
+	Refunded money.Money
+	Void     *sales.Void
+}
+
+// Line is a line item along with the name of its item.
+type Line struct {
+	Name string
+	*sales.LineItem
+	// Quantity is the quantity of the line item as printed, along with its unit scale unless the
+	// item is sold by the unit, ie "0.25kg".
+	Quantity string
+	// Total is the quantity of the line item at its unit price, before discounts.
+	Total money.Money
+}
+
+// Tax is the tax charged at a tax rate, the rate is a percentage.
+type Tax struct {
+	Name   string
+	Rate   string
+	Amount money.Money
+}
+
+// Build works out the receipt of the sale, names hold the name of every item sold by item id.
+func Build(sale *sales.Sale, retailer string, saleID string, names map[int]string) (*Receipt, error) {
+	receipt := &Receipt{
+		Retailer:    retailer,
+		SaleID:      saleID,
+		SaleDate:    sale.SaleDate,
+		Currency:    sale.Currency,
+		Status:      sale.Status,
+		TaxIncluded: sale.PricesIncludeTax,
+		Payments:    sale.Payments,
+		Void:        sale.Void,
+	}
+
+	for _, line := range sale.LineItems {
+		total, err := line.Total()
+		if err != nil {
+			return nil, fmt.Errorf("failed to work out line total: %w", err)
+		}
+		quantity := inventory.FormatQuantity(line.Quantity)
+		if line.UnitScale != items.Unit {
+			quantity += string(line.UnitScale)
+		}
+		receipt.Lines = append(receipt.Lines, Line{Name: names[line.ItemID.ID], LineItem: line, Quantity: quantity, Total: total})
+	}
+	for _, line := range sale.Taxes {
+		receipt.Taxes = append(receipt.Taxes, Tax{Name: line.Name, Rate: percent(line.Rate), Amount: line.Amount})
+	}
+
+	var err error
+	if receipt.Subtotal, err = sale.Subtotal(); err != nil {
+		return nil, fmt.Errorf("failed to work out subtotal: %w", err)
+	}
+	if receipt.Total, err = sale.Total(); err != nil {
+		return nil, fmt.Errorf("failed to work out total: %w", err)
+	}
+	if receipt.Due, err = sale.Due(); err != nil {
+		return nil, fmt.Errorf("failed to work out what is due: %w", err)
+	}
+	if receipt.Refunded, err = sale.Refunded(); err != nil {
+		return nil, fmt.Errorf("failed to work out what was refunded: %w", err)
+	}
+	return receipt, nil
+}
+
+// percent formats a rate as a percentage, ie "15%".
+func percent(rate *big.Rat) string {
+	return tax.FormatRate(new(big.Rat).Mul(rate, big.NewRat(100, 1))) + "%"
+}
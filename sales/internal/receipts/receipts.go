@@ -0,0 +1,133 @@
+package receipts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+var ErrFormat = errors.New("unsupported receipt format")
+
+type saleStore interface {
+	GetSale(context.Context, int) (*sales.Sale, error)
+}
+
+type itemStore interface {
+	GetItem(context.Context, int) (*items.Item, error)
+}
+
+// retailerStore tells who the retailer heading receipts is.
+type retailerStore interface {
+	GetRetailer(context.Context, int) (*retailers.Retailer, error)
+}
+
+type ReceiptManager struct {
+	Sales     saleStore
+	Items     itemStore
+	Retailers retailerStore
+	// Codec encodes the id of the sale printed on receipts and decodes the ids of sales that
+	// receipts are downloaded for.
+	Codec keys.EncoderDecoder
+}
+
+// Render renders the receipt of the sale in the format.
+func (m *ReceiptManager) Render(ctx context.Context, w io.Writer, sale *sales.Sale, format Format) error {
+	if err := format.valid(); err != nil {
+		return err
+	}
+
+	receipt, err := m.receipt(ctx, sale)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case Text58mm, Text80mm:
+		return writeText(w, receipt, format)
+	case ESCPOS58mm, ESCPOS80mm:
+		return writeESCPOS(w, receipt, format)
+	case HTML:
+		return writeHTML(w, receipt)
+	default:
+		return writePDF(w, receipt)
+	}
+}
+
+func (m *ReceiptManager) receipt(ctx context.Context, sale *sales.Sale) (*Receipt, error) {
+	retailer, err := m.Retailers.GetRetailer(ctx, sale.RetailerID.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer of sale: %w", err)
+	}
+
+	saleID, err := m.Codec.Encode(ctx, sale.ID.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sale id: %w", err)
+	}
+
+	var names = make(map[int]string, len(sale.LineItems))
+	for _, line := range sale.LineItems {
+		if _, ok := names[line.ItemID.ID]; ok {
+			continue
+		}
+		item, err := m.Items.GetItem(ctx, line.ItemID.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get item of line item: %w", err)
+		}
+		names[line.ItemID.ID] = item.Name
+	}
+
+	return Build(sale, retailer.Name, saleID, names)
+}
+
+// Handler downloads the receipt of the sale of the external id that the path is, in the format
+// asked for by the query, ie /{id}?format=PDF.
+func (m *ReceiptManager) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := Format(strings.ToUpper(r.URL.Query().Get("format")))
+		if format == "" {
+			format = PDF
+		}
+		if err := format.valid(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		external := strings.TrimPrefix(r.URL.Path, "/")
+		id, err := m.Codec.Decode(r.Context(), external)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		sale, err := m.Sales.GetSale(r.Context(), id)
+		if errors.Is(err, sales.ErrSaleNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			slog.Error(fmt.Sprintf("failed to get sale %d: %v", id, err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		// receipts are small, rendering them whole means failures can still be reported
+		var receipt strings.Builder
+		if err := m.Render(r.Context(), &receipt, sale, format); err != nil {
+			slog.Error(fmt.Sprintf("failed to render receipt of sale %d: %v", id, err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"receipt-%s.%s\"", external, format.Extension()))
+		io.WriteString(w, receipt.String())
+	})
+}
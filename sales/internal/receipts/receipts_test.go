@@ -0,0 +1,198 @@
+package receipts
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+var update = flag.Bool("update", false, "update the golden files of receipts")
+
+func nzd(t *testing.T, amount string) money.Money {
+	m, err := money.Parse(amount, "NZD")
+	require.NoError(t, err)
+	return m
+}
+
+// paidSale sells 3 coffees at 4.00 with 10% off and a muffin at 3.50, 15% tax is added on top and
+// it is paid by card and cash.
+func paidSale(t *testing.T) *sales.Sale {
+	var (
+		at        = time.Date(2026, time.March, 10, 12, 30, 0, 0, time.UTC)
+		reference = "local-1"
+		tendered  = nzd(t, "10.00")
+		change    = nzd(t, "2.76")
+	)
+	return &sales.Sale{
+		ID:         &keys.OpaqueID{ID: 7},
+		RetailerID: &keys.OpaqueID{ID: 1},
+		SaleDate:   at,
+		Currency:   "NZD",
+		Status:     sales.Paid,
+		LineItems: []*sales.LineItem{
+			{ItemID: &keys.OpaqueID{ID: 1}, Quantity: big.NewRat(3, 1), UnitPrice: nzd(t, "4.00"), Discounts: []*promotions.Discount{
+				{Name: "10% off coffee", Amount: nzd(t, "1.20")},
+			}},
+			{ItemID: &keys.OpaqueID{ID: 2}, Quantity: big.NewRat(1, 1), UnitPrice: nzd(t, "3.50")},
+		},
+		Taxes: []*tax.Line{
+			{Name: "GST", Rate: big.NewRat(15, 100), Taxable: nzd(t, "14.30"), Amount: nzd(t, "2.15")},
+		},
+		Payments: []*payments.Payment{
+			{Tender: payments.Card, Amount: nzd(t, "9.21"), Reference: &reference, PaidAt: at},
+			{Tender: payments.Cash, Amount: nzd(t, "7.24"), Tendered: &tendered, Change: &change, PaidAt: at},
+		},
+		Refunds: []*sales.Refund{},
+	}
+}
+
+// voidedSale sells a pastry with a long name, tax is included in its price and the card payment
+// was reversed by voiding the sale.
+func voidedSale(t *testing.T) *sales.Sale {
+	var (
+		at        = time.Date(2026, time.March, 10, 12, 30, 0, 0, time.UTC)
+		reference = "local-2"
+	)
+	return &sales.Sale{
+		ID:               &keys.OpaqueID{ID: 8},
+		RetailerID:       &keys.OpaqueID{ID: 1},
+		SaleDate:         at,
+		Currency:         "NZD",
+		PricesIncludeTax: true,
+		Status:           sales.Voided,
+		LineItems: []*sales.LineItem{
+			{ItemID: &keys.OpaqueID{ID: 3}, Quantity: big.NewRat(12, 1), UnitPrice: nzd(t, "1234.50")},
+		},
+		Taxes: []*tax.Line{
+			{Name: "GST", Rate: big.NewRat(15, 100), Taxable: nzd(t, "12881.74"), Amount: nzd(t, "1932.26")},
+		},
+		Payments: []*payments.Payment{
+			{Tender: payments.StoreCredit, Amount: nzd(t, "14814.00"), Reference: &reference, PaidAt: at, ReversedAt: &at},
+		},
+		Void:    &sales.Void{At: at, Actor: "till-1", Reason: "rung up twice"},
+		Refunds: []*sales.Refund{},
+	}
+}
+
+var names = map[int]string{1: "flat white", 2: "muffin", 3: "crème brûlée with seasonal berries (family size)"}
+
+func TestReceiptsGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		sale    func(t *testing.T) *sales.Sale
+		formats []Format
+	}{
+		{name: "paid", sale: paidSale, formats: []Format{Text58mm, Text80mm, ESCPOS58mm, ESCPOS80mm, HTML, PDF}},
+		{name: "voided", sale: voidedSale, formats: []Format{Text58mm, ESCPOS58mm, HTML, PDF}},
+	}
+
+	for _, tt := range tests {
+		for _, format := range tt.formats {
+			t.Run(tt.name+" "+string(format), func(t *testing.T) {
+				ctx := context.Background()
+				manager := ReceiptManager{Items: catalogue{}, Retailers: directory{}, Codec: codec{}}
+
+				var rendered bytes.Buffer
+				require.NoError(t, manager.Render(ctx, &rendered, tt.sale(t), format))
+
+				golden := filepath.Join("testdata", tt.name+"_"+strings.ToLower(string(format))+".golden")
+				if *update {
+					require.NoError(t, os.WriteFile(golden, rendered.Bytes(), 0o644))
+				}
+				want, err := os.ReadFile(golden)
+				require.NoError(t, err)
+				assert.Equal(t, string(want), rendered.String())
+			})
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	assert.Equal(t, []string{"flat white"}, wrap("flat white", 12))
+	assert.Equal(t, []string{"a long item", "name"}, wrap("a long item name", 12))
+	assert.Equal(t, []string{"  Ref", "  abcdefghij", "  klm"}, wrap("  Ref abcdefghijklm", 12))
+	assert.Equal(t, []string{""}, wrap("", 12))
+
+	assert.Equal(t, []string{"Subtotal   1.00"}, justify("Subtotal", "1.00", 15))
+	assert.Equal(t, []string{"Sub total", "        1000.00"}, justify("Sub total", "1000.00", 15))
+}
+
+func TestHandler(t *testing.T) {
+	sale := paidSale(t)
+	manager := ReceiptManager{Sales: &oneSale{sale: sale}, Items: catalogue{}, Retailers: directory{}, Codec: codec{}}
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{path: "/7", status: http.StatusOK, contentType: "application/pdf"},
+		{path: "/7?format=text_80mm", status: http.StatusOK, contentType: "text/plain; charset=utf-8"},
+		{path: "/7?format=docx", status: http.StatusBadRequest},
+		{path: "/8", status: http.StatusNotFound},
+		{path: "/unknown", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			manager.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.status, recorder.Code)
+			if tt.contentType != "" {
+				assert.Equal(t, tt.contentType, recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+type oneSale struct{ sale *sales.Sale }
+
+func (s *oneSale) GetSale(ctx context.Context, id int) (*sales.Sale, error) {
+	if id != s.sale.ID.ID {
+		return nil, sales.ErrSaleNotFound
+	}
+	return s.sale, nil
+}
+
+type catalogue struct{}
+
+func (catalogue) GetItem(ctx context.Context, id int) (*items.Item, error) {
+	return &items.Item{ID: &keys.OpaqueID{ID: id}, Details: items.Details{Name: names[id]}}, nil
+}
+
+type directory struct{}
+
+func (directory) GetRetailer(ctx context.Context, id int) (*retailers.Retailer, error) {
+	return &retailers.Retailer{ID: &keys.OpaqueID{ID: id}, Name: "Pedlar Coffee", Domain: "pedlar.test"}, nil
+}
+
+// codec leaves ids as they are.
+type codec struct{}
+
+func (codec) Encode(ctx context.Context, id int) (string, error) {
+	return strconv.Itoa(id), nil
+}
+
+func (codec) Decode(ctx context.Context, id string) (int, error) {
+	return strconv.Atoi(id)
+}
@@ -0,0 +1,214 @@
+package receipts
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+)
+
+// row is a line of a receipt laid out in columns, bold is honoured by the formats that can print
+// it.
+type row struct {
+	text string
+	bold bool
+}
+
+// layout lays the receipt out as text in the number of columns, every row fits within them.
+func layout(r *Receipt, columns int) []row {
+	var (
+		rows  []row
+		rule  = row{text: strings.Repeat("-", columns)}
+		lines = func(bold bool, texts ...string) {
+			for _, text := range texts {
+				rows = append(rows, row{text: text, bold: bold})
+			}
+		}
+	)
+
+	lines(true, centre(r.Retailer, columns)...)
+	lines(false, centre("Sale "+r.SaleID, columns)...)
+	lines(false, centre(r.SaleDate.Format("2006-01-02 15:04"), columns)...)
+	if r.Void != nil {
+		lines(true, centre("*** VOIDED ***", columns)...)
+		lines(false, centre(r.Void.Reason, columns)...)
+	}
+	rows = append(rows, rule)
+
+	for _, line := range r.Lines {
+		lines(false, wrap(line.Name, columns)...)
+		lines(false, justify("  "+line.Quantity+" x "+line.UnitPrice.Decimal(), line.Total.Decimal(), columns)...)
+		for _, discount := range line.Discounts {
+			lines(false, justify("  "+discount.Name, discount.Amount.Neg().Decimal(), columns)...)
+		}
+	}
+	rows = append(rows, rule)
+
+	lines(false, justify("Subtotal", r.Subtotal.Decimal(), columns)...)
+	if !r.TaxIncluded {
+		for _, tax := range r.Taxes {
+			lines(false, justify(tax.Name+" "+tax.Rate, tax.Amount.Decimal(), columns)...)
+		}
+	}
+	lines(true, justify("TOTAL "+string(r.Currency), r.Total.Decimal(), columns)...)
+	if r.TaxIncluded {
+		for _, tax := range r.Taxes {
+			lines(false, justify("Includes "+tax.Name+" "+tax.Rate, tax.Amount.Decimal(), columns)...)
+		}
+	}
+
+	if len(r.Payments) > 0 {
+		rows = append(rows, rule)
+	}
+	for _, payment := range r.Payments {
+		tender := strings.ReplaceAll(string(payment.Tender), "_", " ")
+		if payment.ReversedAt != nil {
+			tender += " (reversed)"
+		}
+		lines(false, justify(tender, payment.Amount.Decimal(), columns)...)
+		if payment.Tendered != nil && payment.Change != nil {
+			lines(false, justify("  Tendered", payment.Tendered.Decimal(), columns)...)
+			lines(false, justify("  Change", payment.Change.Decimal(), columns)...)
+		}
+		if payment.Reference != nil {
+			lines(false, wrap("  Ref "+*payment.Reference, columns)...)
+		}
+	}
+	if r.Void == nil && r.Status == sales.Open {
+		lines(true, justify("DUE", r.Due.Decimal(), columns)...)
+	}
+	if !r.Refunded.IsZero() {
+		lines(false, justify("Refunded", r.Refunded.Neg().Decimal(), columns)...)
+	}
+
+	rows = append(rows, rule)
+	lines(false, centre("Thank you", columns)...)
+	return rows
+}
+
+// writeText renders the receipt as plain text in the columns of the format.
+func writeText(w io.Writer, r *Receipt, format Format) error {
+	buffered := bufio.NewWriter(w)
+	for _, row := range layout(r, format.columns()) {
+		buffered.WriteString(row.text)
+		buffered.WriteByte('\n')
+	}
+	return buffered.Flush()
+}
+
+// ESC/POS commands, see the command reference of any Epson thermal printer.
+var (
+	escInitialise = []byte{0x1b, '@'}
+	escBoldOn     = []byte{0x1b, 'E', 1}
+	escBoldOff    = []byte{0x1b, 'E', 0}
+	// escFeed feeds the paper past the cutter before cutting it, leaving a hinge.
+	escFeed = []byte{0x1b, 'd', 4}
+	escCut  = []byte{0x1d, 'V', 1}
+)
+
+// writeESCPOS renders the receipt as the commands a thermal printer takes to print it in the columns
+// of the format. Printers print the ASCII of their default code page, anything else is printed as
+// a question mark.
+func writeESCPOS(w io.Writer, r *Receipt, format Format) error {
+	buffered := bufio.NewWriter(w)
+	buffered.Write(escInitialise)
+	for _, row := range layout(r, format.columns()) {
+		if row.bold {
+			buffered.Write(escBoldOn)
+		}
+		for _, c := range row.text {
+			if c >= utf8.RuneSelf || c < ' ' {
+				c = '?'
+			}
+			buffered.WriteByte(byte(c))
+		}
+		if row.bold {
+			buffered.Write(escBoldOff)
+		}
+		buffered.WriteByte('\n')
+	}
+	buffered.Write(escFeed)
+	buffered.Write(escCut)
+	return buffered.Flush()
+}
+
+// justify puts left and right at either end of a row, the right goes on a row of its own when
+// they don't both fit.
+func justify(left, right string, columns int) []string {
+	gap := columns - width(left) - width(right)
+	if gap > 0 {
+		return []string{left + strings.Repeat(" ", gap) + right}
+	}
+
+	wrapped := wrap(left, columns)
+	last := wrapped[len(wrapped)-1]
+	if gap = columns - width(last) - width(right); gap > 0 {
+		wrapped[len(wrapped)-1] = last + strings.Repeat(" ", gap) + right
+		return wrapped
+	}
+	return append(wrapped, strings.Repeat(" ", max(columns-width(right), 0))+right)
+}
+
+// centre centres text within the columns, text too long for a row is wrapped.
+func centre(text string, columns int) []string {
+	var centred []string
+	for _, line := range wrap(text, columns) {
+		centred = append(centred, strings.Repeat(" ", (columns-width(line))/2)+line)
+	}
+	return centred
+}
+
+// wrap breaks text into rows at spaces, words too long for a row are broken wherever they have
+// to be. Indentation of the text is kept on every row.
+func wrap(text string, columns int) []string {
+	indent := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	if width(indent) >= columns {
+		indent = ""
+	}
+
+	var (
+		rows    []string
+		current = indent
+	)
+	for _, word := range strings.Fields(text) {
+		for width(indent)+width(word) > columns {
+			if current != indent {
+				rows, current = append(rows, current), indent
+			}
+			split := columns - width(indent)
+			head, tail := runes(word, split)
+			rows, word = append(rows, indent+head), tail
+		}
+
+		switch {
+		case current == indent:
+			current += word
+		case width(current)+1+width(word) <= columns:
+			current += " " + word
+		default:
+			rows, current = append(rows, current), indent+word
+		}
+	}
+	if current != indent || len(rows) == 0 {
+		rows = append(rows, current)
+	}
+	return rows
+}
+
+func width(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// runes splits text after n runes.
+func runes(text string, n int) (string, string) {
+	var i int
+	for at := range text {
+		if i == n {
+			return text[:at], text[at:]
+		}
+		i++
+	}
+	return text, ""
+}
@@ -0,0 +1,62 @@
+package reorder
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// Point is the stock on hand of an item at a location below which it should be reordered.
+type Point struct {
+	ItemID     *keys.OpaqueID
+	LocationID *keys.OpaqueID
+	// Threshold is in the unit scale of the item, an alert is raised once stock on hand falls
+	// below it.
+	Threshold *big.Rat
+	// CoverDays is how many days of recent sales a reorder should last.
+	CoverDays int
+	UpdatedAt time.Time
+}
+
+// Alert is raised when the stock on hand of an item at a location falls below its reorder point,
+// it is resolved once stock is back at or above it.
+type Alert struct {
+	ID         *keys.OpaqueID
+	RetailerID *keys.OpaqueID
+	ItemID     *keys.OpaqueID
+	LocationID *keys.OpaqueID
+	// OnHand and Threshold are as they were when the alert was raised.
+	OnHand    *big.Rat
+	Threshold *big.Rat
+	RaisedAt  time.Time
+	// NotifiedAt is when the alert was pushed through the notifier, alerts are pushed until they
+	// have been once.
+	NotifiedAt *time.Time
+	ResolvedAt *time.Time
+}
+
+// Demand is a reorder point along with the stock on hand and the stock sold recently, what
+// suggestions are worked out from.
+type Demand struct {
+	Point     *Point
+	UnitScale items.UnitScale
+	OnHand    *big.Rat
+	// Sold is the stock of the item taken by sales at the location within the window, kits sold
+	// count towards the items they are made of.
+	Sold *big.Rat
+}
+
+// Suggestion is how much of an item to reorder for a location.
+type Suggestion struct {
+	ItemID     *keys.OpaqueID
+	LocationID *keys.OpaqueID
+	OnHand     *big.Rat
+	Threshold  *big.Rat
+	// DailySales is the average stock sold a day over the window.
+	DailySales *big.Rat
+	// Quantity brings stock back up to the reorder point with enough on top to cover the days of
+	// sales of the point, in the unit scale of the item.
+	Quantity *big.Rat
+}
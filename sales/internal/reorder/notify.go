@@ -0,0 +1,110 @@
+package reorder
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// Notifier pushes stock alerts to wherever the retailer hears about them.
+type Notifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+// Log writes alerts to the log of the server.
+type Log struct{}
+
+func (Log) Notify(ctx context.Context, alert *Alert) error {
+	slog.Warn(fmt.Sprintf("stock of item %d at location %d of retailer %d is %s, below its reorder point of %s",
+		alert.ItemID.ID, alert.LocationID.ID, alert.RetailerID.ID, inventory.FormatQuantity(alert.OnHand), inventory.FormatQuantity(alert.Threshold)))
+	return nil
+}
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the body of a webhook, keyed by the secret
+// shared with the receiver.
+const SignatureHeader = "X-Pedlar-Signature"
+
+// Webhook posts alerts as JSON to the URL, with ids encoded as they are over the API.
+type Webhook struct {
+	URL string
+	// Secret signs the body of every post when given.
+	Secret []byte
+	Codec  keys.EncoderDecoder
+	Client *http.Client
+}
+
+// payload is the body posted for an alert.
+type payload struct {
+	ID        string    `json:"id"`
+	Retailer  string    `json:"retailer"`
+	Item      string    `json:"item"`
+	Location  string    `json:"location"`
+	OnHand    string    `json:"on_hand"`
+	Threshold string    `json:"threshold"`
+	RaisedAt  time.Time `json:"raised_at"`
+}
+
+func (w *Webhook) Notify(ctx context.Context, alert *Alert) error {
+	var (
+		body = payload{
+			OnHand:    inventory.FormatQuantity(alert.OnHand),
+			Threshold: inventory.FormatQuantity(alert.Threshold),
+			RaisedAt:  alert.RaisedAt,
+		}
+		err error
+	)
+	for _, id := range []struct {
+		into *string
+		id   *keys.OpaqueID
+	}{
+		{&body.ID, alert.ID},
+		{&body.Retailer, alert.RetailerID},
+		{&body.Item, alert.ItemID},
+		{&body.Location, alert.LocationID},
+	} {
+		if *id.into, err = w.Codec.Encode(ctx, id.id.ID); err != nil {
+			return fmt.Errorf("failed to encode id: %w", err)
+		}
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stock alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.Secret) > 0 {
+		mac := hmac.New(sha256.New, w.Secret)
+		mac.Write(encoded)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	var client = w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post stock alert: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
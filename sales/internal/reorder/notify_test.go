@@ -0,0 +1,65 @@
+package reorder
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+func TestWebhook(t *testing.T) {
+	var (
+		received payload
+		status   = http.StatusNoContent
+		secret   = []byte("shared")
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), r.Header.Get(SignatureHeader))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	webhook := &Webhook{URL: server.URL, Secret: secret, Codec: codec{}}
+	alert := &Alert{
+		ID:         &keys.OpaqueID{ID: 4},
+		RetailerID: &keys.OpaqueID{ID: 3},
+		ItemID:     &keys.OpaqueID{ID: 2},
+		LocationID: &keys.OpaqueID{ID: 1},
+		OnHand:     big.NewRat(3, 2),
+		Threshold:  big.NewRat(5, 1),
+		RaisedAt:   time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	require.NoError(t, webhook.Notify(context.Background(), alert))
+	assert.Equal(t, payload{
+		ID:        "4",
+		Retailer:  "3",
+		Item:      "2",
+		Location:  "1",
+		OnHand:    "1.5",
+		Threshold: "5",
+		RaisedAt:  alert.RaisedAt,
+	}, received)
+
+	status = http.StatusBadGateway
+	require.ErrorContains(t, webhook.Notify(context.Background(), alert), "status 502")
+}
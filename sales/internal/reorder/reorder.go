@@ -0,0 +1,316 @@
+// Package reorder watches stock on hand against the reorder points of retailers, raising alerts when
+// stock runs low and suggesting how much to reorder from recent sales.
+package reorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+type store interface {
+	// SetPoint adds the reorder point, replacing the one of the item at the location if there is
+	// one already.
+	SetPoint(context.Context, *Point) error
+	RemovePoint(ctx context.Context, item int, location int) error
+	// GetPoints is the reorder points that match the filter, ordered by location then item.
+	GetPoints(context.Context, inventory.Filter) ([]*Point, error)
+
+	// EvaluatePoints raises alerts for items below their reorder point that aren't alerted on
+	// already, and resolves the alerts of items that no longer are.
+	EvaluatePoints(context.Context) (raised int, resolved int, err error)
+	// PendingAlerts is the open alerts that haven't been pushed through the notifier, oldest
+	// first.
+	PendingAlerts(context.Context) ([]*Alert, error)
+	MarkNotified(ctx context.Context, id int, at time.Time) error
+	// PageAlerts pages through the alerts that match the filter, most recent first.
+	PageAlerts(context.Context, AlertFilter, paginate.Paginate) ([]*Alert, error)
+
+	// GetDemand is every reorder point of items that aren't archived, at the location if given,
+	// along with the stock sold there since.
+	GetDemand(ctx context.Context, location *int, since time.Time) ([]*Demand, error)
+}
+
+// itemStore resolves the items that reorder points are of.
+type itemStore interface {
+	GetItem(context.Context, int) (*items.Item, error)
+}
+
+// stockStore tells kits apart, kits hold no stock of their own to reorder.
+type stockStore interface {
+	GetComponents(ctx context.Context, item int) ([]*inventory.Component, error)
+}
+
+type ReorderManager struct {
+	Store store
+	Items itemStore
+	Stock stockStore
+	// Notifier pushes alerts as they are raised.
+	Notifier Notifier
+}
+
+// window is how far back sales are looked at to suggest reorders.
+const window = 28 * 24 * time.Hour
+
+// DefaultCoverDays is how many days of sales a reorder lasts unless the reorder point says
+// otherwise.
+const DefaultCoverDays = 14
+
+var (
+	ErrPointNotFound = errors.New("reorder point not found")
+	ErrThreshold     = errors.New("reorder point cannot be negative")
+	ErrCoverDays     = errors.New("reorder must cover at least one day of sales")
+)
+
+// NewPoint sets the reorder point of an item at a location, the threshold is in the unit scale of
+// the item unless another unit scale of the same dimension is given.
+type NewPoint struct {
+	Item      *keys.OpaqueID
+	Location  *keys.OpaqueID
+	Threshold string
+	UnitScale *items.UnitScale
+	CoverDays *int
+}
+
+// AlertFilter narrows alerts down to those open or resolved, of an item, at a location, or any of
+// them together.
+type AlertFilter struct {
+	Open     *bool
+	Item     *keys.OpaqueID
+	Location *keys.OpaqueID
+}
+
+// Evaluation is what came of evaluating the reorder points of a retailer.
+type Evaluation struct {
+	Raised   int
+	Resolved int
+	Notified int
+}
+
+func (m *ReorderManager) SetReorderPoint(ctx context.Context, input NewPoint) (*Point, error) {
+	id, err := input.Item.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode item id: %w", err)
+	}
+	location, err := input.Location.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode location id: %w", err)
+	}
+
+	item, err := m.Items.GetItem(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item of reorder point: %w", err)
+	}
+	components, err := m.Stock.GetComponents(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get components of item: %w", err)
+	}
+	if len(components) > 0 {
+		return nil, inventory.ErrKitStock
+	}
+
+	threshold, err := inventory.ParseQuantity(input.Threshold, input.UnitScale, item.UnitScale)
+	if err != nil {
+		return nil, err
+	}
+	if threshold.Sign() < 0 {
+		return nil, ErrThreshold
+	}
+
+	var cover = DefaultCoverDays
+	if input.CoverDays != nil {
+		if cover = *input.CoverDays; cover <= 0 {
+			return nil, ErrCoverDays
+		}
+	}
+
+	point := &Point{
+		ItemID:     &keys.OpaqueID{ID: id},
+		LocationID: &keys.OpaqueID{ID: location},
+		Threshold:  threshold,
+		CoverDays:  cover,
+	}
+	if err := m.Store.SetPoint(ctx, point); err != nil {
+		return nil, fmt.Errorf("failed to set reorder point: %w", err)
+	}
+	return point, nil
+}
+
+// RemoveReorderPoint stops the item being reordered for the location, an open alert of it is
+// resolved on the next evaluation.
+func (m *ReorderManager) RemoveReorderPoint(ctx context.Context, itemID, locationID *keys.OpaqueID) (*Point, error) {
+	filter, err := decodeFilter(ctx, &inventory.Filter{Item: itemID, Location: locationID})
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := m.Store.GetPoints(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reorder point: %w", err)
+	}
+	if len(points) == 0 {
+		return nil, ErrPointNotFound
+	}
+
+	if err := m.Store.RemovePoint(ctx, filter.Item.ID, filter.Location.ID); err != nil {
+		return nil, fmt.Errorf("failed to remove reorder point: %w", err)
+	}
+	return points[0], nil
+}
+
+func (m *ReorderManager) ReorderPoints(ctx context.Context, filter *inventory.Filter) ([]*Point, error) {
+	decoded, err := decodeFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return m.Store.GetPoints(ctx, decoded)
+}
+
+func (m *ReorderManager) SearchAlerts(ctx context.Context, filter *AlertFilter, page *paginate.Paginate) ([]*Alert, error) {
+	var decoded AlertFilter
+	if filter != nil {
+		var err error
+		if decoded.Item, err = keys.DecodeOptional(ctx, filter.Item); err != nil {
+			return nil, fmt.Errorf("failed to decode item id: %w", err)
+		}
+		if decoded.Location, err = keys.DecodeOptional(ctx, filter.Location); err != nil {
+			return nil, fmt.Errorf("failed to decode location id: %w", err)
+		}
+		decoded.Open = filter.Open
+	}
+
+	decodedPage, err := paginate.Decode(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts, err := m.Store.PageAlerts(ctx, decoded, decodedPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to page through stock alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+// Evaluate raises and resolves the alerts of the retailer of the context, then pushes the alerts
+// that haven't been yet through the notifier. Alerts that fail to be pushed are pushed again on
+// the next evaluation, so notifiers see an alert at least once.
+func (m *ReorderManager) Evaluate(ctx context.Context) (*Evaluation, error) {
+	raised, resolved, err := m.Store.EvaluatePoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate reorder points: %w", err)
+	}
+	evaluation := &Evaluation{Raised: raised, Resolved: resolved}
+
+	pending, err := m.Store.PendingAlerts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending stock alerts: %w", err)
+	}
+	for _, alert := range pending {
+		if err := m.Notifier.Notify(ctx, alert); err != nil {
+			return evaluation, fmt.Errorf("failed to notify of stock alert %d: %w", alert.ID.ID, err)
+		}
+
+		now := time.Now()
+		if err := m.Store.MarkNotified(ctx, alert.ID.ID, now); err != nil {
+			return evaluation, fmt.Errorf("failed to mark stock alert %d notified: %w", alert.ID.ID, err)
+		}
+		alert.NotifiedAt = &now
+		evaluation.Notified++
+	}
+	return evaluation, nil
+}
+
+// Suggestions is what to reorder for the items below their reorder point, at the location if
+// given, worked out from how fast they sold over the last four weeks.
+func (m *ReorderManager) Suggestions(ctx context.Context, locationID *keys.OpaqueID) ([]*Suggestion, error) {
+	var location *int
+	if locationID != nil {
+		id, err := locationID.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode location id: %w", err)
+		}
+		location = &id
+	}
+
+	demands, err := m.Store.GetDemand(ctx, location, time.Now().Add(-window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get demand of reorder points: %w", err)
+	}
+
+	var suggestions = []*Suggestion{}
+	for _, demand := range demands {
+		if suggestion := suggest(demand, window); suggestion != nil {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
+}
+
+// suggest reorders enough to bring stock back up to the reorder point and to cover the days of
+// sales of the point at the rate it sold over the window, rounded up to whole units for items
+// sold by the unit. Items at or above their reorder point need no reorder.
+func suggest(demand *Demand, window time.Duration) *Suggestion {
+	point := demand.Point
+	if demand.OnHand.Cmp(point.Threshold) >= 0 {
+		return nil
+	}
+
+	var (
+		days  = new(big.Rat).SetFrac64(int64(window), int64(24*time.Hour))
+		daily = new(big.Rat).Quo(demand.Sold, days)
+		cover = new(big.Rat).Mul(daily, new(big.Rat).SetInt64(int64(point.CoverDays)))
+	)
+
+	quantity := new(big.Rat).Sub(point.Threshold, demand.OnHand)
+	quantity.Add(quantity, cover)
+
+	return &Suggestion{
+		ItemID:     point.ItemID,
+		LocationID: point.LocationID,
+		OnHand:     demand.OnHand,
+		Threshold:  point.Threshold,
+		DailySales: daily,
+		Quantity:   roundUp(quantity, demand.UnitScale == items.Unit),
+	}
+}
+
+// roundUp rounds the quantity up to a whole number, or to the ten thousandths the ledger holds.
+func roundUp(quantity *big.Rat, whole bool) *big.Rat {
+	var unit = big.NewInt(10000)
+	if whole {
+		unit = big.NewInt(1)
+	}
+
+	var (
+		scaled    = new(big.Int).Mul(quantity.Num(), unit)
+		remainder = new(big.Int)
+	)
+	scaled.QuoRem(scaled, quantity.Denom(), remainder)
+	if remainder.Sign() > 0 {
+		scaled.Add(scaled, big.NewInt(1))
+	}
+	return new(big.Rat).SetFrac(scaled, unit)
+}
+
+func decodeFilter(ctx context.Context, filter *inventory.Filter) (inventory.Filter, error) {
+	var decoded inventory.Filter
+	if filter == nil {
+		return decoded, nil
+	}
+
+	var err error
+	if decoded.Item, err = keys.DecodeOptional(ctx, filter.Item); err != nil {
+		return decoded, fmt.Errorf("failed to decode item id: %w", err)
+	}
+	if decoded.Location, err = keys.DecodeOptional(ctx, filter.Location); err != nil {
+		return decoded, fmt.Errorf("failed to decode location id: %w", err)
+	}
+	return decoded, nil
+}
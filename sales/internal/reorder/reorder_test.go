@@ -0,0 +1,185 @@
+package reorder
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+func TestSuggest(t *testing.T) {
+	var demand = func(threshold, onHand, sold *big.Rat, scale items.UnitScale) *Demand {
+		return &Demand{
+			Point:     &Point{ItemID: &keys.OpaqueID{ID: 1}, LocationID: &keys.OpaqueID{ID: 2}, Threshold: threshold, CoverDays: 14},
+			UnitScale: scale,
+			OnHand:    onHand,
+			Sold:      sold,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		demand *Demand
+		daily  string
+		want   string
+	}{
+		// 56 sold over 4 weeks is 2 a day, 14 days of which is 28 on top of the 7 short
+		{name: "selling", demand: demand(big.NewRat(10, 1), big.NewRat(3, 1), big.NewRat(56, 1), items.Unit), daily: "2", want: "35"},
+		{name: "rounded up to whole units", demand: demand(big.NewRat(10, 1), big.NewRat(3, 1), big.NewRat(1, 1), items.Unit), daily: "0.0357", want: "8"},
+		{name: "by measure", demand: demand(big.NewRat(5, 1), big.NewRat(1, 1), big.NewRat(7, 1), items.Kilogram), daily: "0.25", want: "7.5"},
+		{name: "not selling", demand: demand(big.NewRat(10, 1), big.NewRat(-2, 1), new(big.Rat), items.Unit), daily: "0", want: "12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestion := suggest(tt.demand, 28*24*time.Hour)
+			require.NotNil(t, suggestion)
+			assert.Equal(t, tt.daily, inventory.FormatQuantity(suggestion.DailySales))
+			assert.Equal(t, tt.want, inventory.FormatQuantity(suggestion.Quantity))
+		})
+	}
+
+	assert.Nil(t, suggest(demand(big.NewRat(10, 1), big.NewRat(10, 1), big.NewRat(56, 1), items.Unit), window))
+}
+
+// codec takes external ids to be internal ids written out.
+type codec struct{}
+
+func (codec) Encode(ctx context.Context, id int) (string, error) {
+	return strconv.Itoa(id), nil
+}
+
+func (codec) Decode(ctx context.Context, id string) (int, error) {
+	return strconv.Atoi(id)
+}
+
+func opaque(t *testing.T, id int) *keys.OpaqueID {
+	t.Helper()
+
+	var external = &keys.OpaqueID{}
+	require.NoError(t, external.UnmarshalGQLContext(context.Background(), strconv.Itoa(id)))
+	return external.WithCodec(codec{})
+}
+
+// shelf holds alerts waiting to be notified of, every item is sold by the kg and item 9 is a kit.
+type shelf struct {
+	pending  []*Alert
+	notified []int
+	set      []*Point
+}
+
+func (s *shelf) SetPoint(ctx context.Context, point *Point) error {
+	s.set = append(s.set, point)
+	return nil
+}
+
+func (s *shelf) RemovePoint(ctx context.Context, item int, location int) error { return nil }
+
+func (s *shelf) GetPoints(ctx context.Context, filter inventory.Filter) ([]*Point, error) {
+	return nil, nil
+}
+
+func (s *shelf) EvaluatePoints(ctx context.Context) (int, int, error) {
+	return len(s.pending), 0, nil
+}
+
+func (s *shelf) PendingAlerts(ctx context.Context) ([]*Alert, error) {
+	var pending []*Alert
+	for _, alert := range s.pending {
+		if alert.NotifiedAt == nil {
+			pending = append(pending, alert)
+		}
+	}
+	return pending, nil
+}
+
+func (s *shelf) MarkNotified(ctx context.Context, id int, at time.Time) error {
+	s.notified = append(s.notified, id)
+	return nil
+}
+
+func (s *shelf) PageAlerts(ctx context.Context, filter AlertFilter, page paginate.Paginate) ([]*Alert, error) {
+	return nil, nil
+}
+
+func (s *shelf) GetDemand(ctx context.Context, location *int, since time.Time) ([]*Demand, error) {
+	return nil, nil
+}
+
+func (s *shelf) GetItem(ctx context.Context, id int) (*items.Item, error) {
+	return &items.Item{ID: &keys.OpaqueID{ID: id}, Details: items.Details{Name: "tea", UnitScale: items.Kilogram}}, nil
+}
+
+func (s *shelf) GetComponents(ctx context.Context, item int) ([]*inventory.Component, error) {
+	if item != 9 {
+		return nil, nil
+	}
+	return []*inventory.Component{{ItemID: &keys.OpaqueID{ID: 1}, Quantity: big.NewRat(1, 1)}}, nil
+}
+
+// flaky fails to notify of the alerts it is told to.
+type flaky struct {
+	fail map[int]bool
+	seen []int
+}
+
+func (f *flaky) Notify(ctx context.Context, alert *Alert) error {
+	f.seen = append(f.seen, alert.ID.ID)
+	if f.fail[alert.ID.ID] {
+		return errors.New("unreachable")
+	}
+	return nil
+}
+
+func TestEvaluateRetriesUnnotified(t *testing.T) {
+	var (
+		stock    = &shelf{pending: []*Alert{{ID: &keys.OpaqueID{ID: 1}}, {ID: &keys.OpaqueID{ID: 2}}}}
+		notifier = &flaky{fail: map[int]bool{2: true}}
+		manager  = &ReorderManager{Store: stock, Notifier: notifier}
+	)
+
+	evaluation, err := manager.Evaluate(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 1, evaluation.Notified)
+	require.Equal(t, []int{1}, stock.notified)
+
+	notifier.fail = nil
+	evaluation, err = manager.Evaluate(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, evaluation.Notified)
+	require.Equal(t, []int{1, 2, 2}, notifier.seen)
+	require.Equal(t, []int{1, 2}, stock.notified)
+}
+
+func TestSetReorderPoint(t *testing.T) {
+	var (
+		stock   = &shelf{}
+		manager = &ReorderManager{Store: stock, Items: stock, Stock: stock}
+		grams   = items.Gram
+		days    = 0
+	)
+
+	point, err := manager.SetReorderPoint(context.Background(), NewPoint{Item: opaque(t, 1), Location: opaque(t, 2), Threshold: "500", UnitScale: &grams})
+	require.NoError(t, err)
+	assert.Equal(t, "0.5", inventory.FormatQuantity(point.Threshold))
+	assert.Equal(t, DefaultCoverDays, point.CoverDays)
+
+	_, err = manager.SetReorderPoint(context.Background(), NewPoint{Item: opaque(t, 1), Location: opaque(t, 2), Threshold: "-1"})
+	require.ErrorIs(t, err, ErrThreshold)
+
+	_, err = manager.SetReorderPoint(context.Background(), NewPoint{Item: opaque(t, 1), Location: opaque(t, 2), Threshold: "1", CoverDays: &days})
+	require.ErrorIs(t, err, ErrCoverDays)
+
+	_, err = manager.SetReorderPoint(context.Background(), NewPoint{Item: opaque(t, 9), Location: opaque(t, 2), Threshold: "1"})
+	require.ErrorIs(t, err, inventory.ErrKitStock)
+	require.Len(t, stock.set, 1)
+}
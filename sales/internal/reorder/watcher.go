@@ -0,0 +1,58 @@
+package reorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+)
+
+// retailerStore lists every retailer to evaluate the reorder points of.
+type retailerStore interface {
+	GetRetailers(context.Context) ([]*retailers.Retailer, error)
+}
+
+// Watcher evaluates the reorder points of every retailer in the background.
+type Watcher struct {
+	Manager   *ReorderManager
+	Retailers retailerStore
+	Interval  time.Duration
+}
+
+// Run evaluates straight away and then on every interval until the context is done.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Evaluate(ctx); err != nil {
+			slog.Error(fmt.Sprintf("failed to evaluate reorder points: %v", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Evaluate evaluates the reorder points of every retailer, each scoped to its own retailer. A
+// retailer that fails doesn't hold up the others.
+func (w *Watcher) Evaluate(ctx context.Context) error {
+	all, err := w.Retailers.GetRetailers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get retailers: %w", err)
+	}
+
+	var errs []error
+	for _, retailer := range all {
+		if _, err := w.Manager.Evaluate(retailers.With(ctx, retailer.ID.ID)); err != nil {
+			errs = append(errs, fmt.Errorf("retailer %d: %w", retailer.ID.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
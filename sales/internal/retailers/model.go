@@ -0,0 +1,18 @@
+package retailers
+
+import (
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// Retailer is a tenant, everything a retailer sells and every sale they make is theirs alone.
+type Retailer struct {
+	ID   *keys.OpaqueID
+	Name string
+	// Domain is the primary domain of the retailer, it is held lower cased. Retailers can be
+	// reached through other domains too.
+	Domain    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,246 @@
+// Package retailers manages the tenants of pedlar and scopes requests to them.
+package retailers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+type store interface {
+	// CreateRetailer adds the retailer with its id and timestamps assigned in place.
+	CreateRetailer(context.Context, *Retailer) error
+	GetRetailer(context.Context, int) (*Retailer, error)
+	GetRetailers(context.Context) ([]*Retailer, error)
+	UpdateRetailer(context.Context, *Retailer) error
+	// DeleteRetailer refuses with ErrRetailerInUse once the retailer has items, sales or customers.
+	DeleteRetailer(context.Context, int) error
+
+	// GetDomains lists every domain of the retailer, its primary domain included.
+	GetDomains(ctx context.Context, retailer int) ([]string, error)
+	AddDomain(ctx context.Context, retailer int, domain string) error
+	RemoveDomain(ctx context.Context, retailer int, domain string) error
+}
+
+type RetailerManager struct {
+	Store store
+	// Tenancy forgets domains that change hands, if given.
+	Tenancy *Tenancy
+}
+
+var (
+	ErrRetailerNotFound = errors.New("retailer not found")
+	ErrNoNameRetailer   = errors.New("retailer must have a name")
+	ErrDomain           = errors.New("domain must be a host name, ie shop.example.com")
+	ErrDuplicateDomain  = errors.New("domain belongs to another retailer")
+	ErrRetailerInUse    = errors.New("retailer has items, sales or customers and cannot be deleted")
+	ErrDomainNotFound   = errors.New("domain is not a domain of the retailer")
+	// ErrPrimaryDomain is returned when removing the domain a retailer is known by, it can only be
+	// replaced by updating the retailer.
+	ErrPrimaryDomain = errors.New("primary domain of a retailer cannot be removed")
+)
+
+var labelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// NormaliseDomain lower cases the host name, ie Shop.Example.com. is shop.example.com.
+func NormaliseDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" || len(domain) > 253 {
+		return "", fmt.Errorf("%w: %q", ErrDomain, domain)
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if !labelPattern.MatchString(label) {
+			return "", fmt.Errorf("%w: %q", ErrDomain, domain)
+		}
+	}
+	return domain, nil
+}
+
+type NewRetailer struct {
+	Name   string
+	Domain string
+}
+
+// RetailerPatch changes the fields that are set.
+type RetailerPatch struct {
+	Name   *string
+	Domain *string
+}
+
+// CreateRetailer is only for requests authenticated as the platform, as is every other change to
+// retailers.
+func (r *RetailerManager) CreateRetailer(ctx context.Context, input NewRetailer) (*Retailer, error) {
+	if err := Platform(ctx); err != nil {
+		return nil, err
+	}
+
+	retailer := &Retailer{Name: strings.TrimSpace(input.Name)}
+	if retailer.Name == "" {
+		return nil, ErrNoNameRetailer
+	}
+
+	var err error
+	if retailer.Domain, err = NormaliseDomain(input.Domain); err != nil {
+		return nil, err
+	}
+
+	if err := r.Store.CreateRetailer(ctx, retailer); err != nil {
+		return nil, fmt.Errorf("failed to create retailer: %w", err)
+	}
+	return retailer, nil
+}
+
+// GetRetailer gets the retailer of the id, requests scoped to a retailer only ever get their own
+// and any other request must be authenticated as the platform.
+func (r *RetailerManager) GetRetailer(ctx context.Context, externalID *keys.OpaqueID) (*Retailer, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	if scoped, err := From(ctx); err == nil {
+		if scoped != id {
+			return nil, ErrRetailerNotFound
+		}
+	} else if err := Platform(ctx); err != nil {
+		return nil, err
+	}
+	return r.Store.GetRetailer(ctx, id)
+}
+
+// CurrentRetailer is the retailer the request is scoped to.
+func (r *RetailerManager) CurrentRetailer(ctx context.Context) (*Retailer, error) {
+	id, err := From(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Store.GetRetailer(ctx, id)
+}
+
+// GetRetailers lists every retailer for requests authenticated as the platform, or just its own for
+// a request scoped to a retailer.
+func (r *RetailerManager) GetRetailers(ctx context.Context) ([]*Retailer, error) {
+	if _, err := From(ctx); err == nil {
+		retailer, err := r.CurrentRetailer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []*Retailer{retailer}, nil
+	}
+	if err := Platform(ctx); err != nil {
+		return nil, err
+	}
+	return r.Store.GetRetailers(ctx)
+}
+
+func (r *RetailerManager) UpdateRetailer(ctx context.Context, externalID *keys.OpaqueID, patch RetailerPatch) (*Retailer, error) {
+	if err := Platform(ctx); err != nil {
+		return nil, err
+	}
+
+	retailer, err := r.GetRetailer(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Name != nil {
+		if retailer.Name = strings.TrimSpace(*patch.Name); retailer.Name == "" {
+			return nil, ErrNoNameRetailer
+		}
+	}
+	previous := retailer.Domain
+	if patch.Domain != nil {
+		if retailer.Domain, err = NormaliseDomain(*patch.Domain); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.Store.UpdateRetailer(ctx, retailer); err != nil {
+		return nil, fmt.Errorf("failed to update retailer: %w", err)
+	}
+	r.forget(previous)
+	return retailer, nil
+}
+
+// DeleteRetailer deletes a retailer that has yet to sell anything or know any customer, along with
+// its settings.
+func (r *RetailerManager) DeleteRetailer(ctx context.Context, externalID *keys.OpaqueID) (*Retailer, error) {
+	if err := Platform(ctx); err != nil {
+		return nil, err
+	}
+
+	retailer, err := r.GetRetailer(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := r.Store.GetDomains(ctx, retailer.ID.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domains of retailer: %w", err)
+	}
+
+	if err := r.Store.DeleteRetailer(ctx, retailer.ID.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete retailer: %w", err)
+	}
+	r.forget(domains...)
+	return retailer, nil
+}
+
+func (r *RetailerManager) GetDomains(ctx context.Context, retailer *Retailer) ([]string, error) {
+	return r.Store.GetDomains(ctx, retailer.ID.ID)
+}
+
+// AddDomain lets the retailer be reached through another domain, a domain only ever belongs to one
+// retailer.
+func (r *RetailerManager) AddDomain(ctx context.Context, externalID *keys.OpaqueID, domain string) (*Retailer, error) {
+	if err := Platform(ctx); err != nil {
+		return nil, err
+	}
+
+	retailer, err := r.GetRetailer(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if domain, err = NormaliseDomain(domain); err != nil {
+		return nil, err
+	}
+
+	if err := r.Store.AddDomain(ctx, retailer.ID.ID, domain); err != nil {
+		return nil, fmt.Errorf("failed to add domain of retailer: %w", err)
+	}
+	return retailer, nil
+}
+
+func (r *RetailerManager) RemoveDomain(ctx context.Context, externalID *keys.OpaqueID, domain string) (*Retailer, error) {
+	if err := Platform(ctx); err != nil {
+		return nil, err
+	}
+
+	retailer, err := r.GetRetailer(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if domain, err = NormaliseDomain(domain); err != nil {
+		return nil, err
+	}
+	if domain == retailer.Domain {
+		return nil, ErrPrimaryDomain
+	}
+
+	if err := r.Store.RemoveDomain(ctx, retailer.ID.ID, domain); err != nil {
+		return nil, fmt.Errorf("failed to remove domain of retailer: %w", err)
+	}
+	r.forget(domain)
+	return retailer, nil
+}
+
+func (r *RetailerManager) forget(domains ...string) {
+	if r.Tenancy != nil {
+		r.Tenancy.Forget(domains...)
+	}
+}
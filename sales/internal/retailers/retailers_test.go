@@ -0,0 +1,246 @@
+package retailers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+func TestNormaliseDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+		err    error
+	}{
+		{domain: " Shop.Example.com. ", want: "shop.example.com"},
+		{domain: "localhost", want: "localhost"},
+		{domain: "corner-store.co.nz", want: "corner-store.co.nz"},
+		{domain: "", err: ErrDomain},
+		{domain: "-shop.example.com", err: ErrDomain},
+		{domain: "shop..example.com", err: ErrDomain},
+		{domain: "shop.example.com:8080", err: ErrDomain},
+		{domain: "https://shop.example.com", err: ErrDomain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			got, err := NormaliseDomain(tt.domain)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// codec takes external ids to be internal ids written out.
+type codec struct{}
+
+func (codec) Encode(ctx context.Context, id int) (string, error) {
+	return strconv.Itoa(id), nil
+}
+
+func (codec) Decode(ctx context.Context, id string) (int, error) {
+	return strconv.Atoi(id)
+}
+
+// domains knows of two retailers and counts how often it's asked.
+type domains struct{ lookups int }
+
+func (d *domains) RetailerByDomain(ctx context.Context, domain string) (int, error) {
+	d.lookups++
+	switch domain {
+	case "mine.test", "www.mine.test":
+		return 1, nil
+	case "theirs.test":
+		return 2, nil
+	}
+	return -1, ErrRetailerNotFound
+}
+
+func TestTenancyMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		header   string
+		secret   string
+		status   int
+		retailer int
+		platform bool
+	}{
+		{name: "domain", host: "mine.test", status: http.StatusOK, retailer: 1},
+		{name: "other domain", host: "WWW.Mine.test:8080", status: http.StatusOK, retailer: 1},
+		{name: "domain naming itself", host: "theirs.test", header: "2", status: http.StatusOK, retailer: 2},
+		{name: "domain naming another retailer", host: "theirs.test", header: "1", status: http.StatusForbidden},
+		{name: "unknown host", host: "nobody.test", status: http.StatusNotFound},
+		{name: "platform", host: "localhost:8080", status: http.StatusOK, retailer: -1},
+		{name: "platform authenticated", host: "localhost", secret: "secret", status: http.StatusOK, retailer: -1, platform: true},
+		{name: "platform with the wrong secret", host: "localhost", secret: "guess", status: http.StatusOK, retailer: -1},
+		{name: "platform naming a retailer", host: "localhost", header: "2", secret: "secret", status: http.StatusOK, retailer: 2, platform: true},
+		{name: "platform naming a retailer unauthenticated", host: "localhost", header: "2", status: http.StatusUnauthorized},
+		{name: "platform naming nonsense", host: "localhost", header: "unknown", secret: "secret", status: http.StatusForbidden},
+		{name: "domain authenticated", host: "mine.test", secret: "secret", status: http.StatusOK, retailer: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenancy := &Tenancy{Store: &domains{}, Codec: codec{}, Platform: []string{"localhost"}, Secret: []byte("secret")}
+
+			var (
+				scoped   = -1
+				platform bool
+			)
+			handler := tenancy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if retailer, err := From(r.Context()); err == nil {
+					scoped = retailer
+				}
+				platform = Platform(r.Context()) == nil
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Host = tt.host
+			if tt.header != "" {
+				request.Header.Set(Header, tt.header)
+			}
+			if tt.secret != "" {
+				request.Header.Set("Authorization", "Bearer "+tt.secret)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.retailer, scoped)
+				assert.Equal(t, tt.platform, platform)
+			}
+		})
+	}
+}
+
+func TestTenancyCache(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		store   = &domains{}
+		tenancy = &Tenancy{Store: store, Codec: codec{}, TTL: time.Hour}
+	)
+
+	for i := 0; i < 3; i++ {
+		retailer, err := tenancy.Resolve(ctx, "mine.test")
+		require.NoError(t, err)
+		assert.Equal(t, 1, retailer)
+	}
+	assert.Equal(t, 1, store.lookups)
+
+	tenancy.Forget("mine.test")
+	_, err := tenancy.Resolve(ctx, "mine.test")
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.lookups)
+
+	// unknown hosts are looked up every time
+	for i := 0; i < 2; i++ {
+		_, err := tenancy.Resolve(ctx, "nobody.test")
+		require.ErrorIs(t, err, ErrUnknownHost)
+	}
+	assert.Equal(t, 4, store.lookups)
+}
+
+// registry holds retailers 1 and 2.
+type registry struct{}
+
+func (r *registry) CreateRetailer(ctx context.Context, retailer *Retailer) error { return nil }
+
+func (r *registry) GetRetailer(ctx context.Context, id int) (*Retailer, error) {
+	switch id {
+	case 1:
+		return &Retailer{ID: &keys.OpaqueID{ID: 1}, Name: "mine", Domain: "mine.test"}, nil
+	case 2:
+		return &Retailer{ID: &keys.OpaqueID{ID: 2}, Name: "theirs", Domain: "theirs.test"}, nil
+	}
+	return nil, ErrRetailerNotFound
+}
+
+func (r *registry) GetRetailers(ctx context.Context) ([]*Retailer, error) {
+	mine, _ := r.GetRetailer(ctx, 1)
+	theirs, _ := r.GetRetailer(ctx, 2)
+	return []*Retailer{mine, theirs}, nil
+}
+
+func (r *registry) UpdateRetailer(ctx context.Context, retailer *Retailer) error { return nil }
+
+func (r *registry) DeleteRetailer(ctx context.Context, id int) error { return nil }
+
+func (r *registry) GetDomains(ctx context.Context, retailer int) ([]string, error) { return nil, nil }
+
+func (r *registry) AddDomain(ctx context.Context, retailer int, domain string) error { return nil }
+
+func (r *registry) RemoveDomain(ctx context.Context, retailer int, domain string) error { return nil }
+
+func TestRetailerAdministrationScoped(t *testing.T) {
+	var (
+		ctx     = With(AsPlatform(context.Background()), 1)
+		manager = &RetailerManager{Store: &registry{}}
+	)
+	var opaque = func(id int) *keys.OpaqueID {
+		var external = &keys.OpaqueID{}
+		require.NoError(t, external.UnmarshalGQLContext(context.Background(), strconv.Itoa(id)))
+		return external.WithCodec(codec{})
+	}
+
+	mine, err := manager.GetRetailer(ctx, opaque(1))
+	require.NoError(t, err)
+	assert.Equal(t, "mine", mine.Name)
+
+	name := "taken"
+	_, err = manager.GetRetailer(ctx, opaque(2))
+	require.ErrorIs(t, err, ErrRetailerNotFound)
+	_, err = manager.UpdateRetailer(ctx, opaque(2), RetailerPatch{Name: &name})
+	require.ErrorIs(t, err, ErrRetailerNotFound)
+	_, err = manager.DeleteRetailer(ctx, opaque(2))
+	require.ErrorIs(t, err, ErrRetailerNotFound)
+	_, err = manager.AddDomain(ctx, opaque(2), "taken.test")
+	require.ErrorIs(t, err, ErrRetailerNotFound)
+	_, err = manager.RemoveDomain(ctx, opaque(2), "theirs.test")
+	require.ErrorIs(t, err, ErrRetailerNotFound)
+
+	scoped, err := manager.GetRetailers(ctx)
+	require.NoError(t, err)
+	require.Len(t, scoped, 1)
+	assert.Equal(t, 1, scoped[0].ID.ID)
+
+	// requests to the platform administer every retailer
+	all, err := manager.GetRetailers(AsPlatform(context.Background()))
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	_, err = manager.UpdateRetailer(AsPlatform(context.Background()), opaque(2), RetailerPatch{Name: &name})
+	require.NoError(t, err)
+
+	// but nothing else does, whether scoped to a retailer or not
+	for _, unauthenticated := range []context.Context{context.Background(), With(context.Background(), 1)} {
+		_, err = manager.CreateRetailer(unauthenticated, NewRetailer{Name: "new", Domain: "new.test"})
+		require.ErrorIs(t, err, ErrNotPlatform)
+		_, err = manager.UpdateRetailer(unauthenticated, opaque(1), RetailerPatch{Name: &name})
+		require.ErrorIs(t, err, ErrNotPlatform)
+		_, err = manager.DeleteRetailer(unauthenticated, opaque(1))
+		require.ErrorIs(t, err, ErrNotPlatform)
+		_, err = manager.AddDomain(unauthenticated, opaque(1), "new.test")
+		require.ErrorIs(t, err, ErrNotPlatform)
+		_, err = manager.RemoveDomain(unauthenticated, opaque(1), "mine.test")
+		require.ErrorIs(t, err, ErrNotPlatform)
+	}
+	_, err = manager.GetRetailers(context.Background())
+	require.ErrorIs(t, err, ErrNotPlatform)
+	_, err = manager.GetRetailer(context.Background(), opaque(2))
+	require.ErrorIs(t, err, ErrNotPlatform)
+	mine, err = manager.GetRetailer(With(context.Background(), 1), opaque(1))
+	require.NoError(t, err)
+	assert.Equal(t, "mine", mine.Name)
+}
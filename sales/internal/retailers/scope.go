@@ -0,0 +1,57 @@
+package retailers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrNoRetailer is returned for requests that aren't scoped to a retailer, retailer owned data is
+// never read or written outside of the scope of its retailer.
+var ErrNoRetailer = errors.New("request is not scoped to a retailer")
+
+// ErrNotPlatform is returned for requests administering retailers that didn't authenticate as the
+// platform.
+var ErrNotPlatform = errors.New("request is not authenticated as the platform")
+
+type key struct{}
+
+type platformKey struct{}
+
+// With scopes the context to the retailer.
+func With(ctx context.Context, retailer int) context.Context {
+	return context.WithValue(ctx, key{}, retailer)
+}
+
+// From is the retailer the context is scoped to.
+func From(ctx context.Context) (int, error) {
+	retailer, ok := ctx.Value(key{}).(int)
+	if !ok {
+		return -1, ErrNoRetailer
+	}
+	return retailer, nil
+}
+
+// AsPlatform marks the context as authenticated as the platform, which administers retailers.
+func AsPlatform(ctx context.Context) context.Context {
+	return context.WithValue(ctx, platformKey{}, true)
+}
+
+// Platform refuses contexts that weren't authenticated as the platform.
+func Platform(ctx context.Context) error {
+	if platform, _ := ctx.Value(platformKey{}).(bool); !platform {
+		return ErrNotPlatform
+	}
+	return nil
+}
+
+// Require refuses requests that Tenancy didn't scope to a retailer.
+func Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := From(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
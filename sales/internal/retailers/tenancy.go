@@ -0,0 +1,170 @@
+package retailers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// ErrUnknownHost is returned for requests made to a host that is neither a domain of a retailer nor
+// a host of the platform.
+var ErrUnknownHost = errors.New("host is not a domain of any retailer")
+
+// Header names the retailer of requests made by API clients, ie tills, to a host of the platform.
+// It is only honoured for requests authenticated as the platform.
+const Header = "X-Retailer"
+
+// DefaultTTL is how long the retailer of a domain is cached for when Tenancy has no TTL.
+const DefaultTTL = time.Minute
+
+type domainStore interface {
+	// RetailerByDomain finds the retailer the domain belongs to, ErrRetailerNotFound otherwise.
+	RetailerByDomain(ctx context.Context, domain string) (int, error)
+}
+
+// Tenancy scopes requests to the retailer whose domain they were made to, the retailers of domains
+// are cached so that requests don't look them up every time.
+type Tenancy struct {
+	Store domainStore
+	Codec keys.EncoderDecoder
+	// Platform are the hosts of pedlar itself, requests made to them are left unscoped unless they
+	// name a retailer with the Header.
+	Platform []string
+	// Secret authenticates requests to a host of the platform as the platform when presented as a
+	// bearer token, no request is without one.
+	Secret []byte
+	TTL    time.Duration
+
+	mu      sync.Mutex
+	domains map[string]cached
+}
+
+type cached struct {
+	retailer int
+	expires  time.Time
+}
+
+// Resolve finds the retailer of the host, the port of the host is ignored.
+func (t *Tenancy) Resolve(ctx context.Context, host string) (int, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	domain, err := NormaliseDomain(host)
+	if err != nil {
+		return -1, ErrUnknownHost
+	}
+
+	t.mu.Lock()
+	hit, ok := t.domains[domain]
+	t.mu.Unlock()
+	if ok && time.Now().Before(hit.expires) {
+		return hit.retailer, nil
+	}
+
+	retailer, err := t.Store.RetailerByDomain(ctx, domain)
+	if errors.Is(err, ErrRetailerNotFound) {
+		// unknown hosts aren't cached, anyone can make up as many as they like
+		return -1, ErrUnknownHost
+	} else if err != nil {
+		return -1, fmt.Errorf("failed to find retailer of domain: %w", err)
+	}
+
+	ttl := t.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	t.mu.Lock()
+	if t.domains == nil {
+		t.domains = make(map[string]cached)
+	}
+	t.domains[domain] = cached{retailer: retailer, expires: time.Now().Add(ttl)}
+	t.mu.Unlock()
+	return retailer, nil
+}
+
+// Forget drops the domains from the cache, so that changes to them are seen by the next request
+// rather than once they expire.
+func (t *Tenancy) Forget(domains ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, domain := range domains {
+		delete(t.domains, domain)
+	}
+}
+
+func (t *Tenancy) platform(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, platform := range t.Platform {
+		if strings.EqualFold(host, strings.TrimSpace(platform)) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticated is whether the request presents the secret of the platform.
+func (t *Tenancy) authenticated(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && len(t.Secret) > 0 && subtle.ConstantTimeCompare([]byte(token), t.Secret) == 1
+}
+
+// Middleware scopes requests to the retailer of their host and refuses requests to unknown hosts.
+// Requests to a host of the platform that authenticate as the platform are scoped to the retailer
+// named by the Header, if any, the Header of any other request to the platform is refused.
+func (t *Tenancy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			retailer = -1
+			platform = t.platform(r.Host)
+			err      error
+		)
+		if !platform {
+			retailer, err = t.Resolve(r.Context(), r.Host)
+			if errors.Is(err, ErrUnknownHost) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			} else if err != nil {
+				slog.Error(fmt.Sprintf("failed to resolve retailer of host %q: %v", r.Host, err))
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		var admin = platform && t.authenticated(r)
+		if external := strings.TrimSpace(r.Header.Get(Header)); external != "" {
+			if platform && !admin {
+				http.Error(w, ErrNotPlatform.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			named, err := t.Codec.Decode(r.Context(), external)
+			// a retailer's own domain can't be used to reach another retailer
+			if err != nil || (retailer != -1 && named != retailer) {
+				http.Error(w, ErrRetailerNotFound.Error(), http.StatusForbidden)
+				return
+			}
+			retailer = named
+		}
+
+		ctx := r.Context()
+		if admin {
+			ctx = AsPlatform(ctx)
+		}
+		if retailer != -1 {
+			ctx = With(ctx, retailer)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,214 @@
+package sales
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type Sale struct {
+	ID         *keys.OpaqueID
+	RetailerID *keys.OpaqueID
+	// CustomerID is nil for anonymous sales.
+	CustomerID *keys.OpaqueID
+	// LocationID is the stock location the sale took its stock from, nil when the retailer doesn't
+	// track stock.
+	LocationID *keys.OpaqueID
+	SaleDate   time.Time
+	// Currency is the currency of every amount on the sale.
+	Currency money.Currency
+	// PriceListID is the price list line items were priced from, nil when the retailer had none.
+	PriceListID *keys.OpaqueID
+	// PricesIncludeTax is whether line item prices include tax, tax is added on top of them
+	// otherwise.
+	PricesIncludeTax bool
+
+	LineItems []*LineItem
+	// Taxes break the tax of the sale out per tax rate, there are none when the retailer had no
+	// tax settings at the time of sale.
+	Taxes []*tax.Line
+
+	Status Status
+	// Payments hold every tender the sale was paid by in the order they were paid.
+	Payments []*payments.Payment
+	// Void is set once the sale is voided, voided sales count for nothing.
+	Void *Void
+	// Refunds hold every refund made against the sale in the order they were made.
+	Refunds []*Refund
+}
+
+type LineItem struct {
+	ID     *keys.OpaqueID
+	ItemID *keys.OpaqueID
+	// Quantity is in the unit scale of the item at the time of sale, which the unit price is the
+	// price of one of, ie 0.25 of an item sold by the kg.
+	Quantity  *big.Rat
+	UnitScale items.UnitScale
+	UnitPrice money.Money
+	// ListPrice is the price of the item on the price list of the sale at the time of sale, nil
+	// when the item had no price there.
+	ListPrice *money.Money
+	// Override is set when the till gave the unit price rather than taking it from the price list.
+	Override *PriceOverride
+	// Discounts are taken off the total of the line item by promotions, in the order they applied.
+	Discounts []*promotions.Discount
+}
+
+// PriceOverride audits a unit price given by the till.
+type PriceOverride struct {
+	Actor actor.Actor
+	// Reason is required when overriding a price the item has on the price list.
+	Reason *string
+}
+
+// Total is the quantity of the line item at its unit price, rounded to the minor unit.
+func (l *LineItem) Total() (money.Money, error) {
+	return l.UnitPrice.Mul(l.Quantity, money.HalfEven)
+}
+
+// Discount sums the discounts of the line item.
+func (l *LineItem) Discount() (money.Money, error) {
+	var amounts = make([]money.Money, 0, len(l.Discounts))
+	for _, discount := range l.Discounts {
+		amounts = append(amounts, discount.Amount)
+	}
+	return money.Sum(l.UnitPrice.Currency(), amounts...)
+}
+
+// Net is the total of the line item less its discounts, it is what tax is charged on.
+func (l *LineItem) Net() (money.Money, error) {
+	discount, err := l.Discount()
+	if err != nil {
+		return money.Money{}, err
+	}
+	total, err := l.Total()
+	if err != nil {
+		return money.Money{}, err
+	}
+	return total.Sub(discount)
+}
+
+// Subtotal sums the net totals of all line items of the sale, which fails when a line item is
+// priced in another currency than the sale.
+func (s *Sale) Subtotal() (money.Money, error) {
+	var totals = make([]money.Money, 0, len(s.LineItems))
+	for _, line := range s.LineItems {
+		net, err := line.Net()
+		if err != nil {
+			return money.Money{}, err
+		}
+		totals = append(totals, net)
+	}
+	return money.Sum(s.Currency, totals...)
+}
+
+// Discount sums the discounts of all line items of the sale.
+func (s *Sale) Discount() (money.Money, error) {
+	var amounts = make([]money.Money, 0, len(s.LineItems))
+	for _, line := range s.LineItems {
+		discount, err := line.Discount()
+		if err != nil {
+			return money.Money{}, err
+		}
+		amounts = append(amounts, discount)
+	}
+	return money.Sum(s.Currency, amounts...)
+}
+
+// Tax sums the tax of every tax rate charged on the sale.
+func (s *Sale) Tax() (money.Money, error) {
+	var amounts = make([]money.Money, 0, len(s.Taxes))
+	for _, line := range s.Taxes {
+		amounts = append(amounts, line.Amount)
+	}
+	return money.Sum(s.Currency, amounts...)
+}
+
+// Total is what the customer pays, the subtotal along with tax unless prices already include it.
+func (s *Sale) Total() (money.Money, error) {
+	subtotal, err := s.Subtotal()
+	if err != nil {
+		return money.Money{}, err
+	}
+	if s.PricesIncludeTax {
+		return subtotal, nil
+	}
+
+	tax, err := s.Tax()
+	if err != nil {
+		return money.Money{}, err
+	}
+	return subtotal.Add(tax)
+}
+
+// AmountPaid sums the payments of the sale that weren't reversed.
+func (s *Sale) AmountPaid() (money.Money, error) {
+	var amounts = make([]money.Money, 0, len(s.Payments))
+	for _, payment := range s.Payments {
+		if payment.ReversedAt == nil {
+			amounts = append(amounts, payment.Amount)
+		}
+	}
+	return money.Sum(s.Currency, amounts...)
+}
+
+// Due is what remains to be paid of the total of the sale.
+func (s *Sale) Due() (money.Money, error) {
+	total, err := s.Total()
+	if err != nil {
+		return money.Money{}, err
+	}
+	paid, err := s.AmountPaid()
+	if err != nil {
+		return money.Money{}, err
+	}
+	return total.Sub(paid)
+}
+
+// Refunded sums the refunds of the sale.
+func (s *Sale) Refunded() (money.Money, error) {
+	var amounts = make([]money.Money, 0, len(s.Refunds))
+	for _, refund := range s.Refunds {
+		for _, line := range refund.Lines {
+			amounts = append(amounts, line.Amount)
+		}
+	}
+	return money.Sum(s.Currency, amounts...)
+}
+
+// NetTotal is what the retailer keeps of the sale, its total less refunds or nothing at all once
+// the sale is voided.
+func (s *Sale) NetTotal() (money.Money, error) {
+	if s.Void != nil {
+		return money.Zero(s.Currency), nil
+	}
+
+	total, err := s.Total()
+	if err != nil {
+		return money.Money{}, err
+	}
+	refunded, err := s.Refunded()
+	if err != nil {
+		return money.Money{}, err
+	}
+	return total.Sub(refunded)
+}
+
+// SaleFilter narrows down pages of sales, sales are within [From, To) when given and an item
+// filter matches sales with any line item of the item.
+type SaleFilter struct {
+	// Retailer must be the retailer of the request when given, sales of other retailers are never
+	// searched.
+	Retailer *keys.OpaqueID `json:"retailer"`
+	Customer *keys.OpaqueID `json:"customer"`
+	Item     *keys.OpaqueID `json:"item"`
+	From     *time.Time     `json:"from"`
+	To       *time.Time     `json:"to"`
+}
@@ -0,0 +1,150 @@
+package sales
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+var (
+	ErrTender        = errors.New("unknown tender")
+	ErrPaymentAmount = errors.New("payment must be positive")
+	// ErrOverpaid is returned for payments other than cash of more than is due, only cash can be
+	// over paid as the change is given back.
+	ErrOverpaid   = errors.New("payment is more than is due on the sale")
+	ErrNoTendered = errors.New("cash payment must say how much cash was tendered")
+	ErrNoToken    = errors.New("payment charged by the payment provider must carry a token")
+	// ErrPaymentConflict is returned when the sale was paid or voided by someone else while the
+	// payment was being worked out.
+	ErrPaymentConflict = errors.New("sale has been paid or voided since it was read")
+)
+
+// NewPayment pays a tender towards an open sale. Cash pays off what it can of the cash tendered
+// and gives back change, other tenders pay the amount, or what is due when not given, through the
+// payment provider.
+type NewPayment struct {
+	Sale     *keys.OpaqueID
+	Tender   payments.Tender
+	Amount   *money.Input
+	Tendered *money.Input
+	// Token identifies what the payment provider charges, ie a card or a voucher code.
+	Token *string
+}
+
+// PaySale records a payment towards the sale attributed to the actor of the context, the sale is
+// paid once nothing is due.
+func (s *SaleManager) PaySale(ctx context.Context, input NewPayment) (*Sale, error) {
+	switch input.Tender {
+	case payments.Cash, payments.Card, payments.Voucher, payments.StoreCredit:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrTender, input.Tender)
+	}
+
+	sale, err := s.GetSale(ctx, input.Sale)
+	if err != nil {
+		return nil, err
+	}
+	if sale.Status != Open {
+		return nil, fmt.Errorf("%w: %s sale cannot be paid", ErrSaleStatus, sale.Status)
+	}
+
+	due, err := sale.Due()
+	if err != nil {
+		return nil, fmt.Errorf("failed to work out what is due: %w", err)
+	}
+
+	var payment = &payments.Payment{
+		SaleID: sale.ID,
+		Tender: input.Tender,
+		Actor:  actor.From(ctx),
+		PaidAt: time.Now(),
+	}
+	if input.Tender == payments.Cash {
+		err = cash(payment, input, due)
+	} else {
+		err = s.charge(ctx, payment, input, due)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var next = Open
+	if payment.Amount == due {
+		next = Paid
+	}
+	if err := sale.Status.to(next); err != nil {
+		return nil, err
+	}
+
+	if err := s.Store.CreatePayment(ctx, payment, len(sale.Payments), next); err != nil {
+		if payment.Reference != nil {
+			// the payment wasn't recorded so the customer mustn't be charged for it either
+			if reverseErr := s.Provider.Reverse(ctx, *payment.Reference); reverseErr != nil {
+				return nil, fmt.Errorf("failed to record payment: %w, then failed to reverse it: %w", err, reverseErr)
+			}
+		}
+		return nil, fmt.Errorf("failed to record payment: %w", err)
+	}
+
+	sale.Payments, sale.Status = append(sale.Payments, payment), next
+	return sale, nil
+}
+
+// cash applies the cash tendered to what is due.
+func cash(payment *payments.Payment, input NewPayment, due money.Money) error {
+	if input.Tendered == nil {
+		return ErrNoTendered
+	}
+	tendered, err := input.Tendered.Money()
+	if err != nil {
+		return fmt.Errorf("failed to parse cash tendered: %w", err)
+	}
+	if tendered.Sign() <= 0 {
+		return ErrPaymentAmount
+	}
+
+	applied, change, err := payments.Change(due, tendered)
+	if err != nil {
+		return err
+	}
+	payment.Amount, payment.Tendered, payment.Change = applied, &tendered, &change
+	return nil
+}
+
+// charge takes the payment through the payment provider.
+func (s *SaleManager) charge(ctx context.Context, payment *payments.Payment, input NewPayment, due money.Money) error {
+	if input.Token == nil || *input.Token == "" {
+		return ErrNoToken
+	}
+
+	var amount = due
+	if input.Amount != nil {
+		var err error
+		if amount, err = input.Amount.Money(); err != nil {
+			return fmt.Errorf("failed to parse payment amount: %w", err)
+		}
+	}
+	if amount.Sign() <= 0 {
+		return ErrPaymentAmount
+	}
+	over, err := amount.Cmp(due)
+	if err != nil {
+		return err
+	}
+	if over > 0 {
+		return fmt.Errorf("%w: %s of %s due", ErrOverpaid, amount, due)
+	}
+
+	reference, err := s.Provider.Charge(ctx, input.Tender, amount, *input.Token)
+	if err != nil {
+		return fmt.Errorf("failed to charge %s payment: %w", input.Tender, err)
+	}
+	payment.Amount, payment.Reference = amount, &reference
+	return nil
+}
@@ -0,0 +1,206 @@
+package sales
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestPaySaleSplitTenders(t *testing.T) {
+	ctx := context.Background()
+	sale := taxedSale(t, time.Now(), Open)
+	provider := &payments.Local{}
+	manager := SaleManager{Store: &refundingStore{sale: sale}, Provider: provider}
+
+	var card = "4242"
+	paid, err := manager.PaySale(ctx, NewPayment{
+		Sale: external(t, "1"), Tender: payments.Card, Token: &card, Amount: &money.Input{Amount: "10.00", Currency: "NZD"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Open, paid.Status)
+	require.NotNil(t, paid.Payments[0].Reference)
+
+	due, err := paid.Due()
+	require.NoError(t, err)
+	assert.Equal(t, "7.83", due.Decimal())
+
+	// only cash can be over paid
+	_, err = manager.PaySale(ctx, NewPayment{
+		Sale: external(t, "1"), Tender: payments.Voucher, Token: &card, Amount: &money.Input{Amount: "8.00", Currency: "NZD"},
+	})
+	require.ErrorIs(t, err, ErrOverpaid)
+
+	paid, err = manager.PaySale(ctx, NewPayment{
+		Sale: external(t, "1"), Tender: payments.Cash, Tendered: &money.Input{Amount: "10.00", Currency: "NZD"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Paid, paid.Status)
+
+	cash := paid.Payments[1]
+	assert.Equal(t, "7.83", cash.Amount.Decimal())
+	assert.Equal(t, "2.17", cash.Change.Decimal())
+	assert.Nil(t, cash.Reference)
+
+	_, err = manager.PaySale(ctx, NewPayment{
+		Sale: external(t, "1"), Tender: payments.Cash, Tendered: &money.Input{Amount: "1.00", Currency: "NZD"},
+	})
+	require.ErrorIs(t, err, ErrSaleStatus)
+
+	// voiding reverses what the payment provider charged
+	voided, err := manager.VoidSale(ctx, external(t, "1"), "customer walked out")
+	require.NoError(t, err)
+	assert.Equal(t, Voided, voided.Status)
+	assert.True(t, provider.Reversed(*paid.Payments[0].Reference))
+	assert.NotNil(t, voided.Payments[1].ReversedAt)
+}
+
+// offline fails to reverse any charge while it is offline.
+type offline struct {
+	*payments.Local
+	offline bool
+}
+
+func (o *offline) Reverse(ctx context.Context, reference string) error {
+	if o.offline {
+		return errors.New("terminal offline")
+	}
+	return o.Local.Reverse(ctx, reference)
+}
+
+func TestVoidSaleFailedReversal(t *testing.T) {
+	ctx := context.Background()
+	sale := taxedSale(t, time.Now(), Open)
+	provider := &offline{Local: &payments.Local{}}
+	store := &refundingStore{sale: sale}
+	manager := SaleManager{Store: store, Provider: provider}
+
+	var card = "4242"
+	_, err := manager.PaySale(ctx, NewPayment{
+		Sale: external(t, "1"), Tender: payments.Card, Token: &card, Amount: &money.Input{Amount: "10.00", Currency: "NZD"},
+	})
+	require.NoError(t, err)
+	paid, err := manager.PaySale(ctx, NewPayment{
+		Sale: external(t, "1"), Tender: payments.Cash, Tendered: &money.Input{Amount: "10.00", Currency: "NZD"},
+	})
+	require.NoError(t, err)
+
+	_, err = manager.RetryReversals(ctx, external(t, "1"))
+	require.ErrorIs(t, err, ErrSaleStatus)
+
+	// the sale is voided even though the card couldn't be reversed, which is left to retry
+	provider.offline = true
+	voided, err := manager.VoidSale(ctx, external(t, "1"), "customer walked out")
+	require.NoError(t, err)
+	assert.Equal(t, Voided, voided.Status)
+	assert.Nil(t, voided.Payments[0].ReversedAt)
+	require.NotNil(t, voided.Payments[0].ReversalFailure)
+	assert.Equal(t, "terminal offline", *voided.Payments[0].ReversalFailure)
+	assert.NotNil(t, voided.Payments[1].ReversedAt)
+
+	found, err := manager.GetSale(ctx, external(t, "1"))
+	require.NoError(t, err)
+	assert.Nil(t, found.Payments[0].ReversedAt)
+	assert.NotNil(t, found.Payments[0].ReversalFailure)
+
+	provider.offline = false
+	retried, err := manager.RetryReversals(ctx, external(t, "1"))
+	require.NoError(t, err)
+	assert.True(t, provider.Reversed(*paid.Payments[0].Reference))
+	assert.NotNil(t, retried.Payments[0].ReversedAt)
+	assert.Nil(t, retried.Payments[0].ReversalFailure)
+
+	found, err = manager.GetSale(ctx, external(t, "1"))
+	require.NoError(t, err)
+	for _, payment := range found.Payments {
+		assert.NotNil(t, payment.ReversedAt)
+	}
+}
+
+func TestPaySale(t *testing.T) {
+	var (
+		card    = "4242"
+		decline = "decline"
+	)
+
+	tests := []struct {
+		name    string
+		status  Status
+		payment NewPayment
+		err     error
+	}{
+		{name: "unknown tender", status: Open, payment: NewPayment{Tender: "CHEQUE"}, err: ErrTender},
+		{name: "cash without tendered", status: Open, payment: NewPayment{Tender: payments.Cash}, err: ErrNoTendered},
+		{name: "card without token", status: Open, payment: NewPayment{Tender: payments.Card}, err: ErrNoToken},
+		{name: "declined", status: Open, payment: NewPayment{Tender: payments.Card, Token: &decline}, err: payments.ErrDeclined},
+		{name: "other currency", status: Open, payment: NewPayment{Tender: payments.Card, Token: &card, Amount: &money.Input{Amount: "1.00", Currency: "AUD"}}, err: money.ErrCurrencyMismatch},
+		{name: "nothing", status: Open, payment: NewPayment{Tender: payments.Cash, Tendered: &money.Input{Amount: "0", Currency: "NZD"}}, err: ErrPaymentAmount},
+		{name: "voided", status: Voided, payment: NewPayment{Tender: payments.Card, Token: &card}, err: ErrSaleStatus},
+		{name: "what is due", status: Open, payment: NewPayment{Tender: payments.StoreCredit, Token: &card}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			sale := taxedSale(t, time.Now(), tt.status)
+			manager := SaleManager{Store: &refundingStore{sale: sale}, Provider: &payments.Local{}}
+
+			tt.payment.Sale = external(t, "1")
+			paid, err := manager.PaySale(ctx, tt.payment)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				assert.Empty(t, sale.Payments)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, Paid, paid.Status)
+			assert.Equal(t, "17.83", paid.Payments[0].Amount.Decimal())
+		})
+	}
+}
+
+func TestSaleStatus(t *testing.T) {
+	ctx := context.Background()
+	sale := taxedSale(t, time.Now(), Open)
+	manager := SaleManager{Store: &refundingStore{sale: sale}}
+
+	_, err := manager.RefundSale(ctx, NewRefund{Sale: external(t, "1"), Reason: Unwanted})
+	require.ErrorIs(t, err, ErrSaleStatus)
+
+	require.NoError(t, Open.to(Paid))
+	require.NoError(t, Paid.to(Refunded))
+	require.ErrorIs(t, Refunded.to(Voided), ErrSaleStatus)
+	require.ErrorIs(t, Voided.to(Open), ErrSaleStatus)
+	require.ErrorIs(t, Paid.to(Open), ErrSaleStatus)
+}
+
+// voidedMeanwhile is voided by someone else between reading the sale and voiding it.
+type voidedMeanwhile struct {
+	*refundingStore
+}
+
+func (v voidedMeanwhile) VoidSale(ctx context.Context, id int, void *Void) error {
+	return ErrSaleVoided
+}
+
+func TestVoidSaleReversesOnlyOnceVoided(t *testing.T) {
+	ctx := context.Background()
+	sale := taxedSale(t, time.Now(), Open)
+	provider := &payments.Local{}
+	manager := SaleManager{Store: &refundingStore{sale: sale}, Provider: provider}
+
+	var card = "4242"
+	paid, err := manager.PaySale(ctx, NewPayment{Sale: external(t, "1"), Tender: payments.Card, Token: &card})
+	require.NoError(t, err)
+	require.Equal(t, Paid, paid.Status)
+
+	manager.Store = voidedMeanwhile{&refundingStore{sale: sale}}
+	_, err = manager.VoidSale(ctx, external(t, "1"), "customer walked out")
+	require.ErrorIs(t, err, ErrSaleVoided)
+	assert.False(t, provider.Reversed(*paid.Payments[0].Reference))
+}
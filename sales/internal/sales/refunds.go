@@ -0,0 +1,388 @@
+package sales
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// RefundReason is the reason code a refund is made for.
+type RefundReason string
+
+const (
+	Damaged      RefundReason = "DAMAGED"
+	Defective    RefundReason = "DEFECTIVE"
+	WrongItem    RefundReason = "WRONG_ITEM"
+	Unwanted     RefundReason = "UNWANTED"
+	PricingError RefundReason = "PRICING_ERROR"
+	// OtherReason must be explained by a note.
+	OtherReason RefundReason = "OTHER"
+)
+
+// DefaultVoidWindow is how long after a sale it can be voided unless the manager says otherwise.
+const DefaultVoidWindow = 30 * time.Minute
+
+// Void cancels a sale in full shortly after it was made, ie when it was rung up by mistake.
+type Void struct {
+	At     time.Time
+	Actor  actor.Actor
+	Reason string
+}
+
+// Refund gives money back on a sale for units of its line items.
+type Refund struct {
+	ID         *keys.OpaqueID
+	SaleID     *keys.OpaqueID
+	Reason     RefundReason
+	Note       *string
+	Actor      actor.Actor
+	RefundedAt time.Time
+	// Currency is the currency of the sale refunded.
+	Currency money.Currency
+	Lines    []*RefundLine
+}
+
+// RefundLine refunds a quantity of a line item in the unit scale of its item, Amount is what the
+// customer paid for that quantity including any tax added on top of the price.
+type RefundLine struct {
+	LineItemID *keys.OpaqueID
+	Quantity   *big.Rat
+	Amount     money.Money
+}
+
+// Amount sums the lines of the refund.
+func (r *Refund) Amount() (money.Money, error) {
+	var amounts = make([]money.Money, 0, len(r.Lines))
+	for _, line := range r.Lines {
+		amounts = append(amounts, line.Amount)
+	}
+	return money.Sum(r.Currency, amounts...)
+}
+
+var (
+	ErrSaleVoided      = errors.New("sale is voided")
+	ErrVoidWindow      = errors.New("sale can no longer be voided, refund it instead")
+	ErrNoVoidReason    = errors.New("voiding a sale must give a reason")
+	ErrSaleRefunded    = errors.New("sale with refunds cannot be voided")
+	ErrRefundReason    = errors.New("unknown refund reason")
+	ErrRefundNote      = errors.New("refund for other reasons must come with a note")
+	ErrRefundLine      = errors.New("refunded line item is not on the sale")
+	ErrRefundQuantity  = errors.New("refund quantity must be a positive decimal number and at most what remains unrefunded of the line item")
+	ErrNothingToRefund = errors.New("sale has been refunded in full")
+	// ErrRefundConflict is returned when the sale was refunded or voided by someone else while
+	// the refund was being worked out.
+	ErrRefundConflict = errors.New("sale has been refunded or voided since it was read")
+)
+
+// NewRefund refunds the line items of the sale, everything that remains unrefunded is refunded
+// when no line items are given.
+type NewRefund struct {
+	Sale   *keys.OpaqueID
+	Reason RefundReason
+	Note   *string
+	Lines  []NewRefundLine
+}
+
+// NewRefundLine refunds a quantity of a line item, given in the unit scale of its item.
+type NewRefundLine struct {
+	LineItem *keys.OpaqueID
+	Quantity string
+}
+
+// RefundedQuantity sums the quantity of the line item refunded so far.
+func (s *Sale) RefundedQuantity(line *LineItem) *big.Rat {
+	var refunded = new(big.Rat)
+	for _, refund := range s.Refunds {
+		for _, refunding := range refund.Lines {
+			if refunding.LineItemID.ID == line.ID.ID {
+				refunded.Add(refunded, refunding.Quantity)
+			}
+		}
+	}
+	return refunded
+}
+
+// linePaid works out what the customer paid for every line item, the tax added on top of prices is
+// shared out between line items by their net totals.
+func (s *Sale) linePaid() ([]money.Money, error) {
+	var (
+		paid    = make([]money.Money, len(s.LineItems))
+		weights = make([]int64, len(s.LineItems))
+		weight  int64
+	)
+	for i, line := range s.LineItems {
+		net, err := line.Net()
+		if err != nil {
+			return nil, err
+		}
+		paid[i], weights[i] = net, net.Minor()
+		weight += net.Minor()
+	}
+
+	charged, err := s.Tax()
+	if err != nil {
+		return nil, err
+	}
+	if s.PricesIncludeTax || charged.IsZero() || weight == 0 {
+		return paid, nil
+	}
+
+	shares, err := charged.Allocate(weights...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range paid {
+		if paid[i], err = paid[i].Add(shares[i]); err != nil {
+			return nil, err
+		}
+	}
+	return paid, nil
+}
+
+// refundOf is the amount refunded for quantity of a line item that the customer paid for the total
+// quantity of, after refunded was already. Every part of the quantity is worth a share of what was
+// paid such that refunding all of it in any number of refunds gives back exactly what was paid.
+func refundOf(paid money.Money, total, refunded, quantity *big.Rat) (money.Money, error) {
+	var share = func(of *big.Rat) (money.Money, error) {
+		if of.Cmp(total) == 0 {
+			return paid, nil
+		}
+		if of.Sign() == 0 || paid.IsZero() {
+			return money.Zero(paid.Currency()), nil
+		}
+		shares, err := paid.Allocate(counted(of), counted(new(big.Rat).Sub(total, of)))
+		if err != nil {
+			return money.Money{}, err
+		}
+		return shares[0], nil
+	}
+
+	before, err := share(refunded)
+	if err != nil {
+		return money.Money{}, err
+	}
+	after, err := share(new(big.Rat).Add(refunded, quantity))
+	if err != nil {
+		return money.Money{}, err
+	}
+	return after.Sub(before)
+}
+
+// counted is how many parts make up the quantity of a line item, which line item quantities are
+// always a whole number of.
+func counted(quantity *big.Rat) int64 {
+	return new(big.Rat).Mul(quantity, big.NewRat(parts, 1)).Num().Int64()
+}
+
+// RefundSale refunds units of line items of the sale, attributed to the actor of the context.
+func (s *SaleManager) RefundSale(ctx context.Context, input NewRefund) (*Refund, error) {
+	switch input.Reason {
+	case Damaged, Defective, WrongItem, Unwanted, PricingError, OtherReason:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrRefundReason, input.Reason)
+	}
+
+	var note *string
+	if input.Note != nil && strings.TrimSpace(*input.Note) != "" {
+		trimmed := strings.TrimSpace(*input.Note)
+		note = &trimmed
+	}
+	if input.Reason == OtherReason && note == nil {
+		return nil, ErrRefundNote
+	}
+
+	sale, err := s.GetSale(ctx, input.Sale)
+	if err != nil {
+		return nil, err
+	}
+	if sale.Void != nil {
+		return nil, ErrSaleVoided
+	}
+	if sale.Status == Refunded {
+		return nil, ErrNothingToRefund
+	}
+	if sale.Status != Paid {
+		return nil, fmt.Errorf("%w: %s sale cannot be refunded until paid", ErrSaleStatus, sale.Status)
+	}
+
+	var quantities = make(map[int]*big.Rat, len(input.Lines))
+	for _, line := range input.Lines {
+		id, err := line.LineItem.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode line item id: %w", err)
+		}
+		quantity, err := measure(line.Quantity, nil, items.Unit)
+		if errors.Is(err, ErrQuantity) || errors.Is(err, ErrQuantityPlaces) {
+			return nil, fmt.Errorf("%w: %q", ErrRefundQuantity, line.Quantity)
+		} else if err != nil {
+			return nil, err
+		}
+		if quantities[id] == nil {
+			quantities[id] = new(big.Rat)
+		}
+		quantities[id].Add(quantities[id], quantity)
+	}
+
+	paid, err := sale.linePaid()
+	if err != nil {
+		return nil, fmt.Errorf("failed to work out what was paid for line items: %w", err)
+	}
+
+	var refund = &Refund{
+		SaleID:     sale.ID,
+		Reason:     input.Reason,
+		Note:       note,
+		Actor:      actor.From(ctx),
+		RefundedAt: time.Now(),
+		Currency:   sale.Currency,
+		Lines:      []*RefundLine{},
+	}
+	// the sale is refunded once nothing remains unrefunded
+	var next = Refunded
+	for i, line := range sale.LineItems {
+		var (
+			refunded  = sale.RefundedQuantity(line)
+			remaining = new(big.Rat).Sub(line.Quantity, refunded)
+			quantity  = remaining
+		)
+		if len(input.Lines) > 0 {
+			quantity = new(big.Rat)
+			if given, ok := quantities[line.ID.ID]; ok {
+				quantity = given
+			}
+			delete(quantities, line.ID.ID)
+			if quantity.Cmp(remaining) > 0 {
+				return nil, fmt.Errorf("%w: %s of line item with %s remaining", ErrRefundQuantity,
+					items.Quantity{Amount: quantity, Scale: line.UnitScale}, items.Quantity{Amount: remaining, Scale: line.UnitScale})
+			}
+		}
+		if quantity.Cmp(remaining) < 0 {
+			next = Paid
+		}
+		if quantity.Sign() == 0 {
+			continue
+		}
+
+		amount, err := refundOf(paid[i], line.Quantity, refunded, quantity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to work out refund of line item: %w", err)
+		}
+		refund.Lines = append(refund.Lines, &RefundLine{LineItemID: line.ID, Quantity: quantity, Amount: amount})
+	}
+	if len(quantities) > 0 {
+		return nil, ErrRefundLine
+	}
+	if len(refund.Lines) == 0 {
+		return nil, ErrNothingToRefund
+	}
+
+	if err := sale.Status.to(next); err != nil {
+		return nil, err
+	}
+	if err := s.Store.CreateRefund(ctx, refund, len(sale.Refunds), next); err != nil {
+		return nil, fmt.Errorf("failed to refund sale: %w", err)
+	}
+	return refund, nil
+}
+
+// VoidSale cancels the sale in full, sales can only be voided within the void window of when
+// they were made and before anything was refunded on them. Charges of the payment provider are
+// reversed once the sale is voided, charges that fail to reverse are recorded on their payments
+// of the voided sale for RetryReversals to try again.
+func (s *SaleManager) VoidSale(ctx context.Context, externalID *keys.OpaqueID, reason string) (*Sale, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrNoVoidReason
+	}
+
+	sale, err := s.GetSale(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	if sale.Void != nil {
+		return nil, ErrSaleVoided
+	}
+	if len(sale.Refunds) > 0 {
+		return nil, ErrSaleRefunded
+	}
+	if err := sale.Status.to(Voided); err != nil {
+		return nil, err
+	}
+
+	var window = s.VoidWindow
+	if window == 0 {
+		window = DefaultVoidWindow
+	}
+	now := time.Now()
+	if now.Sub(sale.SaleDate) > window {
+		return nil, ErrVoidWindow
+	}
+
+	// the sale is voided first, the store refuses sales voided or refunded by someone else in the
+	// meantime so charges are only ever reversed for the one void that went through
+	void := &Void{At: now, Actor: actor.From(ctx), Reason: strings.TrimSpace(reason)}
+	if err := s.Store.VoidSale(ctx, sale.ID.ID, void); err != nil {
+		return nil, fmt.Errorf("failed to void sale: %w", err)
+	}
+	sale.Status, sale.Void = Voided, void
+
+	if err := s.reverse(ctx, sale, now); err != nil {
+		return nil, err
+	}
+	return sale, nil
+}
+
+// RetryReversals reverses the charges of the voided sale that failed to reverse when it was
+// voided.
+func (s *SaleManager) RetryReversals(ctx context.Context, externalID *keys.OpaqueID) (*Sale, error) {
+	sale, err := s.GetSale(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	if sale.Void == nil {
+		return nil, fmt.Errorf("%w: %s sale has no charges to reverse", ErrSaleStatus, sale.Status)
+	}
+
+	if err := s.reverse(ctx, sale, time.Now()); err != nil {
+		return nil, err
+	}
+	return sale, nil
+}
+
+// reverse reverses what the payments of the voided sale charged that wasn't reversed yet, each
+// payment records whether it was reversed or why it couldn't be.
+func (s *SaleManager) reverse(ctx context.Context, sale *Sale, now time.Time) error {
+	var reversals []*payments.Payment
+	for _, payment := range sale.Payments {
+		if payment.ReversedAt != nil {
+			continue
+		}
+		reversals = append(reversals, payment)
+
+		if payment.Reference != nil {
+			err := s.Provider.Reverse(ctx, *payment.Reference)
+			if err != nil && !errors.Is(err, payments.ErrReversed) {
+				failure := err.Error()
+				payment.ReversalFailure = &failure
+				continue
+			}
+		}
+		payment.ReversedAt, payment.ReversalFailure = &now, nil
+	}
+	if len(reversals) == 0 {
+		return nil
+	}
+
+	if err := s.Store.RecordReversals(ctx, reversals); err != nil {
+		return fmt.Errorf("failed to record reversals of sale: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package sales
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// refundingStore holds a single sale that payments, refunds and voids are made against.
+type refundingStore struct {
+	store
+	sale *Sale
+}
+
+func (r *refundingStore) CreatePayment(ctx context.Context, payment *payments.Payment, seen int, status Status) error {
+	if seen != len(r.sale.Payments) {
+		return ErrPaymentConflict
+	}
+	payment.ID = &keys.OpaqueID{ID: len(r.sale.Payments) + 1}
+	r.sale.Payments, r.sale.Status = append(r.sale.Payments, payment), status
+	return nil
+}
+
+func (r *refundingStore) GetSale(ctx context.Context, id int) (*Sale, error) {
+	if r.sale.ID.ID != id {
+		return nil, ErrSaleNotFound
+	}
+	// like any store, what is read is a copy of what is held
+	read := *r.sale
+	read.Payments, read.Refunds = slices.Clone(r.sale.Payments), slices.Clone(r.sale.Refunds)
+	return &read, nil
+}
+
+func (r *refundingStore) CreateRefund(ctx context.Context, refund *Refund, seen int, status Status) error {
+	if seen != len(r.sale.Refunds) {
+		return ErrRefundConflict
+	}
+	refund.ID = &keys.OpaqueID{ID: len(r.sale.Refunds) + 1}
+	r.sale.Refunds, r.sale.Status = append(r.sale.Refunds, refund), status
+	return nil
+}
+
+func (r *refundingStore) VoidSale(ctx context.Context, id int, void *Void) error {
+	r.sale.Void, r.sale.Status = void, Voided
+	return nil
+}
+
+func (r *refundingStore) RecordReversals(ctx context.Context, reversals []*payments.Payment) error {
+	for _, reversal := range reversals {
+		for i, payment := range r.sale.Payments {
+			if payment.ID.ID == reversal.ID.ID {
+				recorded := *reversal
+				r.sale.Payments[i] = &recorded
+			}
+		}
+	}
+	return nil
+}
+
+// taxedSale sells 3 coffees at 4.00 and a muffin at 3.50, 15% tax is added on top.
+func taxedSale(t *testing.T, at time.Time, status Status) *Sale {
+	return &Sale{
+		ID:       &keys.OpaqueID{ID: 1},
+		SaleDate: at,
+		Currency: "NZD",
+		Status:   status,
+		LineItems: []*LineItem{
+			{ID: &keys.OpaqueID{ID: 10}, ItemID: &keys.OpaqueID{ID: 1}, Quantity: big.NewRat(3, 1), UnitPrice: mustMoney(t, "4.00", "NZD")},
+			{ID: &keys.OpaqueID{ID: 11}, ItemID: &keys.OpaqueID{ID: 2}, Quantity: big.NewRat(1, 1), UnitPrice: mustMoney(t, "3.50", "NZD")},
+		},
+		Taxes: []*tax.Line{
+			{Name: "GST", Rate: big.NewRat(15, 100), Taxable: mustMoney(t, "15.50", "NZD"), Amount: mustMoney(t, "2.33", "NZD")},
+		},
+		Payments: []*payments.Payment{},
+		Refunds:  []*Refund{},
+	}
+}
+
+func TestRefundOf(t *testing.T) {
+	paid := mustMoney(t, "10.00", "NZD")
+
+	var refunded []string
+	for units := int64(0); units < 3; units++ {
+		amount, err := refundOf(paid, big.NewRat(3, 1), big.NewRat(units, 1), big.NewRat(1, 1))
+		require.NoError(t, err)
+		refunded = append(refunded, amount.Decimal())
+	}
+	assert.Equal(t, []string{"3.34", "3.33", "3.33"}, refunded)
+
+	amount, err := refundOf(paid, big.NewRat(3, 1), big.NewRat(1, 1), big.NewRat(2, 1))
+	require.NoError(t, err)
+	assert.Equal(t, "6.66", amount.Decimal())
+
+	// weighed line items are refunded in proportion to the quantity refunded, 0.1kg of 0.25kg
+	amount, err = refundOf(paid, big.NewRat(1, 4), new(big.Rat), big.NewRat(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, "4.00", amount.Decimal())
+}
+
+func TestRefundSale(t *testing.T) {
+	var note = "customer changed their mind"
+
+	tests := []struct {
+		name  string
+		input func(t *testing.T) NewRefund
+		// want holds the refunded quantity and amount of every refunded line item
+		want []string
+		err  error
+	}{
+		{
+			name:  "everything",
+			input: func(t *testing.T) NewRefund { return NewRefund{Reason: Unwanted} },
+			// the 2.33 of tax is shared out 12.00 to 3.50
+			want: []string{"3 13.81", "1 4.02"},
+		},
+		{
+			name: "some units of a line item",
+			input: func(t *testing.T) NewRefund {
+				return NewRefund{Reason: Damaged, Lines: []NewRefundLine{{LineItem: external(t, "10"), Quantity: "2"}}}
+			},
+			want: []string{"2 9.21"},
+		},
+		{
+			name: "more units than sold",
+			input: func(t *testing.T) NewRefund {
+				return NewRefund{Reason: Damaged, Lines: []NewRefundLine{{LineItem: external(t, "11"), Quantity: "2"}}}
+			},
+			err: ErrRefundQuantity,
+		},
+		{
+			name: "line item of another sale",
+			input: func(t *testing.T) NewRefund {
+				return NewRefund{Reason: Damaged, Lines: []NewRefundLine{{LineItem: external(t, "99"), Quantity: "1"}}}
+			},
+			err: ErrRefundLine,
+		},
+		{
+			name:  "unknown reason",
+			input: func(t *testing.T) NewRefund { return NewRefund{Reason: "BORED"} },
+			err:   ErrRefundReason,
+		},
+		{
+			name:  "other reason without a note",
+			input: func(t *testing.T) NewRefund { return NewRefund{Reason: OtherReason} },
+			err:   ErrRefundNote,
+		},
+		{
+			name:  "other reason with a note",
+			input: func(t *testing.T) NewRefund { return NewRefund{Reason: OtherReason, Note: &note} },
+			want:  []string{"3 13.81", "1 4.02"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := actor.With(context.Background(), "till-1")
+			sale := taxedSale(t, time.Now(), Paid)
+			manager := SaleManager{Store: &refundingStore{sale: sale}}
+
+			input := tt.input(t)
+			input.Sale = external(t, "1")
+
+			refund, err := manager.RefundSale(ctx, input)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, actor.Actor("till-1"), refund.Actor)
+
+			var got []string
+			for _, line := range refund.Lines {
+				got = append(got, fmt.Sprintf("%s %s", line.Quantity.RatString(), line.Amount.Decimal()))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRefundSaleInParts(t *testing.T) {
+	ctx := context.Background()
+	sale := taxedSale(t, time.Now(), Paid)
+	manager := SaleManager{Store: &refundingStore{sale: sale}}
+
+	for i := 0; i < 3; i++ {
+		_, err := manager.RefundSale(ctx, NewRefund{Sale: external(t, "1"), Reason: Defective, Lines: []NewRefundLine{
+			{LineItem: external(t, "10"), Quantity: "1"},
+		}})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "3", sale.RefundedQuantity(sale.LineItems[0]).RatString())
+	assert.Equal(t, Paid, sale.Status)
+
+	_, err := manager.RefundSale(ctx, NewRefund{Sale: external(t, "1"), Reason: Defective, Lines: []NewRefundLine{
+		{LineItem: external(t, "10"), Quantity: "1"},
+	}})
+	require.ErrorIs(t, err, ErrRefundQuantity)
+
+	// the rest of the sale is what remains
+	_, err = manager.RefundSale(ctx, NewRefund{Sale: external(t, "1"), Reason: Unwanted})
+	require.NoError(t, err)
+	_, err = manager.RefundSale(ctx, NewRefund{Sale: external(t, "1"), Reason: Unwanted})
+	require.ErrorIs(t, err, ErrNothingToRefund)
+
+	// refunding everything in parts gives back exactly what was paid
+	total, err := sale.Total()
+	require.NoError(t, err)
+	assert.Equal(t, "17.83", total.Decimal())
+	refunded, err := sale.Refunded()
+	require.NoError(t, err)
+	assert.Equal(t, total, refunded)
+	net, err := sale.NetTotal()
+	require.NoError(t, err)
+	assert.True(t, net.IsZero())
+	assert.Equal(t, Refunded, sale.Status)
+
+	_, err = manager.VoidSale(ctx, external(t, "1"), "rung up twice")
+	require.ErrorIs(t, err, ErrSaleRefunded)
+}
+
+func TestVoidSale(t *testing.T) {
+	tests := []struct {
+		name   string
+		age    time.Duration
+		reason string
+		err    error
+	}{
+		{name: "within the window", age: time.Minute, reason: "rung up twice"},
+		{name: "outside of the window", age: time.Hour, reason: "rung up twice", err: ErrVoidWindow},
+		{name: "without a reason", age: time.Minute, reason: " ", err: ErrNoVoidReason},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := actor.With(context.Background(), "till-1")
+			sale := taxedSale(t, time.Now().Add(-tt.age), Paid)
+			manager := SaleManager{Store: &refundingStore{sale: sale}}
+
+			voided, err := manager.VoidSale(ctx, external(t, "1"), tt.reason)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, actor.Actor("till-1"), voided.Void.Actor)
+
+			net, err := voided.NetTotal()
+			require.NoError(t, err)
+			assert.Equal(t, "0.00", net.Decimal())
+
+			_, err = manager.VoidSale(ctx, external(t, "1"), tt.reason)
+			require.ErrorIs(t, err, ErrSaleVoided)
+			_, err = manager.RefundSale(ctx, NewRefund{Sale: external(t, "1"), Reason: Unwanted})
+			require.ErrorIs(t, err, ErrSaleVoided)
+		})
+	}
+}
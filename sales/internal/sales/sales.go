@@ -0,0 +1,436 @@
+package sales
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/pricing"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type store interface {
+	// CreateSale records the sale along with its line items, the ids of which are assigned in place.
+	CreateSale(context.Context, *Sale) error
+	GetSale(context.Context, int) (*Sale, error)
+	// PageSales pages through sales that match the filter, most recent sales first.
+	PageSales(context.Context, SaleFilter, paginate.Paginate) ([]*Sale, error)
+	// CreatePayment records the payment with its id assigned in place and moves the sale to the
+	// status, as long as the sale is open and still has as many payments as seen when the payment
+	// was worked out.
+	CreatePayment(ctx context.Context, payment *payments.Payment, seen int, status Status) error
+	// CreateRefund records the refund with its id assigned in place and moves the sale to the
+	// status, as long as the sale isn't voided and still has as many refunds as seen when the
+	// refund was worked out.
+	CreateRefund(ctx context.Context, refund *Refund, seen int, status Status) error
+	// VoidSale voids the sale, unless it is voided or refunded already.
+	VoidSale(ctx context.Context, id int, void *Void) error
+	// RecordReversals records when the payments were reversed, or why they couldn't be.
+	RecordReversals(ctx context.Context, reversals []*payments.Payment) error
+}
+
+// itemStore resolves the items that line items refer to.
+type itemStore interface {
+	GetItem(context.Context, int) (*items.Item, error)
+}
+
+// priceStore prices line items that the till didn't give a price for.
+type priceStore interface {
+	SaleList(ctx context.Context, retailer int, list *keys.OpaqueID, currency money.Currency) (*pricing.PriceList, error)
+	Quote(ctx context.Context, list *pricing.PriceList, item int, at time.Time) (money.Money, error)
+	PriceListByID(context.Context, int) (*pricing.PriceList, error)
+}
+
+// promotionStore discounts line items by the running promotions of the retailer.
+type promotionStore interface {
+	Apply(ctx context.Context, retailer int, basket promotions.Basket) ([][]*promotions.Discount, error)
+	PromotionByID(context.Context, int) (*promotions.Promotion, error)
+}
+
+// taxStore works out the tax charged on sales.
+type taxStore interface {
+	Assess(ctx context.Context, retailer int, at time.Time, currency money.Currency, taxables []tax.Taxable) (*tax.Assessment, error)
+}
+
+type SaleManager struct {
+	Store      store
+	Items      itemStore
+	Prices     priceStore
+	Promotions promotionStore
+	Taxes      taxStore
+	Provider   payments.PaymentProvider
+	// VoidWindow is how long after a sale it can be voided, DefaultVoidWindow when zero.
+	VoidWindow time.Duration
+}
+
+// MaxQuantity is the most of the unit scale of its item a line item sells, far beyond any till so
+// that line totals and promotions never have to deal with absurd quantities.
+const MaxQuantity = 100_000
+
+// parts is how finely line items hold quantities of the unit scale of their item, to the ten
+// thousandth like the stock ledger they take stock from.
+const parts = 10_000
+
+var (
+	ErrSaleNotFound     = errors.New("sale not found")
+	ErrRetailerNotFound = errors.New("retailer not found")
+	ErrCustomerNotFound = errors.New("customer not found")
+	ErrLocationNotFound = errors.New("stock location not found")
+	ErrNoLineItems      = errors.New("sale must have at least one line item")
+	ErrQuantity         = fmt.Errorf("line item quantity must be a positive decimal number of at most %d of the unit scale of the item", MaxQuantity)
+	ErrQuantityPlaces   = errors.New("line item quantity must be a whole number of ten thousandths of the unit scale of the item")
+	ErrUnitPrice        = errors.New("line item unit price must not be negative")
+	ErrNoUnitPrice      = errors.New("line item has no unit price and its item has no price on the price list")
+	// ErrOverrideReason is returned for line items priced differently to the price list without
+	// saying why.
+	ErrOverrideReason = errors.New("line item overriding the price list must give a reason")
+	// ErrUnknownItem is returned when a line item refers to an item that doesn't exist or is
+	// archived, archived items can no longer be sold.
+	ErrUnknownItem = errors.New("line item refers to an unknown item")
+)
+
+// NewSale is a sale as presented by a till, all ids are external.
+type NewSale struct {
+	Retailer *keys.OpaqueID
+	Customer *keys.OpaqueID
+	// Location defaults to the default stock location of the retailer, if it has one.
+	Location *keys.OpaqueID
+	SaleDate *time.Time
+	Currency money.Currency
+	// PriceList defaults to the retail price list of the retailer in the currency of the sale.
+	PriceList *keys.OpaqueID
+	// Coupons unlock promotions that aren't applied automatically.
+	Coupons   []string
+	LineItems []NewLineItem
+}
+
+// NewLineItem is priced from the price list of the sale unless the till gives a unit price. The
+// quantity is given in the unit scale of the item unless another unit scale of the same dimension
+// is given, ie 250 in g of an item sold by the kg.
+type NewLineItem struct {
+	Item           *keys.OpaqueID
+	Quantity       string
+	UnitScale      *items.UnitScale
+	UnitPrice      *money.Input
+	OverrideReason *string
+}
+
+func (s *SaleManager) RecordSale(ctx context.Context, input NewSale) (*Sale, error) {
+	sale, err := s.sell(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// sales that come to nothing, ie given away by promotions, have nothing left to pay
+	total, err := sale.Total()
+	if err != nil {
+		return nil, err
+	}
+	if total.IsZero() {
+		sale.Status = Paid
+	}
+
+	if err := s.Store.CreateSale(ctx, sale); err != nil {
+		return nil, fmt.Errorf("failed to record sale: %w", err)
+	}
+	return sale, nil
+}
+
+// PreviewSale prices, discounts and taxes the sale exactly like RecordSale would without
+// recording it, the sale and its line items have no ids.
+func (s *SaleManager) PreviewSale(ctx context.Context, input NewSale) (*Sale, error) {
+	return s.sell(ctx, input)
+}
+
+// sell works out everything about the sale that is recorded with it.
+func (s *SaleManager) sell(ctx context.Context, input NewSale) (*Sale, error) {
+	if len(input.LineItems) == 0 {
+		return nil, ErrNoLineItems
+	}
+
+	retailer, err := scope(ctx, input.Retailer)
+	if err != nil {
+		return nil, err
+	}
+
+	var sale = &Sale{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		SaleDate:   time.Now(),
+		Currency:   input.Currency,
+		Status:     Open,
+		LineItems:  make([]*LineItem, 0, len(input.LineItems)),
+		Payments:   []*payments.Payment{},
+		Refunds:    []*Refund{},
+	}
+	if input.Customer != nil {
+		customer, err := input.Customer.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode customer id: %w", err)
+		}
+		sale.CustomerID = &keys.OpaqueID{ID: customer}
+	}
+	if input.Location != nil {
+		location, err := input.Location.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode location id: %w", err)
+		}
+		sale.LocationID = &keys.OpaqueID{ID: location}
+	}
+	if input.SaleDate != nil {
+		sale.SaleDate = *input.SaleDate
+	}
+
+	list, err := s.Prices.SaleList(ctx, retailer, input.PriceList, sale.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price list of sale: %w", err)
+	}
+	if list != nil {
+		sale.PriceListID = list.ID
+	}
+
+	var (
+		classes = make([]string, 0, len(input.LineItems))
+		basket  = promotions.Basket{Currency: sale.Currency, At: sale.SaleDate, Coupons: input.Coupons}
+	)
+	for _, line := range input.LineItems {
+		item, err := line.Item.Decode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode item id: %w", err)
+		}
+
+		sold, err := s.Items.GetItem(ctx, item)
+		if errors.Is(err, items.ErrItemNotFound) {
+			return nil, ErrUnknownItem
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get item of line item: %w", err)
+		}
+		if sold.ArchivedAt != nil {
+			return nil, ErrUnknownItem
+		}
+
+		// quantities are priced and take stock in the unit scale of the item
+		quantity, err := measure(line.Quantity, line.UnitScale, sold.UnitScale)
+		if err != nil {
+			return nil, err
+		}
+
+		var priced = &LineItem{
+			ItemID:    &keys.OpaqueID{ID: item},
+			Quantity:  quantity,
+			UnitScale: sold.UnitScale,
+		}
+		if list != nil {
+			quoted, err := s.Prices.Quote(ctx, list, item, sale.SaleDate)
+			switch {
+			case err == nil:
+				priced.ListPrice = &quoted
+			case errors.Is(err, items.ErrItemNotFound):
+				return nil, ErrUnknownItem
+			case !errors.Is(err, pricing.ErrNoPrice):
+				return nil, fmt.Errorf("failed to price line item: %w", err)
+			}
+		}
+
+		if err := price(ctx, priced, line, sale.Currency); err != nil {
+			return nil, err
+		}
+		sale.LineItems = append(sale.LineItems, priced)
+		classes = append(classes, sold.TaxClass)
+		basket.Lines = append(basket.Lines, promotions.Line{Item: item, Quantity: priced.Quantity, UnitPrice: priced.UnitPrice})
+	}
+
+	discounts, err := s.Promotions.Apply(ctx, retailer, basket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply promotions: %w", err)
+	}
+
+	var taxables = make([]tax.Taxable, 0, len(sale.LineItems))
+	for i, line := range sale.LineItems {
+		line.Discounts = discounts[i]
+
+		net, err := line.Net()
+		if err != nil {
+			return nil, err
+		}
+		taxables = append(taxables, tax.Taxable{Class: classes[i], Amount: net})
+	}
+
+	assessment, err := s.Taxes.Assess(ctx, retailer, sale.SaleDate, sale.Currency, taxables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to tax sale: %w", err)
+	}
+	sale.Taxes = []*tax.Line{}
+	if assessment != nil {
+		sale.PricesIncludeTax, sale.Taxes = assessment.PricesIncludeTax, assessment.Lines
+	}
+	return sale, nil
+}
+
+// scope is the retailer the request is scoped to, a retailer named by the request must be that one.
+func scope(ctx context.Context, named *keys.OpaqueID) (int, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	if named != nil {
+		id, err := named.Decode(ctx)
+		if err != nil {
+			return -1, fmt.Errorf("failed to decode retailer id: %w", err)
+		}
+		if id != retailer {
+			return -1, ErrRetailerNotFound
+		}
+	}
+	return retailer, nil
+}
+
+func (s *SaleManager) GetSale(ctx context.Context, externalID *keys.OpaqueID) (*Sale, error) {
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	return s.Store.GetSale(ctx, id)
+}
+
+func (s *SaleManager) SearchSales(ctx context.Context, filter *SaleFilter, page *paginate.Paginate) ([]*Sale, error) {
+	var decoded SaleFilter
+	if filter != nil {
+		var err error
+		// sales are only ever searched within the retailer of the request
+		if _, err = scope(ctx, filter.Retailer); err != nil {
+			return nil, err
+		}
+		if decoded.Customer, err = keys.DecodeOptional(ctx, filter.Customer); err != nil {
+			return nil, fmt.Errorf("failed to decode customer id: %w", err)
+		}
+		if decoded.Item, err = keys.DecodeOptional(ctx, filter.Item); err != nil {
+			return nil, fmt.Errorf("failed to decode item id: %w", err)
+		}
+		decoded.From, decoded.To = filter.From, filter.To
+	}
+
+	decodedPage, err := paginate.Decode(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	sales, err := s.Store.PageSales(ctx, decoded, decodedPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to page through sales: %w", err)
+	}
+	return sales, nil
+}
+
+// CustomerSales pages through the sales made to the customer, most recent sales first.
+func (s *SaleManager) CustomerSales(ctx context.Context, customer int, page *paginate.Paginate) ([]*Sale, error) {
+	decodedPage, err := paginate.Decode(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	sales, err := s.Store.PageSales(ctx, SaleFilter{Customer: &keys.OpaqueID{ID: customer}}, decodedPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to page through sales of customer: %w", err)
+	}
+	return sales, nil
+}
+
+// SalePriceList resolves the price list the sale was priced from.
+func (s *SaleManager) SalePriceList(ctx context.Context, sale *Sale) (*pricing.PriceList, error) {
+	if sale.PriceListID == nil {
+		return nil, nil
+	}
+	return s.Prices.PriceListByID(ctx, sale.PriceListID.ID)
+}
+
+// DiscountPromotion resolves the promotion that gave the discount.
+func (s *SaleManager) DiscountPromotion(ctx context.Context, discount *promotions.Discount) (*promotions.Promotion, error) {
+	return s.Promotions.PromotionByID(ctx, discount.PromotionID.ID)
+}
+
+// LineItemItem resolves the item that was sold on the line item.
+func (s *SaleManager) LineItemItem(ctx context.Context, line *LineItem) (*items.Item, error) {
+	return s.Items.GetItem(ctx, line.ItemID.ID)
+}
+
+var quantityPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// measure reads the decimal quantity given in the unit scale and converts it into the unit scale
+// of the item, ie 250 in g is 0.25 of an item sold by the kg. Quantities in no unit scale are in
+// the unit scale of the item already.
+func measure(quantity string, given *items.UnitScale, scale items.UnitScale) (*big.Rat, error) {
+	quantity = strings.TrimSpace(quantity)
+	amount, ok := new(big.Rat).SetString(quantity)
+	if !quantityPattern.MatchString(quantity) || !ok {
+		return nil, fmt.Errorf("%w: %q", ErrQuantity, quantity)
+	}
+
+	if given != nil {
+		converted, err := items.Quantity{Amount: amount, Scale: *given}.In(scale)
+		if err != nil {
+			return nil, err
+		}
+		amount = converted.Amount
+	}
+
+	if amount.Sign() <= 0 || amount.Cmp(big.NewRat(MaxQuantity, 1)) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrQuantity, items.Quantity{Amount: amount, Scale: scale})
+	}
+	if !new(big.Rat).Mul(amount, big.NewRat(parts, 1)).IsInt() {
+		return nil, fmt.Errorf("%w: %s", ErrQuantityPlaces, quantity)
+	}
+	return amount, nil
+}
+
+// price sets the unit price of the line item, taking the list price unless the till gave one in
+// which case the override is attributed to the actor of the context.
+func price(ctx context.Context, priced *LineItem, line NewLineItem, currency money.Currency) error {
+	if line.UnitPrice == nil {
+		if priced.ListPrice == nil {
+			return ErrNoUnitPrice
+		}
+		priced.UnitPrice = *priced.ListPrice
+		return nil
+	}
+
+	given, err := line.UnitPrice.Money()
+	if err != nil {
+		return fmt.Errorf("failed to parse unit price: %w", err)
+	}
+	if given.Sign() < 0 {
+		return ErrUnitPrice
+	}
+	if given.Currency() != currency {
+		return fmt.Errorf("%w: line item priced in %s on a sale in %s", money.ErrCurrencyMismatch, given.Currency(), currency)
+	}
+	priced.UnitPrice = given
+
+	if priced.ListPrice != nil && *priced.ListPrice == given {
+		return nil
+	}
+
+	var reason *string
+	if line.OverrideReason != nil && strings.TrimSpace(*line.OverrideReason) != "" {
+		trimmed := strings.TrimSpace(*line.OverrideReason)
+		reason = &trimmed
+	}
+	if priced.ListPrice != nil && reason == nil {
+		return ErrOverrideReason
+	}
+
+	priced.Override = &PriceOverride{Actor: actor.From(ctx), Reason: reason}
+	return nil
+}
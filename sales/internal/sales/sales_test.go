@@ -0,0 +1,467 @@
+package sales
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/pricing"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type plainCodec struct{}
+
+func (plainCodec) Encode(ctx context.Context, id int) (string, error) {
+	return strconv.Itoa(id), nil
+}
+
+func (plainCodec) Decode(ctx context.Context, id string) (int, error) {
+	return strconv.Atoi(id)
+}
+
+func external(t *testing.T, id string) *keys.OpaqueID {
+	t.Helper()
+	var opaque keys.OpaqueID
+	require.NoError(t, opaque.UnmarshalGQLContext(context.Background(), id))
+	return opaque.WithCodec(plainCodec{})
+}
+
+// recordingStore keeps the last sale created instead of storing it.
+type recordingStore struct {
+	store
+	created *Sale
+}
+
+func (r *recordingStore) CreateSale(ctx context.Context, sale *Sale) error {
+	r.created = sale
+	return nil
+}
+
+// fixedPrices prices items from a single price list, items without a quote have no price.
+type fixedPrices struct {
+	list   *pricing.PriceList
+	quotes map[int]money.Money
+}
+
+func (f *fixedPrices) SaleList(ctx context.Context, retailer int, list *keys.OpaqueID, currency money.Currency) (*pricing.PriceList, error) {
+	return f.list, nil
+}
+
+func (f *fixedPrices) Quote(ctx context.Context, list *pricing.PriceList, item int, at time.Time) (money.Money, error) {
+	quoted, ok := f.quotes[item]
+	if !ok {
+		return money.Money{}, pricing.ErrNoPrice
+	}
+	return quoted, nil
+}
+
+func (f *fixedPrices) PriceListByID(ctx context.Context, id int) (*pricing.PriceList, error) {
+	return f.list, nil
+}
+
+// fixedItems has an item of the default tax class for every id.
+// fixedItems are sold by the unit, apart from item 5 which is sold by the kg, and item 7 is
+// archived.
+type fixedItems struct{}
+
+func (fixedItems) GetItem(ctx context.Context, id int) (*items.Item, error) {
+	var scale = items.Unit
+	if id == 5 {
+		scale = items.Kilogram
+	}
+	item := &items.Item{ID: &keys.OpaqueID{ID: id}, Details: items.Details{UnitScale: scale}, TaxClass: items.DefaultTaxClass}
+	if id == 7 {
+		archived := time.Now()
+		item.ArchivedAt = &archived
+	}
+	return item, nil
+}
+
+// fixedTaxes charges a single rate on every tax class, or no tax at all without a rate.
+type fixedTaxes struct {
+	rate      *tax.Rate
+	inclusive bool
+}
+
+func (f *fixedTaxes) Assess(ctx context.Context, retailer int, at time.Time, currency money.Currency, taxables []tax.Taxable) (*tax.Assessment, error) {
+	if f.rate == nil {
+		return nil, nil
+	}
+	lines, err := tax.Calculate(currency, taxables, []*tax.Rate{f.rate}, tax.Settings{PricesIncludeTax: f.inclusive, Rounding: tax.PerLine})
+	if err != nil {
+		return nil, err
+	}
+	return &tax.Assessment{PricesIncludeTax: f.inclusive, Lines: lines}, nil
+}
+
+// fixedPromotions runs the same promotions for every retailer, or no promotions at all.
+type fixedPromotions struct {
+	running []*promotions.Promotion
+}
+
+func (f *fixedPromotions) Apply(ctx context.Context, retailer int, basket promotions.Basket) ([][]*promotions.Discount, error) {
+	return promotions.Evaluate(basket, f.running)
+}
+
+func (f *fixedPromotions) PromotionByID(ctx context.Context, id int) (*promotions.Promotion, error) {
+	for _, promotion := range f.running {
+		if promotion.ID.ID == id {
+			return promotion, nil
+		}
+	}
+	return nil, promotions.ErrPromotionNotFound
+}
+
+func mustMoney(t *testing.T, amount string, currency money.Currency) money.Money {
+	t.Helper()
+	m, err := money.Parse(amount, currency)
+	require.NoError(t, err)
+	return m
+}
+
+func TestRecordSaleUnitPrice(t *testing.T) {
+	tests := []struct {
+		name  string
+		price money.Input
+		err   error
+	}{
+		{name: "sub cent", price: money.Input{Amount: "0.125", Currency: "NZD"}, err: money.ErrPrecision},
+		{name: "negative", price: money.Input{Amount: "-1", Currency: "NZD"}, err: ErrUnitPrice},
+		{name: "not a number", price: money.Input{Amount: "four", Currency: "NZD"}, err: money.ErrAmount},
+		{name: "other currency", price: money.Input{Amount: "4.50", Currency: "USD"}, err: money.ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := retailers.With(context.Background(), 1)
+			manager := SaleManager{Store: &recordingStore{}, Items: fixedItems{}, Prices: &fixedPrices{}, Promotions: &fixedPromotions{}, Taxes: &fixedTaxes{}}
+
+			_, err := manager.RecordSale(ctx, NewSale{
+				Retailer: external(t, "1"),
+				Currency: "NZD",
+				LineItems: []NewLineItem{
+					{Item: external(t, "2"), Quantity: "1", UnitPrice: &tt.price},
+				},
+			})
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestRecordSaleQuantity(t *testing.T) {
+	var grams, litres = items.Gram, items.Litre
+
+	tests := []struct {
+		name     string
+		quantity string
+		scale    *items.UnitScale
+		err      error
+	}{
+		{name: "zero", quantity: "0", err: ErrQuantity},
+		{name: "negative", quantity: "-1", err: ErrQuantity},
+		{name: "too many", quantity: "100001", err: ErrQuantity},
+		{name: "too many once converted", quantity: "100000001", scale: &grams, err: ErrQuantity},
+		{name: "fraction", quantity: "1/3", err: ErrQuantity},
+		{name: "exponent", quantity: "1e3", err: ErrQuantity},
+		{name: "empty", quantity: "", err: ErrQuantity},
+		{name: "finer than the ledger", quantity: "0.01", scale: &grams, err: ErrQuantityPlaces},
+		{name: "another dimension", quantity: "1", scale: &litres, err: items.ErrIncompatibleUnitScale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := retailers.With(context.Background(), 1)
+			manager := SaleManager{Store: &recordingStore{}, Items: fixedItems{}, Prices: &fixedPrices{}, Promotions: &fixedPromotions{}, Taxes: &fixedTaxes{}}
+
+			_, err := manager.RecordSale(ctx, NewSale{
+				Retailer: external(t, "1"),
+				Currency: "NZD",
+				LineItems: []NewLineItem{
+					{Item: external(t, "5"), Quantity: tt.quantity, UnitScale: tt.scale, UnitPrice: &money.Input{Amount: "4.50", Currency: "NZD"}},
+				},
+			})
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestRecordSaleMeasured(t *testing.T) {
+	var grams = items.Gram
+
+	ctx := retailers.With(context.Background(), 1)
+	store := &recordingStore{}
+	manager := SaleManager{Store: store, Items: fixedItems{}, Promotions: &fixedPromotions{}, Taxes: &fixedTaxes{}, Prices: &fixedPrices{
+		list:   &pricing.PriceList{ID: &keys.OpaqueID{ID: 9}, Currency: "NZD"},
+		quotes: map[int]money.Money{5: mustMoney(t, "30.10", "NZD")},
+	}}
+
+	// 250g of an item priced at 30.10 per kg is a quarter of a kg at 30.10, 7.525 rounded half to even
+	sale, err := manager.RecordSale(ctx, NewSale{
+		Retailer:  external(t, "1"),
+		Currency:  "NZD",
+		LineItems: []NewLineItem{{Item: external(t, "5"), Quantity: "250", UnitScale: &grams}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, sale, store.created)
+
+	line := sale.LineItems[0]
+	assert.Equal(t, "1/4", line.Quantity.RatString())
+	assert.Equal(t, items.Kilogram, line.UnitScale)
+	assert.Equal(t, "30.10", line.UnitPrice.Decimal())
+
+	total, err := line.Total()
+	require.NoError(t, err)
+	assert.Equal(t, "7.52", total.Decimal())
+
+	subtotal, err := sale.Subtotal()
+	require.NoError(t, err)
+	assert.Equal(t, "7.52", subtotal.Decimal())
+}
+
+func TestRecordSaleArchivedItem(t *testing.T) {
+	ctx := retailers.With(context.Background(), 1)
+	store := &recordingStore{}
+	manager := SaleManager{Store: store, Items: fixedItems{}, Prices: &fixedPrices{}, Promotions: &fixedPromotions{}, Taxes: &fixedTaxes{}}
+
+	input := NewSale{
+		Retailer:  external(t, "1"),
+		Currency:  "NZD",
+		LineItems: []NewLineItem{{Item: external(t, "7"), Quantity: "1", UnitPrice: &money.Input{Amount: "4.50", Currency: "NZD"}}},
+	}
+
+	// archived items can't be sold, nor previewed as if they could be
+	_, err := manager.RecordSale(ctx, input)
+	require.ErrorIs(t, err, ErrUnknownItem)
+	require.Nil(t, store.created)
+
+	_, err = manager.PreviewSale(ctx, input)
+	require.ErrorIs(t, err, ErrUnknownItem)
+}
+
+func TestRecordSaleRetailer(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		retailer *keys.OpaqueID
+		err      error
+	}{
+		{name: "scoped", ctx: retailers.With(context.Background(), 1)},
+		{name: "named", ctx: retailers.With(context.Background(), 1), retailer: external(t, "1")},
+		{name: "another retailer", ctx: retailers.With(context.Background(), 1), retailer: external(t, "2"), err: ErrRetailerNotFound},
+		{name: "unscoped", ctx: context.Background(), retailer: external(t, "1"), err: retailers.ErrNoRetailer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recordingStore{}
+			manager := SaleManager{Store: store, Items: fixedItems{}, Prices: &fixedPrices{}, Promotions: &fixedPromotions{}, Taxes: &fixedTaxes{}}
+
+			price := money.Input{Amount: "4.50", Currency: "NZD"}
+			_, err := manager.RecordSale(tt.ctx, NewSale{
+				Retailer:  tt.retailer,
+				Currency:  "NZD",
+				LineItems: []NewLineItem{{Item: external(t, "2"), Quantity: "1", UnitPrice: &price}},
+			})
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, store.created.RetailerID.ID)
+		})
+	}
+}
+
+func TestRecordSalePricing(t *testing.T) {
+	var (
+		listed   = mustMoney(t, "4.50", "NZD")
+		discount = mustMoney(t, "4.00", "NZD")
+		reason   = "damaged packaging"
+	)
+
+	tests := []struct {
+		name      string
+		item      string
+		price     *money.Input
+		reason    *string
+		unitPrice money.Money
+		override  *PriceOverride
+		err       error
+	}{
+		{name: "list price", item: "1", unitPrice: listed},
+		{name: "unpriced item", item: "2", err: ErrNoUnitPrice},
+		{name: "same as list price", item: "1", price: &money.Input{Amount: "4.5", Currency: "NZD"}, unitPrice: listed},
+		{name: "override without reason", item: "1", price: &money.Input{Amount: "4.00", Currency: "NZD"}, err: ErrOverrideReason},
+		{name: "override with blank reason", item: "1", price: &money.Input{Amount: "4.00", Currency: "NZD"}, reason: new(string), err: ErrOverrideReason},
+		{name: "override", item: "1", price: &money.Input{Amount: "4.00", Currency: "NZD"}, reason: &reason, unitPrice: discount, override: &PriceOverride{Actor: "till-1", Reason: &reason}},
+		{name: "priced by till", item: "2", price: &money.Input{Amount: "4.00", Currency: "NZD"}, unitPrice: discount, override: &PriceOverride{Actor: "till-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := actor.With(retailers.With(context.Background(), 1), "till-1")
+			store := &recordingStore{}
+			manager := SaleManager{Store: store, Items: fixedItems{}, Promotions: &fixedPromotions{}, Taxes: &fixedTaxes{}, Prices: &fixedPrices{
+				list:   &pricing.PriceList{ID: &keys.OpaqueID{ID: 9}, Currency: "NZD"},
+				quotes: map[int]money.Money{1: listed},
+			}}
+
+			_, err := manager.RecordSale(ctx, NewSale{
+				Retailer: external(t, "1"),
+				Currency: "NZD",
+				LineItems: []NewLineItem{
+					{Item: external(t, tt.item), Quantity: "2", UnitPrice: tt.price, OverrideReason: tt.reason},
+				},
+			})
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, 9, store.created.PriceListID.ID)
+			line := store.created.LineItems[0]
+			assert.Equal(t, tt.unitPrice, line.UnitPrice)
+			assert.Equal(t, tt.override, line.Override)
+		})
+	}
+}
+
+func TestSaleTotal(t *testing.T) {
+	var price = func(amount string, currency money.Currency) money.Money {
+		return mustMoney(t, amount, currency)
+	}
+
+	sale := &Sale{Currency: "NZD", LineItems: []*LineItem{
+		{Quantity: big.NewRat(3, 1), UnitPrice: price("1.10", "NZD")},
+		{Quantity: big.NewRat(1, 1), UnitPrice: price("4.50", "NZD")},
+	}}
+
+	total, err := sale.LineItems[0].Total()
+	require.NoError(t, err)
+	assert.Equal(t, "3.30", total.Decimal())
+	total, err = sale.Total()
+	require.NoError(t, err)
+	assert.Equal(t, "7.80 NZD", total.String())
+
+	total, err = (&Sale{Currency: "JPY"}).Total()
+	require.NoError(t, err)
+	assert.Equal(t, "0 JPY", total.String())
+
+	sale.LineItems = append(sale.LineItems, &LineItem{Quantity: big.NewRat(1, 1), UnitPrice: price("1.00", "USD")})
+	_, err = sale.Total()
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
+
+func TestRecordSaleTax(t *testing.T) {
+	var gst = &tax.Rate{ID: &keys.OpaqueID{ID: 3}, Class: items.DefaultTaxClass, Name: "GST", Rate: big.NewRat(15, 100)}
+
+	tests := []struct {
+		name      string
+		taxes     *fixedTaxes
+		inclusive bool
+		subtotal  string
+		tax       string
+		total     string
+	}{
+		{name: "untaxed", taxes: &fixedTaxes{}, subtotal: "9.00", tax: "0.00", total: "9.00"},
+		{name: "exclusive", taxes: &fixedTaxes{rate: gst}, subtotal: "9.00", tax: "1.35", total: "10.35"},
+		{name: "inclusive", taxes: &fixedTaxes{rate: gst, inclusive: true}, inclusive: true, subtotal: "9.00", tax: "1.17", total: "9.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := retailers.With(context.Background(), 1)
+			store := &recordingStore{}
+			manager := SaleManager{Store: store, Items: fixedItems{}, Promotions: &fixedPromotions{}, Taxes: tt.taxes, Prices: &fixedPrices{
+				list:   &pricing.PriceList{ID: &keys.OpaqueID{ID: 9}, Currency: "NZD"},
+				quotes: map[int]money.Money{1: mustMoney(t, "4.50", "NZD")},
+			}}
+
+			_, err := manager.RecordSale(ctx, NewSale{
+				Retailer:  external(t, "1"),
+				Currency:  "NZD",
+				LineItems: []NewLineItem{{Item: external(t, "1"), Quantity: "2"}},
+			})
+			require.NoError(t, err)
+
+			sale := store.created
+			assert.Equal(t, tt.inclusive, sale.PricesIncludeTax)
+			assert.NotNil(t, sale.Taxes)
+
+			subtotal, err := sale.Subtotal()
+			require.NoError(t, err)
+			assert.Equal(t, tt.subtotal, subtotal.Decimal())
+
+			charged, err := sale.Tax()
+			require.NoError(t, err)
+			assert.Equal(t, tt.tax, charged.Decimal())
+
+			total, err := sale.Total()
+			require.NoError(t, err)
+			assert.Equal(t, tt.total, total.Decimal())
+		})
+	}
+}
+
+func TestPreviewSaleDiscount(t *testing.T) {
+	var (
+		gst  = &tax.Rate{ID: &keys.OpaqueID{ID: 3}, Class: items.DefaultTaxClass, Name: "GST", Rate: big.NewRat(15, 100)}
+		half = &promotions.Promotion{
+			ID: &keys.OpaqueID{ID: 7}, Name: "half price", Kind: promotions.PercentOff, Percent: big.NewRat(1, 2),
+			StartsAt: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		}
+	)
+
+	ctx := retailers.With(context.Background(), 1)
+	store := &recordingStore{}
+	manager := SaleManager{
+		Store:      store,
+		Items:      fixedItems{},
+		Promotions: &fixedPromotions{running: []*promotions.Promotion{half}},
+		Taxes:      &fixedTaxes{rate: gst},
+		Prices: &fixedPrices{
+			list:   &pricing.PriceList{ID: &keys.OpaqueID{ID: 9}, Currency: "NZD"},
+			quotes: map[int]money.Money{1: mustMoney(t, "4.50", "NZD")},
+		},
+	}
+
+	sale, err := manager.PreviewSale(ctx, NewSale{
+		Retailer:  external(t, "1"),
+		Currency:  "NZD",
+		LineItems: []NewLineItem{{Item: external(t, "1"), Quantity: "2"}},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, store.created, "previews mustn't be recorded")
+
+	line := sale.LineItems[0]
+	require.Len(t, line.Discounts, 1)
+	assert.Equal(t, "half price", line.Discounts[0].Name)
+
+	promotion, err := manager.DiscountPromotion(ctx, line.Discounts[0])
+	require.NoError(t, err)
+	assert.Equal(t, half, promotion)
+
+	discount, err := sale.Discount()
+	require.NoError(t, err)
+	assert.Equal(t, "4.50", discount.Decimal())
+
+	// tax is charged on what the customer pays after discounts
+	charged, err := sale.Tax()
+	require.NoError(t, err)
+	assert.Equal(t, "0.68", charged.Decimal())
+
+	total, err := sale.Total()
+	require.NoError(t, err)
+	assert.Equal(t, "5.18", total.Decimal())
+}
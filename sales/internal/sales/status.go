@@ -0,0 +1,36 @@
+package sales
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Status is where a sale is in its life. Sales are OPEN until paid in full, PAID sales become
+// REFUNDED once every unit sold is refunded. OPEN and PAID sales can be VOIDED shortly after they
+// were made.
+type Status string
+
+const (
+	Open     Status = "OPEN"
+	Paid     Status = "PAID"
+	Refunded Status = "REFUNDED"
+	Voided   Status = "VOIDED"
+)
+
+var transitions = map[Status][]Status{
+	Open: {Paid, Voided},
+	Paid: {Refunded, Voided},
+}
+
+// ErrSaleStatus is returned for changes to a sale that its status doesn't allow, ie refunding a
+// sale that was never paid.
+var ErrSaleStatus = errors.New("sale status does not allow this")
+
+// to checks that the sale can move from the status to the next, staying put is always allowed.
+func (s Status) to(next Status) error {
+	if s == next || slices.Contains(transitions[s], next) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s sale cannot become %s", ErrSaleStatus, s, next)
+}
@@ -2,9 +2,12 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/require"
 )
@@ -24,14 +27,16 @@ func TestCyclesDisallowedOnItemRelationships(t *testing.T) {
 	require.NoError(t, err)
 	defer conn.Close()
 
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
 	var (
 		first  int
 		second int
 	)
-	err = conn.QueryRow(ctx, `INSERT INTO items (name) VALUES ('some_item') RETURNING id`).Scan(&first)
+	err = conn.QueryRow(ctx, `INSERT INTO items (retailer_id, name) VALUES ($1, 'some_item') RETURNING id`, retailer).Scan(&first)
 	require.NoError(t, err)
 
-	err = conn.QueryRow(ctx, `INSERT INTO items (name) VALUES ('another_item') RETURNING id`).Scan(&second)
+	err = conn.QueryRow(ctx, `INSERT INTO items (retailer_id, name) VALUES ($1, 'another_item') RETURNING id`, retailer).Scan(&second)
 	require.NoError(t, err)
 
 	_, err = conn.Exec(ctx, `INSERT INTO item_relationships (parent_id, child_id) VALUES ($1, $2)`, first, second)
@@ -41,3 +46,236 @@ func TestCyclesDisallowedOnItemRelationships(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "cyclical relationship detected")
 }
+
+func TestItemHistoryAppendOnly(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	var item int
+	err = conn.QueryRow(ctx, `INSERT INTO items (retailer_id, name) VALUES ($1, 'some_item') RETURNING id`, retailer).Scan(&item)
+	require.NoError(t, err)
+
+	var change int
+	err = conn.QueryRow(ctx, `INSERT INTO item_history (item_id, actor, action) VALUES ($1, 'someone', 'CREATED') RETURNING id`, item).Scan(&change)
+	require.NoError(t, err)
+
+	_, err = conn.Exec(ctx, `UPDATE item_history SET actor = 'someone_else' WHERE id = $1`, change)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "item history is append only")
+
+	_, err = conn.Exec(ctx, `DELETE FROM item_history WHERE id = $1`, change)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "item history is append only")
+}
+
+func TestSalesNeverDeleted(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	var sale int
+	err = conn.QueryRow(ctx, `INSERT INTO sales (retailer_id, currency) VALUES ($1, 'NZD') RETURNING id`, retailer).Scan(&sale)
+	require.NoError(t, err)
+
+	_, err = conn.Exec(ctx, `DELETE FROM sales WHERE id = $1`, sale)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "sales cannot be deleted")
+
+	_, err = conn.Exec(ctx, `DELETE FROM retailers WHERE id = $1`, retailer)
+	require.Error(t, err)
+}
+
+func TestRowsHiddenFromOtherRetailers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	mine, retailer := scope(t, ctx, conn, "mine.test")
+	theirs, other := scope(t, ctx, conn, "theirs.test")
+
+	var insert = func(ctx context.Context, retailer int) {
+		var parent, child, sale, line, location, supplier, order, category, list, rate, promotion, refund int
+		err := conn.QueryRow(ctx, `INSERT INTO items (retailer_id, name) VALUES ($1, 'parent') RETURNING id`, retailer).Scan(&parent)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO items (retailer_id, name) VALUES ($1, 'child') RETURNING id`, retailer).Scan(&child)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO item_relationships (parent_id, child_id) VALUES ($1, $2)`, parent, child)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO item_images (retailer_id, item_id, blob_key, content_type, width, height, size, checksum, position) VALUES ($1, $2, $3, 'image/png', 1, 1, 1, $4, 0)`,
+			retailer, parent, fmt.Sprintf("images/%d.png", parent), strings.Repeat("0", 64))
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO sales (retailer_id, currency) VALUES ($1, 'NZD') RETURNING id`, retailer).Scan(&sale)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO line_items (sale_id, product_id, quantity, unit_price) VALUES ($1, $2, 1, 1) RETURNING id`, sale, parent).Scan(&line)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO customers (retailer_id, name) VALUES ($1, 'someone')`, retailer)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO stock_locations (retailer_id, name) VALUES ($1, 'shop') RETURNING id`, retailer).Scan(&location)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO stock_movements (retailer_id, item_id, location_id, quantity, reason, actor) VALUES ($1, $2, $3, 1, 'RECEIPT', 'test')`, retailer, child, location)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO reorder_points (item_id, location_id, retailer_id, threshold, cover_days) VALUES ($1, $2, $3, 5, 14)`, child, location, retailer)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO stock_alerts (retailer_id, item_id, location_id, on_hand, threshold) VALUES ($1, $2, $3, 1, 5)`, retailer, child, location)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO suppliers (retailer_id, name, currency) VALUES ($1, 'wholesaler', 'NZD') RETURNING id`, retailer).Scan(&supplier)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO supplier_items (supplier_id, item_id, retailer_id, cost) VALUES ($1, $2, $3, 1)`, supplier, child, retailer)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO purchase_orders (retailer_id, supplier_id, location_id, currency, created_by) VALUES ($1, $2, $3, 'NZD', 'test') RETURNING id`, retailer, supplier, location).Scan(&order)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO purchase_order_lines (purchase_order_id, retailer_id, item_id, quantity, unit_cost) VALUES ($1, $2, $3, 1, 1)`, order, retailer, child)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO item_history (item_id, actor, action) VALUES ($1, 'test', 'CREATED')`, parent)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO categories (retailer_id, name) VALUES ($1, 'drinks') RETURNING id`, retailer).Scan(&category)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO item_categories (retailer_id, item_id, category_id) VALUES ($1, $2, $3)`, retailer, parent, category)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO item_tags (item_id, tag) VALUES ($1, 'hot')`, parent)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO price_lists (retailer_id, name, kind, currency) VALUES ($1, 'retail', 'RETAIL', 'NZD') RETURNING id`, retailer).Scan(&list)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO item_prices (price_list_id, item_id, amount, unit_scale, effective_from) VALUES ($1, $2, 1, 'unit', now())`, list, parent)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO tax_rates (retailer_id, jurisdiction, tax_class, name, rate, effective_from) VALUES ($1, 'NZ', 'STANDARD', 'GST', 0.15, now()) RETURNING id`, retailer).Scan(&rate)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO tax_settings (retailer_id, jurisdiction, prices_include_tax, rounding) VALUES ($1, 'NZ', true, 'LINE')`, retailer)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO sale_taxes (sale_id, tax_rate_id, name, tax_class, rate, taxable, amount) VALUES ($1, $2, 'GST', 'STANDARD', 0.15, 1, 0.13)`, sale, rate)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO promotions (retailer_id, name, kind, percent, starts_at) VALUES ($1, 'sale', 'PERCENT_OFF', 0.1, now()) RETURNING id`, retailer).Scan(&promotion)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO line_item_discounts (line_item_id, promotion_id, name, amount) VALUES ($1, $2, 'sale', 0.1)`, line, promotion)
+		require.NoError(t, err)
+		err = conn.QueryRow(ctx, `INSERT INTO refunds (sale_id, reason, actor, refunded_at) VALUES ($1, 'UNWANTED', 'test', now()) RETURNING id`, sale).Scan(&refund)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO refund_lines (refund_id, line_item_id, quantity, amount) VALUES ($1, $2, 1, 1)`, refund, line)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO payments (sale_id, tender, amount, reference, actor, paid_at) VALUES ($1, 'CARD', 1, 'ch_1', 'test', now())`, sale)
+		require.NoError(t, err)
+	}
+	insert(mine, retailer)
+	insert(theirs, other)
+
+	// none of these queries remember to filter by retailer
+	var count = func(ctx context.Context, query string) int {
+		rows, err := conn.Query(ctx, query)
+		require.NoError(t, err)
+		counted, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		require.NoError(t, err)
+		return len(counted)
+	}
+	for query, each := range map[string]int{
+		`SELECT id FROM items`:                     2,
+		`SELECT parent_id FROM item_relationships`: 1,
+		`SELECT id FROM item_images`:               1,
+		`SELECT id FROM sales`:                     1,
+		`SELECT id FROM line_items`:                1,
+		`SELECT id FROM customers`:                 1,
+		`SELECT id FROM stock_locations`:           1,
+		`SELECT id FROM stock_movements`:           1,
+		`SELECT item_id FROM stock_levels`:         1,
+		`SELECT item_id FROM reorder_points`:       1,
+		`SELECT id FROM stock_alerts`:              1,
+		`SELECT id FROM suppliers`:                 1,
+		`SELECT item_id FROM supplier_items`:       1,
+		`SELECT id FROM purchase_orders`:           1,
+		`SELECT id FROM purchase_order_lines`:      1,
+		`SELECT id FROM item_history`:              1,
+		`SELECT id FROM categories`:                1,
+		`SELECT item_id FROM item_categories`:      1,
+		`SELECT item_id FROM item_tags`:            1,
+		`SELECT id FROM price_lists`:               1,
+		`SELECT id FROM item_prices`:               1,
+		`SELECT id FROM tax_rates`:                 1,
+		`SELECT retailer_id FROM tax_settings`:     1,
+		`SELECT id FROM sale_taxes`:                1,
+		`SELECT id FROM promotions`:                1,
+		`SELECT id FROM line_item_discounts`:       1,
+		`SELECT id FROM refunds`:                   1,
+		`SELECT id FROM refund_lines`:              1,
+		`SELECT id FROM payments`:                  1,
+		`SELECT retailer_id FROM retailer_domains`: 1,
+	} {
+		require.Equal(t, each, count(mine, query), query)
+		require.Equal(t, each, count(theirs, query), query)
+		require.Equal(t, 2*each, count(ctx, query), "unscoped %s", query)
+	}
+
+	// rows can't be written for another retailer either
+	_, err = conn.Exec(mine, `INSERT INTO items (retailer_id, name) VALUES ($1, 'planted')`, other)
+	require.Error(t, err)
+
+	tag, err := conn.Exec(mine, `UPDATE items SET name = 'renamed'`)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, tag.RowsAffected())
+}
+
+func TestTenantEndsWithTransaction(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	scoped, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	var (
+		setting string
+		role    string
+	)
+	require.NoError(t, conn.QueryRow(scoped, `SELECT current_setting('app.retailer_id', true), current_user`).Scan(&setting, &role))
+	require.Equal(t, fmt.Sprint(retailer), setting)
+	require.Equal(t, tenantRole, role)
+
+	// whichever connection serves the next statement, it carries nothing of the last
+	for i := 0; i < 10; i++ {
+		require.NoError(t, conn.QueryRow(ctx, `SELECT COALESCE(current_setting('app.retailer_id', true), ''), current_user`).Scan(&setting, &role))
+		require.Empty(t, setting)
+		require.NotEqual(t, tenantRole, role)
+	}
+}
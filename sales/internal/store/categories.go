@@ -0,0 +1,313 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// CreateCategory adds a category of the retailer the context is scoped to, a category is only ever
+// the parent of categories of its own retailer.
+func (i *Items) CreateCategory(ctx context.Context, name string, parent *keys.OpaqueID) (*items.Category, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var parentID *int
+	if parent != nil {
+		parentID = &parent.ID
+	}
+
+	var assigned int
+	err = i.Conn.QueryRow(ctx, `INSERT INTO categories (retailer_id, name, parent_id) VALUES ($1, $2, $3) RETURNING id`, retailer, name, parentID).Scan(&assigned)
+	if isUniqueViolation(err) {
+		return nil, items.ErrDuplicateCategory
+	} else if isForeignKeyViolation(err) {
+		return nil, items.ErrCategoryNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to insert into categories: %w", err)
+	}
+
+	category := &items.Category{ID: &keys.OpaqueID{ID: assigned}, Name: name}
+	if parent != nil {
+		category.ParentID = &keys.OpaqueID{ID: parent.ID}
+	}
+	return category, nil
+}
+
+func (i *Items) UpdateCategory(ctx context.Context, id int, name string, parent *keys.OpaqueID) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	var parentID *int
+	if parent != nil {
+		parentID = &parent.ID
+	}
+
+	tag, err := i.Conn.Exec(ctx, `UPDATE categories SET name = $1, parent_id = $2 WHERE id = $3 AND retailer_id = $4`, name, parentID, id, retailer)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Message == "cyclical category detected" {
+		return items.ErrCyclicCategory
+	} else if isUniqueViolation(err) {
+		return items.ErrDuplicateCategory
+	} else if isForeignKeyViolation(err) {
+		return items.ErrCategoryNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to update categories: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return items.ErrCategoryNotFound
+	}
+	return nil
+}
+
+func (i *Items) DeleteCategory(ctx context.Context, id int) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := i.Conn.Exec(ctx, `DELETE FROM categories WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	var pgErr *pgconn.PgError
+	if isForeignKeyViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "promotions_category_id_fkey" {
+		return items.ErrCategoryPromoted
+	} else if isForeignKeyViolation(err) {
+		return items.ErrCategoryNotEmpty
+	} else if err != nil {
+		return fmt.Errorf("failed to delete from categories: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return items.ErrCategoryNotFound
+	}
+	return nil
+}
+
+func (i *Items) GetCategory(ctx context.Context, id int) (*items.Category, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT id, name, parent_id FROM categories WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from categories: %w", err)
+	}
+
+	categories, err := scanCategories(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) == 0 {
+		return nil, items.ErrCategoryNotFound
+	}
+	return categories[0], nil
+}
+
+func (i *Items) GetCategories(ctx context.Context, parent *keys.OpaqueID) ([]*items.Category, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var parentID *int
+	if parent != nil {
+		parentID = &parent.ID
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT id, name, parent_id FROM categories WHERE retailer_id = $1 AND parent_id IS NOT DISTINCT FROM $2 ORDER BY name`, retailer, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from categories: %w", err)
+	}
+	return scanCategories(rows)
+}
+
+func (i *Items) GetItemCategories(ctx context.Context, item int) ([]*items.Category, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT c.id, c.name, c.parent_id FROM item_categories ic JOIN categories c ON c.id = ic.category_id
+		WHERE ic.item_id = $1 AND ic.retailer_id = $2 ORDER BY c.name`, item, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select categories of item from item_categories: %w", err)
+	}
+	return scanCategories(rows)
+}
+
+func scanCategories(rows pgx.Rows) ([]*items.Category, error) {
+	defer rows.Close()
+
+	var results []*items.Category
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			name     string
+			parentID *int
+		)
+		if err := rows.Scan(&id, &name, &parentID); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from categories: %w", err)
+		}
+
+		category := &items.Category{ID: &id, Name: name}
+		if parentID != nil {
+			category.ParentID = &keys.OpaqueID{ID: *parentID}
+		}
+		results = append(results, category)
+	}
+
+	return results, rows.Err()
+}
+
+// CategoryReport aggregates over the subtree of the category, an item in several categories of the
+// subtree is only counted once. Only the items and sales of the retailer are reported on.
+func (i *Items) CategoryReport(ctx context.Context, id int, from, to *time.Time) (*items.CategoryReport, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	const subtree = `WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $1 AND retailer_id = $4
+
+			UNION ALL
+
+			SELECT c.id FROM categories c
+			JOIN subtree s ON c.parent_id = s.id
+		), members AS (
+			SELECT DISTINCT ic.item_id FROM item_categories ic
+			JOIN items i ON i.id = ic.item_id
+			WHERE ic.category_id IN (SELECT id FROM subtree) AND i.retailer_id = $4
+		), refunded AS (
+			SELECT line_item_id, SUM(quantity) AS quantity FROM refund_lines GROUP BY line_item_id
+		)`
+
+	var report = items.CategoryReport{Revenue: []money.Money{}}
+	err = i.Conn.QueryRow(ctx, subtree+`
+		SELECT
+			(SELECT COUNT(*) - 1 FROM subtree),
+			(SELECT COUNT(*) FROM members m JOIN items i ON i.id = m.item_id WHERE i.archived_at IS NULL),
+			COALESCE(SUM(l.quantity - COALESCE(r.quantity, 0)) FILTER (WHERE l.unit_scale = $5), 0)::INTEGER
+		FROM line_items l
+		JOIN sales s ON s.id = l.sale_id
+		LEFT JOIN refunded r ON r.line_item_id = l.id
+		WHERE l.product_id IN (SELECT item_id FROM members) AND s.retailer_id = $4 AND s.voided_at IS NULL
+		AND ($2::timestamp IS NULL OR s.sale_date >= $2)
+		AND ($3::timestamp IS NULL OR s.sale_date < $3)`, id, from, to, retailer, items.Unit).Scan(&report.Categories, &report.Items, &report.UnitsSold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate category from categories: %w", err)
+	}
+
+	// revenue is never summed across currencies
+	rows, err := i.Conn.Query(ctx, subtree+`
+		SELECT s.currency, SUM((l.quantity - COALESCE(r.quantity, 0)) * l.unit_price)
+		FROM line_items l
+		JOIN sales s ON s.id = l.sale_id
+		LEFT JOIN refunded r ON r.line_item_id = l.id
+		WHERE l.product_id IN (SELECT item_id FROM members) AND s.retailer_id = $4 AND s.voided_at IS NULL
+		AND ($2::timestamp IS NULL OR s.sale_date >= $2)
+		AND ($3::timestamp IS NULL OR s.sale_date < $3)
+		GROUP BY s.currency
+		ORDER BY s.currency`, id, from, to, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate revenue of category from categories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			currency money.Currency
+			revenue  money.Decimal
+		)
+		if err := rows.Scan(&currency, &revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when aggregating revenue of category: %w", err)
+		}
+
+		// weighed and measured line items make for revenue finer than the minor unit
+		amount, err := money.Round(revenue.Rat(), currency, money.HalfEven)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read revenue of category: %w", err)
+		}
+		report.Revenue = append(report.Revenue, amount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result when aggregating revenue of category: %w", err)
+	}
+
+	return &report, nil
+}
+
+func (i *Items) CategoriseItem(ctx context.Context, item int, category int) (bool, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := i.Conn.Exec(ctx, `INSERT INTO item_categories (retailer_id, item_id, category_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, retailer, item, category)
+	var pgErr *pgconn.PgError
+	if isForeignKeyViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "item_categories_category_id_fkey" {
+		return false, items.ErrCategoryNotFound
+	} else if isForeignKeyViolation(err) {
+		return false, items.ErrItemNotFound
+	} else if err != nil {
+		return false, fmt.Errorf("failed to insert into item_categories: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (i *Items) UncategoriseItem(ctx context.Context, item int, category int) (bool, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := i.Conn.Exec(ctx, `DELETE FROM item_categories WHERE item_id = $1 AND category_id = $2 AND retailer_id = $3`, item, category, retailer)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete from item_categories: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (i *Items) GetTags(ctx context.Context, item int) ([]string, error) {
+	rows, err := i.Conn.Query(ctx, `SELECT tag FROM item_tags WHERE item_id = $1 ORDER BY tag`, item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from item_tags: %w", err)
+	}
+
+	tags, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan from rows result when selecting from item_tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (i *Items) TagItem(ctx context.Context, item int, tag string) (bool, error) {
+	result, err := i.Conn.Exec(ctx, `INSERT INTO item_tags (item_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING`, item, tag)
+	if isForeignKeyViolation(err) {
+		return false, items.ErrItemNotFound
+	} else if err != nil {
+		return false, fmt.Errorf("failed to insert into item_tags: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (i *Items) UntagItem(ctx context.Context, item int, tag string) (bool, error) {
+	result, err := i.Conn.Exec(ctx, `DELETE FROM item_tags WHERE item_id = $1 AND tag = $2`, item, tag)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete from item_tags: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/customers"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+type Customers struct {
+	Conn *Pool
+}
+
+const customerColumns = `id, retailer_id, name, email, phone, created_at, updated_at`
+
+func (c *Customers) CreateCustomer(ctx context.Context, customer *customers.Customer) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	var assigned int
+	err = c.Conn.QueryRow(ctx, `INSERT INTO customers (retailer_id, name, email, phone) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`,
+		retailer, customer.Name, customer.Email, customer.Phone).Scan(&assigned, &customer.CreatedAt, &customer.UpdatedAt)
+	if duplicate := duplicateContact(err); duplicate != nil {
+		return duplicate
+	} else if isForeignKeyViolation(err) {
+		return retailers.ErrRetailerNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into customers: %w", err)
+	}
+
+	customer.ID, customer.RetailerID = &keys.OpaqueID{ID: assigned}, &keys.OpaqueID{ID: retailer}
+	return nil
+}
+
+func (c *Customers) GetCustomer(ctx context.Context, id int) (*customers.Customer, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.Conn.Query(ctx, `SELECT `+customerColumns+` FROM customers WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from customers: %w", err)
+	}
+
+	found, err := scanCustomers(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, customers.ErrCustomerNotFound
+	}
+	return found[0], nil
+}
+
+func (c *Customers) PageCustomers(ctx context.Context, filter customers.Filter, page paginate.Paginate) ([]*customers.Customer, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		conditions = []string{"retailer_id = $1"}
+		args       = []any{retailer}
+	)
+	var condition = func(format string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if page.Cursor != nil {
+		condition("id > $%d", page.Cursor)
+	}
+	if filter.Email != nil {
+		condition("email = $%d", *filter.Email)
+	}
+	if filter.Phone != nil {
+		condition("phone = $%d", *filter.Phone)
+	}
+	args = append(args, page.Limit)
+
+	rows, err := c.Conn.Query(ctx, fmt.Sprintf(`SELECT `+customerColumns+` FROM customers WHERE %s ORDER BY id LIMIT $%d`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a page from customers: %w", err)
+	}
+	return scanCustomers(rows)
+}
+
+func (c *Customers) UpdateCustomer(ctx context.Context, customer *customers.Customer) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.Conn.QueryRow(ctx, `UPDATE customers SET name = $3, email = $4, phone = $5, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND retailer_id = $2 RETURNING updated_at`,
+		customer.ID.ID, retailer, customer.Name, customer.Email, customer.Phone).Scan(&customer.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customers.ErrCustomerNotFound
+	} else if duplicate := duplicateContact(err); duplicate != nil {
+		return duplicate
+	} else if err != nil {
+		return fmt.Errorf("failed to update customers: %w", err)
+	}
+	return nil
+}
+
+// EraseCustomer turns the sales of the customer into walk-in sales before deleting them, sales are
+// never deleted.
+func (c *Customers) EraseCustomer(ctx context.Context, id int) (int, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	tx, err := c.Conn.Begin(ctx)
+	if err != nil {
+		return -1, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// sales can't be recorded against the customer while they're being erased
+	var locked int
+	err = tx.QueryRow(ctx, `SELECT id FROM customers WHERE id = $1 AND retailer_id = $2 FOR UPDATE`, id, retailer).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return -1, customers.ErrCustomerNotFound
+	} else if err != nil {
+		return -1, fmt.Errorf("failed to lock customer of customers: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE sales SET customer_id = NULL WHERE customer_id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return -1, fmt.Errorf("failed to update sales: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM customers WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return -1, fmt.Errorf("failed to delete from customers: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return -1, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// duplicateContact tells which contact detail of the customer is already another customer's.
+func duplicateContact(err error) error {
+	var pgErr *pgconn.PgError
+	if !isUniqueViolation(err) || !errors.As(err, &pgErr) {
+		return nil
+	}
+	switch pgErr.ConstraintName {
+	case "customers_email_key":
+		return customers.ErrDuplicateEmail
+	case "customers_phone_key":
+		return customers.ErrDuplicatePhone
+	}
+	return nil
+}
+
+func scanCustomers(rows pgx.Rows) ([]*customers.Customer, error) {
+	defer rows.Close()
+
+	var results []*customers.Customer
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			retailer keys.OpaqueID
+			customer customers.Customer
+		)
+		if err := rows.Scan(&id, &retailer, &customer.Name, &customer.Email, &customer.Phone, &customer.CreatedAt, &customer.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from customers: %w", err)
+		}
+		customer.ID, customer.RetailerID = &id, &retailer
+		results = append(results, &customer)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/customers"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestCustomers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, _ = scope(t, ctx, conn, "pedlar.test")
+	theirs, _ := scope(t, context.Background(), conn, "theirs.test")
+
+	store := &Customers{Conn: conn}
+
+	var (
+		email = "ada@example.com"
+		phone = "+64211234567"
+	)
+	ada := &customers.Customer{Name: "Ada", Email: &email, Phone: &phone}
+	require.NoError(t, store.CreateCustomer(ctx, ada))
+	require.NotNil(t, ada.ID)
+
+	require.ErrorIs(t, store.CreateCustomer(ctx, &customers.Customer{Name: "Imposter", Email: &email}), customers.ErrDuplicateEmail)
+	require.ErrorIs(t, store.CreateCustomer(ctx, &customers.Customer{Name: "Imposter", Phone: &phone}), customers.ErrDuplicatePhone)
+	// contact details are only unique within a retailer
+	require.NoError(t, store.CreateCustomer(theirs, &customers.Customer{Name: "Ada", Email: &email, Phone: &phone}))
+
+	walkIn := &customers.Customer{Name: "Grace"}
+	require.NoError(t, store.CreateCustomer(ctx, walkIn))
+
+	found, err := store.PageCustomers(ctx, customers.Filter{Email: &email}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, ada.ID.ID, found[0].ID.ID)
+
+	found, err = store.PageCustomers(ctx, customers.Filter{}, paginate.Paginate{Cursor: ada.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, walkIn.ID.ID, found[0].ID.ID)
+
+	_, err = store.GetCustomer(theirs, ada.ID.ID)
+	require.ErrorIs(t, err, customers.ErrCustomerNotFound)
+
+	walkIn.Email = &email
+	require.ErrorIs(t, store.UpdateCustomer(ctx, walkIn), customers.ErrDuplicateEmail)
+	walkIn.Email = nil
+	walkIn.Name = "Grace Hopper"
+	require.NoError(t, store.UpdateCustomer(ctx, walkIn))
+
+	got, err := store.GetCustomer(ctx, walkIn.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Grace Hopper", got.Name)
+	require.Nil(t, got.Email)
+
+	_, err = store.EraseCustomer(theirs, ada.ID.ID)
+	require.ErrorIs(t, err, customers.ErrCustomerNotFound)
+}
+
+func TestCustomersErasedKeepSales(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+	theirs, _ := scope(t, context.Background(), conn, "theirs.test")
+
+	coffee, err := (&Items{Conn: conn}).CreateItem(ctx, items.Details{Name: "flat white", UnitScale: items.Unit})
+	require.NoError(t, err)
+
+	store := &Customers{Conn: conn}
+	ada := &customers.Customer{Name: "Ada"}
+	require.NoError(t, store.CreateCustomer(ctx, ada))
+	stranger := &customers.Customer{Name: "Stranger"}
+	require.NoError(t, store.CreateCustomer(theirs, stranger))
+
+	price, err := money.Parse("4.50", "NZD")
+	require.NoError(t, err)
+
+	salesStore := &Sales{Conn: conn}
+	var sale = func(customer int) *sales.Sale {
+		return &sales.Sale{
+			RetailerID: &keys.OpaqueID{ID: retailer},
+			CustomerID: &keys.OpaqueID{ID: customer},
+			SaleDate:   time.Now(),
+			Currency:   "NZD",
+			Status:     sales.Paid,
+			LineItems: []*sales.LineItem{
+				{ItemID: &keys.OpaqueID{ID: coffee.ID.ID}, Quantity: big.NewRat(1, 1), UnitScale: items.Unit, UnitPrice: price},
+			},
+		}
+	}
+
+	sold := sale(ada.ID.ID)
+	require.NoError(t, salesStore.CreateSale(ctx, sold))
+	require.NoError(t, salesStore.CreateSale(ctx, sale(ada.ID.ID)))
+	// customers of other retailers can't be sold to
+	require.ErrorIs(t, salesStore.CreateSale(ctx, sale(stranger.ID.ID)), sales.ErrCustomerNotFound)
+
+	erased, err := store.EraseCustomer(ctx, ada.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, erased)
+
+	_, err = store.GetCustomer(ctx, ada.ID.ID)
+	require.ErrorIs(t, err, customers.ErrCustomerNotFound)
+
+	kept, err := salesStore.GetSale(ctx, sold.ID.ID)
+	require.NoError(t, err)
+	require.Nil(t, kept.CustomerID)
+
+	require.ErrorIs(t, salesStore.CreateSale(ctx, sale(ada.ID.ID)), sales.ErrCustomerNotFound)
+}
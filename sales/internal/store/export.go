@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+)
+
+// exportBatch is the number of items fetched from the cursor at a time.
+const exportBatch = 500
+
+// StreamItems reads items through a server side cursor in batches so that exports of large
+// catalogues don't load every item into memory.
+func (i *Items) StreamItems(ctx context.Context, fn func(*items.CatalogueItem) error) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := i.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DECLARE catalogue NO SCROLL CURSOR FOR
+		SELECT i.id, i.name, COALESCE(i.description, ''), i.unit_scale, i.sku,
+			ARRAY(SELECT b.code FROM item_barcodes b WHERE b.item_id = i.id AND b.variant_id IS NULL ORDER BY b.code),
+			ARRAY(SELECT r.parent_id FROM item_relationships r WHERE r.child_id = i.id ORDER BY r.parent_id),
+			ARRAY(SELECT p.sku FROM item_relationships r JOIN items p ON p.id = r.parent_id WHERE r.child_id = i.id AND p.sku IS NOT NULL ORDER BY p.id),
+			ARRAY(SELECT r.child_id FROM item_relationships r WHERE r.parent_id = i.id ORDER BY r.child_id),
+			COALESCE((
+				SELECT jsonb_agg(jsonb_build_object('id', v.id, 'options', v.options, 'sku', v.sku, 'barcode', b.code, 'price_override', v.price_override::text, 'price_override_currency', v.price_override_currency) ORDER BY v.id)
+				FROM item_variants v LEFT JOIN item_barcodes b ON b.variant_id = v.id
+				WHERE v.item_id = i.id
+			), '[]')
+		FROM items i
+		WHERE i.retailer_id = $1 AND i.archived_at IS NULL
+		ORDER BY i.id`, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to declare cursor over items: %w", err)
+	}
+
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf(`FETCH FORWARD %d FROM catalogue`, exportBatch))
+		if err != nil {
+			return fmt.Errorf("failed to fetch from cursor over items: %w", err)
+		}
+
+		var fetched int
+		for rows.Next() {
+			fetched++
+
+			var item items.CatalogueItem
+			err := rows.Scan(&item.ID, &item.Name, &item.Description, &item.UnitScale, &item.SKU,
+				&item.Barcodes, &item.ParentIDs, &item.ParentSKUs, &item.ChildIDs, &item.Variants)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan from rows result when fetching from cursor over items: %w", err)
+			}
+
+			if err := fn(&item); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to fetch from cursor over items: %w", err)
+		}
+
+		if fetched < exportBatch {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+type ItemHistory struct {
+	Conn *Pool
+}
+
+// Record makes the write and appends the changes it describes to the history of their items in a
+// single transaction, the write joins it through the context it is handed.
+func (h *ItemHistory) Record(ctx context.Context, write func(ctx context.Context) ([]items.Change, error)) error {
+	tx, err := h.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	changes, err := write(within(ctx, tx))
+	if err != nil {
+		return err
+	}
+
+	if err := recordChanges(ctx, tx, changes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// recordChanges appends the changes in one batch, row level security leaves COPY out.
+func recordChanges(ctx context.Context, tx pgx.Tx, changes []items.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var batch = &pgx.Batch{}
+	for _, change := range changes {
+		var related *int
+		if change.Related != nil {
+			related = &change.Related.ID
+		}
+
+		diff := change.Diff
+		if diff == nil {
+			diff = []items.FieldChange{}
+		}
+
+		batch.Queue(`INSERT INTO item_history (item_id, related_id, actor, action, diff) VALUES ($1, $2, $3, $4, $5)`,
+			change.ItemID.ID, related, change.Actor, change.Action, diff)
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert into item_history: %w", err)
+	}
+	return nil
+}
+
+// PageItemHistory pages through the changes made to the item of the retailer.
+func (h *ItemHistory) PageItemHistory(ctx context.Context, id int, page paginate.Paginate) ([]*items.Change, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows pgx.Rows
+	if page.Cursor == nil {
+		rows, err = h.Conn.Query(ctx, `SELECT id, item_id, related_id, actor, action, diff, recorded_at FROM item_history WHERE item_id = $1 AND item_id IN (SELECT id FROM items WHERE retailer_id = $2) ORDER BY id DESC LIMIT $3`, id, retailer, page.Limit)
+	} else {
+		rows, err = h.Conn.Query(ctx, `SELECT id, item_id, related_id, actor, action, diff, recorded_at FROM item_history WHERE item_id = $1 AND item_id IN (SELECT id FROM items WHERE retailer_id = $2) AND id < $3 ORDER BY id DESC LIMIT $4`, id, retailer, page.Cursor, page.Limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a page from item_history: %w", err)
+	}
+
+	return scanChanges(rows, page.Limit)
+}
+
+// PageActorHistory pages through the changes the actor made to items of the retailer.
+func (h *ItemHistory) PageActorHistory(ctx context.Context, actor actor.Actor, page paginate.Paginate) ([]*items.Change, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows pgx.Rows
+	if page.Cursor == nil {
+		rows, err = h.Conn.Query(ctx, `SELECT id, item_id, related_id, actor, action, diff, recorded_at FROM item_history WHERE actor = $1 AND item_id IN (SELECT id FROM items WHERE retailer_id = $2) ORDER BY id DESC LIMIT $3`, actor, retailer, page.Limit)
+	} else {
+		rows, err = h.Conn.Query(ctx, `SELECT id, item_id, related_id, actor, action, diff, recorded_at FROM item_history WHERE actor = $1 AND item_id IN (SELECT id FROM items WHERE retailer_id = $2) AND id < $3 ORDER BY id DESC LIMIT $4`, actor, retailer, page.Cursor, page.Limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a page from item_history by actor: %w", err)
+	}
+
+	return scanChanges(rows, page.Limit)
+}
+
+func scanChanges(rows pgx.Rows, limit int) ([]*items.Change, error) {
+	defer rows.Close()
+
+	var results = make([]*items.Change, 0, limit)
+	for rows.Next() {
+		var (
+			id      keys.OpaqueID
+			itemID  keys.OpaqueID
+			related *int
+			change  items.Change
+		)
+		err := rows.Scan(&id, &itemID, &related, &change.Actor, &change.Action, &change.Diff, &change.RecordedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from item_history: %w", err)
+		}
+
+		change.ID, change.ItemID = &id, &itemID
+		if related != nil {
+			change.Related = &keys.OpaqueID{ID: *related}
+		}
+		results = append(results, &change)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+)
+
+func (i *Items) GetItemBySKU(ctx context.Context, sku string) (int, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	var id int
+	err = i.Conn.QueryRow(ctx, `SELECT id FROM items WHERE sku = $1 AND retailer_id = $2 UNION ALL SELECT item_id FROM item_variants WHERE sku = $1 AND retailer_id = $2 LIMIT 1`, sku, retailer).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return -1, items.ErrItemNotFound
+	} else if err != nil {
+		return -1, fmt.Errorf("failed to select from items by sku: %w", err)
+	}
+	return id, nil
+}
+
+func (i *Items) GetItemByBarcode(ctx context.Context, gtin string) (int, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	var id int
+	err = i.Conn.QueryRow(ctx, `SELECT item_id FROM item_barcodes WHERE gtin = $1 AND retailer_id = $2`, gtin, retailer).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return -1, items.ErrItemNotFound
+	} else if err != nil {
+		return -1, fmt.Errorf("failed to select from item_barcodes: %w", err)
+	}
+	return id, nil
+}
+
+// GetBarcodes lists the barcodes of the item itself, barcodes of its variants are excluded.
+func (i *Items) GetBarcodes(ctx context.Context, item int) ([]string, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT code FROM item_barcodes WHERE item_id = $1 AND retailer_id = $2 AND variant_id IS NULL ORDER BY code`, item, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from item_barcodes: %w", err)
+	}
+
+	codes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan from rows result when selecting from item_barcodes: %w", err)
+	}
+	return codes, nil
+}
+
+func (i *Items) AddBarcode(ctx context.Context, item int, gtin string, code string) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the item must belong to the retailer too, its reference is by both
+	_, err = i.Conn.Exec(ctx, `INSERT INTO item_barcodes (retailer_id, gtin, code, item_id) VALUES ($1, $2, $3, $4)`, retailer, gtin, code, item)
+	if isUniqueViolation(err) {
+		return items.ErrDuplicateBarcode
+	} else if isForeignKeyViolation(err) {
+		return items.ErrItemNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into item_barcodes: %w", err)
+	}
+
+	return nil
+}
+
+func (i *Items) RemoveBarcode(ctx context.Context, item int, gtin string) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := i.Conn.Exec(ctx, `DELETE FROM item_barcodes WHERE retailer_id = $1 AND gtin = $2 AND item_id = $3 AND variant_id IS NULL`, retailer, gtin, item)
+	if err != nil {
+		return fmt.Errorf("failed to delete from item_barcodes: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return items.ErrBarcodeNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+)
+
+// ImportItems stages the rows into temporary tables with COPY and then upserts them into items in
+// a single statement, a dry run rolls back once the outcome is known.
+func (i *Items) ImportItems(ctx context.Context, rows []items.ImportRow, dryRun bool) ([]items.ImportOutcome, []items.ImportedRelationship, []items.RowError, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tx, err := i.Conn.Begin(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `CREATE TEMPORARY TABLE import_items (line INTEGER, name VARCHAR(255), description TEXT, unit_scale VARCHAR(255), sku VARCHAR(255)) ON COMMIT DROP`)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create staging table for items: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `CREATE TEMPORARY TABLE import_relationships (line INTEGER, parent_sku VARCHAR(255), child_sku VARCHAR(255)) ON COMMIT DROP`)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create staging table for relationships: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"import_items"}, []string{"line", "name", "description", "unit_scale", "sku"},
+		pgx.CopyFromSlice(len(rows), func(n int) ([]any, error) {
+			return []any{rows[n].Line, rows[n].Name, rows[n].Description, string(rows[n].UnitScale), rows[n].SKU}, nil
+		}))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to copy into staging table for items: %w", err)
+	}
+
+	var relationships [][]any
+	for _, row := range rows {
+		for _, parent := range row.ParentSKUs {
+			relationships = append(relationships, []any{row.Line, parent, row.SKU})
+		}
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"import_relationships"}, []string{"line", "parent_sku", "child_sku"}, pgx.CopyFromRows(relationships))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to copy into staging table for relationships: %w", err)
+	}
+
+	unknown, err := tx.Query(ctx, `SELECT r.line, r.parent_sku FROM import_relationships r
+		WHERE NOT EXISTS (SELECT 1 FROM items WHERE sku = r.parent_sku AND retailer_id = $1)
+		AND NOT EXISTS (SELECT 1 FROM import_items WHERE sku = r.parent_sku)
+		ORDER BY r.line`, retailer)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to select unknown parents from staging table: %w", err)
+	}
+
+	errs, err := pgx.CollectRows(unknown, func(row pgx.CollectableRow) (items.RowError, error) {
+		var (
+			line   int
+			parent string
+		)
+		err := row.Scan(&line, &parent)
+		return items.RowError{Line: line, Field: "parent_skus", Message: fmt.Sprintf("no item with sku %q", parent)}, err
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to scan from rows result when selecting unknown parents: %w", err)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs, nil
+	}
+
+	// the stock ledger holds quantities in the unit scale of the item
+	stocked, err := tx.Query(ctx, `SELECT s.line FROM import_items s JOIN items i ON i.sku = s.sku AND i.retailer_id = $1
+		WHERE s.unit_scale IS DISTINCT FROM i.unit_scale AND EXISTS (SELECT 1 FROM stock_movements m WHERE m.item_id = i.id)
+		ORDER BY s.line`, retailer)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to select stocked items of staging table: %w", err)
+	}
+
+	errs, err = pgx.CollectRows(stocked, func(row pgx.CollectableRow) (items.RowError, error) {
+		var line int
+		err := row.Scan(&line)
+		return items.RowError{Line: line, Field: "unit_scale", Message: items.ErrUnitScaleStocked.Error()}, err
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to scan from rows result when selecting stocked items: %w", err)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs, nil
+	}
+
+	existing, err := tx.Query(ctx, `SELECT s.sku, i.id, i.name, COALESCE(i.description, ''), i.unit_scale FROM import_items s JOIN items i ON i.sku = s.sku AND i.retailer_id = $1`, retailer)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to select existing items of staging table: %w", err)
+	}
+	defer existing.Close()
+
+	var before = make(map[string]items.ImportOutcome)
+	for existing.Next() {
+		var (
+			sku     string
+			outcome = items.ImportOutcome{Before: &items.Details{}}
+		)
+		err := existing.Scan(&sku, &outcome.ID, &outcome.Before.Name, &outcome.Before.Description, &outcome.Before.UnitScale)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to scan from rows result when selecting existing items: %w", err)
+		}
+		outcome.Before.SKU = &sku
+		before[sku] = outcome
+	}
+	if err := existing.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to select existing items of staging table: %w", err)
+	}
+
+	upserted, err := tx.Query(ctx, `INSERT INTO items (retailer_id, name, description, unit_scale, sku)
+		SELECT $1, name, description, unit_scale, sku FROM import_items ORDER BY line
+		ON CONFLICT (retailer_id, sku) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, unit_scale = EXCLUDED.unit_scale, version = items.version + 1
+		WHERE (items.name, items.description, items.unit_scale) IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.description, EXCLUDED.unit_scale)
+		RETURNING id, sku, xmax = 0`, retailer)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to upsert into items: %w", err)
+	}
+	defer upserted.Close()
+
+	type written struct {
+		id      int
+		created bool
+	}
+	var writes = make(map[string]written, len(rows))
+	for upserted.Next() {
+		var (
+			sku   string
+			write written
+		)
+		if err := upserted.Scan(&write.id, &sku, &write.created); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to scan from rows result when upserting into items: %w", err)
+		}
+		writes[sku] = write
+	}
+	if err := upserted.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to upsert into items: %w", err)
+	}
+
+	var outcomes = make([]items.ImportOutcome, 0, len(rows))
+	for _, row := range rows {
+		sku := row.SKU
+		outcome := items.ImportOutcome{
+			Line: row.Line,
+			After: items.Details{
+				Name:        row.Name,
+				Description: row.Description,
+				UnitScale:   row.UnitScale,
+				SKU:         &sku,
+			},
+		}
+
+		write, wrote := writes[row.SKU]
+		switch {
+		case wrote && write.created:
+			outcome.ID, outcome.Created = write.id, true
+		case wrote:
+			outcome.ID, outcome.Before = write.id, before[row.SKU].Before
+		default:
+			// the upsert skipped the row as nothing about the item changed
+			outcome.ID = before[row.SKU].ID
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	related, err := tx.Query(ctx, `INSERT INTO item_relationships (parent_id, child_id)
+		SELECT p.id, c.id FROM import_relationships r
+		JOIN items p ON p.sku = r.parent_sku AND p.retailer_id = $1
+		JOIN items c ON c.sku = r.child_sku AND c.retailer_id = $1
+		ON CONFLICT DO NOTHING
+		RETURNING parent_id, child_id`, retailer)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to insert into item_relationships: %w", err)
+	}
+
+	added, err := pgx.CollectRows(related, func(row pgx.CollectableRow) (items.ImportedRelationship, error) {
+		var relationship items.ImportedRelationship
+		err := row.Scan(&relationship.Parent, &relationship.Child)
+		return relationship, err
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Message == "cyclical relationship detected" {
+		return nil, nil, nil, items.ErrCyclicRelationship
+	} else if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to insert into item_relationships: %w", err)
+	}
+
+	if dryRun {
+		return outcomes, added, nil, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return outcomes, added, nil, nil
+}
@@ -0,0 +1,455 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type Inventory struct {
+	Conn *Pool
+}
+
+const (
+	locationColumns = `id, retailer_id, name, is_default, created_at`
+	movementColumns = `id, item_id, location_id, quantity, reason, sale_id, purchase_order_id, transfer_location_id, note, actor, moved_at`
+)
+
+func (i *Inventory) CreateLocation(ctx context.Context, location *inventory.Location) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := i.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if location.Default {
+		if err := undefault(ctx, tx, retailer); err != nil {
+			return err
+		}
+	}
+
+	var assigned int
+	err = tx.QueryRow(ctx, `INSERT INTO stock_locations (retailer_id, name, is_default)
+		VALUES ($1, $2, $3 OR NOT EXISTS (SELECT 1 FROM stock_locations WHERE retailer_id = $1))
+		RETURNING id, is_default, created_at`, retailer, location.Name, location.Default).Scan(&assigned, &location.Default, &location.CreatedAt)
+	if isUniqueViolation(err) {
+		return inventory.ErrDuplicateLocation
+	} else if isForeignKeyViolation(err) {
+		return retailers.ErrRetailerNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into stock_locations: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	location.ID, location.RetailerID = &keys.OpaqueID{ID: assigned}, &keys.OpaqueID{ID: retailer}
+	return nil
+}
+
+func (i *Inventory) GetLocation(ctx context.Context, id int) (*inventory.Location, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT `+locationColumns+` FROM stock_locations WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from stock_locations: %w", err)
+	}
+
+	found, err := scanLocations(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, inventory.ErrLocationNotFound
+	}
+	return found[0], nil
+}
+
+func (i *Inventory) GetLocations(ctx context.Context) ([]*inventory.Location, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT `+locationColumns+` FROM stock_locations WHERE retailer_id = $1 ORDER BY id`, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from stock_locations: %w", err)
+	}
+	return scanLocations(rows)
+}
+
+func (i *Inventory) UpdateLocation(ctx context.Context, location *inventory.Location) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := i.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if location.Default {
+		if err := undefault(ctx, tx, retailer); err != nil {
+			return err
+		}
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE stock_locations SET name = $3, is_default = $4 WHERE id = $1 AND retailer_id = $2`, location.ID.ID, retailer, location.Name, location.Default)
+	if isUniqueViolation(err) {
+		return inventory.ErrDuplicateLocation
+	} else if err != nil {
+		return fmt.Errorf("failed to update stock_locations: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return inventory.ErrLocationNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// undefault takes the default away from every location of the retailer, so that another can have
+// it.
+func undefault(ctx context.Context, tx pgx.Tx, retailer int) error {
+	_, err := tx.Exec(ctx, `UPDATE stock_locations SET is_default = FALSE WHERE retailer_id = $1 AND is_default`, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to update default of stock_locations: %w", err)
+	}
+	return nil
+}
+
+func (i *Inventory) PostMovements(ctx context.Context, movements []*inventory.Movement) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := i.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	assigned, err := insertMovements(ctx, tx, retailer, movements)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	for n, movement := range movements {
+		movement.ID = &keys.OpaqueID{ID: assigned[n]}
+	}
+	return nil
+}
+
+// insertMovements appends the movements to the ledger within the transaction, returning their ids
+// to be assigned once the transaction commits.
+func insertMovements(ctx context.Context, tx pgx.Tx, retailer int, movements []*inventory.Movement) ([]int, error) {
+	var assigned = make([]int, len(movements))
+	for n, movement := range movements {
+		var sale, order, transfer *int
+		if movement.SaleID != nil {
+			sale = &movement.SaleID.ID
+		}
+		if movement.PurchaseOrderID != nil {
+			order = &movement.PurchaseOrderID.ID
+		}
+		if movement.TransferLocationID != nil {
+			transfer = &movement.TransferLocationID.ID
+		}
+
+		err := tx.QueryRow(ctx, `INSERT INTO stock_movements (retailer_id, item_id, location_id, quantity, reason, sale_id, purchase_order_id, transfer_location_id, note, actor)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, moved_at`,
+			retailer, movement.ItemID.ID, movement.LocationID.ID, (*money.Decimal)(movement.Quantity), movement.Reason, sale, order, transfer, movement.Note, movement.Actor).Scan(&assigned[n], &movement.MovedAt)
+		var pgErr *pgconn.PgError
+		if isForeignKeyViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "stock_movements_item_id_fkey" {
+			return nil, items.ErrItemNotFound
+		} else if isForeignKeyViolation(err) {
+			return nil, inventory.ErrLocationNotFound
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to insert into stock_movements: %w", err)
+		}
+	}
+	return assigned, nil
+}
+
+// postStock moves the stock sold on line items of a sale out of the location of the sale, or back
+// into it when quantities are positive. Kits are taken apart into the items they are made of at the
+// time of posting, with the quantities multiplied down the hierarchy, as only those hold stock.
+// Every movement remembers the line item it was posted for. Sales without a location don't move
+// stock.
+func postStock(ctx context.Context, tx pgx.Tx, reason inventory.Reason, lines []int, quantities []*money.Decimal) error {
+	_, err := tx.Exec(ctx, `WITH RECURSIVE sold (retailer_id, location_id, sale_id, line_item_id, item_id, quantity) AS (
+			SELECT s.retailer_id, s.location_id, s.id, l.id, l.product_id, m.quantity
+			FROM unnest($1::INTEGER[], $2::NUMERIC[]) AS m (line_item_id, quantity)
+			JOIN line_items l ON l.id = m.line_item_id
+			JOIN sales s ON s.id = l.sale_id
+			WHERE s.location_id IS NOT NULL
+			UNION ALL
+			SELECT sold.retailer_id, sold.location_id, sold.sale_id, sold.line_item_id, r.child_id, sold.quantity * r.quantity
+			FROM sold
+			JOIN item_relationships r ON r.parent_id = sold.item_id
+		)
+		INSERT INTO stock_movements (retailer_id, item_id, location_id, quantity, reason, sale_id, line_item_id, actor)
+		SELECT retailer_id, item_id, location_id, ROUND(SUM(quantity), 4), $3, sale_id, line_item_id, $4
+		FROM sold
+		WHERE NOT EXISTS (SELECT 1 FROM item_relationships r WHERE r.parent_id = sold.item_id)
+		GROUP BY retailer_id, location_id, sale_id, line_item_id, item_id
+		HAVING ROUND(SUM(quantity), 4) <> 0`, lines, quantities, reason, actor.From(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to insert stock of line items into stock_movements: %w", err)
+	}
+	return nil
+}
+
+// restockSold puts back the stock the sale took for units of its line items, as posted when the
+// sale was made rather than from what kits are made of now. Units returned before, by refunds
+// already recorded, are counted so that returning every unit in parts puts back exactly what was
+// taken. Line items sold before movements remembered their line item are restocked from the kits
+// as they are now, there being nothing else to go by.
+func restockSold(ctx context.Context, tx pgx.Tx, reason inventory.Reason, lines []int, quantities []*money.Decimal) error {
+	_, err := tx.Exec(ctx, `INSERT INTO stock_movements (retailer_id, item_id, location_id, quantity, reason, sale_id, line_item_id, actor)
+		SELECT retailer_id, item_id, location_id, quantity, $3, sale_id, line_item_id, $4 FROM (
+			SELECT m.retailer_id, m.item_id, m.location_id, m.sale_id, m.line_item_id,
+				ROUND(-m.quantity * (p.returned + r.quantity) / l.quantity, 4) - ROUND(-m.quantity * p.returned / l.quantity, 4) AS quantity
+			FROM unnest($1::INTEGER[], $2::NUMERIC[]) AS r (line_item_id, quantity)
+			JOIN line_items l ON l.id = r.line_item_id
+			JOIN stock_movements m ON m.line_item_id = l.id AND m.reason = $5
+			CROSS JOIN LATERAL (SELECT COALESCE(SUM(quantity), 0) AS returned FROM refund_lines WHERE line_item_id = l.id) p
+		) returned
+		WHERE quantity <> 0`, lines, quantities, reason, actor.From(ctx), inventory.Sale)
+	if err != nil {
+		return fmt.Errorf("failed to insert stock returned on line items into stock_movements: %w", err)
+	}
+
+	var unposted []int
+	rows, err := tx.Query(ctx, `SELECT l.id FROM line_items l WHERE l.id = ANY($1)
+		AND NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.line_item_id = l.id)
+		AND EXISTS (SELECT 1 FROM stock_movements m WHERE m.sale_id = l.sale_id AND m.line_item_id IS NULL AND m.reason = $2)`, lines, inventory.Sale)
+	if err != nil {
+		return fmt.Errorf("failed to select line items sold before movements remembered them: %w", err)
+	}
+	if unposted, err = pgx.CollectRows(rows, pgx.RowTo[int]); err != nil {
+		return fmt.Errorf("failed to scan from rows result when selecting line items sold before movements remembered them: %w", err)
+	}
+	if len(unposted) == 0 {
+		return nil
+	}
+
+	var (
+		legacy   []int
+		returned []*money.Decimal
+	)
+	for n, line := range lines {
+		if slices.Contains(unposted, line) {
+			legacy, returned = append(legacy, line), append(returned, quantities[n])
+		}
+	}
+	return postStock(ctx, tx, reason, legacy, returned)
+}
+
+func (i *Inventory) PageMovements(ctx context.Context, filter inventory.Filter, page paginate.Paginate) ([]*inventory.Movement, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		conditions = []string{"retailer_id = $1"}
+		args       = []any{retailer}
+	)
+	var condition = func(format string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if page.Cursor != nil {
+		condition("id < $%d", page.Cursor)
+	}
+	if filter.Item != nil {
+		condition("item_id = $%d", filter.Item.ID)
+	}
+	if filter.Location != nil {
+		condition("location_id = $%d", filter.Location.ID)
+	}
+	args = append(args, page.Limit)
+
+	rows, err := i.Conn.Query(ctx, fmt.Sprintf(`SELECT `+movementColumns+` FROM stock_movements WHERE %s ORDER BY id DESC LIMIT $%d`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a page from stock_movements: %w", err)
+	}
+	return scanMovements(rows)
+}
+
+func scanMovements(rows pgx.Rows) ([]*inventory.Movement, error) {
+	defer rows.Close()
+
+	var results = []*inventory.Movement{}
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			item     keys.OpaqueID
+			location keys.OpaqueID
+			quantity money.Decimal
+			sale     *int
+			order    *int
+			transfer *int
+			movement inventory.Movement
+		)
+		err := rows.Scan(&id, &item, &location, &quantity, &movement.Reason, &sale, &order, &transfer, &movement.Note, &movement.Actor, &movement.MovedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from stock_movements: %w", err)
+		}
+
+		movement.ID, movement.ItemID, movement.LocationID = &id, &item, &location
+		movement.Quantity = new(big.Rat).Set(quantity.Rat())
+		if sale != nil {
+			movement.SaleID = &keys.OpaqueID{ID: *sale}
+		}
+		if order != nil {
+			movement.PurchaseOrderID = &keys.OpaqueID{ID: *order}
+		}
+		if transfer != nil {
+			movement.TransferLocationID = &keys.OpaqueID{ID: *transfer}
+		}
+		results = append(results, &movement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from stock_movements: %w", err)
+	}
+	return results, nil
+}
+
+func (i *Inventory) GetLevels(ctx context.Context, filter inventory.Filter) ([]*inventory.Level, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		conditions = []string{"retailer_id = $1"}
+		args       = []any{retailer}
+	)
+	if filter.Item != nil {
+		args = append(args, filter.Item.ID)
+		conditions = append(conditions, fmt.Sprintf("item_id = $%d", len(args)))
+	}
+	if filter.Location != nil {
+		args = append(args, filter.Location.ID)
+		conditions = append(conditions, fmt.Sprintf("location_id = $%d", len(args)))
+	}
+
+	rows, err := i.Conn.Query(ctx, fmt.Sprintf(`SELECT item_id, location_id, on_hand, updated_at FROM stock_levels WHERE %s ORDER BY item_id, location_id`, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from stock_levels: %w", err)
+	}
+	defer rows.Close()
+
+	var results = []*inventory.Level{}
+	for rows.Next() {
+		var (
+			item      keys.OpaqueID
+			location  keys.OpaqueID
+			onHand    money.Decimal
+			updatedAt time.Time
+		)
+		if err := rows.Scan(&item, &location, &onHand, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from stock_levels: %w", err)
+		}
+		results = append(results, &inventory.Level{ItemID: &item, LocationID: &location, OnHand: new(big.Rat).Set(onHand.Rat()), UpdatedAt: updatedAt})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from stock_levels: %w", err)
+	}
+	return results, nil
+}
+
+func (i *Inventory) GetComponents(ctx context.Context, item int) ([]*inventory.Component, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `WITH RECURSIVE parts (item_id, quantity) AS (
+			SELECT r.child_id, r.quantity::NUMERIC
+			FROM item_relationships r
+			JOIN items i ON i.id = r.parent_id
+			WHERE r.parent_id = $1 AND i.retailer_id = $2
+			UNION ALL
+			SELECT r.child_id, parts.quantity * r.quantity
+			FROM parts
+			JOIN item_relationships r ON r.parent_id = parts.item_id
+		)
+		SELECT item_id, SUM(quantity)
+		FROM parts
+		WHERE NOT EXISTS (SELECT 1 FROM item_relationships r WHERE r.parent_id = parts.item_id)
+		GROUP BY item_id
+		ORDER BY item_id`, item, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select components from item_relationships: %w", err)
+	}
+	defer rows.Close()
+
+	var results = []*inventory.Component{}
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			quantity money.Decimal
+		)
+		if err := rows.Scan(&id, &quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from item_relationships: %w", err)
+		}
+		results = append(results, &inventory.Component{ItemID: &id, Quantity: new(big.Rat).Set(quantity.Rat())})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from item_relationships: %w", err)
+	}
+	return results, nil
+}
+
+func scanLocations(rows pgx.Rows) ([]*inventory.Location, error) {
+	defer rows.Close()
+
+	var results []*inventory.Location
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			retailer keys.OpaqueID
+			location inventory.Location
+		)
+		if err := rows.Scan(&id, &retailer, &location.Name, &location.Default, &location.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from stock_locations: %w", err)
+		}
+		location.ID, location.RetailerID = &id, &retailer
+		results = append(results, &location)
+	}
+	return results, rows.Err()
+}
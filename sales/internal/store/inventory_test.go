@@ -0,0 +1,328 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestInventoryLocations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, _ = scope(t, ctx, conn, "pedlar.test")
+	theirs, _ := scope(t, context.Background(), conn, "theirs.test")
+
+	store := &Inventory{Conn: conn}
+
+	// the first location of a retailer is where sales take stock from
+	floor := &inventory.Location{Name: "floor"}
+	require.NoError(t, store.CreateLocation(ctx, floor))
+	require.True(t, floor.Default)
+
+	back := &inventory.Location{Name: "back room"}
+	require.NoError(t, store.CreateLocation(ctx, back))
+	require.False(t, back.Default)
+
+	require.ErrorIs(t, store.CreateLocation(ctx, &inventory.Location{Name: "floor"}), inventory.ErrDuplicateLocation)
+	require.NoError(t, store.CreateLocation(theirs, &inventory.Location{Name: "floor"}))
+
+	back.Default = true
+	require.NoError(t, store.UpdateLocation(ctx, back))
+
+	found, err := store.GetLocations(ctx)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	require.False(t, found[0].Default)
+	require.True(t, found[1].Default)
+
+	_, err = store.GetLocation(theirs, floor.ID.ID)
+	require.ErrorIs(t, err, inventory.ErrLocationNotFound)
+	require.ErrorIs(t, store.UpdateLocation(theirs, floor), inventory.ErrLocationNotFound)
+}
+
+func TestInventoryLedger(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, _ = scope(t, ctx, conn, "pedlar.test")
+
+	itemStore := &Items{Conn: conn}
+	beans, err := itemStore.CreateItem(ctx, items.Details{Name: "beans", UnitScale: items.Kilogram})
+	require.NoError(t, err)
+
+	store := &Inventory{Conn: conn}
+	floor, back := &inventory.Location{Name: "floor"}, &inventory.Location{Name: "back room"}
+	require.NoError(t, store.CreateLocation(ctx, floor))
+	require.NoError(t, store.CreateLocation(ctx, back))
+
+	var movement = func(location *keys.OpaqueID, quantity *big.Rat, reason inventory.Reason, transfer *keys.OpaqueID) *inventory.Movement {
+		return &inventory.Movement{ItemID: beans.ID, LocationID: location, Quantity: quantity, Reason: reason, TransferLocationID: transfer, Actor: "till-1"}
+	}
+	require.NoError(t, store.PostMovements(ctx, []*inventory.Movement{movement(back.ID, big.NewRat(10, 1), inventory.Receipt, nil)}))
+	require.NoError(t, store.PostMovements(ctx, []*inventory.Movement{
+		movement(back.ID, big.NewRat(-5, 2), inventory.Transfer, floor.ID),
+		movement(floor.ID, big.NewRat(5, 2), inventory.Transfer, back.ID),
+	}))
+	require.NoError(t, store.PostMovements(ctx, []*inventory.Movement{movement(floor.ID, big.NewRat(-1, 4), inventory.Adjustment, nil)}))
+
+	require.ErrorIs(t, store.PostMovements(ctx, []*inventory.Movement{
+		{ItemID: &keys.OpaqueID{ID: -1}, LocationID: floor.ID, Quantity: big.NewRat(1, 1), Reason: inventory.Receipt, Actor: "till-1"},
+	}), items.ErrItemNotFound)
+	require.ErrorIs(t, store.PostMovements(ctx, []*inventory.Movement{movement(&keys.OpaqueID{ID: -1}, big.NewRat(1, 1), inventory.Receipt, nil)}), inventory.ErrLocationNotFound)
+
+	levels, err := store.GetLevels(ctx, inventory.Filter{Item: beans.ID})
+	require.NoError(t, err)
+	require.Len(t, levels, 2)
+	require.Equal(t, "2.25", inventory.FormatQuantity(levels[0].OnHand))
+	require.Equal(t, "7.5", inventory.FormatQuantity(levels[1].OnHand))
+
+	moved, err := store.PageMovements(ctx, inventory.Filter{Location: floor.ID}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, moved, 2)
+	require.Equal(t, inventory.Adjustment, moved[0].Reason)
+	require.Equal(t, back.ID.ID, moved[1].TransferLocationID.ID)
+
+	// the ledger is never rewritten, levels only move by posting more movements
+	_, err = conn.Exec(ctx, `UPDATE stock_movements SET quantity = 100 WHERE id = $1`, moved[0].ID.ID)
+	require.ErrorContains(t, err, "stock movements are append only")
+	_, err = conn.Exec(ctx, `DELETE FROM stock_movements WHERE id = $1`, moved[0].ID.ID)
+	require.ErrorContains(t, err, "stock movements are append only")
+
+	beans.Details.UnitScale = items.Gram
+	require.ErrorIs(t, itemStore.UpdateItemDetails(ctx, beans.ID.ID, beans.Version, beans.Details), items.ErrUnitScaleStocked)
+	beans.Details.Name = "house beans"
+	beans.Details.UnitScale = items.Kilogram
+	require.NoError(t, itemStore.UpdateItemDetails(ctx, beans.ID.ID, beans.Version, beans.Details))
+}
+
+func TestInventorySales(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+	theirs, _ := scope(t, context.Background(), conn, "theirs.test")
+
+	coffee, err := (&Items{Conn: conn}).CreateItem(ctx, items.Details{Name: "flat white", UnitScale: items.Unit})
+	require.NoError(t, err)
+
+	salesStore := &Sales{Conn: conn}
+	price, err := money.Parse("4.50", "NZD")
+	require.NoError(t, err)
+
+	var sale = func(location *keys.OpaqueID) *sales.Sale {
+		return &sales.Sale{
+			RetailerID: &keys.OpaqueID{ID: retailer},
+			LocationID: location,
+			SaleDate:   time.Now(),
+			Currency:   "NZD",
+			Status:     sales.Paid,
+			LineItems: []*sales.LineItem{
+				{ItemID: &keys.OpaqueID{ID: coffee.ID.ID}, Quantity: big.NewRat(3, 1), UnitScale: items.Unit, UnitPrice: price},
+			},
+		}
+	}
+
+	// retailers without locations don't track stock
+	untracked := sale(nil)
+	require.NoError(t, salesStore.CreateSale(ctx, untracked))
+	require.Nil(t, untracked.LocationID)
+
+	store := &Inventory{Conn: conn}
+	floor, cart := &inventory.Location{Name: "floor"}, &inventory.Location{Name: "cart"}
+	require.NoError(t, store.CreateLocation(ctx, floor))
+	require.NoError(t, store.CreateLocation(ctx, cart))
+	elsewhere := &inventory.Location{Name: "floor"}
+	require.NoError(t, store.CreateLocation(theirs, elsewhere))
+
+	refunded := sale(nil)
+	require.NoError(t, salesStore.CreateSale(ctx, refunded))
+	require.Equal(t, floor.ID.ID, refunded.LocationID.ID)
+
+	voided := sale(cart.ID)
+	require.NoError(t, salesStore.CreateSale(ctx, voided))
+	require.Equal(t, cart.ID.ID, voided.LocationID.ID)
+
+	require.ErrorIs(t, salesStore.CreateSale(ctx, sale(elsewhere.ID)), sales.ErrLocationNotFound)
+
+	refund := &sales.Refund{
+		SaleID:     refunded.ID,
+		Reason:     sales.Damaged,
+		Actor:      "till-1",
+		RefundedAt: time.Now(),
+		Currency:   "NZD",
+		Lines:      []*sales.RefundLine{{LineItemID: refunded.LineItems[0].ID, Quantity: big.NewRat(1, 1), Amount: price}},
+	}
+	require.NoError(t, salesStore.CreateRefund(ctx, refund, 0, sales.Paid))
+	require.NoError(t, salesStore.VoidSale(ctx, voided.ID.ID, &sales.Void{At: time.Now(), Actor: "till-1", Reason: "mistake"}))
+
+	levels, err := store.GetLevels(ctx, inventory.Filter{Item: coffee.ID})
+	require.NoError(t, err)
+	require.Len(t, levels, 2)
+	require.Equal(t, floor.ID.ID, levels[0].LocationID.ID)
+	require.Equal(t, "-2", inventory.FormatQuantity(levels[0].OnHand))
+	require.Equal(t, cart.ID.ID, levels[1].LocationID.ID)
+	require.Equal(t, "0", inventory.FormatQuantity(levels[1].OnHand))
+
+	moved, err := store.PageMovements(ctx, inventory.Filter{Item: coffee.ID}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, moved, 4)
+	for _, movement := range moved {
+		require.NotNil(t, movement.SaleID)
+	}
+	require.Equal(t, inventory.Void, moved[0].Reason)
+	require.Equal(t, "3", inventory.FormatQuantity(moved[0].Quantity))
+	require.Equal(t, inventory.Refund, moved[1].Reason)
+	require.Equal(t, "1", inventory.FormatQuantity(moved[1].Quantity))
+}
+
+func TestInventoryKits(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	itemStore := &Items{Conn: conn}
+	var item = func(name string, scale items.UnitScale) *items.Item {
+		created, err := itemStore.CreateItem(ctx, items.Details{Name: name, UnitScale: scale})
+		require.NoError(t, err)
+		return created
+	}
+	candle, tea := item("candle", items.Unit), item("tea", items.Kilogram)
+	box, hamper := item("gift box", items.Unit), item("hamper", items.Unit)
+
+	// a hamper is two gift boxes and another candle, a gift box is two candles and 250g of tea
+	_, err = itemStore.AddChild(ctx, box.ID.ID, candle.ID.ID, big.NewRat(2, 1))
+	require.NoError(t, err)
+	_, err = itemStore.AddChild(ctx, box.ID.ID, tea.ID.ID, big.NewRat(1, 4))
+	require.NoError(t, err)
+	_, err = itemStore.AddChild(ctx, hamper.ID.ID, box.ID.ID, big.NewRat(2, 1))
+	require.NoError(t, err)
+	_, err = itemStore.AddChild(ctx, hamper.ID.ID, candle.ID.ID, big.NewRat(1, 1))
+	require.NoError(t, err)
+
+	store := &Inventory{Conn: conn}
+	components, err := store.GetComponents(ctx, hamper.ID.ID)
+	require.NoError(t, err)
+	require.Len(t, components, 2)
+	require.Equal(t, candle.ID.ID, components[0].ItemID.ID)
+	require.Equal(t, "5", inventory.FormatQuantity(components[0].Quantity))
+	require.Equal(t, tea.ID.ID, components[1].ItemID.ID)
+	require.Equal(t, "0.5", inventory.FormatQuantity(components[1].Quantity))
+
+	components, err = store.GetComponents(ctx, candle.ID.ID)
+	require.NoError(t, err)
+	require.Empty(t, components)
+
+	floor := &inventory.Location{Name: "floor"}
+	require.NoError(t, store.CreateLocation(ctx, floor))
+
+	price, err := money.Parse("80.00", "NZD")
+	require.NoError(t, err)
+	sold := &sales.Sale{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		SaleDate:   time.Now(),
+		Currency:   "NZD",
+		Status:     sales.Paid,
+		LineItems: []*sales.LineItem{
+			{ItemID: &keys.OpaqueID{ID: hamper.ID.ID}, Quantity: big.NewRat(2, 1), UnitScale: items.Unit, UnitPrice: price},
+			{ItemID: &keys.OpaqueID{ID: candle.ID.ID}, Quantity: big.NewRat(1, 1), UnitScale: items.Unit, UnitPrice: price},
+		},
+	}
+	require.NoError(t, (&Sales{Conn: conn}).CreateSale(ctx, sold))
+
+	// kits hold no stock, the candles of both lines are taken together
+	levels, err := store.GetLevels(ctx, inventory.Filter{Location: floor.ID})
+	require.NoError(t, err)
+	require.Len(t, levels, 2)
+	require.Equal(t, candle.ID.ID, levels[0].ItemID.ID)
+	require.Equal(t, "-11", inventory.FormatQuantity(levels[0].OnHand))
+	require.Equal(t, tea.ID.ID, levels[1].ItemID.ID)
+	require.Equal(t, "-1", inventory.FormatQuantity(levels[1].OnHand))
+
+	// what is returned is what the sale took, however the kits are made up since
+	removed, err := itemStore.RemoveChild(ctx, box.ID.ID, candle.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, "2", inventory.FormatQuantity(removed))
+	removed, err = itemStore.RemoveChild(ctx, box.ID.ID, candle.ID.ID)
+	require.NoError(t, err)
+	require.Nil(t, removed, "the second removal removed nothing")
+	var refund = func(seen int, quantity int64, status sales.Status) {
+		require.NoError(t, (&Sales{Conn: conn}).CreateRefund(ctx, &sales.Refund{
+			SaleID:     sold.ID,
+			Reason:     sales.Unwanted,
+			Actor:      "till-1",
+			RefundedAt: time.Now(),
+			Currency:   "NZD",
+			Lines:      []*sales.RefundLine{{LineItemID: sold.LineItems[0].ID, Quantity: big.NewRat(quantity, 1), Amount: price}},
+		}, seen, status))
+	}
+	refund(0, 1, sales.Paid)
+
+	levels, err = store.GetLevels(ctx, inventory.Filter{Location: floor.ID})
+	require.NoError(t, err)
+	require.Equal(t, "-6", inventory.FormatQuantity(levels[0].OnHand))
+	require.Equal(t, "-0.5", inventory.FormatQuantity(levels[1].OnHand))
+
+	refund(1, 1, sales.Paid)
+
+	levels, err = store.GetLevels(ctx, inventory.Filter{Location: floor.ID})
+	require.NoError(t, err)
+	require.Equal(t, "-1", inventory.FormatQuantity(levels[0].OnHand))
+	require.Equal(t, "0", inventory.FormatQuantity(levels[1].OnHand))
+}
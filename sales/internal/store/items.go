@@ -2,23 +2,38 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
 	"github.com/suessflorian/pedlar/sales/pkg/keys"
 	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
 )
 
+// Items are owned by the retailer the context is scoped to, items of other retailers are never
+// read or written.
 type Items struct {
-	Conn *pgxpool.Pool
+	Conn *Pool
 }
 
 func (i *Items) CreateItem(ctx context.Context, deets items.Details) (*items.Item, error) {
-	var assigned int
-	err := i.Conn.QueryRow(ctx, `INSERT INTO items (name, description, unit_scale) VALUES ($1, $2, $3) RETURNING id`, deets.Name, deets.Description, deets.UnitScale).Scan(&assigned)
+	retailer, err := retailers.From(ctx)
 	if err != nil {
+		return nil, err
+	}
+
+	var assigned int
+	err = i.Conn.QueryRow(ctx, `INSERT INTO items (retailer_id, name, description, unit_scale, sku) VALUES ($1, $2, $3, $4, $5) RETURNING id`, retailer, deets.Name, deets.Description, deets.UnitScale, deets.SKU).Scan(&assigned)
+	if isUniqueViolation(err) {
+		return nil, items.ErrDuplicateSKU
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to insert into items: %w", err)
 	}
 
@@ -26,38 +41,158 @@ func (i *Items) CreateItem(ctx context.Context, deets items.Details) (*items.Ite
 		ID: &keys.OpaqueID{
 			ID: assigned,
 		},
-		Details: deets,
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		Details:    deets,
+		Version:    1,
+		OptionAxes: []string{},
+		TaxClass:   items.DefaultTaxClass,
 	}, nil
 }
 
-func (i *Items) UpdateItemDetails(ctx context.Context, id *keys.OpaqueID, deets items.Details) error {
-	_, err := i.Conn.Exec(ctx, `UPDATE items SET name = $1, description = $2, unit_scale = $3 WHERE id = $4`, deets.Name, deets.Description, deets.UnitScale, id.ID)
-	return err
+func (i *Items) UpdateItemDetails(ctx context.Context, id int, version int, deets items.Details) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := i.Conn.Exec(ctx, `UPDATE items SET name = $1, description = $2, unit_scale = $3, sku = $4, version = version + 1 WHERE id = $5 AND version = $6 AND archived_at IS NULL AND retailer_id = $7`, deets.Name, deets.Description, deets.UnitScale, deets.SKU, id, version, retailer)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Message == "unit scale of stocked item cannot change" {
+		return items.ErrUnitScaleStocked
+	} else if isUniqueViolation(err) {
+		return items.ErrDuplicateSKU
+	} else if err != nil {
+		return fmt.Errorf("failed to update items: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return i.conflict(ctx, id)
+	}
+
+	return nil
+}
+
+func (i *Items) SetItemArchived(ctx context.Context, id int, version int, archived bool) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := i.Conn.Exec(ctx, `UPDATE items SET archived_at = CASE WHEN $1 THEN CURRENT_TIMESTAMP END, version = version + 1 WHERE id = $2 AND version = $3 AND retailer_id = $4`, archived, id, version, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to update archived status of items: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return i.conflict(ctx, id)
+	}
+
+	return nil
+}
+
+func (i *Items) SetOptionAxes(ctx context.Context, id int, version int, axes []string) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := i.Conn.Exec(ctx, `UPDATE items SET option_axes = $1, version = version + 1 WHERE id = $2 AND version = $3 AND archived_at IS NULL AND retailer_id = $4`, axes, id, version, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to update option axes of items: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return i.conflict(ctx, id)
+	}
+
+	return nil
+}
+
+func (i *Items) SetTaxClass(ctx context.Context, id int, version int, class string) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := i.Conn.Exec(ctx, `UPDATE items SET tax_class = $1, version = version + 1 WHERE id = $2 AND version = $3 AND archived_at IS NULL AND retailer_id = $4`, class, id, version, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to update tax class of items: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return i.conflict(ctx, id)
+	}
+
+	return nil
+}
+
+// conflict determines why a versioned write to an item affected no rows.
+func (i *Items) conflict(ctx context.Context, id int) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	var archived bool
+	err = i.Conn.QueryRow(ctx, `SELECT archived_at IS NOT NULL FROM items WHERE id = $1 AND retailer_id = $2`, id, retailer).Scan(&archived)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return items.ErrItemNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to select from items: %w", err)
+	}
+
+	if archived {
+		return items.ErrItemArchived
+	}
+	return items.ErrVersionConflict
 }
 
 func (i *Items) GetItem(ctx context.Context, id int) (*items.Item, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
 		name        string
 		description string
 		scale       items.UnitScale
+		sku         *string
+		version     int
+		archivedAt  *time.Time
+		axes        []string
+		taxClass    string
 	)
-	err := i.Conn.QueryRow(ctx, `SELECT name, description, unit_scale FROM items WHERE id = $1`, id).Scan(&name, &description, &scale)
-	if err != nil {
+	err = i.Conn.QueryRow(ctx, `SELECT name, description, unit_scale, sku, version, archived_at, option_axes, tax_class FROM items WHERE id = $1 AND retailer_id = $2`, id, retailer).Scan(&name, &description, &scale, &sku, &version, &archivedAt, &axes, &taxClass)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, items.ErrItemNotFound
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to select from items: %w", err)
 	}
 
 	return &items.Item{
-		ID: &keys.OpaqueID{ID: id},
+		ID:         &keys.OpaqueID{ID: id},
+		RetailerID: &keys.OpaqueID{ID: retailer},
 		Details: items.Details{
 			Name:        name,
 			Description: description,
 			UnitScale:   scale,
+			SKU:         sku,
 		},
+		Version:    version,
+		ArchivedAt: archivedAt,
+		OptionAxes: axes,
+		TaxClass:   taxClass,
 	}, nil
 }
 
 func (i *Items) GetItems(ctx context.Context, ids ...int) ([]*items.Item, error) {
-	rows, err := i.Conn.Query(ctx, `SELECT id, name, description, unit_scale FROM items WHERE id = ANY($1)`, ids)
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT id, name, description, unit_scale, sku, version, archived_at, option_axes, tax_class FROM items WHERE id = ANY($1) AND retailer_id = $2 ORDER BY id`, ids, retailer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to select many from items: %w", err)
 	}
@@ -69,39 +204,68 @@ func (i *Items) GetItems(ctx context.Context, ids ...int) ([]*items.Item, error)
 			name        string
 			description string
 			scale       items.UnitScale
+			sku         *string
+			version     int
+			archivedAt  *time.Time
+			axes        []string
+			taxClass    string
 		)
-		err := rows.Scan(&id, &name, &description, &scale)
+		err := rows.Scan(&id, &name, &description, &scale, &sku, &version, &archivedAt, &axes, &taxClass)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan from rows result when selecting from items: %w", err)
 		}
 		results = append(results, &items.Item{
-			ID: &id,
+			ID:         &id,
+			RetailerID: &keys.OpaqueID{ID: retailer},
 			Details: items.Details{
 				Name:        name,
 				Description: description,
 				UnitScale:   scale,
+				SKU:         sku,
 			},
+			Version:    version,
+			ArchivedAt: archivedAt,
+			OptionAxes: axes,
+			TaxClass:   taxClass,
 		})
 	}
 
 	return results, nil
 }
 
-func (i *Items) PageItems(ctx context.Context, page paginate.Paginate) ([]*keys.OpaqueID, error) {
+// PageItems pages through items that aren't archived, a category filter matches items in any
+// descendant of the category and a tags filter matches items carrying all of the tags.
+func (i *Items) PageItems(ctx context.Context, filter items.ItemFilter, page paginate.Paginate) ([]*keys.OpaqueID, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
-		rows pgx.Rows
-		err  error
+		conditions = []string{"retailer_id = $1", "archived_at IS NULL"}
+		args       = []any{retailer}
 	)
-	if page.Cursor == nil {
-		rows, err = i.Conn.Query(ctx, `SELECT id FROM items LIMIT $1`, page.Limit)
-		if err != nil {
-			return nil, fmt.Errorf("failed to select a page from items: %w", err)
-		}
-	} else {
-		rows, err = i.Conn.Query(ctx, `SELECT id FROM items WHERE id > $1 LIMIT $2`, page.Cursor, page.Limit)
-		if err != nil {
-			return nil, fmt.Errorf("failed to select a page from items with cursor condition: %w", err)
-		}
+	if page.Cursor != nil {
+		args = append(args, page.Cursor)
+		conditions = append(conditions, fmt.Sprintf("id > $%d", len(args)))
+	}
+	if filter.Category != nil {
+		args = append(args, filter.Category.ID)
+		conditions = append(conditions, fmt.Sprintf(`id IN (WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = $%d AND retailer_id = $1
+				UNION ALL
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+			) SELECT item_id FROM item_categories WHERE category_id IN (SELECT id FROM subtree))`, len(args)))
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, filter.Tags, len(filter.Tags))
+		conditions = append(conditions, fmt.Sprintf(`id IN (SELECT item_id FROM item_tags WHERE tag = ANY($%d) GROUP BY item_id HAVING COUNT(*) = $%d)`, len(args)-1, len(args)))
+	}
+	args = append(args, page.Limit)
+
+	rows, err := i.Conn.Query(ctx, fmt.Sprintf(`SELECT id FROM items WHERE %s ORDER BY id LIMIT $%d`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a page from items: %w", err)
 	}
 
 	var results = make([]*keys.OpaqueID, 0, page.Limit)
@@ -118,3 +282,85 @@ func (i *Items) PageItems(ctx context.Context, page paginate.Paginate) ([]*keys.
 
 	return results, nil
 }
+
+func (i *Items) GetChildren(ctx context.Context, id int) ([]*items.Item, error) {
+	rows, err := i.Conn.Query(ctx, `SELECT child_id FROM item_relationships WHERE parent_id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select children from item_relationships: %w", err)
+	}
+
+	children, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan from rows result when selecting from item_relationships: %w", err)
+	}
+
+	return i.GetItems(ctx, children...)
+}
+
+// AddChild relates items of the same retailer, either being unknown to the retailer is
+// ErrItemNotFound.
+func (i *Items) AddChild(ctx context.Context, parent int, child int, quantity *big.Rat) (*big.Rat, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var found int
+	err = i.Conn.QueryRow(ctx, `SELECT COUNT(*) FROM items WHERE id IN ($1, $2) AND retailer_id = $3`, parent, child, retailer).Scan(&found)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from items: %w", err)
+	}
+	if found != 2 {
+		return nil, items.ErrItemNotFound
+	}
+
+	tx, err := i.Conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the quantity being replaced is locked so that it is still the one replaced
+	var before *money.Decimal
+	err = tx.QueryRow(ctx, `SELECT quantity FROM item_relationships WHERE parent_id = $1 AND child_id = $2 FOR UPDATE`, parent, child).Scan(&before)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to select from item_relationships: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO item_relationships (parent_id, child_id, quantity) VALUES ($1, $2, $3)
+		ON CONFLICT (parent_id, child_id) DO UPDATE SET quantity = EXCLUDED.quantity`, parent, child, (*money.Decimal)(quantity))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Message == "cyclical relationship detected" {
+		return nil, items.ErrCyclicRelationship
+	} else if isForeignKeyViolation(err) {
+		return nil, items.ErrItemNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to insert into item_relationships: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if before == nil {
+		return nil, nil
+	}
+	return new(big.Rat).Set(before.Rat()), nil
+}
+
+// RemoveChild returns nil when the items weren't related.
+func (i *Items) RemoveChild(ctx context.Context, parent int, child int) (*big.Rat, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed money.Decimal
+	err = i.Conn.QueryRow(ctx, `DELETE FROM item_relationships WHERE parent_id = $1 AND child_id = $2 AND parent_id IN (SELECT id FROM items WHERE retailer_id = $3) RETURNING quantity`, parent, child, retailer).Scan(&removed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to delete from item_relationships: %w", err)
+	}
+	return new(big.Rat).Set(removed.Rat()), nil
+}
@@ -1 +1,262 @@
 package store
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/pkg/gtin"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+)
+
+func TestItemsVersionedWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, _ = scope(t, ctx, conn, "pedlar.test")
+
+	store, history := &Items{Conn: conn}, &ItemHistory{Conn: conn}
+
+	var item *items.Item
+	err = history.Record(ctx, func(ctx context.Context) ([]items.Change, error) {
+		created, err := store.CreateItem(ctx, items.Details{Name: "flat white", UnitScale: items.Unit})
+		if err != nil {
+			return nil, err
+		}
+		item = created
+		return []items.Change{recorded(created.ID.ID, items.Created)}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, item.Version)
+
+	err = record(ctx, history, recorded(item.ID.ID, items.Updated), func(ctx context.Context) error {
+		return store.UpdateItemDetails(ctx, item.ID.ID, 1, items.Details{Name: "long black", UnitScale: items.Unit})
+	})
+	require.NoError(t, err)
+
+	// a second till still holding version 1 must not overwrite the first write
+	err = record(ctx, history, recorded(item.ID.ID, items.Updated), func(ctx context.Context) error {
+		return store.UpdateItemDetails(ctx, item.ID.ID, 1, items.Details{Name: "latte", UnitScale: items.Unit})
+	})
+	require.ErrorIs(t, err, items.ErrVersionConflict)
+
+	err = record(ctx, history, recorded(item.ID.ID, items.Archived), func(ctx context.Context) error {
+		return store.SetItemArchived(ctx, item.ID.ID, 2, true)
+	})
+	require.NoError(t, err)
+
+	err = record(ctx, history, recorded(item.ID.ID, items.Updated), func(ctx context.Context) error {
+		return store.UpdateItemDetails(ctx, item.ID.ID, 3, items.Details{Name: "latte", UnitScale: items.Unit})
+	})
+	require.ErrorIs(t, err, items.ErrItemArchived)
+
+	archived, err := store.GetItem(ctx, item.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, "long black", archived.Name)
+	require.NotNil(t, archived.ArchivedAt)
+
+	err = record(ctx, history, recorded(item.ID.ID, items.Restored), func(ctx context.Context) error {
+		return store.SetItemArchived(ctx, item.ID.ID, 3, false)
+	})
+	require.NoError(t, err)
+
+	// a write is undone along with its history when the history can't be recorded
+	err = record(ctx, history, recorded(-1, items.Updated), func(ctx context.Context) error {
+		return store.UpdateItemDetails(ctx, item.ID.ID, 4, items.Details{Name: "mocha", UnitScale: items.Unit})
+	})
+	require.Error(t, err)
+
+	restored, err := store.GetItem(ctx, item.ID.ID)
+	require.NoError(t, err)
+	require.Nil(t, restored.ArchivedAt)
+	require.Equal(t, "long black", restored.Name)
+	require.Equal(t, 4, restored.Version)
+
+	// only the writes that went through are in the history of the item
+	changes, err := history.PageItemHistory(ctx, item.ID.ID, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	var actions []items.Action
+	for _, change := range changes {
+		actions = append(actions, change.Action)
+	}
+	require.Equal(t, []items.Action{items.Restored, items.Archived, items.Updated, items.Created}, actions)
+}
+
+// recorded is the change describing a write to the item, as the item manager would describe it.
+func recorded(item int, action items.Action) items.Change {
+	return items.Change{ItemID: &keys.OpaqueID{ID: item}, Actor: "pedlar.test", Action: action}
+}
+
+// record makes the write and records the change describing it as one unit of work, like the item
+// manager does.
+func record(ctx context.Context, history *ItemHistory, change items.Change, write func(ctx context.Context) error) error {
+	return history.Record(ctx, func(ctx context.Context) ([]items.Change, error) {
+		if err := write(ctx); err != nil {
+			return nil, err
+		}
+		return []items.Change{change}, nil
+	})
+}
+
+func TestItemsIdentifierLookup(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, _ = scope(t, ctx, conn, "pedlar.test")
+
+	store := &Items{Conn: conn}
+
+	sku := uuid.NewString()
+	item, err := store.CreateItem(ctx, items.Details{Name: "cold brew", UnitScale: items.Unit, SKU: &sku})
+	require.NoError(t, err)
+
+	_, err = store.CreateItem(ctx, items.Details{Name: "cold brew again", UnitScale: items.Unit, SKU: &sku})
+	require.ErrorIs(t, err, items.ErrDuplicateSKU)
+
+	found, err := store.GetItemBySKU(ctx, sku)
+	require.NoError(t, err)
+	require.Equal(t, item.ID.ID, found)
+
+	// the same product scanned as a UPC-A and as an EAN-13
+	upc, ean := "036000291452", "0036000291452"
+	upcGTIN, err := gtin.Normalise(upc)
+	require.NoError(t, err)
+	eanGTIN, err := gtin.Normalise(ean)
+	require.NoError(t, err)
+
+	_, _ = conn.Exec(ctx, `DELETE FROM item_barcodes WHERE gtin = $1`, upcGTIN)
+
+	err = store.AddBarcode(ctx, item.ID.ID, upcGTIN, upc)
+	require.NoError(t, err)
+
+	err = store.AddBarcode(ctx, item.ID.ID, eanGTIN, ean)
+	require.ErrorIs(t, err, items.ErrDuplicateBarcode)
+
+	found, err = store.GetItemByBarcode(ctx, eanGTIN)
+	require.NoError(t, err)
+	require.Equal(t, item.ID.ID, found)
+
+	codes, err := store.GetBarcodes(ctx, item.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{upc}, codes)
+}
+
+func TestItemsCategoryFilter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, _ = scope(t, ctx, conn, "pedlar.test")
+
+	store := &Items{Conn: conn}
+
+	beverages, err := store.CreateCategory(ctx, uuid.NewString(), nil)
+	require.NoError(t, err)
+	coffee, err := store.CreateCategory(ctx, "coffee", beverages.ID)
+	require.NoError(t, err)
+	beans, err := store.CreateCategory(ctx, "beans", coffee.ID)
+	require.NoError(t, err)
+
+	_, err = store.CreateCategory(ctx, "coffee", beverages.ID)
+	require.ErrorIs(t, err, items.ErrDuplicateCategory)
+
+	// beverages can't be moved beneath its own grandchild
+	err = store.UpdateCategory(ctx, beverages.ID.ID, beverages.Name, beans.ID)
+	require.ErrorIs(t, err, items.ErrCyclicCategory)
+
+	err = store.DeleteCategory(ctx, coffee.ID.ID)
+	require.ErrorIs(t, err, items.ErrCategoryNotEmpty)
+
+	arabica, err := store.CreateItem(ctx, items.Details{Name: "arabica", UnitScale: items.Kilogram})
+	require.NoError(t, err)
+	robusta, err := store.CreateItem(ctx, items.Details{Name: "robusta", UnitScale: items.Kilogram})
+	require.NoError(t, err)
+
+	changed, err := store.CategoriseItem(ctx, arabica.ID.ID, beans.ID.ID)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	changed, err = store.CategoriseItem(ctx, arabica.ID.ID, beans.ID.ID)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	_, err = store.CategoriseItem(ctx, robusta.ID.ID, coffee.ID.ID)
+	require.NoError(t, err)
+
+	_, err = store.TagItem(ctx, arabica.ID.ID, "organic")
+	require.NoError(t, err)
+	_, err = store.TagItem(ctx, arabica.ID.ID, "fairtrade")
+	require.NoError(t, err)
+	_, err = store.TagItem(ctx, robusta.ID.ID, "organic")
+	require.NoError(t, err)
+
+	page := paginate.Paginate{Limit: 10}
+
+	// filtering by a category includes items of its descendants
+	ids, err := store.PageItems(ctx, items.ItemFilter{Category: &keys.OpaqueID{ID: beverages.ID.ID}}, page)
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+
+	ids, err = store.PageItems(ctx, items.ItemFilter{Category: &keys.OpaqueID{ID: beverages.ID.ID}, Tags: []string{"organic", "fairtrade"}}, page)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	require.Equal(t, arabica.ID.ID, ids[0].ID)
+
+	report, err := store.CategoryReport(ctx, beverages.ID.ID, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Categories)
+	require.Equal(t, 2, report.Items)
+	require.Equal(t, 0, report.UnitsSold)
+
+	// categories belong to one retailer, others name theirs as they like and never see ours
+	theirs, _ := scope(t, context.Background(), conn, "theirs.test")
+	_, err = store.CreateCategory(theirs, beverages.Name, nil)
+	require.NoError(t, err)
+	_, err = store.GetCategory(theirs, beverages.ID.ID)
+	require.ErrorIs(t, err, items.ErrCategoryNotFound)
+	_, err = store.CreateCategory(theirs, "tea", beverages.ID)
+	require.ErrorIs(t, err, items.ErrCategoryNotFound)
+	require.ErrorIs(t, store.DeleteCategory(theirs, beans.ID.ID), items.ErrCategoryNotFound)
+
+	mug, err := store.CreateItem(theirs, items.Details{Name: "mug", UnitScale: items.Unit})
+	require.NoError(t, err)
+	_, err = store.CategoriseItem(theirs, mug.ID.ID, beans.ID.ID)
+	require.ErrorIs(t, err, items.ErrCategoryNotFound)
+}
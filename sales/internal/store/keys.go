@@ -7,12 +7,11 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/suessflorian/pedlar/sales/pkg/keys"
 )
 
 type Keys struct {
-	Conn *pgxpool.Pool
+	Conn *Pool
 }
 
 func (k *Keys) GetActiveKeySet(ctx context.Context) (*keys.KeySet, error) {
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/media"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// Images are attached to items of the retailer the context is scoped to.
+type Images struct {
+	Conn *Pool
+}
+
+const imageColumns = `id, item_id, blob_key, content_type, width, height, size, checksum, position, created_at`
+
+// CreateImage appends the image after the existing images of the item.
+func (i *Images) CreateImage(ctx context.Context, item int, key string, meta media.Metadata) (*media.Image, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `INSERT INTO item_images (retailer_id, item_id, blob_key, content_type, width, height, size, checksum, position)
+		SELECT $8, $1, $2, $3, $4, $5, $6, $7, COALESCE(MAX(position) + 1, 0) FROM item_images WHERE item_id = $1
+		RETURNING `+imageColumns,
+		item, key, meta.ContentType, meta.Width, meta.Height, meta.Size, meta.Checksum, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert into item_images: %w", err)
+	}
+
+	images, err := scanImages(rows)
+	if isUniqueViolation(err) {
+		return nil, media.ErrDuplicateImage
+	} else if isForeignKeyViolation(err) {
+		return nil, items.ErrItemNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return images[0], nil
+}
+
+func (i *Images) GetImage(ctx context.Context, id int) (*media.Image, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT `+imageColumns+` FROM item_images WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from item_images: %w", err)
+	}
+
+	images, err := scanImages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, media.ErrImageNotFound
+	}
+	return images[0], nil
+}
+
+func (i *Images) GetImages(ctx context.Context, item int) ([]*media.Image, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT `+imageColumns+` FROM item_images WHERE item_id = $1 AND retailer_id = $2 ORDER BY position`, item, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from item_images: %w", err)
+	}
+	return scanImages(rows)
+}
+
+func (i *Images) DeleteImage(ctx context.Context, id int) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := i.Conn.Exec(ctx, `DELETE FROM item_images WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to delete from item_images: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return media.ErrImageNotFound
+	}
+	return nil
+}
+
+func (i *Images) ReorderImages(ctx context.Context, item int, ids []int) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := i.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT id FROM item_images WHERE item_id = $1 AND retailer_id = $2 ORDER BY id FOR UPDATE`, item, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to select from item_images: %w", err)
+	}
+
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return fmt.Errorf("failed to scan from rows result when selecting from item_images: %w", err)
+	}
+
+	var sorted = slices.Clone(ids)
+	slices.Sort(sorted)
+	if !slices.Equal(existing, sorted) {
+		return media.ErrImageOrder
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE item_images SET position = o.position - 1 FROM UNNEST($1::integer[]) WITH ORDINALITY AS o(id, position) WHERE item_images.id = o.id`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to update positions of item_images: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func scanImages(rows pgx.Rows) ([]*media.Image, error) {
+	defer rows.Close()
+
+	var results []*media.Image
+	for rows.Next() {
+		var (
+			id     keys.OpaqueID
+			itemID keys.OpaqueID
+			img    media.Image
+		)
+		err := rows.Scan(&id, &itemID, &img.Key, &img.ContentType, &img.Width, &img.Height, &img.Size, &img.Checksum, &img.Position, &img.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from item_images: %w", err)
+		}
+
+		img.ID, img.ItemID = &id, &itemID
+		results = append(results, &img)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from item_images: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,3 @@
+ALTER TABLE items
+  DROP COLUMN IF EXISTS version,
+  DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,3 @@
+ALTER TABLE items
+  ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1,
+  ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
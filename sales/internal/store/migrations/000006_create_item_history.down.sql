@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS item_history;
+DROP FUNCTION IF EXISTS reject_history_rewrite;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS item_history (
+  id SERIAL PRIMARY KEY,
+  item_id INTEGER NOT NULL,
+  related_id INTEGER,
+  actor VARCHAR(255) NOT NULL,
+  action VARCHAR(32) NOT NULL,
+  diff JSONB NOT NULL DEFAULT '[]',
+  recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (item_id) REFERENCES items(id),
+  FOREIGN KEY (related_id) REFERENCES items(id)
+);
+
+CREATE INDEX IF NOT EXISTS item_history_item_id_idx ON item_history (item_id, id);
+CREATE INDEX IF NOT EXISTS item_history_actor_idx ON item_history (actor, id);
+
+CREATE OR REPLACE FUNCTION reject_history_rewrite() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'item history is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER item_history_append_only_trigger
+BEFORE UPDATE OR DELETE ON item_history
+FOR EACH ROW
+EXECUTE FUNCTION reject_history_rewrite();
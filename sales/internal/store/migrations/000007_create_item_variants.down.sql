@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS item_variants;
+ALTER TABLE items DROP COLUMN IF EXISTS option_axes;
@@ -0,0 +1,13 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS option_axes TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS item_variants (
+  id SERIAL PRIMARY KEY,
+  item_id INTEGER NOT NULL,
+  options JSONB NOT NULL,
+  sku VARCHAR(255),
+  barcode VARCHAR(32),
+  price_override NUMERIC(10, 2),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE,
+  UNIQUE (item_id, options)
+);
@@ -0,0 +1,7 @@
+ALTER TABLE item_variants ADD COLUMN IF NOT EXISTS barcode VARCHAR(32);
+
+UPDATE item_variants v SET barcode = b.code FROM item_barcodes b WHERE b.variant_id = v.id;
+
+DROP TABLE IF EXISTS item_barcodes;
+ALTER TABLE item_variants DROP CONSTRAINT IF EXISTS item_variants_sku_key;
+ALTER TABLE items DROP COLUMN IF EXISTS sku;
@@ -0,0 +1,20 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS sku VARCHAR(255) UNIQUE;
+ALTER TABLE item_variants ADD CONSTRAINT item_variants_sku_key UNIQUE (sku);
+
+-- gtin holds the zero padded 14 digit form of the code so that UPC-A and EAN-13 scans of
+-- the same product resolve to the same row, code is kept as it was registered.
+CREATE TABLE IF NOT EXISTS item_barcodes (
+  gtin CHAR(14) PRIMARY KEY,
+  code VARCHAR(14) NOT NULL,
+  item_id INTEGER NOT NULL,
+  variant_id INTEGER,
+  FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE,
+  FOREIGN KEY (variant_id) REFERENCES item_variants(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS item_barcodes_item_id_idx ON item_barcodes (item_id);
+
+INSERT INTO item_barcodes (gtin, code, item_id, variant_id)
+SELECT LPAD(barcode, 14, '0'), barcode, item_id, id FROM item_variants WHERE barcode IS NOT NULL;
+
+ALTER TABLE item_variants DROP COLUMN IF EXISTS barcode;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS item_tags;
+DROP TABLE IF EXISTS item_categories;
+DROP TRIGGER IF EXISTS check_category_cycle_trigger ON categories;
+DROP FUNCTION IF EXISTS check_category_cycle();
+DROP TABLE IF EXISTS categories;
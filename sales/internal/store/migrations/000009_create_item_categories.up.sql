@@ -0,0 +1,61 @@
+CREATE TABLE IF NOT EXISTS categories (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  parent_id INTEGER,
+  FOREIGN KEY (parent_id) REFERENCES categories(id) ON DELETE RESTRICT,
+  CHECK (parent_id <> id)
+);
+
+-- sibling categories must be distinguishable, roots are siblings of each other
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_id_name_idx ON categories (COALESCE(parent_id, 0), name);
+
+CREATE OR REPLACE FUNCTION check_category_cycle() RETURNS TRIGGER AS $$
+DECLARE
+  cycle BOOLEAN;
+BEGIN
+  WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id
+    FROM categories
+    WHERE id = NEW.parent_id
+
+    UNION ALL
+
+    SELECT c.id, c.parent_id
+    FROM categories c
+    JOIN ancestors a ON c.id = a.parent_id
+  )
+  SELECT TRUE INTO cycle
+  FROM ancestors
+  WHERE id = NEW.id;
+
+  IF cycle THEN
+    RAISE EXCEPTION 'cyclical category detected';
+  END IF;
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER check_category_cycle_trigger
+BEFORE UPDATE OF parent_id ON categories
+FOR EACH ROW
+EXECUTE FUNCTION check_category_cycle();
+
+CREATE TABLE IF NOT EXISTS item_categories (
+  item_id INTEGER NOT NULL,
+  category_id INTEGER NOT NULL,
+  PRIMARY KEY (item_id, category_id),
+  FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE,
+  FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS item_categories_category_id_idx ON item_categories (category_id);
+
+CREATE TABLE IF NOT EXISTS item_tags (
+  item_id INTEGER NOT NULL,
+  tag VARCHAR(64) NOT NULL,
+  PRIMARY KEY (item_id, tag),
+  FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS item_tags_tag_idx ON item_tags (tag);
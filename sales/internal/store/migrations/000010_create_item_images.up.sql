@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS item_images (
+  id SERIAL PRIMARY KEY,
+  item_id INTEGER NOT NULL,
+  blob_key VARCHAR(255) NOT NULL UNIQUE,
+  content_type VARCHAR(64) NOT NULL,
+  width INTEGER NOT NULL,
+  height INTEGER NOT NULL,
+  size BIGINT NOT NULL,
+  checksum CHAR(64) NOT NULL,
+  position INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (item_id, checksum),
+  FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS item_images_item_id_idx ON item_images (item_id, position);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS sales_retailer_id_idx;
+DROP INDEX IF EXISTS line_items_product_id_idx;
+DROP INDEX IF EXISTS line_items_sale_id_idx;
+ALTER TABLE line_items DROP CONSTRAINT IF EXISTS line_items_unit_price_check;
+ALTER TABLE line_items DROP CONSTRAINT IF EXISTS line_items_quantity_check;
+ALTER TABLE line_items DROP CONSTRAINT IF EXISTS line_items_product_id_fkey;
+ALTER TABLE sales ALTER COLUMN customer_id SET NOT NULL;
@@ -0,0 +1,11 @@
+-- sales made before customers are known to the system are anonymous
+ALTER TABLE sales ALTER COLUMN customer_id DROP NOT NULL;
+
+-- NOT VALID leaves historic line items alone while enforcing the reference for new ones
+ALTER TABLE line_items ADD CONSTRAINT line_items_product_id_fkey FOREIGN KEY (product_id) REFERENCES items(id) ON DELETE RESTRICT NOT VALID;
+ALTER TABLE line_items ADD CONSTRAINT line_items_quantity_check CHECK (quantity > 0) NOT VALID;
+ALTER TABLE line_items ADD CONSTRAINT line_items_unit_price_check CHECK (unit_price >= 0) NOT VALID;
+
+CREATE INDEX IF NOT EXISTS line_items_sale_id_idx ON line_items (sale_id);
+CREATE INDEX IF NOT EXISTS line_items_product_id_idx ON line_items (product_id);
+CREATE INDEX IF NOT EXISTS sales_retailer_id_idx ON sales (retailer_id, id);
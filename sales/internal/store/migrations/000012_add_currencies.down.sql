@@ -0,0 +1,5 @@
+ALTER TABLE item_variants DROP CONSTRAINT IF EXISTS item_variants_price_override_currency_check;
+ALTER TABLE item_variants ALTER COLUMN price_override TYPE NUMERIC(10, 2);
+ALTER TABLE item_variants DROP COLUMN IF EXISTS price_override_currency;
+ALTER TABLE line_items ALTER COLUMN unit_price TYPE NUMERIC(10, 2);
+ALTER TABLE sales DROP COLUMN IF EXISTS currency;
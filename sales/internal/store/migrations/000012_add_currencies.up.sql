@@ -0,0 +1,11 @@
+-- every amount is held next to the ISO-4217 code of its currency, existing amounts were all NZD
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'NZD';
+ALTER TABLE sales ALTER COLUMN currency DROP DEFAULT;
+
+-- wide enough for currencies with three minor unit digits
+ALTER TABLE line_items ALTER COLUMN unit_price TYPE NUMERIC(19, 4);
+
+ALTER TABLE item_variants ADD COLUMN IF NOT EXISTS price_override_currency CHAR(3);
+UPDATE item_variants SET price_override_currency = 'NZD' WHERE price_override IS NOT NULL;
+ALTER TABLE item_variants ALTER COLUMN price_override TYPE NUMERIC(19, 4);
+ALTER TABLE item_variants ADD CONSTRAINT item_variants_price_override_currency_check CHECK ((price_override IS NULL) = (price_override_currency IS NULL));
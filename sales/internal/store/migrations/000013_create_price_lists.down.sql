@@ -0,0 +1,6 @@
+ALTER TABLE line_items DROP COLUMN IF EXISTS override_reason;
+ALTER TABLE line_items DROP COLUMN IF EXISTS overridden_by;
+ALTER TABLE line_items DROP COLUMN IF EXISTS list_price;
+ALTER TABLE sales DROP COLUMN IF EXISTS price_list_id;
+DROP TABLE IF EXISTS item_prices;
+DROP TABLE IF EXISTS price_lists;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS price_lists (
+  id SERIAL PRIMARY KEY,
+  retailer_id INTEGER NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  kind VARCHAR(16) NOT NULL CHECK (kind IN ('RETAIL', 'WHOLESALE', 'MEMBER')),
+  currency CHAR(3) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE CASCADE,
+  UNIQUE (retailer_id, name)
+);
+
+-- sales are priced from the retail list of their retailer and currency by default
+CREATE UNIQUE INDEX IF NOT EXISTS price_lists_retail_idx ON price_lists (retailer_id, currency) WHERE kind = 'RETAIL';
+
+CREATE TABLE IF NOT EXISTS item_prices (
+  id SERIAL PRIMARY KEY,
+  price_list_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  amount NUMERIC(19, 4) NOT NULL CHECK (amount >= 0),
+  unit_scale VARCHAR(255) NOT NULL,
+  effective_from TIMESTAMP NOT NULL,
+  effective_to TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (price_list_id) REFERENCES price_lists (id) ON DELETE CASCADE,
+  FOREIGN KEY (item_id) REFERENCES items (id) ON DELETE CASCADE,
+  CHECK (effective_to IS NULL OR effective_to > effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS item_prices_lookup_idx ON item_prices (price_list_id, item_id, effective_from);
+
+-- sales remember the list they were priced from and line items whether the till overrode it
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS price_list_id INTEGER REFERENCES price_lists (id) ON DELETE RESTRICT;
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS list_price NUMERIC(19, 4);
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS overridden_by VARCHAR(255);
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS override_reason TEXT;
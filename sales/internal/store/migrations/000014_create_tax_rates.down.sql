@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS sale_taxes;
+ALTER TABLE sales DROP COLUMN IF EXISTS prices_include_tax;
+DROP TABLE IF EXISTS tax_settings;
+DROP TABLE IF EXISTS tax_rates;
+ALTER TABLE items DROP COLUMN IF EXISTS tax_class;
@@ -0,0 +1,42 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS tax_class VARCHAR(32) NOT NULL DEFAULT 'STANDARD';
+
+CREATE TABLE IF NOT EXISTS tax_rates (
+  id SERIAL PRIMARY KEY,
+  jurisdiction VARCHAR(16) NOT NULL,
+  tax_class VARCHAR(32) NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  rate NUMERIC(7, 6) NOT NULL CHECK (rate >= 0 AND rate <= 1),
+  effective_from TIMESTAMP NOT NULL,
+  effective_to TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CHECK (effective_to IS NULL OR effective_to > effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS tax_rates_lookup_idx ON tax_rates (jurisdiction, effective_from);
+
+CREATE TABLE IF NOT EXISTS tax_settings (
+  retailer_id INTEGER PRIMARY KEY,
+  jurisdiction VARCHAR(16) NOT NULL,
+  prices_include_tax BOOLEAN NOT NULL,
+  rounding VARCHAR(16) NOT NULL CHECK (rounding IN ('LINE', 'INVOICE')),
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE CASCADE
+);
+
+-- sales keep the tax they were charged, rates and settings may well change after the fact
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS sale_taxes (
+  id SERIAL PRIMARY KEY,
+  sale_id INTEGER NOT NULL,
+  tax_rate_id INTEGER NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  tax_class VARCHAR(32) NOT NULL,
+  rate NUMERIC(7, 6) NOT NULL,
+  taxable NUMERIC(19, 4) NOT NULL,
+  amount NUMERIC(19, 4) NOT NULL,
+  FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE CASCADE,
+  FOREIGN KEY (tax_rate_id) REFERENCES tax_rates (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS sale_taxes_sale_id_idx ON sale_taxes (sale_id);
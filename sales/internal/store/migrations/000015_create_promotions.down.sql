@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS line_item_discounts;
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS promotions (
+  id SERIAL PRIMARY KEY,
+  retailer_id INTEGER NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  kind VARCHAR(16) NOT NULL CHECK (kind IN ('BUY_X_GET_Y', 'PERCENT_OFF', 'AMOUNT_OFF')),
+  priority INTEGER NOT NULL DEFAULT 0,
+  exclusive BOOLEAN NOT NULL DEFAULT FALSE,
+  item_id INTEGER,
+  category_id INTEGER,
+  min_spend NUMERIC(19, 4),
+  coupon VARCHAR(32),
+  buy INTEGER,
+  free INTEGER,
+  percent NUMERIC(7, 6),
+  amount NUMERIC(19, 4),
+  -- the currency of min_spend and amount, promotions without amounts apply in any currency
+  currency CHAR(3),
+  starts_at TIMESTAMP NOT NULL,
+  ends_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE CASCADE,
+  FOREIGN KEY (item_id) REFERENCES items (id) ON DELETE RESTRICT,
+  FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE RESTRICT,
+  CHECK (item_id IS NULL OR category_id IS NULL),
+  CHECK ((min_spend IS NULL AND amount IS NULL) = (currency IS NULL)),
+  -- promotions ended before they start have an empty window
+  CHECK (ends_at IS NULL OR ends_at >= starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS promotions_retailer_idx ON promotions (retailer_id, starts_at);
+
+-- discounts keep the name of their promotion as it was at the time of sale
+CREATE TABLE IF NOT EXISTS line_item_discounts (
+  id SERIAL PRIMARY KEY,
+  line_item_id INTEGER NOT NULL,
+  promotion_id INTEGER NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  amount NUMERIC(19, 4) NOT NULL CHECK (amount > 0),
+  FOREIGN KEY (line_item_id) REFERENCES line_items (id) ON DELETE CASCADE,
+  FOREIGN KEY (promotion_id) REFERENCES promotions (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS line_item_discounts_line_item_id_idx ON line_item_discounts (line_item_id);
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS refund_lines;
+DROP TABLE IF EXISTS refunds;
+ALTER TABLE sales DROP COLUMN IF EXISTS void_reason;
+ALTER TABLE sales DROP COLUMN IF EXISTS voided_by;
+ALTER TABLE sales DROP COLUMN IF EXISTS voided_at;
+DROP TRIGGER IF EXISTS line_items_reject_delete_trigger ON line_items;
+DROP TRIGGER IF EXISTS sales_reject_delete_trigger ON sales;
+DROP FUNCTION IF EXISTS reject_sale_delete;
+ALTER TABLE line_item_discounts DROP CONSTRAINT IF EXISTS line_item_discounts_line_item_id_fkey;
+ALTER TABLE line_item_discounts ADD CONSTRAINT line_item_discounts_line_item_id_fkey FOREIGN KEY (line_item_id) REFERENCES line_items (id) ON DELETE CASCADE;
+ALTER TABLE sale_taxes DROP CONSTRAINT IF EXISTS sale_taxes_sale_id_fkey;
+ALTER TABLE sale_taxes ADD CONSTRAINT sale_taxes_sale_id_fkey FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE CASCADE;
+ALTER TABLE line_items DROP CONSTRAINT IF EXISTS line_items_sale_id_fkey;
+ALTER TABLE line_items ADD CONSTRAINT line_items_sale_id_fkey FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE CASCADE;
+ALTER TABLE sales DROP CONSTRAINT IF EXISTS sales_retailer_id_fkey;
+ALTER TABLE sales ADD CONSTRAINT sales_retailer_id_fkey FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE CASCADE;
@@ -0,0 +1,54 @@
+-- sales are history, they are voided or refunded but never deleted
+ALTER TABLE sales DROP CONSTRAINT IF EXISTS sales_retailer_id_fkey;
+ALTER TABLE sales ADD CONSTRAINT sales_retailer_id_fkey FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE RESTRICT;
+ALTER TABLE line_items DROP CONSTRAINT IF EXISTS line_items_sale_id_fkey;
+ALTER TABLE line_items ADD CONSTRAINT line_items_sale_id_fkey FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE RESTRICT;
+ALTER TABLE sale_taxes DROP CONSTRAINT IF EXISTS sale_taxes_sale_id_fkey;
+ALTER TABLE sale_taxes ADD CONSTRAINT sale_taxes_sale_id_fkey FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE RESTRICT;
+ALTER TABLE line_item_discounts DROP CONSTRAINT IF EXISTS line_item_discounts_line_item_id_fkey;
+ALTER TABLE line_item_discounts ADD CONSTRAINT line_item_discounts_line_item_id_fkey FOREIGN KEY (line_item_id) REFERENCES line_items (id) ON DELETE RESTRICT;
+
+CREATE OR REPLACE FUNCTION reject_sale_delete() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'sales cannot be deleted, void or refund them instead';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER sales_reject_delete_trigger
+BEFORE DELETE ON sales
+FOR EACH ROW
+EXECUTE FUNCTION reject_sale_delete();
+
+CREATE TRIGGER line_items_reject_delete_trigger
+BEFORE DELETE ON line_items
+FOR EACH ROW
+EXECUTE FUNCTION reject_sale_delete();
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS voided_at TIMESTAMP;
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS voided_by VARCHAR(255);
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS void_reason TEXT;
+
+CREATE TABLE IF NOT EXISTS refunds (
+  id SERIAL PRIMARY KEY,
+  sale_id INTEGER NOT NULL,
+  reason VARCHAR(16) NOT NULL CHECK (reason IN ('DAMAGED', 'DEFECTIVE', 'WRONG_ITEM', 'UNWANTED', 'PRICING_ERROR', 'OTHER')),
+  note TEXT,
+  actor VARCHAR(255) NOT NULL,
+  refunded_at TIMESTAMP NOT NULL,
+  FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS refunds_sale_id_idx ON refunds (sale_id);
+
+CREATE TABLE IF NOT EXISTS refund_lines (
+  id SERIAL PRIMARY KEY,
+  refund_id INTEGER NOT NULL,
+  line_item_id INTEGER NOT NULL,
+  quantity INTEGER NOT NULL CHECK (quantity > 0),
+  amount NUMERIC(19, 4) NOT NULL CHECK (amount >= 0),
+  FOREIGN KEY (refund_id) REFERENCES refunds (id) ON DELETE RESTRICT,
+  FOREIGN KEY (line_item_id) REFERENCES line_items (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS refund_lines_refund_id_idx ON refund_lines (refund_id);
+CREATE INDEX IF NOT EXISTS refund_lines_line_item_id_idx ON refund_lines (line_item_id);
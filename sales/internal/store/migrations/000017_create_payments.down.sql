@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payments;
+ALTER TABLE sales DROP COLUMN IF EXISTS status;
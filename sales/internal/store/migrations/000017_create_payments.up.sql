@@ -0,0 +1,31 @@
+-- sales recorded before payments were recorded are taken to have been paid
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'PAID' CHECK (status IN ('OPEN', 'PAID', 'REFUNDED', 'VOIDED'));
+ALTER TABLE sales ALTER COLUMN status SET DEFAULT 'OPEN';
+
+UPDATE sales SET status = 'VOIDED' WHERE voided_at IS NOT NULL;
+UPDATE sales s SET status = 'REFUNDED'
+WHERE s.voided_at IS NULL
+AND EXISTS (SELECT 1 FROM refunds r WHERE r.sale_id = s.id)
+AND NOT EXISTS (
+  SELECT 1 FROM line_items l
+  WHERE l.sale_id = s.id
+  AND l.quantity > (SELECT COALESCE(SUM(rl.quantity), 0) FROM refund_lines rl WHERE rl.line_item_id = l.id)
+);
+
+CREATE TABLE IF NOT EXISTS payments (
+  id SERIAL PRIMARY KEY,
+  sale_id INTEGER NOT NULL,
+  tender VARCHAR(16) NOT NULL CHECK (tender IN ('CASH', 'CARD', 'VOUCHER', 'STORE_CREDIT')),
+  amount NUMERIC(19, 4) NOT NULL CHECK (amount > 0),
+  tendered NUMERIC(19, 4),
+  change NUMERIC(19, 4),
+  reference VARCHAR(255),
+  actor VARCHAR(255) NOT NULL,
+  paid_at TIMESTAMP NOT NULL,
+  reversed_at TIMESTAMP,
+  FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE RESTRICT,
+  -- cash changes hands at the till, everything else is charged by the payment provider
+  CHECK ((tender = 'CASH') = (tendered IS NOT NULL AND change IS NOT NULL AND reference IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS payments_sale_id_idx ON payments (sale_id);
@@ -0,0 +1,20 @@
+ALTER TABLE item_barcodes DROP CONSTRAINT IF EXISTS item_barcodes_pkey;
+ALTER TABLE item_barcodes ADD PRIMARY KEY (gtin);
+ALTER TABLE item_barcodes DROP CONSTRAINT IF EXISTS item_barcodes_item_id_fkey;
+ALTER TABLE item_barcodes ADD CONSTRAINT item_barcodes_item_id_fkey FOREIGN KEY (item_id) REFERENCES items (id) ON DELETE CASCADE;
+ALTER TABLE item_barcodes DROP COLUMN IF EXISTS retailer_id;
+
+ALTER TABLE item_variants DROP CONSTRAINT IF EXISTS item_variants_sku_key;
+ALTER TABLE item_variants ADD CONSTRAINT item_variants_sku_key UNIQUE (sku);
+ALTER TABLE item_variants DROP CONSTRAINT IF EXISTS item_variants_item_id_fkey;
+ALTER TABLE item_variants ADD CONSTRAINT item_variants_item_id_fkey FOREIGN KEY (item_id) REFERENCES items (id) ON DELETE CASCADE;
+ALTER TABLE item_variants DROP COLUMN IF EXISTS retailer_id;
+
+ALTER TABLE items DROP CONSTRAINT IF EXISTS items_sku_key;
+ALTER TABLE items ADD CONSTRAINT items_sku_key UNIQUE (sku);
+DROP INDEX IF EXISTS items_retailer_id_idx;
+ALTER TABLE items DROP CONSTRAINT IF EXISTS items_id_retailer_id_key;
+ALTER TABLE items DROP COLUMN IF EXISTS retailer_id;
+
+DROP INDEX IF EXISTS retailers_domain_key;
+ALTER TABLE retailers DROP COLUMN IF EXISTS name;
@@ -0,0 +1,41 @@
+ALTER TABLE retailers ADD COLUMN IF NOT EXISTS name VARCHAR(255);
+UPDATE retailers SET name = domain, domain = LOWER(domain);
+ALTER TABLE retailers ALTER COLUMN name SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS retailers_domain_key ON retailers (domain);
+
+-- items made before retailers owned them go to the retailer that sold them first, or the first
+-- retailer there is
+INSERT INTO retailers (name, domain)
+SELECT 'localhost', 'localhost'
+WHERE EXISTS (SELECT 1 FROM items) AND NOT EXISTS (SELECT 1 FROM retailers);
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS retailer_id INTEGER;
+UPDATE items i SET retailer_id = COALESCE(
+  (SELECT s.retailer_id FROM line_items l JOIN sales s ON s.id = l.sale_id WHERE l.product_id = i.id ORDER BY s.id LIMIT 1),
+  (SELECT MIN(id) FROM retailers)
+);
+ALTER TABLE items ALTER COLUMN retailer_id SET NOT NULL;
+ALTER TABLE items ADD CONSTRAINT items_retailer_id_fkey FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE RESTRICT;
+ALTER TABLE items ADD CONSTRAINT items_id_retailer_id_key UNIQUE (id, retailer_id);
+CREATE INDEX IF NOT EXISTS items_retailer_id_idx ON items (retailer_id, id);
+
+-- skus and barcodes are unique to a retailer, two retailers stock the same products
+ALTER TABLE items DROP CONSTRAINT IF EXISTS items_sku_key;
+ALTER TABLE items ADD CONSTRAINT items_sku_key UNIQUE (retailer_id, sku);
+
+ALTER TABLE item_variants ADD COLUMN IF NOT EXISTS retailer_id INTEGER;
+UPDATE item_variants v SET retailer_id = i.retailer_id FROM items i WHERE i.id = v.item_id;
+ALTER TABLE item_variants ALTER COLUMN retailer_id SET NOT NULL;
+ALTER TABLE item_variants DROP CONSTRAINT IF EXISTS item_variants_item_id_fkey;
+ALTER TABLE item_variants ADD CONSTRAINT item_variants_item_id_fkey FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE CASCADE;
+ALTER TABLE item_variants DROP CONSTRAINT IF EXISTS item_variants_sku_key;
+ALTER TABLE item_variants ADD CONSTRAINT item_variants_sku_key UNIQUE (retailer_id, sku);
+
+ALTER TABLE item_barcodes ADD COLUMN IF NOT EXISTS retailer_id INTEGER;
+UPDATE item_barcodes b SET retailer_id = i.retailer_id FROM items i WHERE i.id = b.item_id;
+ALTER TABLE item_barcodes ALTER COLUMN retailer_id SET NOT NULL;
+ALTER TABLE item_barcodes DROP CONSTRAINT IF EXISTS item_barcodes_item_id_fkey;
+ALTER TABLE item_barcodes ADD CONSTRAINT item_barcodes_item_id_fkey FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE CASCADE;
+ALTER TABLE item_barcodes DROP CONSTRAINT IF EXISTS item_barcodes_pkey;
+ALTER TABLE item_barcodes ADD PRIMARY KEY (retailer_id, gtin);
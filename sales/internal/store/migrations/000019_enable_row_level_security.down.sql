@@ -0,0 +1,26 @@
+DROP POLICY IF EXISTS line_items_retailer ON line_items;
+ALTER TABLE line_items DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS sales_retailer ON sales;
+ALTER TABLE sales DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS item_relationships_retailer ON item_relationships;
+ALTER TABLE item_relationships DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS item_barcodes_retailer ON item_barcodes;
+ALTER TABLE item_barcodes DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS item_variants_retailer ON item_variants;
+ALTER TABLE item_variants DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS items_retailer ON items;
+ALTER TABLE items DISABLE ROW LEVEL SECURITY;
+
+DROP FUNCTION IF EXISTS current_retailer();
+
+-- the role is left in place as other databases of the cluster may still grant to it
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE USAGE, SELECT ON SEQUENCES FROM pedlar_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM pedlar_tenant;
+REVOKE USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public FROM pedlar_tenant;
+REVOKE SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public FROM pedlar_tenant;
+REVOKE USAGE ON SCHEMA public FROM pedlar_tenant;
@@ -0,0 +1,46 @@
+-- connections scoped to a retailer assume pedlar_tenant, roles belong to the cluster rather than
+-- the database so it may already be there
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'pedlar_tenant') THEN
+    CREATE ROLE pedlar_tenant NOLOGIN;
+  END IF;
+END
+$$;
+
+GRANT pedlar_tenant TO CURRENT_USER;
+GRANT USAGE ON SCHEMA public TO pedlar_tenant;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO pedlar_tenant;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO pedlar_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO pedlar_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO pedlar_tenant;
+
+-- the setting is empty rather than missing once a connection has been scoped and unscoped again
+CREATE OR REPLACE FUNCTION current_retailer() RETURNS INTEGER AS $$
+  SELECT NULLIF(current_setting('app.retailer_id', true), '')::INTEGER
+$$ LANGUAGE sql STABLE;
+
+ALTER TABLE items ENABLE ROW LEVEL SECURITY;
+CREATE POLICY items_retailer ON items TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE item_variants ENABLE ROW LEVEL SECURITY;
+CREATE POLICY item_variants_retailer ON item_variants TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE item_barcodes ENABLE ROW LEVEL SECURITY;
+CREATE POLICY item_barcodes_retailer ON item_barcodes TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+-- both ends of a relationship are items of the retailer, the items policy narrows the subqueries
+ALTER TABLE item_relationships ENABLE ROW LEVEL SECURITY;
+CREATE POLICY item_relationships_retailer ON item_relationships TO pedlar_tenant
+  USING (parent_id IN (SELECT id FROM items) AND child_id IN (SELECT id FROM items));
+
+ALTER TABLE sales ENABLE ROW LEVEL SECURITY;
+CREATE POLICY sales_retailer ON sales TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE line_items ENABLE ROW LEVEL SECURITY;
+CREATE POLICY line_items_retailer ON line_items TO pedlar_tenant
+  USING (sale_id IN (SELECT id FROM sales) AND product_id IN (SELECT id FROM items));
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS retailer_domains;
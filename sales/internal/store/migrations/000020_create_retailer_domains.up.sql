@@ -0,0 +1,11 @@
+-- every domain a retailer is reached through, its primary domain among them
+CREATE TABLE IF NOT EXISTS retailer_domains (
+  domain VARCHAR(255) PRIMARY KEY,
+  retailer_id INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS retailer_domains_retailer_id_idx ON retailer_domains (retailer_id);
+
+INSERT INTO retailer_domains (domain, retailer_id) SELECT domain, id FROM retailers ON CONFLICT DO NOTHING;
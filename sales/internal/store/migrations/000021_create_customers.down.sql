@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS sales_customer_id_idx;
+ALTER TABLE sales DROP CONSTRAINT IF EXISTS sales_customer_id_fkey;
+DROP TABLE IF EXISTS customers;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS customers (
+  id SERIAL PRIMARY KEY,
+  retailer_id INTEGER NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  email VARCHAR(255),
+  phone VARCHAR(16),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE RESTRICT,
+  CONSTRAINT customers_id_retailer_id_key UNIQUE (id, retailer_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS customers_email_key ON customers (retailer_id, email);
+CREATE UNIQUE INDEX IF NOT EXISTS customers_phone_key ON customers (retailer_id, phone);
+
+-- sales recorded against customers before there were customers referred to nobody in particular
+UPDATE sales SET customer_id = NULL WHERE customer_id IS NOT NULL;
+
+-- a sale and its customer belong to the same retailer, customers are unlinked from their sales
+-- before they are erased rather than taking the sales with them
+ALTER TABLE sales ADD CONSTRAINT sales_customer_id_fkey FOREIGN KEY (customer_id, retailer_id) REFERENCES customers (id, retailer_id) ON DELETE RESTRICT;
+CREATE INDEX IF NOT EXISTS sales_customer_id_idx ON sales (customer_id);
+
+ALTER TABLE customers ENABLE ROW LEVEL SECURITY;
+CREATE POLICY customers_retailer ON customers TO pedlar_tenant
+  USING (retailer_id = current_retailer());
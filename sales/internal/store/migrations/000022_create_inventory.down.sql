@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS items_stocked_unit_scale_trigger ON items;
+DROP FUNCTION IF EXISTS reject_stocked_unit_scale;
+
+DROP TABLE IF EXISTS stock_levels;
+DROP TABLE IF EXISTS stock_movements;
+DROP FUNCTION IF EXISTS snapshot_stock_level;
+DROP FUNCTION IF EXISTS reject_ledger_rewrite;
+
+ALTER TABLE sales DROP CONSTRAINT IF EXISTS sales_location_id_fkey;
+ALTER TABLE sales DROP COLUMN IF EXISTS location_id;
+DROP TABLE IF EXISTS stock_locations;
@@ -0,0 +1,113 @@
+CREATE TABLE IF NOT EXISTS stock_locations (
+  id SERIAL PRIMARY KEY,
+  retailer_id INTEGER NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  is_default BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE RESTRICT,
+  CONSTRAINT stock_locations_id_retailer_id_key UNIQUE (id, retailer_id),
+  CONSTRAINT stock_locations_name_key UNIQUE (retailer_id, name)
+);
+
+-- sales take stock from the default location of the retailer unless the till says otherwise
+CREATE UNIQUE INDEX IF NOT EXISTS stock_locations_default_key ON stock_locations (retailer_id) WHERE is_default;
+
+-- sales recorded before there were locations, or by retailers without any, don't move stock
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS location_id INTEGER;
+ALTER TABLE sales ADD CONSTRAINT sales_location_id_fkey FOREIGN KEY (location_id, retailer_id) REFERENCES stock_locations (id, retailer_id) ON DELETE RESTRICT;
+
+CREATE TABLE IF NOT EXISTS stock_movements (
+  id SERIAL PRIMARY KEY,
+  retailer_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  location_id INTEGER NOT NULL,
+  -- in the unit scale of the item, negative when stock leaves the location
+  quantity NUMERIC(19, 4) NOT NULL CHECK (quantity <> 0),
+  reason VARCHAR(16) NOT NULL CHECK (reason IN ('RECEIPT', 'SALE', 'REFUND', 'ADJUSTMENT', 'TRANSFER')),
+  sale_id INTEGER,
+  -- the location on the other side of a transfer
+  transfer_location_id INTEGER,
+  note TEXT,
+  actor VARCHAR(255) NOT NULL,
+  moved_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT stock_movements_item_id_fkey FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE RESTRICT,
+  CONSTRAINT stock_movements_location_id_fkey FOREIGN KEY (location_id, retailer_id) REFERENCES stock_locations (id, retailer_id) ON DELETE RESTRICT,
+  CONSTRAINT stock_movements_transfer_location_id_fkey FOREIGN KEY (transfer_location_id, retailer_id) REFERENCES stock_locations (id, retailer_id) ON DELETE RESTRICT,
+  FOREIGN KEY (sale_id) REFERENCES sales (id) ON DELETE RESTRICT,
+  CHECK ((reason IN ('SALE', 'REFUND')) = (sale_id IS NOT NULL)),
+  CHECK ((reason = 'TRANSFER') = (transfer_location_id IS NOT NULL)),
+  CHECK (transfer_location_id <> location_id)
+);
+
+CREATE INDEX IF NOT EXISTS stock_movements_item_id_idx ON stock_movements (item_id, id);
+CREATE INDEX IF NOT EXISTS stock_movements_location_id_idx ON stock_movements (location_id, id);
+CREATE INDEX IF NOT EXISTS stock_movements_sale_id_idx ON stock_movements (sale_id);
+
+CREATE OR REPLACE FUNCTION reject_ledger_rewrite() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'stock movements are append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stock_movements_append_only_trigger
+BEFORE UPDATE OR DELETE ON stock_movements
+FOR EACH ROW
+EXECUTE FUNCTION reject_ledger_rewrite();
+
+-- the on hand stock of every item at every location it has moved through, kept in step with the
+-- ledger so that it never has to be summed up on read
+CREATE TABLE IF NOT EXISTS stock_levels (
+  item_id INTEGER NOT NULL,
+  location_id INTEGER NOT NULL,
+  retailer_id INTEGER NOT NULL,
+  on_hand NUMERIC(19, 4) NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (item_id, location_id),
+  FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE RESTRICT,
+  FOREIGN KEY (location_id, retailer_id) REFERENCES stock_locations (id, retailer_id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS stock_levels_location_id_idx ON stock_levels (location_id);
+
+CREATE OR REPLACE FUNCTION snapshot_stock_level() RETURNS TRIGGER AS $$
+BEGIN
+  INSERT INTO stock_levels (item_id, location_id, retailer_id, on_hand, updated_at)
+  VALUES (NEW.item_id, NEW.location_id, NEW.retailer_id, NEW.quantity, NEW.moved_at)
+  ON CONFLICT (item_id, location_id) DO UPDATE
+  SET on_hand = stock_levels.on_hand + EXCLUDED.on_hand, updated_at = EXCLUDED.updated_at;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stock_movements_snapshot_trigger
+AFTER INSERT ON stock_movements
+FOR EACH ROW
+EXECUTE FUNCTION snapshot_stock_level();
+
+-- quantities on the ledger are in the unit scale of the item, which then can't change under them
+CREATE OR REPLACE FUNCTION reject_stocked_unit_scale() RETURNS TRIGGER AS $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM stock_movements WHERE item_id = NEW.id) THEN
+    RAISE EXCEPTION 'unit scale of stocked item cannot change';
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER items_stocked_unit_scale_trigger
+BEFORE UPDATE OF unit_scale ON items
+FOR EACH ROW
+WHEN (OLD.unit_scale IS DISTINCT FROM NEW.unit_scale)
+EXECUTE FUNCTION reject_stocked_unit_scale();
+
+ALTER TABLE stock_locations ENABLE ROW LEVEL SECURITY;
+CREATE POLICY stock_locations_retailer ON stock_locations TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE stock_movements ENABLE ROW LEVEL SECURITY;
+CREATE POLICY stock_movements_retailer ON stock_movements TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE stock_levels ENABLE ROW LEVEL SECURITY;
+CREATE POLICY stock_levels_retailer ON stock_levels TO pedlar_tenant
+  USING (retailer_id = current_retailer());
@@ -0,0 +1 @@
+ALTER TABLE item_relationships DROP COLUMN IF EXISTS quantity;
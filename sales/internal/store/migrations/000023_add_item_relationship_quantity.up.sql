@@ -0,0 +1,3 @@
+-- how much of the child, in its own unit scale, goes into one of the parent, so that selling a kit
+-- can take stock from its components
+ALTER TABLE item_relationships ADD COLUMN IF NOT EXISTS quantity NUMERIC(19, 4) NOT NULL DEFAULT 1 CHECK (quantity > 0);
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS reorder_points (
+  item_id INTEGER NOT NULL,
+  location_id INTEGER NOT NULL,
+  retailer_id INTEGER NOT NULL,
+  -- in the unit scale of the item, stock on hand below it raises an alert
+  threshold NUMERIC(19, 4) NOT NULL CHECK (threshold >= 0),
+  -- how many days of recent sales a reorder should last
+  cover_days INTEGER NOT NULL CHECK (cover_days > 0),
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (item_id, location_id),
+  CONSTRAINT reorder_points_item_id_fkey FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE RESTRICT,
+  CONSTRAINT reorder_points_location_id_fkey FOREIGN KEY (location_id, retailer_id) REFERENCES stock_locations (id, retailer_id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS reorder_points_location_id_idx ON reorder_points (location_id);
+
+CREATE TABLE IF NOT EXISTS stock_alerts (
+  id SERIAL PRIMARY KEY,
+  retailer_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  location_id INTEGER NOT NULL,
+  -- the stock on hand and reorder point when the alert was raised
+  on_hand NUMERIC(19, 4) NOT NULL,
+  threshold NUMERIC(19, 4) NOT NULL,
+  raised_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  notified_at TIMESTAMP,
+  resolved_at TIMESTAMP,
+  FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE RESTRICT,
+  FOREIGN KEY (location_id, retailer_id) REFERENCES stock_locations (id, retailer_id) ON DELETE RESTRICT
+);
+
+-- an item is alerted on at most once at a time at each location
+CREATE UNIQUE INDEX IF NOT EXISTS stock_alerts_open_key ON stock_alerts (item_id, location_id) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS stock_alerts_retailer_id_idx ON stock_alerts (retailer_id, id);
+
+ALTER TABLE reorder_points ENABLE ROW LEVEL SECURITY;
+CREATE POLICY reorder_points_retailer ON reorder_points TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE stock_alerts ENABLE ROW LEVEL SECURITY;
+CREATE POLICY stock_alerts_retailer ON stock_alerts TO pedlar_tenant
+  USING (retailer_id = current_retailer());
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS stock_movements_purchase_order_id_idx;
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_purchase_order_id_check;
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_purchase_order_id_fkey;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS purchase_order_id;
+
+DROP TABLE IF EXISTS purchase_order_lines;
+DROP TABLE IF EXISTS purchase_orders;
+DROP TABLE IF EXISTS supplier_items;
+DROP TABLE IF EXISTS suppliers;
@@ -0,0 +1,94 @@
+CREATE TABLE IF NOT EXISTS suppliers (
+  id SERIAL PRIMARY KEY,
+  retailer_id INTEGER NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  email VARCHAR(255),
+  phone VARCHAR(16),
+  -- purchase orders with the supplier and the costs of its items are in its currency
+  currency CHAR(3) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE RESTRICT,
+  CONSTRAINT suppliers_id_retailer_id_key UNIQUE (id, retailer_id),
+  CONSTRAINT suppliers_name_key UNIQUE (retailer_id, name)
+);
+
+-- the items a supplier sells, under the code the supplier knows them by
+CREATE TABLE IF NOT EXISTS supplier_items (
+  supplier_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  retailer_id INTEGER NOT NULL,
+  code VARCHAR(64),
+  -- the cost of one of the unit scale of the item, in the currency of the supplier
+  cost NUMERIC(19, 4) NOT NULL CHECK (cost >= 0),
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (supplier_id, item_id),
+  CONSTRAINT supplier_items_supplier_id_fkey FOREIGN KEY (supplier_id, retailer_id) REFERENCES suppliers (id, retailer_id) ON DELETE CASCADE,
+  CONSTRAINT supplier_items_item_id_fkey FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE RESTRICT,
+  CONSTRAINT supplier_items_code_key UNIQUE (supplier_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS supplier_items_item_id_idx ON supplier_items (item_id);
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+  id SERIAL PRIMARY KEY,
+  retailer_id INTEGER NOT NULL,
+  supplier_id INTEGER NOT NULL,
+  -- where the stock ordered is delivered to
+  location_id INTEGER NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'DRAFT' CHECK (status IN ('DRAFT', 'ORDERED', 'PARTIALLY_RECEIVED', 'RECEIVED', 'CANCELLED')),
+  currency CHAR(3) NOT NULL,
+  note TEXT,
+  created_by VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ordered_at TIMESTAMP,
+  -- when the order was received in full or cancelled
+  closed_at TIMESTAMP,
+  CONSTRAINT purchase_orders_id_retailer_id_key UNIQUE (id, retailer_id),
+  CONSTRAINT purchase_orders_supplier_id_fkey FOREIGN KEY (supplier_id, retailer_id) REFERENCES suppliers (id, retailer_id) ON DELETE RESTRICT,
+  CONSTRAINT purchase_orders_location_id_fkey FOREIGN KEY (location_id, retailer_id) REFERENCES stock_locations (id, retailer_id) ON DELETE RESTRICT,
+  CHECK ((status IN ('DRAFT', 'CANCELLED')) OR ordered_at IS NOT NULL),
+  CHECK ((status IN ('RECEIVED', 'CANCELLED')) = (closed_at IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS purchase_orders_retailer_id_idx ON purchase_orders (retailer_id, id);
+CREATE INDEX IF NOT EXISTS purchase_orders_supplier_id_idx ON purchase_orders (supplier_id, id);
+
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+  id SERIAL PRIMARY KEY,
+  purchase_order_id INTEGER NOT NULL,
+  retailer_id INTEGER NOT NULL,
+  item_id INTEGER NOT NULL,
+  -- quantities are in the unit scale of the item, no more can be received than was ordered
+  quantity NUMERIC(19, 4) NOT NULL CHECK (quantity > 0),
+  received NUMERIC(19, 4) NOT NULL DEFAULT 0 CHECK (received >= 0),
+  -- the cost of one of the unit scale of the item, in the currency of the order
+  unit_cost NUMERIC(19, 4) NOT NULL CHECK (unit_cost >= 0),
+  CONSTRAINT purchase_order_lines_purchase_order_id_fkey FOREIGN KEY (purchase_order_id, retailer_id) REFERENCES purchase_orders (id, retailer_id) ON DELETE CASCADE,
+  CONSTRAINT purchase_order_lines_item_id_fkey FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE RESTRICT,
+  CONSTRAINT purchase_order_lines_item_key UNIQUE (purchase_order_id, item_id),
+  CONSTRAINT purchase_order_lines_received_check CHECK (received <= quantity)
+);
+
+-- stock received against a purchase order refers back to it
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS purchase_order_id INTEGER;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_purchase_order_id_fkey FOREIGN KEY (purchase_order_id, retailer_id) REFERENCES purchase_orders (id, retailer_id) ON DELETE RESTRICT;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_purchase_order_id_check CHECK (purchase_order_id IS NULL OR reason = 'RECEIPT');
+
+CREATE INDEX IF NOT EXISTS stock_movements_purchase_order_id_idx ON stock_movements (purchase_order_id);
+
+ALTER TABLE suppliers ENABLE ROW LEVEL SECURITY;
+CREATE POLICY suppliers_retailer ON suppliers TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE supplier_items ENABLE ROW LEVEL SECURITY;
+CREATE POLICY supplier_items_retailer ON supplier_items TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE purchase_orders ENABLE ROW LEVEL SECURITY;
+CREATE POLICY purchase_orders_retailer ON purchase_orders TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE purchase_order_lines ENABLE ROW LEVEL SECURITY;
+CREATE POLICY purchase_order_lines_retailer ON purchase_order_lines TO pedlar_tenant
+  USING (retailer_id = current_retailer());
@@ -0,0 +1,17 @@
+ALTER TABLE promotions DROP CONSTRAINT IF EXISTS promotions_category_id_fkey;
+ALTER TABLE promotions ADD CONSTRAINT promotions_category_id_fkey FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE RESTRICT;
+
+ALTER TABLE item_categories DROP CONSTRAINT IF EXISTS item_categories_category_id_fkey;
+ALTER TABLE item_categories ADD CONSTRAINT item_categories_category_id_fkey FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE CASCADE;
+ALTER TABLE item_categories DROP CONSTRAINT IF EXISTS item_categories_item_id_fkey;
+ALTER TABLE item_categories ADD CONSTRAINT item_categories_item_id_fkey FOREIGN KEY (item_id) REFERENCES items (id) ON DELETE CASCADE;
+ALTER TABLE item_categories DROP COLUMN IF EXISTS retailer_id;
+
+DROP INDEX IF EXISTS categories_parent_id_name_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_id_name_idx ON categories (COALESCE(parent_id, 0), name);
+
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_parent_id_fkey;
+ALTER TABLE categories ADD CONSTRAINT categories_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES categories (id) ON DELETE RESTRICT;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_id_retailer_id_key;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_retailer_id_fkey;
+ALTER TABLE categories DROP COLUMN IF EXISTS retailer_id;
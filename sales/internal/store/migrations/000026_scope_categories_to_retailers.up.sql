@@ -0,0 +1,56 @@
+-- categories made before retailers owned them go to the retailer of the first item categorised in
+-- their tree, or the first retailer there is, so that a tree never spans retailers
+INSERT INTO retailers (name, domain)
+SELECT 'localhost', 'localhost'
+WHERE EXISTS (SELECT 1 FROM categories) AND NOT EXISTS (SELECT 1 FROM retailers);
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS retailer_id INTEGER;
+
+WITH RECURSIVE trees AS (
+  SELECT id, id AS root FROM categories WHERE parent_id IS NULL
+
+  UNION ALL
+
+  SELECT c.id, t.root FROM categories c
+  JOIN trees t ON c.parent_id = t.id
+), owners AS (
+  SELECT DISTINCT ON (t.root) t.root, i.retailer_id
+  FROM trees t
+  JOIN item_categories ic ON ic.category_id = t.id
+  JOIN items i ON i.id = ic.item_id
+  ORDER BY t.root, i.id
+)
+UPDATE categories c SET retailer_id = COALESCE(o.retailer_id, (SELECT MIN(id) FROM retailers))
+FROM trees t
+LEFT JOIN owners o ON o.root = t.root
+WHERE t.id = c.id AND c.retailer_id IS NULL;
+
+ALTER TABLE categories ALTER COLUMN retailer_id SET NOT NULL;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_retailer_id_fkey;
+ALTER TABLE categories ADD CONSTRAINT categories_retailer_id_fkey FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE CASCADE;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_id_retailer_id_key;
+ALTER TABLE categories ADD CONSTRAINT categories_id_retailer_id_key UNIQUE (id, retailer_id);
+
+-- parents belong to the same retailer as their children
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_parent_id_fkey;
+ALTER TABLE categories ADD CONSTRAINT categories_parent_id_fkey FOREIGN KEY (parent_id, retailer_id) REFERENCES categories (id, retailer_id);
+
+-- sibling categories must be distinguishable, roots of a retailer are siblings of each other
+DROP INDEX IF EXISTS categories_parent_id_name_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_id_name_idx ON categories (retailer_id, COALESCE(parent_id, 0), name);
+
+-- items were only ever categorised under categories of other retailers through shared trees, those
+-- links can't be kept once the tree belongs to one retailer
+ALTER TABLE item_categories ADD COLUMN IF NOT EXISTS retailer_id INTEGER;
+UPDATE item_categories ic SET retailer_id = i.retailer_id FROM items i WHERE i.id = ic.item_id AND ic.retailer_id IS NULL;
+DELETE FROM item_categories ic USING categories c WHERE c.id = ic.category_id AND c.retailer_id <> ic.retailer_id;
+ALTER TABLE item_categories ALTER COLUMN retailer_id SET NOT NULL;
+ALTER TABLE item_categories DROP CONSTRAINT IF EXISTS item_categories_item_id_fkey;
+ALTER TABLE item_categories ADD CONSTRAINT item_categories_item_id_fkey FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE CASCADE;
+ALTER TABLE item_categories DROP CONSTRAINT IF EXISTS item_categories_category_id_fkey;
+ALTER TABLE item_categories ADD CONSTRAINT item_categories_category_id_fkey FOREIGN KEY (category_id, retailer_id) REFERENCES categories (id, retailer_id) ON DELETE CASCADE;
+
+-- promotions only target categories of their own retailer, any that targeted another retailer's
+-- category before are left as they were
+ALTER TABLE promotions DROP CONSTRAINT IF EXISTS promotions_category_id_fkey;
+ALTER TABLE promotions ADD CONSTRAINT promotions_category_id_fkey FOREIGN KEY (category_id, retailer_id) REFERENCES categories (id, retailer_id) NOT VALID;
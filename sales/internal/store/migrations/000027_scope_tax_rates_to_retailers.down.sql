@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS tax_rates_lookup_idx;
+CREATE INDEX IF NOT EXISTS tax_rates_lookup_idx ON tax_rates (jurisdiction, effective_from);
+
+ALTER TABLE tax_rates DROP CONSTRAINT IF EXISTS tax_rates_retailer_id_fkey;
+ALTER TABLE tax_rates DROP COLUMN IF EXISTS retailer_id;
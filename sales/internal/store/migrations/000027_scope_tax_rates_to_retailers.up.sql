@@ -0,0 +1,45 @@
+-- rates were shared by every retailer, now each retailer keeps its own. A shared rate goes to the
+-- retailer that charged it first, or the first retailer taxed in its jurisdiction, and every other
+-- retailer that charged it or is taxed in its jurisdiction gets a copy of it
+INSERT INTO retailers (name, domain)
+SELECT 'localhost', 'localhost'
+WHERE EXISTS (SELECT 1 FROM tax_rates) AND NOT EXISTS (SELECT 1 FROM retailers);
+
+ALTER TABLE tax_rates ADD COLUMN IF NOT EXISTS retailer_id INTEGER;
+ALTER TABLE tax_rates ADD COLUMN IF NOT EXISTS copied_from INTEGER;
+
+UPDATE tax_rates r SET retailer_id = COALESCE(
+  (SELECT s.retailer_id FROM sale_taxes t JOIN sales s ON s.id = t.sale_id WHERE t.tax_rate_id = r.id ORDER BY s.id LIMIT 1),
+  (SELECT MIN(retailer_id) FROM tax_settings WHERE jurisdiction = r.jurisdiction),
+  (SELECT MIN(id) FROM retailers)
+)
+WHERE r.retailer_id IS NULL;
+
+INSERT INTO tax_rates (retailer_id, jurisdiction, tax_class, name, rate, effective_from, effective_to, created_at, copied_from)
+SELECT o.retailer_id, r.jurisdiction, r.tax_class, r.name, r.rate, r.effective_from, r.effective_to, r.created_at, r.id
+FROM tax_rates r
+JOIN (
+  SELECT retailer_id, jurisdiction FROM tax_settings
+
+  UNION
+
+  SELECT s.retailer_id, charged.jurisdiction FROM sale_taxes t
+  JOIN sales s ON s.id = t.sale_id
+  JOIN tax_rates charged ON charged.id = t.tax_rate_id
+) o ON o.jurisdiction = r.jurisdiction
+WHERE r.copied_from IS NULL AND o.retailer_id <> r.retailer_id
+AND NOT EXISTS (SELECT 1 FROM tax_rates existing WHERE existing.copied_from = r.id AND existing.retailer_id = o.retailer_id);
+
+-- sales keep the rate they were charged, only now it's their retailer's copy of it
+UPDATE sale_taxes t SET tax_rate_id = c.id
+FROM sales s, tax_rates c
+WHERE s.id = t.sale_id AND c.copied_from = t.tax_rate_id AND c.retailer_id = s.retailer_id;
+
+ALTER TABLE tax_rates DROP COLUMN IF EXISTS copied_from;
+
+ALTER TABLE tax_rates ALTER COLUMN retailer_id SET NOT NULL;
+ALTER TABLE tax_rates DROP CONSTRAINT IF EXISTS tax_rates_retailer_id_fkey;
+ALTER TABLE tax_rates ADD CONSTRAINT tax_rates_retailer_id_fkey FOREIGN KEY (retailer_id) REFERENCES retailers (id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS tax_rates_lookup_idx;
+CREATE INDEX IF NOT EXISTS tax_rates_lookup_idx ON tax_rates (retailer_id, jurisdiction, effective_from);
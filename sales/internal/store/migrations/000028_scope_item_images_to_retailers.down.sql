@@ -0,0 +1,6 @@
+DROP POLICY IF EXISTS item_images_retailer ON item_images;
+ALTER TABLE item_images DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE item_images DROP CONSTRAINT IF EXISTS item_images_item_id_fkey;
+ALTER TABLE item_images ADD CONSTRAINT item_images_item_id_fkey FOREIGN KEY (item_id) REFERENCES items (id) ON DELETE CASCADE;
+ALTER TABLE item_images DROP COLUMN IF EXISTS retailer_id;
@@ -0,0 +1,9 @@
+ALTER TABLE item_images ADD COLUMN IF NOT EXISTS retailer_id INTEGER;
+UPDATE item_images m SET retailer_id = i.retailer_id FROM items i WHERE i.id = m.item_id AND m.retailer_id IS NULL;
+ALTER TABLE item_images ALTER COLUMN retailer_id SET NOT NULL;
+ALTER TABLE item_images DROP CONSTRAINT IF EXISTS item_images_item_id_fkey;
+ALTER TABLE item_images ADD CONSTRAINT item_images_item_id_fkey FOREIGN KEY (item_id, retailer_id) REFERENCES items (id, retailer_id) ON DELETE CASCADE;
+
+ALTER TABLE item_images ENABLE ROW LEVEL SECURITY;
+CREATE POLICY item_images_retailer ON item_images TO pedlar_tenant
+  USING (retailer_id = current_retailer());
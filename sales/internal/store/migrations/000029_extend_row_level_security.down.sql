@@ -0,0 +1,44 @@
+DROP POLICY IF EXISTS retailer_domains_retailer ON retailer_domains;
+ALTER TABLE retailer_domains DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS payments_retailer ON payments;
+ALTER TABLE payments DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS refund_lines_retailer ON refund_lines;
+ALTER TABLE refund_lines DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS refunds_retailer ON refunds;
+ALTER TABLE refunds DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS line_item_discounts_retailer ON line_item_discounts;
+ALTER TABLE line_item_discounts DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS promotions_retailer ON promotions;
+ALTER TABLE promotions DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS sale_taxes_retailer ON sale_taxes;
+ALTER TABLE sale_taxes DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tax_settings_retailer ON tax_settings;
+ALTER TABLE tax_settings DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tax_rates_retailer ON tax_rates;
+ALTER TABLE tax_rates DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS item_prices_retailer ON item_prices;
+ALTER TABLE item_prices DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS price_lists_retailer ON price_lists;
+ALTER TABLE price_lists DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS item_tags_retailer ON item_tags;
+ALTER TABLE item_tags DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS item_categories_retailer ON item_categories;
+ALTER TABLE item_categories DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS categories_retailer ON categories;
+ALTER TABLE categories DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS item_history_retailer ON item_history;
+ALTER TABLE item_history DISABLE ROW LEVEL SECURITY;
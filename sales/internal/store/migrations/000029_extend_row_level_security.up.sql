@@ -0,0 +1,62 @@
+-- the tables of a retailer that came before or after row level security, tables without a retailer
+-- of their own are narrowed through the policies of the table they belong to
+ALTER TABLE item_history ENABLE ROW LEVEL SECURITY;
+CREATE POLICY item_history_retailer ON item_history TO pedlar_tenant
+  USING (item_id IN (SELECT id FROM items));
+
+ALTER TABLE categories ENABLE ROW LEVEL SECURITY;
+CREATE POLICY categories_retailer ON categories TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE item_categories ENABLE ROW LEVEL SECURITY;
+CREATE POLICY item_categories_retailer ON item_categories TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE item_tags ENABLE ROW LEVEL SECURITY;
+CREATE POLICY item_tags_retailer ON item_tags TO pedlar_tenant
+  USING (item_id IN (SELECT id FROM items));
+
+ALTER TABLE price_lists ENABLE ROW LEVEL SECURITY;
+CREATE POLICY price_lists_retailer ON price_lists TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE item_prices ENABLE ROW LEVEL SECURITY;
+CREATE POLICY item_prices_retailer ON item_prices TO pedlar_tenant
+  USING (price_list_id IN (SELECT id FROM price_lists) AND item_id IN (SELECT id FROM items));
+
+ALTER TABLE tax_rates ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tax_rates_retailer ON tax_rates TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE tax_settings ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tax_settings_retailer ON tax_settings TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE sale_taxes ENABLE ROW LEVEL SECURITY;
+CREATE POLICY sale_taxes_retailer ON sale_taxes TO pedlar_tenant
+  USING (sale_id IN (SELECT id FROM sales));
+
+ALTER TABLE promotions ENABLE ROW LEVEL SECURITY;
+CREATE POLICY promotions_retailer ON promotions TO pedlar_tenant
+  USING (retailer_id = current_retailer());
+
+ALTER TABLE line_item_discounts ENABLE ROW LEVEL SECURITY;
+CREATE POLICY line_item_discounts_retailer ON line_item_discounts TO pedlar_tenant
+  USING (line_item_id IN (SELECT id FROM line_items));
+
+ALTER TABLE refunds ENABLE ROW LEVEL SECURITY;
+CREATE POLICY refunds_retailer ON refunds TO pedlar_tenant
+  USING (sale_id IN (SELECT id FROM sales));
+
+ALTER TABLE refund_lines ENABLE ROW LEVEL SECURITY;
+CREATE POLICY refund_lines_retailer ON refund_lines TO pedlar_tenant
+  USING (refund_id IN (SELECT id FROM refunds) AND line_item_id IN (SELECT id FROM line_items));
+
+ALTER TABLE payments ENABLE ROW LEVEL SECURITY;
+CREATE POLICY payments_retailer ON payments TO pedlar_tenant
+  USING (sale_id IN (SELECT id FROM sales));
+
+-- domains are looked up before a request is scoped to a retailer, so the lookup never assumes the role
+ALTER TABLE retailer_domains ENABLE ROW LEVEL SECURITY;
+CREATE POLICY retailer_domains_retailer ON retailer_domains TO pedlar_tenant
+  USING (retailer_id = current_retailer());
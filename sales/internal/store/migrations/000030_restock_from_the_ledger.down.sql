@@ -0,0 +1,11 @@
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_line_item_id_check;
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_sale_id_check;
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_reason_check;
+
+-- voids posted since are left in the ledger, only stock moved from now on is checked again
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_reason_check CHECK (reason IN ('RECEIPT', 'SALE', 'REFUND', 'ADJUSTMENT', 'TRANSFER')) NOT VALID;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_sale_id_check CHECK ((reason IN ('SALE', 'REFUND')) = (sale_id IS NOT NULL)) NOT VALID;
+
+DROP INDEX IF EXISTS stock_movements_line_item_id_idx;
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_line_item_id_fkey;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS line_item_id;
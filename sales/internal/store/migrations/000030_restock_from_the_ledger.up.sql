@@ -0,0 +1,35 @@
+-- stock returned by refunds and voids is what the sale took, movements of sales remember the line
+-- item they were posted for so it can be found again whatever kits are made of since
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS line_item_id INTEGER;
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_line_item_id_fkey;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_line_item_id_fkey FOREIGN KEY (line_item_id) REFERENCES line_items (id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS stock_movements_line_item_id_idx ON stock_movements (line_item_id);
+
+-- the stock of a sale with a single line item was taken for that line item, the stock of other
+-- sales posted so far can't be told apart and is returned from the kits as they are now
+ALTER TABLE stock_movements DISABLE TRIGGER stock_movements_append_only_trigger;
+UPDATE stock_movements m SET line_item_id = l.id
+FROM line_items l
+WHERE l.sale_id = m.sale_id AND m.line_item_id IS NULL
+AND (SELECT COUNT(*) FROM line_items o WHERE o.sale_id = m.sale_id) = 1;
+ALTER TABLE stock_movements ENABLE TRIGGER stock_movements_append_only_trigger;
+
+-- voids put back what was sold under a reason of their own, the checks were named by postgres
+DO $$
+DECLARE
+  name TEXT;
+BEGIN
+  FOR name IN
+    SELECT conname FROM pg_constraint
+    WHERE conrelid = 'stock_movements'::regclass AND contype = 'c'
+    AND (pg_get_constraintdef(oid) LIKE '%''RECEIPT''%''TRANSFER''%' OR pg_get_constraintdef(oid) LIKE '%sale_id IS NOT NULL%')
+  LOOP
+    EXECUTE format('ALTER TABLE stock_movements DROP CONSTRAINT %I', name);
+  END LOOP;
+END
+$$;
+
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_reason_check CHECK (reason IN ('RECEIPT', 'SALE', 'REFUND', 'VOID', 'ADJUSTMENT', 'TRANSFER'));
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_sale_id_check CHECK ((reason IN ('SALE', 'REFUND', 'VOID')) = (sale_id IS NOT NULL));
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_line_item_id_check CHECK (line_item_id IS NULL OR sale_id IS NOT NULL);
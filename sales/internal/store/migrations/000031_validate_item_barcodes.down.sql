@@ -0,0 +1 @@
+-- barcodes that weren't kept are gone, there is nothing to put back
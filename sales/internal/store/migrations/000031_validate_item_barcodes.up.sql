@@ -0,0 +1,31 @@
+-- barcodes of variants were copied into item_barcodes without checking them, those that aren't a
+-- GTIN can never be scanned and are reported rather than kept
+DO $$
+DECLARE
+  barcode RECORD;
+  payload TEXT;
+  total INTEGER;
+  digit INTEGER;
+BEGIN
+  FOR barcode IN SELECT retailer_id, gtin, code, item_id, variant_id FROM item_barcodes LOOP
+    IF barcode.code !~ '^[0-9]+$' OR LENGTH(barcode.code) NOT IN (8, 12, 13, 14) THEN
+      RAISE WARNING 'barcode % of item % variant % is not a GTIN and was not kept', barcode.code, barcode.item_id, barcode.variant_id;
+      DELETE FROM item_barcodes WHERE retailer_id = barcode.retailer_id AND gtin = barcode.gtin;
+      CONTINUE;
+    END IF;
+
+    -- digits are weighted 3 and 1 alternately from the rightmost digit of the payload
+    payload := LEFT(barcode.code, -1);
+    total := 0;
+    FOR i IN 0 .. LENGTH(payload) - 1 LOOP
+      digit := SUBSTRING(payload FROM LENGTH(payload) - i FOR 1)::INTEGER;
+      total := total + CASE WHEN i % 2 = 0 THEN digit * 3 ELSE digit END;
+    END LOOP;
+
+    IF (10 - total % 10) % 10 <> RIGHT(barcode.code, 1)::INTEGER THEN
+      RAISE WARNING 'barcode % of item % variant % has the wrong check digit and was not kept', barcode.code, barcode.item_id, barcode.variant_id;
+      DELETE FROM item_barcodes WHERE retailer_id = barcode.retailer_id AND gtin = barcode.gtin;
+    END IF;
+  END LOOP;
+END
+$$;
@@ -0,0 +1 @@
+ALTER TABLE sales DROP COLUMN IF EXISTS legacy_customer_id;
@@ -0,0 +1,3 @@
+-- ids that sales were recorded against before there were customers are kept aside from the
+-- customers they are linked to, databases that already hold them keep them
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS legacy_customer_id INTEGER;
@@ -0,0 +1,4 @@
+ALTER TABLE refund_lines ALTER COLUMN quantity TYPE INTEGER USING CEIL(quantity);
+
+ALTER TABLE line_items DROP COLUMN IF EXISTS unit_scale;
+ALTER TABLE line_items ALTER COLUMN quantity TYPE INTEGER USING CEIL(quantity);
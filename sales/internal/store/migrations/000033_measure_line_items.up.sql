@@ -0,0 +1,10 @@
+-- line items sell a quantity of the unit scale of their item, ie 0.25 of an item sold by the kg,
+-- which is the unit scale their unit price is the price of one of. Line items sold so far were
+-- sold by the unit scale their item has now.
+ALTER TABLE line_items ALTER COLUMN quantity TYPE NUMERIC(19, 4);
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS unit_scale VARCHAR(255);
+UPDATE line_items l SET unit_scale = COALESCE(i.unit_scale, 'unit') FROM items i WHERE i.id = l.product_id AND l.unit_scale IS NULL;
+UPDATE line_items SET unit_scale = 'unit' WHERE unit_scale IS NULL;
+ALTER TABLE line_items ALTER COLUMN unit_scale SET NOT NULL;
+
+ALTER TABLE refund_lines ALTER COLUMN quantity TYPE NUMERIC(19, 4);
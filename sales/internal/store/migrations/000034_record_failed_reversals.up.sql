@@ -0,0 +1,3 @@
+-- charges that fail to reverse when their sale is voided are left unreversed with the reason,
+-- until reversing them is retried
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS reversal_failure TEXT;
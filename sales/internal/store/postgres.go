@@ -3,8 +3,10 @@ package store
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -13,7 +15,9 @@ import (
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
 )
 
 var (
@@ -21,7 +25,7 @@ var (
 	MIGRATIONS_DIR embed.FS
 )
 
-func Conn(ctx context.Context, defaultURL string, name string) (*pgxpool.Pool, error) {
+func Conn(ctx context.Context, defaultURL string, name string) (*Pool, error) {
 	name = strings.ToLower(name)
 
 	url, err := url.Parse(defaultURL)
@@ -60,15 +64,170 @@ func Conn(ctx context.Context, defaultURL string, name string) (*pgxpool.Pool, e
 		return nil, fmt.Errorf("failed to perform migrations: %w", err)
 	}
 
-	config, err := pgxpool.ParseConfig(url.String())
+	pool, err := pgxpool.New(ctx, url.String())
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse pool configuration: %w", err)
+		return nil, fmt.Errorf("failed to establish connection pool: %w", err)
+	}
+
+	return &Pool{pool: pool}, nil
+}
+
+// tenantRole is assumed by transactions made for a retailer, row level security hides the rows of
+// every other retailer from it however the query is written.
+const tenantRole = "pedlar_tenant"
+
+// Pool runs every statement in a transaction scoped to the retailer of its context. The retailer
+// and the tenant role are set local to the transaction, so they end with it and a connection goes
+// back to the pool carrying nothing for whoever uses it next. Statements made without a retailer
+// run as the role the pool connected as.
+type Pool struct {
+	pool *pgxpool.Pool
+}
+
+func (p *Pool) Close() {
+	p.pool.Close()
+}
+
+// unitOfWork keys the transaction of a unit of work in the context.
+type unitOfWork struct{}
+
+// within makes the transaction the unit of work of the context, whatever the pool runs with the
+// context is part of it.
+func within(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, unitOfWork{}, tx)
+}
+
+// Begin starts a transaction scoped to the retailer of the context. Within a unit of work it is a
+// savepoint of the transaction of the unit of work instead, kept only if the unit of work is.
+func (p *Pool) Begin(ctx context.Context) (pgx.Tx, error) {
+	if tx, ok := ctx.Value(unitOfWork{}).(pgx.Tx); ok {
+		return tx.Begin(ctx)
 	}
 
-	pool, err := pgxpool.NewWithConfig(ctx, config)
+	tx, err := p.pool.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to establish connection pool: %w", err)
+		return nil, err
+	}
+
+	if retailer, err := retailers.From(ctx); err == nil {
+		_, err = tx.Exec(ctx, `SELECT set_config('app.retailer_id', $1, true), set_config('role', $2, true)`, strconv.Itoa(retailer), tenantRole)
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, fmt.Errorf("failed to scope transaction to retailer: %w", err)
+		}
+	}
+	return tx, nil
+}
+
+func (p *Pool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx, err := p.Begin(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return tag, err
+	}
+	return tag, tx.Commit(ctx)
+}
+
+// QueryRow ends the transaction of the statement once the row is scanned.
+func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	tx, err := p.Begin(ctx)
+	if err != nil {
+		return failedRow{err: err}
+	}
+	return &scopedRow{ctx: ctx, tx: tx, row: tx.QueryRow(ctx, sql, args...)}
+}
+
+// Query ends the transaction of the statement once the rows are read through or closed.
+func (p *Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx, err := p.Begin(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return pool, nil
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return &scopedRows{Rows: rows, ctx: ctx, tx: tx}, nil
+}
+
+type failedRow struct {
+	err error
+}
+
+func (r failedRow) Scan(...any) error {
+	return r.err
+}
+
+type scopedRow struct {
+	ctx context.Context
+	tx  pgx.Tx
+	row pgx.Row
+}
+
+func (r *scopedRow) Scan(dest ...any) error {
+	defer r.tx.Rollback(r.ctx)
+
+	if err := r.row.Scan(dest...); err != nil {
+		return err
+	}
+	return r.tx.Commit(r.ctx)
+}
+
+type scopedRows struct {
+	pgx.Rows
+	ctx  context.Context
+	tx   pgx.Tx
+	done bool
+	err  error
+}
+
+func (r *scopedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.end()
+	return false
+}
+
+func (r *scopedRows) Close() {
+	r.Rows.Close()
+	r.end()
+}
+
+func (r *scopedRows) Err() error {
+	if err := r.Rows.Err(); err != nil {
+		return err
+	}
+	return r.err
+}
+
+// end commits the transaction of rows read through without error, otherwise rolls it back.
+func (r *scopedRows) end() {
+	if r.done {
+		return
+	}
+	r.done = true
+
+	if r.Rows.Err() != nil {
+		r.tx.Rollback(r.ctx)
+		return
+	}
+	r.err = r.tx.Commit(r.ctx)
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
 }
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/pricing"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type Prices struct {
+	Conn *Pool
+}
+
+func (p *Prices) CreatePriceList(ctx context.Context, retailer int, name string, kind pricing.Kind, currency money.Currency) (*pricing.PriceList, error) {
+	var assigned int
+	err := p.Conn.QueryRow(ctx, `INSERT INTO price_lists (retailer_id, name, kind, currency) VALUES ($1, $2, $3, $4) RETURNING id`,
+		retailer, name, kind, currency).Scan(&assigned)
+	if isUniqueViolation(err) {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "price_lists_retail_idx" {
+			return nil, pricing.ErrDuplicateRetailList
+		}
+		return nil, pricing.ErrDuplicatePriceList
+	} else if isForeignKeyViolation(err) {
+		return nil, pricing.ErrRetailerNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to insert into price_lists: %w", err)
+	}
+
+	return &pricing.PriceList{
+		ID:         &keys.OpaqueID{ID: assigned},
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		Name:       name,
+		Kind:       kind,
+		Currency:   currency,
+	}, nil
+}
+
+func (p *Prices) GetPriceList(ctx context.Context, id int) (*pricing.PriceList, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.Conn.Query(ctx, `SELECT id, retailer_id, name, kind, currency FROM price_lists WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from price_lists: %w", err)
+	}
+
+	lists, err := scanPriceLists(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return nil, pricing.ErrPriceListNotFound
+	}
+	return lists[0], nil
+}
+
+func (p *Prices) GetPriceLists(ctx context.Context, retailer int) ([]*pricing.PriceList, error) {
+	rows, err := p.Conn.Query(ctx, `SELECT id, retailer_id, name, kind, currency FROM price_lists WHERE retailer_id = $1 ORDER BY name`, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from price_lists: %w", err)
+	}
+	return scanPriceLists(rows)
+}
+
+func (p *Prices) DefaultPriceList(ctx context.Context, retailer int, currency money.Currency) (*pricing.PriceList, error) {
+	rows, err := p.Conn.Query(ctx, `SELECT id, retailer_id, name, kind, currency FROM price_lists WHERE retailer_id = $1 AND currency = $2 AND kind = $3`,
+		retailer, currency, pricing.Retail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select default from price_lists: %w", err)
+	}
+
+	lists, err := scanPriceLists(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return nil, pricing.ErrPriceListNotFound
+	}
+	return lists[0], nil
+}
+
+func scanPriceLists(rows pgx.Rows) ([]*pricing.PriceList, error) {
+	defer rows.Close()
+
+	var results []*pricing.PriceList
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			retailer keys.OpaqueID
+			list     pricing.PriceList
+		)
+		err := rows.Scan(&id, &retailer, &list.Name, &list.Kind, &list.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from price_lists: %w", err)
+		}
+
+		list.ID, list.RetailerID = &id, &retailer
+		results = append(results, &list)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from price_lists: %w", err)
+	}
+	return results, nil
+}
+
+func (p *Prices) CreatePrice(ctx context.Context, price *pricing.Price) error {
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// serialises price changes of the list so that concurrent changes can't both pass the overlap check
+	_, err = tx.Exec(ctx, `SELECT id FROM price_lists WHERE id = $1 FOR UPDATE`, price.PriceListID.ID)
+	if err != nil {
+		return fmt.Errorf("failed to lock price_lists: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE item_prices SET effective_to = $3
+		WHERE price_list_id = $1 AND item_id = $2 AND effective_to IS NULL AND effective_from < $3`,
+		price.PriceListID.ID, price.ItemID.ID, price.EffectiveFrom)
+	if err != nil {
+		return fmt.Errorf("failed to end open prices of item_prices: %w", err)
+	}
+
+	var overlaps bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (
+			SELECT 1 FROM item_prices
+			WHERE price_list_id = $1 AND item_id = $2
+			AND effective_from < COALESCE($4, 'infinity'::timestamp)
+			AND COALESCE(effective_to, 'infinity'::timestamp) > $3
+		)`, price.PriceListID.ID, price.ItemID.ID, price.EffectiveFrom, price.EffectiveTo).Scan(&overlaps)
+	if err != nil {
+		return fmt.Errorf("failed to select overlaps from item_prices: %w", err)
+	}
+	if overlaps {
+		return pricing.ErrPriceOverlap
+	}
+
+	var assigned int
+	err = tx.QueryRow(ctx, `INSERT INTO item_prices (price_list_id, item_id, amount, unit_scale, effective_from, effective_to) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		price.PriceListID.ID, price.ItemID.ID, price.Amount, price.UnitScale, price.EffectiveFrom, price.EffectiveTo).Scan(&assigned)
+	if isForeignKeyViolation(err) {
+		return items.ErrItemNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into item_prices: %w", err)
+	}
+	price.ID = &keys.OpaqueID{ID: assigned}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (p *Prices) ActivePrice(ctx context.Context, list int, item int, at time.Time) (*pricing.Price, error) {
+	rows, err := p.Conn.Query(ctx, `SELECT ip.id, ip.price_list_id, ip.item_id, ip.amount, pl.currency, ip.unit_scale, ip.effective_from, ip.effective_to
+		FROM item_prices ip JOIN price_lists pl ON pl.id = ip.price_list_id
+		WHERE ip.price_list_id = $1 AND ip.item_id = $2 AND ip.effective_from <= $3 AND (ip.effective_to IS NULL OR ip.effective_to > $3)`,
+		list, item, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select active from item_prices: %w", err)
+	}
+
+	prices, err := scanPrices(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(prices) == 0 {
+		return nil, pricing.ErrNoPrice
+	}
+	return prices[0], nil
+}
+
+func (p *Prices) GetPrices(ctx context.Context, list int, item int) ([]*pricing.Price, error) {
+	rows, err := p.Conn.Query(ctx, `SELECT ip.id, ip.price_list_id, ip.item_id, ip.amount, pl.currency, ip.unit_scale, ip.effective_from, ip.effective_to
+		FROM item_prices ip JOIN price_lists pl ON pl.id = ip.price_list_id
+		WHERE ip.price_list_id = $1 AND ip.item_id = $2
+		ORDER BY ip.effective_from DESC`, list, item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from item_prices: %w", err)
+	}
+	return scanPrices(rows)
+}
+
+func scanPrices(rows pgx.Rows) ([]*pricing.Price, error) {
+	defer rows.Close()
+
+	var results []*pricing.Price
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			list     keys.OpaqueID
+			item     keys.OpaqueID
+			amount   money.Decimal
+			currency money.Currency
+			price    pricing.Price
+		)
+		err := rows.Scan(&id, &list, &item, &amount, &currency, &price.UnitScale, &price.EffectiveFrom, &price.EffectiveTo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from item_prices: %w", err)
+		}
+
+		price.Amount, err = money.FromRat(amount.Rat(), currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read amount of price %d: %w", id.ID, err)
+		}
+
+		price.ID, price.PriceListID, price.ItemID = &id, &list, &item
+		results = append(results, &price)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from item_prices: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/pricing"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestPricesEffectiveDates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	beans, err := (&Items{Conn: conn}).CreateItem(ctx, items.Details{Name: "beans", UnitScale: items.Gram})
+	require.NoError(t, err)
+
+	store := &Prices{Conn: conn}
+
+	retail, err := store.CreatePriceList(ctx, retailer, "retail", pricing.Retail, "NZD")
+	require.NoError(t, err)
+	_, err = store.CreatePriceList(ctx, retailer, "retail again", pricing.Retail, "NZD")
+	require.ErrorIs(t, err, pricing.ErrDuplicateRetailList)
+	_, err = store.CreatePriceList(ctx, retailer, "retail", pricing.Member, "NZD")
+	require.ErrorIs(t, err, pricing.ErrDuplicatePriceList)
+
+	found, err := store.DefaultPriceList(ctx, retailer, "NZD")
+	require.NoError(t, err)
+	require.Equal(t, retail.ID.ID, found.ID.ID)
+	_, err = store.DefaultPriceList(ctx, retailer, "AUD")
+	require.ErrorIs(t, err, pricing.ErrPriceListNotFound)
+
+	theirs, _ := scope(t, context.Background(), conn, "theirs.test")
+	_, err = store.GetPriceList(theirs, retail.ID.ID)
+	require.ErrorIs(t, err, pricing.ErrPriceListNotFound)
+
+	var (
+		january  = time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+		february = time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)
+		march    = time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	)
+	var price = func(amount string, from time.Time, to *time.Time) *pricing.Price {
+		m, err := money.Parse(amount, "NZD")
+		require.NoError(t, err)
+		return &pricing.Price{
+			PriceListID:   retail.ID,
+			ItemID:        &keys.OpaqueID{ID: beans.ID.ID},
+			Amount:        m,
+			UnitScale:     items.Kilogram,
+			EffectiveFrom: from,
+			EffectiveTo:   to,
+		}
+	}
+
+	require.NoError(t, store.CreatePrice(ctx, price("40.00", january, nil)))
+	// an open ended price is ended where the next one starts
+	require.NoError(t, store.CreatePrice(ctx, price("45.00", march, nil)))
+	require.ErrorIs(t, store.CreatePrice(ctx, price("42.00", february, &march)), pricing.ErrPriceOverlap)
+
+	active, err := store.ActivePrice(ctx, retail.ID.ID, beans.ID.ID, february)
+	require.NoError(t, err)
+	require.Equal(t, "40.00 NZD", active.Amount.String())
+	require.Equal(t, items.Kilogram, active.UnitScale)
+
+	active, err = store.ActivePrice(ctx, retail.ID.ID, beans.ID.ID, march)
+	require.NoError(t, err)
+	require.Equal(t, "45.00 NZD", active.Amount.String())
+
+	_, err = store.ActivePrice(ctx, retail.ID.ID, beans.ID.ID, january.Add(-time.Hour))
+	require.ErrorIs(t, err, pricing.ErrNoPrice)
+
+	history, err := store.GetPrices(ctx, retail.ID.ID, beans.ID.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, march, history[1].EffectiveTo.UTC())
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type Promotions struct {
+	Conn *Pool
+}
+
+const promotionColumns = `id, retailer_id, name, kind, priority, exclusive, item_id, category_id, min_spend, coupon, buy, free, percent, amount, currency, starts_at, ends_at`
+
+func (p *Promotions) CreatePromotion(ctx context.Context, promotion *promotions.Promotion) error {
+	var (
+		item, category *int
+		buy, free      *int
+		percent        *money.Decimal
+		currency       *money.Currency
+	)
+	if promotion.ItemID != nil {
+		item = &promotion.ItemID.ID
+	}
+	if promotion.CategoryID != nil {
+		category = &promotion.CategoryID.ID
+	}
+	if promotion.Kind == promotions.BuyXGetY {
+		buy, free = &promotion.Buy, &promotion.Free
+	}
+	if promotion.Percent != nil {
+		percent = (*money.Decimal)(promotion.Percent)
+	}
+	if c, ok := promotion.Currency(); ok {
+		currency = &c
+	}
+
+	var assigned int
+	err := p.Conn.QueryRow(ctx, `INSERT INTO promotions (retailer_id, name, kind, priority, exclusive, item_id, category_id, min_spend, coupon, buy, free, percent, amount, currency, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) RETURNING id`,
+		promotion.RetailerID.ID, promotion.Name, promotion.Kind, promotion.Priority, promotion.Exclusive, item, category,
+		promotion.MinSpend, promotion.Coupon, buy, free, percent, promotion.Amount, currency, promotion.StartsAt, promotion.EndsAt).Scan(&assigned)
+	if isForeignKeyViolation(err) {
+		return foreignKeyError(err)
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into promotions: %w", err)
+	}
+
+	promotion.ID = &keys.OpaqueID{ID: assigned}
+	return nil
+}
+
+// foreignKeyError tells which reference of a promotion doesn't exist.
+func foreignKeyError(err error) error {
+	var pgErr *pgconn.PgError
+	errors.As(err, &pgErr)
+
+	switch pgErr.ConstraintName {
+	case "promotions_item_id_fkey":
+		return items.ErrItemNotFound
+	case "promotions_category_id_fkey":
+		return items.ErrCategoryNotFound
+	default:
+		return promotions.ErrRetailerNotFound
+	}
+}
+
+func (p *Promotions) GetPromotion(ctx context.Context, id int) (*promotions.Promotion, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.Conn.Query(ctx, `SELECT `+promotionColumns+` FROM promotions WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from promotions: %w", err)
+	}
+
+	found, err := scanPromotions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, promotions.ErrPromotionNotFound
+	}
+	return found[0], nil
+}
+
+func (p *Promotions) GetPromotions(ctx context.Context, retailer int) ([]*promotions.Promotion, error) {
+	rows, err := p.Conn.Query(ctx, `SELECT `+promotionColumns+` FROM promotions WHERE retailer_id = $1 ORDER BY starts_at DESC, id DESC`, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from promotions: %w", err)
+	}
+	return scanPromotions(rows)
+}
+
+func (p *Promotions) ActivePromotions(ctx context.Context, retailer int, at time.Time) ([]*promotions.Promotion, error) {
+	rows, err := p.Conn.Query(ctx, `SELECT `+promotionColumns+` FROM promotions
+		WHERE retailer_id = $1 AND starts_at <= $2 AND (ends_at IS NULL OR ends_at > $2)
+		ORDER BY priority DESC, id`, retailer, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select active from promotions: %w", err)
+	}
+	return scanPromotions(rows)
+}
+
+func (p *Promotions) EndPromotion(ctx context.Context, id int, at time.Time) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	// promotions that are yet to start end right where they start, so they never run
+	tag, err := p.Conn.Exec(ctx, `UPDATE promotions SET ends_at = GREATEST($2, starts_at)
+		WHERE id = $1 AND retailer_id = $3 AND (ends_at IS NULL OR ends_at > $2)`, id, at, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to end promotion of promotions: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		// either the promotion doesn't exist or it has ended already
+		if _, err := p.GetPromotion(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Promotions) ItemCategories(ctx context.Context, ids []int) (map[int][]int, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.Conn.Query(ctx, `WITH RECURSIVE ancestry AS (
+			SELECT ic.item_id, c.id, c.parent_id FROM item_categories ic JOIN categories c ON c.id = ic.category_id
+			WHERE ic.item_id = ANY($1) AND ic.retailer_id = $2
+
+			UNION
+
+			SELECT a.item_id, c.id, c.parent_id FROM categories c JOIN ancestry a ON c.id = a.parent_id
+		) SELECT DISTINCT item_id, id FROM ancestry ORDER BY item_id, id`, ids, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select ancestry from item_categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories = make(map[int][]int, len(ids))
+	for rows.Next() {
+		var item, category int
+		if err := rows.Scan(&item, &category); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting ancestry from item_categories: %w", err)
+		}
+		categories[item] = append(categories[item], category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from item_categories: %w", err)
+	}
+	return categories, nil
+}
+
+func scanPromotions(rows pgx.Rows) ([]*promotions.Promotion, error) {
+	defer rows.Close()
+
+	var results []*promotions.Promotion
+	for rows.Next() {
+		var (
+			id        keys.OpaqueID
+			retailer  keys.OpaqueID
+			item      *int
+			category  *int
+			minSpend  *money.Decimal
+			buy, free *int
+			percent   *money.Decimal
+			amount    *money.Decimal
+			currency  *money.Currency
+			promotion promotions.Promotion
+		)
+		err := rows.Scan(&id, &retailer, &promotion.Name, &promotion.Kind, &promotion.Priority, &promotion.Exclusive, &item, &category,
+			&minSpend, &promotion.Coupon, &buy, &free, &percent, &amount, &currency, &promotion.StartsAt, &promotion.EndsAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from promotions: %w", err)
+		}
+
+		promotion.ID, promotion.RetailerID = &id, &retailer
+		if item != nil {
+			promotion.ItemID = &keys.OpaqueID{ID: *item}
+		}
+		if category != nil {
+			promotion.CategoryID = &keys.OpaqueID{ID: *category}
+		}
+		if buy != nil && free != nil {
+			promotion.Buy, promotion.Free = *buy, *free
+		}
+		if percent != nil {
+			promotion.Percent = new(big.Rat).Set(percent.Rat())
+		}
+		if minSpend != nil {
+			spend, err := money.FromRat(minSpend.Rat(), *currency)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read minimum spend of promotion %d: %w", id.ID, err)
+			}
+			promotion.MinSpend = &spend
+		}
+		if amount != nil {
+			off, err := money.FromRat(amount.Rat(), *currency)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read amount off of promotion %d: %w", id.ID, err)
+			}
+			promotion.Amount = &off
+		}
+		results = append(results, &promotion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from promotions: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestPromotionsAndDiscounts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	var (
+		catalogue = &Items{Conn: conn}
+		store     = &Promotions{Conn: conn}
+	)
+
+	drinks, err := catalogue.CreateCategory(ctx, "drinks", nil)
+	require.NoError(t, err)
+	coffee, err := catalogue.CreateCategory(ctx, "coffee", drinks.ID)
+	require.NoError(t, err)
+
+	flatWhite, err := catalogue.CreateItem(ctx, items.Details{Name: "flat white", UnitScale: items.Unit})
+	require.NoError(t, err)
+	_, err = catalogue.CategoriseItem(ctx, flatWhite.ID.ID, coffee.ID.ID)
+	require.NoError(t, err)
+
+	// an item is in every ancestor of its categories
+	categories, err := store.ItemCategories(ctx, []int{flatWhite.ID.ID})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int{drinks.ID.ID, coffee.ID.ID}, categories[flatWhite.ID.ID])
+
+	var (
+		now     = time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+		later   = now.Add(24 * time.Hour)
+		coupon  = "WELCOME"
+		five, _ = money.Parse("5.00", "NZD")
+	)
+
+	tenOff := &promotions.Promotion{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		Name:       "10% off coffee",
+		Kind:       promotions.PercentOff,
+		CategoryID: &keys.OpaqueID{ID: coffee.ID.ID},
+		Percent:    big.NewRat(1, 10),
+		StartsAt:   now.Add(-time.Hour),
+	}
+	require.NoError(t, store.CreatePromotion(ctx, tenOff))
+
+	welcome := &promotions.Promotion{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		Name:       "welcome",
+		Kind:       promotions.AmountOff,
+		Priority:   1,
+		Coupon:     &coupon,
+		Amount:     &five,
+		StartsAt:   later,
+	}
+	require.NoError(t, store.CreatePromotion(ctx, welcome))
+
+	missing := &promotions.Promotion{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		Name:       "ghost",
+		Kind:       promotions.BuyXGetY,
+		ItemID:     &keys.OpaqueID{ID: -1},
+		Buy:        2,
+		Free:       1,
+		StartsAt:   now,
+	}
+	require.ErrorIs(t, store.CreatePromotion(ctx, missing), items.ErrItemNotFound)
+
+	running, err := store.ActivePromotions(ctx, retailer, now)
+	require.NoError(t, err)
+	require.Len(t, running, 1)
+	require.Equal(t, "0.1", running[0].Percent.RatString())
+
+	running, err = store.ActivePromotions(ctx, retailer, later)
+	require.NoError(t, err)
+	require.Len(t, running, 2)
+	require.Equal(t, welcome.ID.ID, running[0].ID.ID, "higher priority first")
+	require.Equal(t, "5.00 NZD", running[0].Amount.String())
+
+	// promotions yet to start never run once ended
+	require.NoError(t, store.EndPromotion(ctx, welcome.ID.ID, now))
+	running, err = store.ActivePromotions(ctx, retailer, later)
+	require.NoError(t, err)
+	require.Len(t, running, 1)
+	ended, err := store.GetPromotion(ctx, welcome.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, later, ended.EndsAt.UTC())
+
+	require.ErrorIs(t, store.EndPromotion(ctx, -1, now), promotions.ErrPromotionNotFound)
+	theirs, _ := scope(t, context.Background(), conn, "theirs.test")
+	require.ErrorIs(t, store.EndPromotion(theirs, welcome.ID.ID, now), promotions.ErrPromotionNotFound)
+	_, err = store.GetPromotion(theirs, welcome.ID.ID)
+	require.ErrorIs(t, err, promotions.ErrPromotionNotFound)
+	require.ErrorIs(t, catalogue.DeleteCategory(ctx, coffee.ID.ID), items.ErrCategoryPromoted)
+
+	price, err := money.Parse("4.50", "NZD")
+	require.NoError(t, err)
+	off, err := money.Parse("0.90", "NZD")
+	require.NoError(t, err)
+
+	sale := &sales.Sale{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		SaleDate:   now,
+		Currency:   "NZD",
+		Status:     sales.Paid,
+		LineItems: []*sales.LineItem{
+			{ItemID: &keys.OpaqueID{ID: flatWhite.ID.ID}, Quantity: big.NewRat(2, 1), UnitScale: items.Unit, UnitPrice: price, Discounts: []*promotions.Discount{
+				{PromotionID: tenOff.ID, Name: tenOff.Name, Amount: off},
+			}},
+		},
+	}
+	require.NoError(t, (&Sales{Conn: conn}).CreateSale(ctx, sale))
+
+	read, err := (&Sales{Conn: conn}).GetSale(ctx, sale.ID.ID)
+	require.NoError(t, err)
+	require.Len(t, read.LineItems[0].Discounts, 1)
+	require.Equal(t, tenOff.ID.ID, read.LineItems[0].Discounts[0].PromotionID.ID)
+	require.Equal(t, "0.90 NZD", read.LineItems[0].Discounts[0].Amount.String())
+
+	subtotal, err := read.Subtotal()
+	require.NoError(t, err)
+	require.Equal(t, "8.10 NZD", subtotal.String())
+}
@@ -0,0 +1,490 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/purchasing"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type Purchasing struct {
+	Conn *Pool
+}
+
+const (
+	supplierColumns = `id, retailer_id, name, email, phone, currency, created_at, updated_at`
+	orderColumns    = `id, retailer_id, supplier_id, location_id, status, currency, note, created_by, created_at, ordered_at, closed_at`
+)
+
+func (p *Purchasing) CreateSupplier(ctx context.Context, supplier *purchasing.Supplier) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	var assigned int
+	err = p.Conn.QueryRow(ctx, `INSERT INTO suppliers (retailer_id, name, email, phone, currency) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`,
+		retailer, supplier.Name, supplier.Email, supplier.Phone, supplier.Currency).Scan(&assigned, &supplier.CreatedAt, &supplier.UpdatedAt)
+	if isUniqueViolation(err) {
+		return purchasing.ErrDuplicateSupplier
+	} else if isForeignKeyViolation(err) {
+		return retailers.ErrRetailerNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into suppliers: %w", err)
+	}
+
+	supplier.ID, supplier.RetailerID = &keys.OpaqueID{ID: assigned}, &keys.OpaqueID{ID: retailer}
+	return nil
+}
+
+func (p *Purchasing) GetSupplier(ctx context.Context, id int) (*purchasing.Supplier, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.Conn.Query(ctx, `SELECT `+supplierColumns+` FROM suppliers WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from suppliers: %w", err)
+	}
+
+	found, err := scanSuppliers(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, purchasing.ErrSupplierNotFound
+	}
+	return found[0], nil
+}
+
+func (p *Purchasing) GetSuppliers(ctx context.Context) ([]*purchasing.Supplier, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.Conn.Query(ctx, `SELECT `+supplierColumns+` FROM suppliers WHERE retailer_id = $1 ORDER BY name`, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from suppliers: %w", err)
+	}
+	return scanSuppliers(rows)
+}
+
+func (p *Purchasing) UpdateSupplier(ctx context.Context, supplier *purchasing.Supplier) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = p.Conn.QueryRow(ctx, `UPDATE suppliers SET name = $3, email = $4, phone = $5, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND retailer_id = $2 RETURNING updated_at`,
+		supplier.ID.ID, retailer, supplier.Name, supplier.Email, supplier.Phone).Scan(&supplier.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return purchasing.ErrSupplierNotFound
+	} else if isUniqueViolation(err) {
+		return purchasing.ErrDuplicateSupplier
+	} else if err != nil {
+		return fmt.Errorf("failed to update suppliers: %w", err)
+	}
+	return nil
+}
+
+func scanSuppliers(rows pgx.Rows) ([]*purchasing.Supplier, error) {
+	defer rows.Close()
+
+	var results = []*purchasing.Supplier{}
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			retailer keys.OpaqueID
+			supplier purchasing.Supplier
+		)
+		err := rows.Scan(&id, &retailer, &supplier.Name, &supplier.Email, &supplier.Phone, &supplier.Currency, &supplier.CreatedAt, &supplier.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from suppliers: %w", err)
+		}
+		supplier.ID, supplier.RetailerID = &id, &retailer
+		results = append(results, &supplier)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from suppliers: %w", err)
+	}
+	return results, nil
+}
+
+func (p *Purchasing) SetSupplierItem(ctx context.Context, supplied *purchasing.SupplierItem) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = p.Conn.QueryRow(ctx, `INSERT INTO supplier_items (supplier_id, item_id, retailer_id, code, cost) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (supplier_id, item_id) DO UPDATE SET code = EXCLUDED.code, cost = EXCLUDED.cost, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`, supplied.SupplierID.ID, supplied.ItemID.ID, retailer, supplied.Code, supplied.Cost).Scan(&supplied.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if isUniqueViolation(err) {
+		return purchasing.ErrDuplicateCode
+	} else if isForeignKeyViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "supplier_items_item_id_fkey" {
+		return items.ErrItemNotFound
+	} else if isForeignKeyViolation(err) {
+		return purchasing.ErrSupplierNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to upsert into supplier_items: %w", err)
+	}
+	return nil
+}
+
+func (p *Purchasing) RemoveSupplierItem(ctx context.Context, supplier int, item int) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := p.Conn.Exec(ctx, `DELETE FROM supplier_items WHERE supplier_id = $1 AND item_id = $2 AND retailer_id = $3`, supplier, item, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to delete from supplier_items: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return purchasing.ErrSupplierItemNotFound
+	}
+	return nil
+}
+
+func (p *Purchasing) GetSupplierItems(ctx context.Context, filter purchasing.SupplierItemFilter) ([]*purchasing.SupplierItem, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		conditions = []string{"si.retailer_id = $1"}
+		args       = []any{retailer}
+	)
+	if filter.Supplier != nil {
+		args = append(args, filter.Supplier.ID)
+		conditions = append(conditions, fmt.Sprintf("si.supplier_id = $%d", len(args)))
+	}
+	if filter.Item != nil {
+		args = append(args, filter.Item.ID)
+		conditions = append(conditions, fmt.Sprintf("si.item_id = $%d", len(args)))
+	}
+
+	rows, err := p.Conn.Query(ctx, fmt.Sprintf(`SELECT si.supplier_id, si.item_id, si.code, si.cost, s.currency, si.updated_at
+		FROM supplier_items si JOIN suppliers s ON s.id = si.supplier_id
+		WHERE %s ORDER BY si.supplier_id, si.item_id`, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from supplier_items: %w", err)
+	}
+	defer rows.Close()
+
+	var results = []*purchasing.SupplierItem{}
+	for rows.Next() {
+		var (
+			supplier keys.OpaqueID
+			item     keys.OpaqueID
+			cost     money.Decimal
+			currency money.Currency
+			supplied purchasing.SupplierItem
+		)
+		if err := rows.Scan(&supplier, &item, &supplied.Code, &cost, &currency, &supplied.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from supplier_items: %w", err)
+		}
+
+		supplied.SupplierID, supplied.ItemID = &supplier, &item
+		if supplied.Cost, err = money.FromRat(cost.Rat(), currency); err != nil {
+			return nil, fmt.Errorf("failed to read cost of item %d from supplier %d: %w", item.ID, supplier.ID, err)
+		}
+		results = append(results, &supplied)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from supplier_items: %w", err)
+	}
+	return results, nil
+}
+
+func (p *Purchasing) CreateOrder(ctx context.Context, order *purchasing.PurchaseOrder) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var assigned int
+	err = tx.QueryRow(ctx, `INSERT INTO purchase_orders (retailer_id, supplier_id, location_id, status, currency, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		retailer, order.SupplierID.ID, order.LocationID.ID, order.Status, order.Currency, order.Note, order.CreatedBy).Scan(&assigned, &order.CreatedAt)
+	var pgErr *pgconn.PgError
+	if isForeignKeyViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "purchase_orders_location_id_fkey" {
+		return inventory.ErrLocationNotFound
+	} else if isForeignKeyViolation(err) {
+		return purchasing.ErrSupplierNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into purchase_orders: %w", err)
+	}
+
+	var lines = make([]int, len(order.Lines))
+	for n, line := range order.Lines {
+		err := tx.QueryRow(ctx, `INSERT INTO purchase_order_lines (purchase_order_id, retailer_id, item_id, quantity, received, unit_cost)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+			assigned, retailer, line.ItemID.ID, (*money.Decimal)(line.Quantity), (*money.Decimal)(line.Received), line.UnitCost).Scan(&lines[n])
+		if isForeignKeyViolation(err) {
+			return items.ErrItemNotFound
+		} else if isUniqueViolation(err) {
+			return purchasing.ErrDuplicateItem
+		} else if err != nil {
+			return fmt.Errorf("failed to insert into purchase_order_lines: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	order.ID = &keys.OpaqueID{ID: assigned}
+	for n, line := range order.Lines {
+		line.ID = &keys.OpaqueID{ID: lines[n]}
+	}
+	return nil
+}
+
+func (p *Purchasing) GetOrder(ctx context.Context, id int) (*purchasing.PurchaseOrder, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.Conn.Query(ctx, `SELECT `+orderColumns+` FROM purchase_orders WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from purchase_orders: %w", err)
+	}
+
+	found, err := p.scanOrders(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, purchasing.ErrOrderNotFound
+	}
+	return found[0], nil
+}
+
+func (p *Purchasing) PageOrders(ctx context.Context, filter purchasing.OrderFilter, page paginate.Paginate) ([]*purchasing.PurchaseOrder, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		conditions = []string{"retailer_id = $1"}
+		args       = []any{retailer}
+	)
+	var condition = func(format string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if page.Cursor != nil {
+		condition("id < $%d", page.Cursor)
+	}
+	if filter.Supplier != nil {
+		condition("supplier_id = $%d", filter.Supplier.ID)
+	}
+	if filter.Location != nil {
+		condition("location_id = $%d", filter.Location.ID)
+	}
+	if filter.Status != nil {
+		condition("status = $%d", *filter.Status)
+	}
+	args = append(args, page.Limit)
+
+	rows, err := p.Conn.Query(ctx, fmt.Sprintf(`SELECT `+orderColumns+` FROM purchase_orders WHERE %s ORDER BY id DESC LIMIT $%d`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a page from purchase_orders: %w", err)
+	}
+	return p.scanOrders(ctx, rows)
+}
+
+func (p *Purchasing) TransitionOrder(ctx context.Context, order *purchasing.PurchaseOrder, to purchasing.Status, from ...purchasing.Status) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	var statuses = make([]string, 0, len(from))
+	for _, status := range from {
+		statuses = append(statuses, string(status))
+	}
+
+	err = p.Conn.QueryRow(ctx, `UPDATE purchase_orders SET status = $3,
+			ordered_at = CASE WHEN $3 = 'ORDERED' THEN CURRENT_TIMESTAMP ELSE ordered_at END,
+			closed_at = CASE WHEN $3 IN ('RECEIVED', 'CANCELLED') THEN CURRENT_TIMESTAMP ELSE closed_at END
+		WHERE id = $1 AND retailer_id = $2 AND status = ANY($4)
+		RETURNING status, ordered_at, closed_at`, order.ID.ID, retailer, string(to), statuses).Scan(&order.Status, &order.OrderedAt, &order.ClosedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %s", purchasing.ErrOrderStatus, order.Status)
+	} else if err != nil {
+		return fmt.Errorf("failed to update status of purchase_orders: %w", err)
+	}
+	return nil
+}
+
+// ReceiveOrder holds a lock on the order while receiving so that receipts of the same line can't
+// both see it outstanding.
+func (p *Purchasing) ReceiveOrder(ctx context.Context, order *purchasing.PurchaseOrder, movements []*inventory.Movement) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status purchasing.Status
+	err = tx.QueryRow(ctx, `SELECT status FROM purchase_orders WHERE id = $1 AND retailer_id = $2 FOR UPDATE`, order.ID.ID, retailer).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return purchasing.ErrOrderNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to lock purchase order of purchase_orders: %w", err)
+	}
+	if status != purchasing.Ordered && status != purchasing.PartiallyReceived {
+		return fmt.Errorf("%w: %s", purchasing.ErrOrderStatus, status)
+	}
+
+	for _, movement := range movements {
+		tag, err := tx.Exec(ctx, `UPDATE purchase_order_lines SET received = received + $3 WHERE purchase_order_id = $1 AND item_id = $2`,
+			order.ID.ID, movement.ItemID.ID, (*money.Decimal)(movement.Quantity))
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "purchase_order_lines_received_check" {
+			return purchasing.ErrOverReceipt
+		} else if err != nil {
+			return fmt.Errorf("failed to update received of purchase_order_lines: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return purchasing.ErrLineNotFound
+		}
+	}
+
+	assigned, err := insertMovements(ctx, tx, retailer, movements)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, `UPDATE purchase_orders SET
+			status = CASE WHEN outstanding THEN 'PARTIALLY_RECEIVED' ELSE 'RECEIVED' END,
+			closed_at = CASE WHEN outstanding THEN NULL ELSE CURRENT_TIMESTAMP END
+		FROM (SELECT EXISTS (SELECT 1 FROM purchase_order_lines WHERE purchase_order_id = $1 AND received < quantity) AS outstanding) l
+		WHERE id = $1
+		RETURNING status, closed_at`, order.ID.ID).Scan(&order.Status, &order.ClosedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update status of purchase_orders: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	for n, movement := range movements {
+		movement.ID = &keys.OpaqueID{ID: assigned[n]}
+	}
+	return nil
+}
+
+func (p *Purchasing) GetReceipts(ctx context.Context, order int) ([]*inventory.Movement, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.Conn.Query(ctx, `SELECT `+movementColumns+` FROM stock_movements WHERE purchase_order_id = $1 AND retailer_id = $2 ORDER BY id`, order, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select receipts from stock_movements: %w", err)
+	}
+	return scanMovements(rows)
+}
+
+// scanOrders reads the orders along with all of their lines.
+func (p *Purchasing) scanOrders(ctx context.Context, rows pgx.Rows) ([]*purchasing.PurchaseOrder, error) {
+	defer rows.Close()
+
+	var (
+		results = []*purchasing.PurchaseOrder{}
+		byID    = map[int]*purchasing.PurchaseOrder{}
+		ids     []int
+	)
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			retailer keys.OpaqueID
+			supplier keys.OpaqueID
+			location keys.OpaqueID
+			order    purchasing.PurchaseOrder
+		)
+		err := rows.Scan(&id, &retailer, &supplier, &location, &order.Status, &order.Currency, &order.Note, &order.CreatedBy, &order.CreatedAt, &order.OrderedAt, &order.ClosedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from purchase_orders: %w", err)
+		}
+
+		order.ID, order.RetailerID, order.SupplierID, order.LocationID = &id, &retailer, &supplier, &location
+		order.Lines = []*purchasing.Line{}
+		results = append(results, &order)
+		byID[id.ID] = &order
+		ids = append(ids, id.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from purchase_orders: %w", err)
+	}
+	rows.Close()
+
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	lines, err := p.Conn.Query(ctx, `SELECT id, purchase_order_id, item_id, quantity, received, unit_cost FROM purchase_order_lines WHERE purchase_order_id = ANY($1) ORDER BY id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from purchase_order_lines: %w", err)
+	}
+	defer lines.Close()
+
+	for lines.Next() {
+		var (
+			id       keys.OpaqueID
+			order    int
+			item     keys.OpaqueID
+			quantity money.Decimal
+			received money.Decimal
+			cost     money.Decimal
+			line     purchasing.Line
+		)
+		if err := lines.Scan(&id, &order, &item, &quantity, &received, &cost); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from purchase_order_lines: %w", err)
+		}
+
+		line.ID, line.ItemID = &id, &item
+		line.Quantity, line.Received = new(big.Rat).Set(quantity.Rat()), new(big.Rat).Set(received.Rat())
+		if line.UnitCost, err = money.FromRat(cost.Rat(), byID[order].Currency); err != nil {
+			return nil, fmt.Errorf("failed to read unit cost of purchase order line %d: %w", id.ID, err)
+		}
+		byID[order].Lines = append(byID[order].Lines, &line)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from purchase_order_lines: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/purchasing"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestPurchasingSuppliers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, _ = scope(t, ctx, conn, "pedlar.test")
+	theirs, _ := scope(t, context.Background(), conn, "theirs.test")
+
+	tea, err := (&Items{Conn: conn}).CreateItem(ctx, items.Details{Name: "tea", UnitScale: items.Kilogram})
+	require.NoError(t, err)
+
+	store := &Purchasing{Conn: conn}
+	wholesaler := &purchasing.Supplier{Name: "wholesaler", Currency: "NZD"}
+	require.NoError(t, store.CreateSupplier(ctx, wholesaler))
+	require.ErrorIs(t, store.CreateSupplier(ctx, &purchasing.Supplier{Name: "wholesaler", Currency: "NZD"}), purchasing.ErrDuplicateSupplier)
+	require.NoError(t, store.CreateSupplier(theirs, &purchasing.Supplier{Name: "wholesaler", Currency: "AUD"}))
+
+	grower := &purchasing.Supplier{Name: "grower", Currency: "NZD"}
+	require.NoError(t, store.CreateSupplier(ctx, grower))
+
+	suppliers, err := store.GetSuppliers(ctx)
+	require.NoError(t, err)
+	require.Len(t, suppliers, 2)
+	require.Equal(t, "grower", suppliers[0].Name)
+
+	_, err = store.GetSupplier(theirs, wholesaler.ID.ID)
+	require.ErrorIs(t, err, purchasing.ErrSupplierNotFound)
+
+	var cost = func(amount string) money.Money {
+		parsed, err := money.Parse(amount, "NZD")
+		require.NoError(t, err)
+		return parsed
+	}
+	code := "TEA-1"
+	require.NoError(t, store.SetSupplierItem(ctx, &purchasing.SupplierItem{SupplierID: wholesaler.ID, ItemID: tea.ID, Code: &code, Cost: cost("30.00")}))
+	require.NoError(t, store.SetSupplierItem(ctx, &purchasing.SupplierItem{SupplierID: wholesaler.ID, ItemID: tea.ID, Code: &code, Cost: cost("32.50")}))
+	require.NoError(t, store.SetSupplierItem(ctx, &purchasing.SupplierItem{SupplierID: grower.ID, ItemID: tea.ID, Code: &code, Cost: cost("28.00")}))
+
+	coffee, err := (&Items{Conn: conn}).CreateItem(ctx, items.Details{Name: "coffee", UnitScale: items.Kilogram})
+	require.NoError(t, err)
+	require.ErrorIs(t, store.SetSupplierItem(ctx, &purchasing.SupplierItem{SupplierID: wholesaler.ID, ItemID: coffee.ID, Code: &code, Cost: cost("40.00")}), purchasing.ErrDuplicateCode)
+
+	supplied, err := store.GetSupplierItems(ctx, purchasing.SupplierItemFilter{Item: tea.ID})
+	require.NoError(t, err)
+	require.Len(t, supplied, 2)
+	require.Equal(t, "32.50", supplied[0].Cost.Decimal())
+	require.Equal(t, code, *supplied[0].Code)
+
+	require.NoError(t, store.RemoveSupplierItem(ctx, grower.ID.ID, tea.ID.ID))
+	require.ErrorIs(t, store.RemoveSupplierItem(ctx, grower.ID.ID, tea.ID.ID), purchasing.ErrSupplierItemNotFound)
+
+	supplied, err = store.GetSupplierItems(theirs, purchasing.SupplierItemFilter{})
+	require.NoError(t, err)
+	require.Empty(t, supplied)
+}
+
+func TestPurchasingOrders(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	itemStore := &Items{Conn: conn}
+	tea, err := itemStore.CreateItem(ctx, items.Details{Name: "tea", UnitScale: items.Kilogram})
+	require.NoError(t, err)
+	mug, err := itemStore.CreateItem(ctx, items.Details{Name: "mug", UnitScale: items.Unit})
+	require.NoError(t, err)
+
+	stock := &Inventory{Conn: conn}
+	floor := &inventory.Location{Name: "floor"}
+	require.NoError(t, stock.CreateLocation(ctx, floor))
+
+	store := &Purchasing{Conn: conn}
+	wholesaler := &purchasing.Supplier{Name: "wholesaler", Currency: "NZD"}
+	require.NoError(t, store.CreateSupplier(ctx, wholesaler))
+
+	var cost = func(amount string) money.Money {
+		parsed, err := money.Parse(amount, "NZD")
+		require.NoError(t, err)
+		return parsed
+	}
+	order := &purchasing.PurchaseOrder{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		SupplierID: wholesaler.ID,
+		LocationID: floor.ID,
+		Status:     purchasing.Draft,
+		Currency:   "NZD",
+		CreatedBy:  "till-1",
+		Lines: []*purchasing.Line{
+			{ItemID: tea.ID, Quantity: big.NewRat(5, 2), Received: new(big.Rat), UnitCost: cost("30.00")},
+			{ItemID: mug.ID, Quantity: big.NewRat(6, 1), Received: new(big.Rat), UnitCost: cost("4.50")},
+		},
+	}
+	require.NoError(t, store.CreateOrder(ctx, order))
+
+	found, err := store.GetOrder(ctx, order.ID.ID)
+	require.NoError(t, err)
+	require.Len(t, found.Lines, 2)
+	require.Equal(t, "2.5", inventory.FormatQuantity(found.Lines[0].Quantity))
+	total, err := found.Total()
+	require.NoError(t, err)
+	require.Equal(t, "102.00", total.Decimal())
+
+	var receipt = func(item *items.Item, quantity *big.Rat) *inventory.Movement {
+		return &inventory.Movement{ItemID: item.ID, LocationID: floor.ID, Quantity: quantity, Reason: inventory.Receipt, PurchaseOrderID: order.ID, Actor: "till-1"}
+	}
+
+	// drafts aren't received against
+	require.ErrorIs(t, store.ReceiveOrder(ctx, order, []*inventory.Movement{receipt(mug, big.NewRat(1, 1))}), purchasing.ErrOrderStatus)
+	require.NoError(t, store.TransitionOrder(ctx, order, purchasing.Ordered, purchasing.Draft))
+	require.NotNil(t, order.OrderedAt)
+	require.ErrorIs(t, store.TransitionOrder(ctx, order, purchasing.Ordered, purchasing.Draft), purchasing.ErrOrderStatus)
+
+	require.NoError(t, store.ReceiveOrder(ctx, order, []*inventory.Movement{receipt(tea, big.NewRat(5, 2)), receipt(mug, big.NewRat(4, 1))}))
+	require.Equal(t, purchasing.PartiallyReceived, order.Status)
+	require.Nil(t, order.ClosedAt)
+
+	// the whole receipt is refused when a line is over received
+	require.ErrorIs(t, store.ReceiveOrder(ctx, order, []*inventory.Movement{receipt(mug, big.NewRat(1, 1)), receipt(tea, big.NewRat(1, 10))}), purchasing.ErrOverReceipt)
+	levels, err := stock.GetLevels(ctx, inventory.Filter{Item: mug.ID})
+	require.NoError(t, err)
+	require.Equal(t, "4", inventory.FormatQuantity(levels[0].OnHand))
+
+	require.NoError(t, store.ReceiveOrder(ctx, order, []*inventory.Movement{receipt(mug, big.NewRat(2, 1))}))
+	require.Equal(t, purchasing.Received, order.Status)
+	require.NotNil(t, order.ClosedAt)
+	require.ErrorIs(t, store.TransitionOrder(ctx, order, purchasing.Cancelled, purchasing.Draft, purchasing.Ordered, purchasing.PartiallyReceived), purchasing.ErrOrderStatus)
+
+	receipts, err := store.GetReceipts(ctx, order.ID.ID)
+	require.NoError(t, err)
+	require.Len(t, receipts, 3)
+	require.Equal(t, order.ID.ID, receipts[0].PurchaseOrderID.ID)
+
+	found, err = store.GetOrder(ctx, order.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, "6", inventory.FormatQuantity(found.Lines[1].Received))
+
+	// stock received against orders can only be receipts
+	_, err = conn.Exec(ctx, `INSERT INTO stock_movements (retailer_id, item_id, location_id, quantity, reason, purchase_order_id, actor) VALUES ($1, $2, $3, 1, 'ADJUSTMENT', $4, 'test')`,
+		retailer, mug.ID.ID, floor.ID.ID, order.ID.ID)
+	require.Error(t, err)
+
+	received := purchasing.Received
+	orders, err := store.PageOrders(ctx, purchasing.OrderFilter{Supplier: wholesaler.ID, Status: &received}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	require.Len(t, orders[0].Lines, 2)
+
+	ordered := purchasing.Ordered
+	orders, err = store.PageOrders(ctx, purchasing.OrderFilter{Status: &ordered}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, orders)
+}
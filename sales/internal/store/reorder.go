@@ -0,0 +1,301 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/reorder"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type Reorder struct {
+	Conn *Pool
+}
+
+const alertColumns = `id, retailer_id, item_id, location_id, on_hand, threshold, raised_at, notified_at, resolved_at`
+
+func (r *Reorder) SetPoint(ctx context.Context, point *reorder.Point) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.Conn.QueryRow(ctx, `INSERT INTO reorder_points (item_id, location_id, retailer_id, threshold, cover_days)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (item_id, location_id) DO UPDATE
+		SET threshold = EXCLUDED.threshold, cover_days = EXCLUDED.cover_days, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`, point.ItemID.ID, point.LocationID.ID, retailer, (*money.Decimal)(point.Threshold), point.CoverDays).Scan(&point.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if isForeignKeyViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "reorder_points_item_id_fkey" {
+		return items.ErrItemNotFound
+	} else if isForeignKeyViolation(err) {
+		return inventory.ErrLocationNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into reorder_points: %w", err)
+	}
+	return nil
+}
+
+func (r *Reorder) RemovePoint(ctx context.Context, item int, location int) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.Conn.Exec(ctx, `DELETE FROM reorder_points WHERE item_id = $1 AND location_id = $2 AND retailer_id = $3`, item, location, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to delete from reorder_points: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return reorder.ErrPointNotFound
+	}
+	return nil
+}
+
+func (r *Reorder) GetPoints(ctx context.Context, filter inventory.Filter) ([]*reorder.Point, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		conditions = []string{"retailer_id = $1"}
+		args       = []any{retailer}
+	)
+	if filter.Item != nil {
+		args = append(args, filter.Item.ID)
+		conditions = append(conditions, fmt.Sprintf("item_id = $%d", len(args)))
+	}
+	if filter.Location != nil {
+		args = append(args, filter.Location.ID)
+		conditions = append(conditions, fmt.Sprintf("location_id = $%d", len(args)))
+	}
+
+	rows, err := r.Conn.Query(ctx, fmt.Sprintf(`SELECT item_id, location_id, threshold, cover_days, updated_at FROM reorder_points WHERE %s ORDER BY location_id, item_id`, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from reorder_points: %w", err)
+	}
+	defer rows.Close()
+
+	var results = []*reorder.Point{}
+	for rows.Next() {
+		var (
+			item      keys.OpaqueID
+			location  keys.OpaqueID
+			threshold money.Decimal
+			point     reorder.Point
+		)
+		if err := rows.Scan(&item, &location, &threshold, &point.CoverDays, &point.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from reorder_points: %w", err)
+		}
+		point.ItemID, point.LocationID, point.Threshold = &item, &location, new(big.Rat).Set(threshold.Rat())
+		results = append(results, &point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from reorder_points: %w", err)
+	}
+	return results, nil
+}
+
+// EvaluatePoints resolves alerts before raising new ones, so that an item that recovered and fell
+// again since the last evaluation is alerted on afresh. Items that have never moved stock at a
+// location have none on hand there, archived items are no longer reordered.
+func (r *Reorder) EvaluatePoints(ctx context.Context) (int, int, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	resolved, err := tx.Exec(ctx, `UPDATE stock_alerts a SET resolved_at = CURRENT_TIMESTAMP
+		WHERE a.retailer_id = $1 AND a.resolved_at IS NULL AND NOT EXISTS (
+			SELECT 1
+			FROM reorder_points p
+			JOIN items i ON i.id = p.item_id AND i.archived_at IS NULL
+			LEFT JOIN stock_levels l ON l.item_id = p.item_id AND l.location_id = p.location_id
+			WHERE p.item_id = a.item_id AND p.location_id = a.location_id AND COALESCE(l.on_hand, 0) < p.threshold
+		)`, retailer)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to resolve stock_alerts: %w", err)
+	}
+
+	raised, err := tx.Exec(ctx, `INSERT INTO stock_alerts (retailer_id, item_id, location_id, on_hand, threshold)
+		SELECT p.retailer_id, p.item_id, p.location_id, COALESCE(l.on_hand, 0), p.threshold
+		FROM reorder_points p
+		JOIN items i ON i.id = p.item_id AND i.archived_at IS NULL
+		LEFT JOIN stock_levels l ON l.item_id = p.item_id AND l.location_id = p.location_id
+		WHERE p.retailer_id = $1 AND COALESCE(l.on_hand, 0) < p.threshold
+		ON CONFLICT (item_id, location_id) WHERE resolved_at IS NULL DO NOTHING`, retailer)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to insert into stock_alerts: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return int(raised.RowsAffected()), int(resolved.RowsAffected()), nil
+}
+
+func (r *Reorder) PendingAlerts(ctx context.Context) ([]*reorder.Alert, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Conn.Query(ctx, `SELECT `+alertColumns+` FROM stock_alerts WHERE retailer_id = $1 AND resolved_at IS NULL AND notified_at IS NULL ORDER BY id`, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select pending from stock_alerts: %w", err)
+	}
+	return scanAlerts(rows)
+}
+
+func (r *Reorder) MarkNotified(ctx context.Context, id int, at time.Time) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Conn.Exec(ctx, `UPDATE stock_alerts SET notified_at = $3 WHERE id = $1 AND retailer_id = $2 AND notified_at IS NULL`, id, retailer, at)
+	if err != nil {
+		return fmt.Errorf("failed to update notified_at of stock_alerts: %w", err)
+	}
+	return nil
+}
+
+func (r *Reorder) PageAlerts(ctx context.Context, filter reorder.AlertFilter, page paginate.Paginate) ([]*reorder.Alert, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		conditions = []string{"retailer_id = $1"}
+		args       = []any{retailer}
+	)
+	var condition = func(format string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if page.Cursor != nil {
+		condition("id < $%d", page.Cursor)
+	}
+	if filter.Item != nil {
+		condition("item_id = $%d", filter.Item.ID)
+	}
+	if filter.Location != nil {
+		condition("location_id = $%d", filter.Location.ID)
+	}
+	if filter.Open != nil {
+		condition("(resolved_at IS NULL) = $%d", *filter.Open)
+	}
+	args = append(args, page.Limit)
+
+	rows, err := r.Conn.Query(ctx, fmt.Sprintf(`SELECT `+alertColumns+` FROM stock_alerts WHERE %s ORDER BY id DESC LIMIT $%d`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a page from stock_alerts: %w", err)
+	}
+	return scanAlerts(rows)
+}
+
+// GetDemand counts the stock sold by every sale that wasn't voided, taking kits apart the same way
+// selling them takes stock.
+func (r *Reorder) GetDemand(ctx context.Context, location *int, since time.Time) ([]*reorder.Demand, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Conn.Query(ctx, `WITH RECURSIVE sold (location_id, item_id, quantity) AS (
+			SELECT s.location_id, l.product_id, l.quantity::NUMERIC
+			FROM line_items l
+			JOIN sales s ON s.id = l.sale_id
+			WHERE s.retailer_id = $1 AND s.location_id IS NOT NULL AND s.status <> $2 AND s.sale_date >= $3
+			UNION ALL
+			SELECT sold.location_id, r.child_id, sold.quantity * r.quantity
+			FROM sold
+			JOIN item_relationships r ON r.parent_id = sold.item_id
+		)
+		SELECT p.item_id, p.location_id, p.threshold, p.cover_days, p.updated_at, i.unit_scale, COALESCE(l.on_hand, 0), COALESCE(d.quantity, 0)
+		FROM reorder_points p
+		JOIN items i ON i.id = p.item_id AND i.archived_at IS NULL
+		LEFT JOIN stock_levels l ON l.item_id = p.item_id AND l.location_id = p.location_id
+		LEFT JOIN (
+			SELECT location_id, item_id, SUM(quantity) AS quantity FROM sold GROUP BY location_id, item_id
+		) d ON d.item_id = p.item_id AND d.location_id = p.location_id
+		WHERE p.retailer_id = $1 AND ($4::INTEGER IS NULL OR p.location_id = $4)
+		ORDER BY p.location_id, p.item_id`, retailer, sales.Voided, since, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select demand from reorder_points: %w", err)
+	}
+	defer rows.Close()
+
+	var results = []*reorder.Demand{}
+	for rows.Next() {
+		var (
+			item      keys.OpaqueID
+			location  keys.OpaqueID
+			threshold money.Decimal
+			onHand    money.Decimal
+			sold      money.Decimal
+			point     reorder.Point
+			demand    reorder.Demand
+		)
+		err := rows.Scan(&item, &location, &threshold, &point.CoverDays, &point.UpdatedAt, &demand.UnitScale, &onHand, &sold)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting demand from reorder_points: %w", err)
+		}
+		point.ItemID, point.LocationID, point.Threshold = &item, &location, new(big.Rat).Set(threshold.Rat())
+		demand.Point, demand.OnHand, demand.Sold = &point, new(big.Rat).Set(onHand.Rat()), new(big.Rat).Set(sold.Rat())
+		results = append(results, &demand)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from reorder_points: %w", err)
+	}
+	return results, nil
+}
+
+func scanAlerts(rows pgx.Rows) ([]*reorder.Alert, error) {
+	defer rows.Close()
+
+	var results = []*reorder.Alert{}
+	for rows.Next() {
+		var (
+			id        keys.OpaqueID
+			retailer  keys.OpaqueID
+			item      keys.OpaqueID
+			location  keys.OpaqueID
+			onHand    money.Decimal
+			threshold money.Decimal
+			alert     reorder.Alert
+		)
+		err := rows.Scan(&id, &retailer, &item, &location, &onHand, &threshold, &alert.RaisedAt, &alert.NotifiedAt, &alert.ResolvedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from stock_alerts: %w", err)
+		}
+		alert.ID, alert.RetailerID, alert.ItemID, alert.LocationID = &id, &retailer, &item, &location
+		alert.OnHand, alert.Threshold = new(big.Rat).Set(onHand.Rat()), new(big.Rat).Set(threshold.Rat())
+		results = append(results, &alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from stock_alerts: %w", err)
+	}
+	return results, nil
+}
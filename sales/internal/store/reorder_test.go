@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/reorder"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestReorderAlerts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, _ = scope(t, ctx, conn, "pedlar.test")
+	theirs, _ := scope(t, context.Background(), conn, "theirs.test")
+
+	candle, err := (&Items{Conn: conn}).CreateItem(ctx, items.Details{Name: "candle", UnitScale: items.Unit})
+	require.NoError(t, err)
+
+	stock := &Inventory{Conn: conn}
+	floor := &inventory.Location{Name: "floor"}
+	require.NoError(t, stock.CreateLocation(ctx, floor))
+	elsewhere := &inventory.Location{Name: "floor"}
+	require.NoError(t, stock.CreateLocation(theirs, elsewhere))
+
+	store := &Reorder{Conn: conn}
+	point := &reorder.Point{ItemID: candle.ID, LocationID: floor.ID, Threshold: big.NewRat(5, 1), CoverDays: 14}
+	require.NoError(t, store.SetPoint(ctx, point))
+	require.ErrorIs(t, store.SetPoint(ctx, &reorder.Point{ItemID: candle.ID, LocationID: elsewhere.ID, Threshold: big.NewRat(5, 1), CoverDays: 14}), inventory.ErrLocationNotFound)
+
+	// the candle has never been stocked, so there are none on hand
+	raised, resolved, err := store.EvaluatePoints(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, raised)
+	require.Equal(t, 0, resolved)
+
+	raised, _, err = store.EvaluatePoints(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, raised)
+
+	pending, err := store.PendingAlerts(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, "0", inventory.FormatQuantity(pending[0].OnHand))
+	require.NoError(t, store.MarkNotified(ctx, pending[0].ID.ID, time.Now()))
+
+	pending, err = store.PendingAlerts(ctx)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	var receive = func(quantity int64) {
+		require.NoError(t, stock.PostMovements(ctx, []*inventory.Movement{
+			{ItemID: candle.ID, LocationID: floor.ID, Quantity: big.NewRat(quantity, 1), Reason: inventory.Adjustment, Actor: "till-1"},
+		}))
+	}
+	receive(5)
+	_, resolved, err = store.EvaluatePoints(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, resolved)
+
+	receive(-1)
+	raised, _, err = store.EvaluatePoints(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, raised)
+
+	open := true
+	alerts, err := store.PageAlerts(ctx, reorder.AlertFilter{Open: &open}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, "4", inventory.FormatQuantity(alerts[0].OnHand))
+	require.Nil(t, alerts[0].NotifiedAt)
+
+	alerts, err = store.PageAlerts(ctx, reorder.AlertFilter{Item: candle.ID}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	require.NotNil(t, alerts[1].ResolvedAt)
+
+	// removing the point resolves its alert
+	require.NoError(t, store.RemovePoint(ctx, candle.ID.ID, floor.ID.ID))
+	require.ErrorIs(t, store.RemovePoint(ctx, candle.ID.ID, floor.ID.ID), reorder.ErrPointNotFound)
+	_, resolved, err = store.EvaluatePoints(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, resolved)
+
+	alerts, err = store.PageAlerts(theirs, reorder.AlertFilter{}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, alerts)
+}
+
+func TestReorderDemand(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	itemStore := &Items{Conn: conn}
+	candle, err := itemStore.CreateItem(ctx, items.Details{Name: "candle", UnitScale: items.Unit})
+	require.NoError(t, err)
+	box, err := itemStore.CreateItem(ctx, items.Details{Name: "gift box", UnitScale: items.Unit})
+	require.NoError(t, err)
+	_, err = itemStore.AddChild(ctx, box.ID.ID, candle.ID.ID, big.NewRat(2, 1))
+	require.NoError(t, err)
+
+	stock := &Inventory{Conn: conn}
+	floor := &inventory.Location{Name: "floor"}
+	require.NoError(t, stock.CreateLocation(ctx, floor))
+
+	store := &Reorder{Conn: conn}
+	require.NoError(t, store.SetPoint(ctx, &reorder.Point{ItemID: candle.ID, LocationID: floor.ID, Threshold: big.NewRat(5, 1), CoverDays: 7}))
+
+	salesStore := &Sales{Conn: conn}
+	price, err := money.Parse("20.00", "NZD")
+	require.NoError(t, err)
+	var sell = func(at time.Time, item *items.Item, quantity int64) *sales.Sale {
+		sold := &sales.Sale{
+			RetailerID: &keys.OpaqueID{ID: retailer},
+			LocationID: floor.ID,
+			SaleDate:   at,
+			Currency:   "NZD",
+			Status:     sales.Paid,
+			LineItems:  []*sales.LineItem{{ItemID: &keys.OpaqueID{ID: item.ID.ID}, Quantity: big.NewRat(quantity, 1), UnitScale: item.UnitScale, UnitPrice: price}},
+		}
+		require.NoError(t, salesStore.CreateSale(ctx, sold))
+		return sold
+	}
+	sell(time.Now(), candle, 3)
+	sell(time.Now(), box, 2)
+	sell(time.Now().Add(-60*24*time.Hour), candle, 100)
+	voided := sell(time.Now(), candle, 50)
+	require.NoError(t, salesStore.VoidSale(ctx, voided.ID.ID, &sales.Void{At: time.Now(), Actor: "till-1", Reason: "mistake"}))
+
+	demand, err := store.GetDemand(ctx, nil, time.Now().Add(-28*24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, demand, 1)
+	require.Equal(t, candle.ID.ID, demand[0].Point.ItemID.ID)
+	require.Equal(t, items.Unit, demand[0].UnitScale)
+	// three candles sold alone and four in gift boxes, the old and voided sales don't count
+	require.Equal(t, "7", inventory.FormatQuantity(demand[0].Sold))
+	require.Equal(t, "-107", inventory.FormatQuantity(demand[0].OnHand))
+
+	other := &inventory.Location{Name: "back room"}
+	require.NoError(t, stock.CreateLocation(ctx, other))
+	demand, err = store.GetDemand(ctx, &other.ID.ID, time.Now().Add(-28*24*time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, demand)
+}
@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
+
+// Retailers administers the retailers themselves, they're not scoped to a retailer.
+type Retailers struct {
+	Conn *Pool
+}
+
+const retailerColumns = `id, name, domain, created_at, updated_at`
+
+func (r *Retailers) CreateRetailer(ctx context.Context, retailer *retailers.Retailer) error {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var assigned int
+	err = tx.QueryRow(ctx, `INSERT INTO retailers (name, domain) VALUES ($1, $2) RETURNING id, created_at, updated_at`, retailer.Name, retailer.Domain).Scan(&assigned, &retailer.CreatedAt, &retailer.UpdatedAt)
+	if isUniqueViolation(err) {
+		return retailers.ErrDuplicateDomain
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into retailers: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO retailer_domains (domain, retailer_id) VALUES ($1, $2)`, retailer.Domain, assigned)
+	if isUniqueViolation(err) {
+		return retailers.ErrDuplicateDomain
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into retailer_domains: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	retailer.ID = &keys.OpaqueID{ID: assigned}
+	return nil
+}
+
+func (r *Retailers) GetRetailer(ctx context.Context, id int) (*retailers.Retailer, error) {
+	rows, err := r.Conn.Query(ctx, `SELECT `+retailerColumns+` FROM retailers WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from retailers: %w", err)
+	}
+
+	found, err := scanRetailers(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, retailers.ErrRetailerNotFound
+	}
+	return found[0], nil
+}
+
+func (r *Retailers) GetRetailers(ctx context.Context) ([]*retailers.Retailer, error) {
+	rows, err := r.Conn.Query(ctx, `SELECT `+retailerColumns+` FROM retailers ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from retailers: %w", err)
+	}
+	return scanRetailers(rows)
+}
+
+// UpdateRetailer replaces the primary domain of the retailer, the new domain may already be one of
+// its other domains.
+func (r *Retailers) UpdateRetailer(ctx context.Context, retailer *retailers.Retailer) error {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT domain FROM retailers WHERE id = $1 FOR UPDATE`, retailer.ID.ID).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return retailers.ErrRetailerNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to lock retailer of retailers: %w", err)
+	}
+
+	if previous != retailer.Domain {
+		_, err = tx.Exec(ctx, `DELETE FROM retailer_domains WHERE domain = $1 AND retailer_id = $2`, previous, retailer.ID.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete from retailer_domains: %w", err)
+		}
+		if err := addDomain(ctx, tx, retailer.ID.ID, retailer.Domain); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRow(ctx, `UPDATE retailers SET name = $2, domain = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING updated_at`, retailer.ID.ID, retailer.Name, retailer.Domain).Scan(&retailer.UpdatedAt)
+	if isUniqueViolation(err) {
+		return retailers.ErrDuplicateDomain
+	} else if err != nil {
+		return fmt.Errorf("failed to update retailers: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteRetailer takes the price lists, tax settings and promotions of the retailer with it, items,
+// sales and customers hold on to their retailer.
+func (r *Retailers) DeleteRetailer(ctx context.Context, id int) error {
+	tag, err := r.Conn.Exec(ctx, `DELETE FROM retailers WHERE id = $1`, id)
+	if isForeignKeyViolation(err) {
+		return retailers.ErrRetailerInUse
+	} else if err != nil {
+		return fmt.Errorf("failed to delete from retailers: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return retailers.ErrRetailerNotFound
+	}
+	return nil
+}
+
+func (r *Retailers) RetailerByDomain(ctx context.Context, domain string) (int, error) {
+	var retailer int
+	err := r.Conn.QueryRow(ctx, `SELECT retailer_id FROM retailer_domains WHERE domain = $1`, domain).Scan(&retailer)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return -1, retailers.ErrRetailerNotFound
+	} else if err != nil {
+		return -1, fmt.Errorf("failed to select from retailer_domains: %w", err)
+	}
+	return retailer, nil
+}
+
+func (r *Retailers) GetDomains(ctx context.Context, retailer int) ([]string, error) {
+	rows, err := r.Conn.Query(ctx, `SELECT domain FROM retailer_domains WHERE retailer_id = $1 ORDER BY domain`, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from retailer_domains: %w", err)
+	}
+
+	domains, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan from rows result when selecting from retailer_domains: %w", err)
+	}
+	return domains, nil
+}
+
+func (r *Retailers) AddDomain(ctx context.Context, retailer int, domain string) error {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := addDomain(ctx, tx, retailer, domain); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// addDomain adds the domain to the retailer unless it already has it, domains of other retailers
+// are ErrDuplicateDomain.
+func addDomain(ctx context.Context, tx pgx.Tx, retailer int, domain string) error {
+	var owner int
+	err := tx.QueryRow(ctx, `INSERT INTO retailer_domains (domain, retailer_id) VALUES ($1, $2)
+		ON CONFLICT (domain) DO UPDATE SET domain = EXCLUDED.domain
+		RETURNING retailer_id`, domain, retailer).Scan(&owner)
+	if isForeignKeyViolation(err) {
+		return retailers.ErrRetailerNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into retailer_domains: %w", err)
+	}
+
+	if owner != retailer {
+		return retailers.ErrDuplicateDomain
+	}
+	return nil
+}
+
+func (r *Retailers) RemoveDomain(ctx context.Context, retailer int, domain string) error {
+	tag, err := r.Conn.Exec(ctx, `DELETE FROM retailer_domains WHERE domain = $1 AND retailer_id = $2 AND domain <> (SELECT domain FROM retailers WHERE id = $2)`, domain, retailer)
+	if err != nil {
+		return fmt.Errorf("failed to delete from retailer_domains: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return retailers.ErrDomainNotFound
+	}
+	return nil
+}
+
+func scanRetailers(rows pgx.Rows) ([]*retailers.Retailer, error) {
+	defer rows.Close()
+
+	var results []*retailers.Retailer
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			retailer retailers.Retailer
+		)
+		if err := rows.Scan(&id, &retailer.Name, &retailer.Domain, &retailer.CreatedAt, &retailer.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from retailers: %w", err)
+		}
+		retailer.ID = &id
+		results = append(results, &retailer)
+	}
+	return results, rows.Err()
+}
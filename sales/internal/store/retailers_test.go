@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// scope creates a retailer of the domain and scopes the context to it.
+func scope(t *testing.T, ctx context.Context, conn *Pool, domain string) (context.Context, int) {
+	t.Helper()
+
+	retailer := &retailers.Retailer{Name: domain, Domain: domain}
+	require.NoError(t, (&Retailers{Conn: conn}).CreateRetailer(ctx, retailer))
+	return retailers.With(ctx, retailer.ID.ID), retailer.ID.ID
+}
+
+func TestRetailers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	store := &Retailers{Conn: conn}
+
+	retailer := &retailers.Retailer{Name: "pedlar", Domain: "pedlar.test"}
+	require.NoError(t, store.CreateRetailer(ctx, retailer))
+
+	err = store.CreateRetailer(ctx, &retailers.Retailer{Name: "pedlar again", Domain: "pedlar.test"})
+	require.ErrorIs(t, err, retailers.ErrDuplicateDomain)
+
+	retailer.Name = "pedlar coffee"
+	require.NoError(t, store.UpdateRetailer(ctx, retailer))
+
+	found, err := store.GetRetailer(ctx, retailer.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pedlar coffee", found.Name)
+
+	// retailers that have stocked items hold on to them
+	scoped := retailers.With(ctx, retailer.ID.ID)
+	_, err = (&Items{Conn: conn}).CreateItem(scoped, items.Details{Name: "flat white", UnitScale: items.Unit})
+	require.NoError(t, err)
+	require.ErrorIs(t, store.DeleteRetailer(ctx, retailer.ID.ID), retailers.ErrRetailerInUse)
+
+	// retailers are reached through any of their domains, which are never shared
+	require.NoError(t, store.AddDomain(ctx, retailer.ID.ID, "shop.pedlar.test"))
+	require.NoError(t, store.AddDomain(ctx, retailer.ID.ID, "shop.pedlar.test"))
+
+	owner, err := store.RetailerByDomain(ctx, "shop.pedlar.test")
+	require.NoError(t, err)
+	require.Equal(t, retailer.ID.ID, owner)
+
+	empty := &retailers.Retailer{Name: "empty", Domain: "empty.test"}
+	require.NoError(t, store.CreateRetailer(ctx, empty))
+	require.ErrorIs(t, store.AddDomain(ctx, empty.ID.ID, "shop.pedlar.test"), retailers.ErrDuplicateDomain)
+	require.ErrorIs(t, store.CreateRetailer(ctx, &retailers.Retailer{Name: "taken", Domain: "shop.pedlar.test"}), retailers.ErrDuplicateDomain)
+
+	// the primary domain moves onto one of the other domains of the retailer
+	retailer.Domain = "shop.pedlar.test"
+	require.NoError(t, store.UpdateRetailer(ctx, retailer))
+
+	domains, err := store.GetDomains(ctx, retailer.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"shop.pedlar.test"}, domains)
+
+	_, err = store.RetailerByDomain(ctx, "pedlar.test")
+	require.ErrorIs(t, err, retailers.ErrRetailerNotFound)
+	require.ErrorIs(t, store.RemoveDomain(ctx, retailer.ID.ID, "shop.pedlar.test"), retailers.ErrDomainNotFound)
+
+	require.NoError(t, store.DeleteRetailer(ctx, empty.ID.ID))
+
+	_, err = store.GetRetailer(ctx, empty.ID.ID)
+	require.ErrorIs(t, err, retailers.ErrRetailerNotFound)
+}
+
+func TestRetailersIsolated(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	mine, retailer := scope(t, ctx, conn, "mine.test")
+	theirs, _ := scope(t, ctx, conn, "theirs.test")
+
+	itemStore := &Items{Conn: conn}
+
+	// two retailers stock the same product under the same sku and barcode
+	sku, code := "BEANS-1KG", "036000291452"
+	gtin := "00036000291452"
+	beans, err := itemStore.CreateItem(mine, items.Details{Name: "beans", UnitScale: items.Kilogram, SKU: &sku})
+	require.NoError(t, err)
+	require.NoError(t, itemStore.AddBarcode(mine, beans.ID.ID, gtin, code))
+
+	other, err := itemStore.CreateItem(theirs, items.Details{Name: "beans", UnitScale: items.Kilogram, SKU: &sku})
+	require.NoError(t, err)
+	require.NoError(t, itemStore.AddBarcode(theirs, other.ID.ID, gtin, code))
+
+	found, err := itemStore.GetItemBySKU(mine, sku)
+	require.NoError(t, err)
+	require.Equal(t, beans.ID.ID, found)
+
+	found, err = itemStore.GetItemByBarcode(theirs, gtin)
+	require.NoError(t, err)
+	require.Equal(t, other.ID.ID, found)
+
+	// items of another retailer can't be read, written or related to
+	_, err = itemStore.GetItem(theirs, beans.ID.ID)
+	require.ErrorIs(t, err, items.ErrItemNotFound)
+
+	err = itemStore.UpdateItemDetails(theirs, beans.ID.ID, 1, items.Details{Name: "stolen", UnitScale: items.Unit})
+	require.ErrorIs(t, err, items.ErrItemNotFound)
+
+	err = itemStore.AddBarcode(theirs, beans.ID.ID, "00012345678905", "012345678905")
+	require.ErrorIs(t, err, items.ErrItemNotFound)
+
+	_, err = itemStore.AddChild(theirs, other.ID.ID, beans.ID.ID, big.NewRat(1, 1))
+	require.ErrorIs(t, err, items.ErrItemNotFound)
+
+	_, err = itemStore.CreateVariant(theirs, beans.ID.ID, items.VariantDetails{Options: []items.Option{{Axis: "roast", Value: "dark"}}})
+	require.ErrorIs(t, err, items.ErrItemNotFound)
+
+	listed, err := itemStore.GetItems(theirs, beans.ID.ID, other.ID.ID)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	require.Equal(t, other.ID.ID, listed[0].ID.ID)
+
+	page, err := itemStore.PageItems(theirs, items.ItemFilter{}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+
+	history, err := (&ItemHistory{Conn: conn}).PageItemHistory(theirs, beans.ID.ID, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	// sales can't sell items of another retailer, nor be read by one
+	price, err := money.Parse("20.00", "NZD")
+	require.NoError(t, err)
+
+	salesStore := &Sales{Conn: conn}
+	stolen := &sales.Sale{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		SaleDate:   time.Now(),
+		Currency:   "NZD",
+		Status:     sales.Paid,
+		LineItems:  []*sales.LineItem{{ItemID: &keys.OpaqueID{ID: other.ID.ID}, Quantity: big.NewRat(1, 1), UnitScale: items.Unit, UnitPrice: price}},
+	}
+	require.ErrorIs(t, salesStore.CreateSale(mine, stolen), sales.ErrUnknownItem)
+
+	sale := &sales.Sale{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		SaleDate:   time.Now(),
+		Currency:   "NZD",
+		Status:     sales.Paid,
+		LineItems:  []*sales.LineItem{{ItemID: &keys.OpaqueID{ID: beans.ID.ID}, Quantity: big.NewRat(1, 1), UnitScale: items.Unit, UnitPrice: price}},
+	}
+	require.NoError(t, salesStore.CreateSale(mine, sale))
+
+	_, err = salesStore.GetSale(theirs, sale.ID.ID)
+	require.ErrorIs(t, err, sales.ErrSaleNotFound)
+
+	paged, err := salesStore.PageSales(theirs, sales.SaleFilter{}, paginate.Paginate{Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, paged)
+
+	// nothing is read without a retailer at all
+	_, err = itemStore.GetItem(ctx, beans.ID.ID)
+	require.ErrorIs(t, err, retailers.ErrNoRetailer)
+}
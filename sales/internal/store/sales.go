@@ -0,0 +1,704 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/actor"
+	"github.com/suessflorian/pedlar/sales/internal/inventory"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/promotions"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type Sales struct {
+	Conn *Pool
+}
+
+const saleColumns = `id, retailer_id, customer_id, location_id, sale_date, currency, price_list_id, prices_include_tax, status, voided_at, voided_by, void_reason`
+
+func (s *Sales) CreateSale(ctx context.Context, sale *sales.Sale) error {
+	tx, err := s.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var ids = make([]int, 0, len(sale.LineItems))
+	for _, line := range sale.LineItems {
+		ids = append(ids, line.ItemID.ID)
+	}
+
+	// line items reference items with a foreign key, but archived items and items of other
+	// retailers must not be sold either
+	var sellable int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM items WHERE id = ANY($1) AND retailer_id = $2 AND archived_at IS NULL`, ids, sale.RetailerID.ID).Scan(&sellable)
+	if err != nil {
+		return fmt.Errorf("failed to select sellable items: %w", err)
+	}
+	if sellable != distinct(ids) {
+		return sales.ErrUnknownItem
+	}
+
+	var customer, location, list *int
+	if sale.CustomerID != nil {
+		customer = &sale.CustomerID.ID
+	}
+	if sale.LocationID != nil {
+		location = &sale.LocationID.ID
+	}
+	if sale.PriceListID != nil {
+		list = &sale.PriceListID.ID
+	}
+
+	// sales without a location take stock from the default location of the retailer, if it has one
+	var assigned int
+	err = tx.QueryRow(ctx, `INSERT INTO sales (retailer_id, customer_id, location_id, sale_date, currency, price_list_id, prices_include_tax, status)
+		VALUES ($1, $2, COALESCE($3, (SELECT id FROM stock_locations WHERE retailer_id = $1 AND is_default)), $4, $5, $6, $7, $8)
+		RETURNING id, location_id`,
+		sale.RetailerID.ID, customer, location, sale.SaleDate, sale.Currency, list, sale.PricesIncludeTax, sale.Status).Scan(&assigned, &location)
+	var pgErr *pgconn.PgError
+	if isForeignKeyViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "sales_customer_id_fkey" {
+		return sales.ErrCustomerNotFound
+	} else if isForeignKeyViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "sales_location_id_fkey" {
+		return sales.ErrLocationNotFound
+	} else if isForeignKeyViolation(err) {
+		return sales.ErrRetailerNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into sales: %w", err)
+	}
+	sale.ID = &keys.OpaqueID{ID: assigned}
+	if location != nil {
+		sale.LocationID = &keys.OpaqueID{ID: *location}
+	}
+
+	for _, line := range sale.LineItems {
+		var (
+			overriddenBy *actor.Actor
+			reason       *string
+		)
+		if line.Override != nil {
+			overriddenBy, reason = &line.Override.Actor, line.Override.Reason
+		}
+
+		var id int
+		err := tx.QueryRow(ctx, `INSERT INTO line_items (sale_id, product_id, quantity, unit_scale, unit_price, list_price, overridden_by, override_reason) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+			assigned, line.ItemID.ID, (*money.Decimal)(line.Quantity), line.UnitScale, line.UnitPrice, line.ListPrice, overriddenBy, reason).Scan(&id)
+		if isForeignKeyViolation(err) {
+			return sales.ErrUnknownItem
+		} else if err != nil {
+			return fmt.Errorf("failed to insert into line_items: %w", err)
+		}
+		line.ID = &keys.OpaqueID{ID: id}
+
+		for _, discount := range line.Discounts {
+			_, err := tx.Exec(ctx, `INSERT INTO line_item_discounts (line_item_id, promotion_id, name, amount) VALUES ($1, $2, $3, $4)`,
+				id, discount.PromotionID.ID, discount.Name, discount.Amount)
+			if err != nil {
+				return fmt.Errorf("failed to insert into line_item_discounts: %w", err)
+			}
+		}
+	}
+
+	for _, line := range sale.Taxes {
+		_, err := tx.Exec(ctx, `INSERT INTO sale_taxes (sale_id, tax_rate_id, name, tax_class, rate, taxable, amount) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			assigned, line.RateID.ID, line.Name, line.Class, (*money.Decimal)(line.Rate), line.Taxable, line.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to insert into sale_taxes: %w", err)
+		}
+	}
+
+	var (
+		lines      = make([]int, 0, len(sale.LineItems))
+		quantities = make([]*money.Decimal, 0, len(sale.LineItems))
+	)
+	for _, line := range sale.LineItems {
+		lines, quantities = append(lines, line.ID.ID), append(quantities, (*money.Decimal)(new(big.Rat).Neg(line.Quantity)))
+	}
+	if err := postStock(ctx, tx, inventory.Sale, lines, quantities); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func distinct(ids []int) int {
+	var seen = make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		seen[id] = struct{}{}
+	}
+	return len(seen)
+}
+
+func (s *Sales) GetSale(ctx context.Context, id int) (*sales.Sale, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Conn.Query(ctx, `SELECT `+saleColumns+` FROM sales WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from sales: %w", err)
+	}
+
+	found, err := s.scanSales(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, sales.ErrSaleNotFound
+	}
+	return found[0], nil
+}
+
+func (s *Sales) PageSales(ctx context.Context, filter sales.SaleFilter, page paginate.Paginate) ([]*sales.Sale, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		conditions = []string{"retailer_id = $1"}
+		args       = []any{retailer}
+	)
+	var condition = func(format string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if page.Cursor != nil {
+		condition("id < $%d", page.Cursor)
+	}
+	if filter.Customer != nil {
+		condition("customer_id = $%d", filter.Customer.ID)
+	}
+	if filter.Item != nil {
+		condition("id IN (SELECT sale_id FROM line_items WHERE product_id = $%d)", filter.Item.ID)
+	}
+	if filter.From != nil {
+		condition("sale_date >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		condition("sale_date < $%d", *filter.To)
+	}
+	args = append(args, page.Limit)
+
+	rows, err := s.Conn.Query(ctx, fmt.Sprintf(`SELECT `+saleColumns+` FROM sales WHERE %s ORDER BY id DESC LIMIT $%d`, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a page from sales: %w", err)
+	}
+
+	return s.scanSales(ctx, rows)
+}
+
+// scanSales reads the sales off the rows and then fetches all of their line items at once.
+func (s *Sales) scanSales(ctx context.Context, rows pgx.Rows) ([]*sales.Sale, error) {
+	defer rows.Close()
+
+	var (
+		results []*sales.Sale
+		byID    = make(map[int]*sales.Sale)
+	)
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			retailer keys.OpaqueID
+			customer *int
+			location *int
+			list     *int
+			voidedAt *time.Time
+			voidedBy *actor.Actor
+			reason   *string
+			sale     = &sales.Sale{LineItems: []*sales.LineItem{}, Taxes: []*tax.Line{}, Payments: []*payments.Payment{}, Refunds: []*sales.Refund{}}
+		)
+		err := rows.Scan(&id, &retailer, &customer, &location, &sale.SaleDate, &sale.Currency, &list, &sale.PricesIncludeTax, &sale.Status, &voidedAt, &voidedBy, &reason)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from sales: %w", err)
+		}
+
+		sale.ID, sale.RetailerID = &id, &retailer
+		if customer != nil {
+			sale.CustomerID = &keys.OpaqueID{ID: *customer}
+		}
+		if location != nil {
+			sale.LocationID = &keys.OpaqueID{ID: *location}
+		}
+		if list != nil {
+			sale.PriceListID = &keys.OpaqueID{ID: *list}
+		}
+		if voidedAt != nil {
+			sale.Void = &sales.Void{At: *voidedAt, Actor: *voidedBy, Reason: *reason}
+		}
+		results = append(results, sale)
+		byID[id.ID] = sale
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from sales: %w", err)
+	}
+	rows.Close()
+
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	var ids = make([]int, 0, len(results))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+
+	var lineByID = make(map[int]*sales.LineItem)
+
+	lines, err := s.Conn.Query(ctx, `SELECT id, sale_id, product_id, quantity, unit_scale, unit_price, list_price, overridden_by, override_reason FROM line_items WHERE sale_id = ANY($1) ORDER BY id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from line_items: %w", err)
+	}
+	defer lines.Close()
+
+	for lines.Next() {
+		var (
+			id           keys.OpaqueID
+			saleID       int
+			item         keys.OpaqueID
+			quantity     money.Decimal
+			price        money.Decimal
+			listPrice    *money.Decimal
+			overriddenBy *actor.Actor
+			reason       *string
+			line         = &sales.LineItem{Discounts: []*promotions.Discount{}}
+		)
+		err := lines.Scan(&id, &saleID, &item, &quantity, &line.UnitScale, &price, &listPrice, &overriddenBy, &reason)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from line_items: %w", err)
+		}
+		line.Quantity = new(big.Rat).Set(quantity.Rat())
+
+		line.UnitPrice, err = money.FromRat(price.Rat(), byID[saleID].Currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read unit price of line item %d: %w", id.ID, err)
+		}
+		if listPrice != nil {
+			listed, err := money.FromRat(listPrice.Rat(), byID[saleID].Currency)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read list price of line item %d: %w", id.ID, err)
+			}
+			line.ListPrice = &listed
+		}
+		if overriddenBy != nil {
+			line.Override = &sales.PriceOverride{Actor: *overriddenBy, Reason: reason}
+		}
+
+		line.ID, line.ItemID = &id, &item
+		byID[saleID].LineItems = append(byID[saleID].LineItems, line)
+		lineByID[id.ID] = line
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from line_items: %w", err)
+	}
+	lines.Close()
+
+	var lineIDs = make([]int, 0, len(lineByID))
+	for id := range lineByID {
+		lineIDs = append(lineIDs, id)
+	}
+
+	discounts, err := s.Conn.Query(ctx, `SELECT line_item_id, promotion_id, name, amount FROM line_item_discounts WHERE line_item_id = ANY($1) ORDER BY id`, lineIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from line_item_discounts: %w", err)
+	}
+	defer discounts.Close()
+
+	for discounts.Next() {
+		var (
+			lineID    int
+			promotion keys.OpaqueID
+			amount    money.Decimal
+			discount  = &promotions.Discount{}
+		)
+		err := discounts.Scan(&lineID, &promotion, &discount.Name, &amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from line_item_discounts: %w", err)
+		}
+
+		line := lineByID[lineID]
+		if discount.Amount, err = money.FromRat(amount.Rat(), line.UnitPrice.Currency()); err != nil {
+			return nil, fmt.Errorf("failed to read discount of line item %d: %w", lineID, err)
+		}
+
+		discount.PromotionID = &promotion
+		line.Discounts = append(line.Discounts, discount)
+	}
+	if err := discounts.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from line_item_discounts: %w", err)
+	}
+	discounts.Close()
+
+	taxes, err := s.Conn.Query(ctx, `SELECT sale_id, tax_rate_id, name, tax_class, rate, taxable, amount FROM sale_taxes WHERE sale_id = ANY($1) ORDER BY id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from sale_taxes: %w", err)
+	}
+	defer taxes.Close()
+
+	for taxes.Next() {
+		var (
+			saleID  int
+			rateID  keys.OpaqueID
+			rate    money.Decimal
+			taxable money.Decimal
+			amount  money.Decimal
+			line    = &tax.Line{}
+		)
+		err := taxes.Scan(&saleID, &rateID, &line.Name, &line.Class, &rate, &taxable, &amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from sale_taxes: %w", err)
+		}
+
+		currency := byID[saleID].Currency
+		if line.Taxable, err = money.FromRat(taxable.Rat(), currency); err != nil {
+			return nil, fmt.Errorf("failed to read taxable amount of sale %d: %w", saleID, err)
+		}
+		if line.Amount, err = money.FromRat(amount.Rat(), currency); err != nil {
+			return nil, fmt.Errorf("failed to read tax of sale %d: %w", saleID, err)
+		}
+
+		line.RateID, line.Rate = &rateID, new(big.Rat).Set(rate.Rat())
+		byID[saleID].Taxes = append(byID[saleID].Taxes, line)
+	}
+	if err := taxes.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from sale_taxes: %w", err)
+	}
+	taxes.Close()
+
+	if err := s.scanPayments(ctx, ids, byID); err != nil {
+		return nil, err
+	}
+	if err := s.scanRefunds(ctx, ids, byID); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// scanPayments fetches the payments of all of the sales at once.
+func (s *Sales) scanPayments(ctx context.Context, ids []int, byID map[int]*sales.Sale) error {
+	rows, err := s.Conn.Query(ctx, `SELECT id, sale_id, tender, amount, tendered, change, reference, actor, paid_at, reversed_at, reversal_failure
+		FROM payments WHERE sale_id = ANY($1) ORDER BY id`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to select from payments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			saleID   int
+			amount   money.Decimal
+			tendered *money.Decimal
+			change   *money.Decimal
+			payment  = &payments.Payment{}
+		)
+		err := rows.Scan(&id, &saleID, &payment.Tender, &amount, &tendered, &change, &payment.Reference, &payment.Actor, &payment.PaidAt, &payment.ReversedAt, &payment.ReversalFailure)
+		if err != nil {
+			return fmt.Errorf("failed to scan from rows result when selecting from payments: %w", err)
+		}
+
+		sale := byID[saleID]
+		if payment.Amount, err = money.FromRat(amount.Rat(), sale.Currency); err != nil {
+			return fmt.Errorf("failed to read amount of payment %d: %w", id.ID, err)
+		}
+		if tendered != nil && change != nil {
+			cash, err := money.FromRat(tendered.Rat(), sale.Currency)
+			if err != nil {
+				return fmt.Errorf("failed to read cash tendered of payment %d: %w", id.ID, err)
+			}
+			given, err := money.FromRat(change.Rat(), sale.Currency)
+			if err != nil {
+				return fmt.Errorf("failed to read change of payment %d: %w", id.ID, err)
+			}
+			payment.Tendered, payment.Change = &cash, &given
+		}
+
+		payment.ID, payment.SaleID = &id, sale.ID
+		sale.Payments = append(sale.Payments, payment)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows result from payments: %w", err)
+	}
+	return nil
+}
+
+func (s *Sales) CreatePayment(ctx context.Context, payment *payments.Payment, seen int, status sales.Status) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// locking the sale serialises payments, refunds and voids of it
+	var current sales.Status
+	err = tx.QueryRow(ctx, `SELECT status FROM sales WHERE id = $1 AND retailer_id = $2 FOR UPDATE`, payment.SaleID.ID, retailer).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sales.ErrSaleNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to lock sale of sales: %w", err)
+	}
+
+	var paid int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM payments WHERE sale_id = $1`, payment.SaleID.ID).Scan(&paid)
+	if err != nil {
+		return fmt.Errorf("failed to count payments of sale: %w", err)
+	}
+	if current != sales.Open || paid != seen {
+		return sales.ErrPaymentConflict
+	}
+
+	var assigned int
+	err = tx.QueryRow(ctx, `INSERT INTO payments (sale_id, tender, amount, tendered, change, reference, actor, paid_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		payment.SaleID.ID, payment.Tender, payment.Amount, payment.Tendered, payment.Change, payment.Reference, payment.Actor, payment.PaidAt).Scan(&assigned)
+	if err != nil {
+		return fmt.Errorf("failed to insert into payments: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE sales SET status = $2 WHERE id = $1`, payment.SaleID.ID, status); err != nil {
+		return fmt.Errorf("failed to update status of sales: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	payment.ID = &keys.OpaqueID{ID: assigned}
+	return nil
+}
+
+// scanRefunds fetches the refunds of all of the sales at once.
+func (s *Sales) scanRefunds(ctx context.Context, ids []int, byID map[int]*sales.Sale) error {
+	rows, err := s.Conn.Query(ctx, `SELECT r.id, r.sale_id, r.reason, r.note, r.actor, r.refunded_at, l.line_item_id, l.quantity, l.amount
+		FROM refunds r JOIN refund_lines l ON l.refund_id = r.id
+		WHERE r.sale_id = ANY($1) ORDER BY r.id, l.id`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to select from refunds: %w", err)
+	}
+	defer rows.Close()
+
+	var refund *sales.Refund
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			saleID   int
+			scanned  sales.Refund
+			lineItem keys.OpaqueID
+			quantity money.Decimal
+			amount   money.Decimal
+			line     = &sales.RefundLine{}
+		)
+		err := rows.Scan(&id, &saleID, &scanned.Reason, &scanned.Note, &scanned.Actor, &scanned.RefundedAt, &lineItem, &quantity, &amount)
+		if err != nil {
+			return fmt.Errorf("failed to scan from rows result when selecting from refunds: %w", err)
+		}
+		line.Quantity = new(big.Rat).Set(quantity.Rat())
+
+		sale := byID[saleID]
+		if refund == nil || refund.ID.ID != id.ID {
+			refund = &scanned
+			refund.ID, refund.SaleID, refund.Currency, refund.Lines = &id, sale.ID, sale.Currency, []*sales.RefundLine{}
+			sale.Refunds = append(sale.Refunds, refund)
+		}
+
+		if line.Amount, err = money.FromRat(amount.Rat(), sale.Currency); err != nil {
+			return fmt.Errorf("failed to read amount of refund %d: %w", id.ID, err)
+		}
+		line.LineItemID = &lineItem
+		refund.Lines = append(refund.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows result from refunds: %w", err)
+	}
+	return nil
+}
+
+func (s *Sales) CreateRefund(ctx context.Context, refund *sales.Refund, seen int, status sales.Status) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// locking the sale serialises payments, refunds and voids of it
+	var voided bool
+	err = tx.QueryRow(ctx, `SELECT voided_at IS NOT NULL FROM sales WHERE id = $1 AND retailer_id = $2 FOR UPDATE`, refund.SaleID.ID, retailer).Scan(&voided)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sales.ErrSaleNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to lock sale of sales: %w", err)
+	}
+	if voided {
+		return sales.ErrSaleVoided
+	}
+
+	var refunds int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM refunds WHERE sale_id = $1`, refund.SaleID.ID).Scan(&refunds)
+	if err != nil {
+		return fmt.Errorf("failed to count refunds of sale: %w", err)
+	}
+	if refunds != seen {
+		return sales.ErrRefundConflict
+	}
+
+	var assigned int
+	err = tx.QueryRow(ctx, `INSERT INTO refunds (sale_id, reason, note, actor, refunded_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		refund.SaleID.ID, refund.Reason, refund.Note, refund.Actor, refund.RefundedAt).Scan(&assigned)
+	if err != nil {
+		return fmt.Errorf("failed to insert into refunds: %w", err)
+	}
+
+	// stock is put back before the refund lines are recorded, those are what was returned before
+	var (
+		lines      = make([]int, 0, len(refund.Lines))
+		quantities = make([]*money.Decimal, 0, len(refund.Lines))
+	)
+	for _, line := range refund.Lines {
+		lines, quantities = append(lines, line.LineItemID.ID), append(quantities, (*money.Decimal)(line.Quantity))
+	}
+	if err := restockSold(ctx, tx, inventory.Refund, lines, quantities); err != nil {
+		return err
+	}
+
+	for _, line := range refund.Lines {
+		_, err := tx.Exec(ctx, `INSERT INTO refund_lines (refund_id, line_item_id, quantity, amount) VALUES ($1, $2, $3, $4)`,
+			assigned, line.LineItemID.ID, (*money.Decimal)(line.Quantity), line.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to insert into refund_lines: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE sales SET status = $2 WHERE id = $1`, refund.SaleID.ID, status); err != nil {
+		return fmt.Errorf("failed to update status of sales: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	refund.ID = &keys.OpaqueID{ID: assigned}
+	return nil
+}
+
+func (s *Sales) VoidSale(ctx context.Context, id int, void *sales.Void) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var voided, refunded bool
+	err = tx.QueryRow(ctx, `SELECT voided_at IS NOT NULL FROM sales WHERE id = $1 AND retailer_id = $2 FOR UPDATE`, id, retailer).Scan(&voided)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sales.ErrSaleNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to lock sale of sales: %w", err)
+	}
+	if voided {
+		return sales.ErrSaleVoided
+	}
+
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM refunds WHERE sale_id = $1)`, id).Scan(&refunded)
+	if err != nil {
+		return fmt.Errorf("failed to select refunds of sale: %w", err)
+	}
+	if refunded {
+		return sales.ErrSaleRefunded
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE sales SET status = $2, voided_at = $3, voided_by = $4, void_reason = $5 WHERE id = $1`, id, sales.Voided, void.At, void.Actor, void.Reason)
+	if err != nil {
+		return fmt.Errorf("failed to void sale of sales: %w", err)
+	}
+
+	// voided sales never took stock, what they took goes back where it came from
+	var (
+		lines      []int
+		quantities []*money.Decimal
+	)
+	rows, err := tx.Query(ctx, `SELECT id, quantity FROM line_items WHERE sale_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to select line items of sale: %w", err)
+	}
+	for rows.Next() {
+		var (
+			line     int
+			quantity = new(money.Decimal)
+		)
+		if err := rows.Scan(&line, quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan from rows result when selecting from line_items: %w", err)
+		}
+		lines, quantities = append(lines, line), append(quantities, quantity)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows result from line_items: %w", err)
+	}
+	if err := restockSold(ctx, tx, inventory.Void, lines, quantities); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *Sales) RecordReversals(ctx context.Context, reversals []*payments.Payment) error {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, payment := range reversals {
+		// reversed payments stay reversed, a retry racing an earlier one can't undo it
+		_, err := tx.Exec(ctx, `UPDATE payments p SET reversed_at = $2, reversal_failure = $3
+			FROM sales s
+			WHERE p.id = $1 AND p.reversed_at IS NULL AND s.id = p.sale_id AND s.retailer_id = $4`,
+			payment.ID.ID, payment.ReversedAt, payment.ReversalFailure, retailer)
+		if err != nil {
+			return fmt.Errorf("failed to record reversal of payment %d: %w", payment.ID.ID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
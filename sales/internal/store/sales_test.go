@@ -0,0 +1,272 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/payments"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/model/paginate"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestSalesRecordAndPage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	itemStore := &Items{Conn: conn}
+	coffee, err := itemStore.CreateItem(ctx, items.Details{Name: "flat white", UnitScale: items.Unit})
+	require.NoError(t, err)
+	archived, err := itemStore.CreateItem(ctx, items.Details{Name: "discontinued", UnitScale: items.Unit})
+	require.NoError(t, err)
+	require.NoError(t, itemStore.SetItemArchived(ctx, archived.ID.ID, 1, true))
+
+	store := &Sales{Conn: conn}
+
+	price, err := money.Parse("4.50", "NZD")
+	require.NoError(t, err)
+
+	var sale = func(item int) *sales.Sale {
+		return &sales.Sale{
+			RetailerID: &keys.OpaqueID{ID: retailer},
+			SaleDate:   time.Now(),
+			Currency:   "NZD",
+			Status:     sales.Paid,
+			LineItems: []*sales.LineItem{
+				{ItemID: &keys.OpaqueID{ID: item}, Quantity: big.NewRat(2, 1), UnitScale: items.Unit, UnitPrice: price},
+			},
+		}
+	}
+
+	first := sale(coffee.ID.ID)
+	require.NoError(t, store.CreateSale(ctx, first))
+	require.NotNil(t, first.ID)
+	require.NotNil(t, first.LineItems[0].ID)
+
+	require.ErrorIs(t, store.CreateSale(ctx, sale(archived.ID.ID)), sales.ErrUnknownItem)
+	require.ErrorIs(t, store.CreateSale(ctx, sale(-1)), sales.ErrUnknownItem)
+
+	second := sale(coffee.ID.ID)
+	require.NoError(t, store.CreateSale(ctx, second))
+
+	found, err := store.GetSale(ctx, first.ID.ID)
+	require.NoError(t, err)
+	require.Len(t, found.LineItems, 1)
+	require.Equal(t, price, found.LineItems[0].UnitPrice)
+	total, err := found.Total()
+	require.NoError(t, err)
+	require.Equal(t, "9.00 NZD", total.String())
+
+	page, err := store.PageSales(ctx, sales.SaleFilter{Item: &keys.OpaqueID{ID: coffee.ID.ID}}, paginate.Paginate{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, second.ID.ID, page[0].ID.ID)
+
+	page, err = store.PageSales(ctx, sales.SaleFilter{Item: &keys.OpaqueID{ID: coffee.ID.ID}}, paginate.Paginate{Cursor: page[0].ID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, first.ID.ID, page[0].ID.ID)
+
+	_, err = store.GetSale(ctx, -1)
+	require.ErrorIs(t, err, sales.ErrSaleNotFound)
+}
+
+func TestSalesRefundAndVoid(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	coffee, err := (&Items{Conn: conn}).CreateItem(ctx, items.Details{Name: "flat white", UnitScale: items.Unit})
+	require.NoError(t, err)
+
+	store := &Sales{Conn: conn}
+
+	price, err := money.Parse("4.50", "NZD")
+	require.NoError(t, err)
+
+	var sale = func() *sales.Sale {
+		s := &sales.Sale{
+			RetailerID: &keys.OpaqueID{ID: retailer},
+			SaleDate:   time.Now(),
+			Currency:   "NZD",
+			Status:     sales.Paid,
+			LineItems: []*sales.LineItem{
+				{ItemID: &keys.OpaqueID{ID: coffee.ID.ID}, Quantity: big.NewRat(2, 1), UnitScale: items.Unit, UnitPrice: price},
+			},
+		}
+		require.NoError(t, store.CreateSale(ctx, s))
+		return s
+	}
+
+	refunded := sale()
+	refund := &sales.Refund{
+		SaleID:     refunded.ID,
+		Reason:     sales.Damaged,
+		Actor:      "till-1",
+		RefundedAt: time.Now(),
+		Currency:   "NZD",
+		Lines:      []*sales.RefundLine{{LineItemID: refunded.LineItems[0].ID, Quantity: big.NewRat(1, 1), Amount: price}},
+	}
+	require.NoError(t, store.CreateRefund(ctx, refund, 0, sales.Paid))
+	require.NotNil(t, refund.ID)
+
+	// the refund was worked out before the first one was made
+	require.ErrorIs(t, store.CreateRefund(ctx, refund, 0, sales.Paid), sales.ErrRefundConflict)
+
+	found, err := store.GetSale(ctx, refunded.ID.ID)
+	require.NoError(t, err)
+	require.Len(t, found.Refunds, 1)
+	require.Equal(t, sales.Damaged, found.Refunds[0].Reason)
+	require.Equal(t, "1", found.RefundedQuantity(found.LineItems[0]).RatString())
+	net, err := found.NetTotal()
+	require.NoError(t, err)
+	require.Equal(t, "4.50 NZD", net.String())
+
+	require.ErrorIs(t, store.VoidSale(ctx, refunded.ID.ID, &sales.Void{At: time.Now(), Actor: "till-1", Reason: "mistake"}), sales.ErrSaleRefunded)
+
+	voided := sale()
+	require.NoError(t, store.VoidSale(ctx, voided.ID.ID, &sales.Void{At: time.Now(), Actor: "till-1", Reason: "mistake"}))
+	require.ErrorIs(t, store.VoidSale(ctx, voided.ID.ID, &sales.Void{At: time.Now(), Actor: "till-1", Reason: "mistake"}), sales.ErrSaleVoided)
+	require.ErrorIs(t, store.VoidSale(ctx, -1, &sales.Void{At: time.Now(), Actor: "till-1", Reason: "mistake"}), sales.ErrSaleNotFound)
+
+	refund.SaleID = voided.ID
+	refund.Lines[0].LineItemID = voided.LineItems[0].ID
+	require.ErrorIs(t, store.CreateRefund(ctx, refund, 0, sales.Paid), sales.ErrSaleVoided)
+
+	found, err = store.GetSale(ctx, voided.ID.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found.Void)
+	require.Equal(t, "mistake", found.Void.Reason)
+	require.Equal(t, sales.Voided, found.Status)
+}
+
+func TestSalesPayments(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	coffee, err := (&Items{Conn: conn}).CreateItem(ctx, items.Details{Name: "flat white", UnitScale: items.Unit})
+	require.NoError(t, err)
+
+	store := &Sales{Conn: conn}
+
+	price, err := money.Parse("4.50", "NZD")
+	require.NoError(t, err)
+
+	sale := &sales.Sale{
+		RetailerID: &keys.OpaqueID{ID: retailer},
+		SaleDate:   time.Now(),
+		Currency:   "NZD",
+		Status:     sales.Open,
+		LineItems: []*sales.LineItem{
+			{ItemID: &keys.OpaqueID{ID: coffee.ID.ID}, Quantity: big.NewRat(2, 1), UnitScale: items.Unit, UnitPrice: price},
+		},
+	}
+	require.NoError(t, store.CreateSale(ctx, sale))
+
+	var reference = "local-1"
+	tendered, err := money.Parse("10.00", "NZD")
+	require.NoError(t, err)
+	change, err := money.Parse("5.50", "NZD")
+	require.NoError(t, err)
+
+	card := &payments.Payment{SaleID: sale.ID, Tender: payments.Card, Amount: price, Reference: &reference, Actor: "till-1", PaidAt: time.Now()}
+	require.NoError(t, store.CreatePayment(ctx, card, 0, sales.Open))
+	require.NotNil(t, card.ID)
+
+	// the payment was worked out before the card payment was made
+	cash := &payments.Payment{SaleID: sale.ID, Tender: payments.Cash, Amount: price, Tendered: &tendered, Change: &change, Actor: "till-1", PaidAt: time.Now()}
+	require.ErrorIs(t, store.CreatePayment(ctx, cash, 0, sales.Paid), sales.ErrPaymentConflict)
+	require.NoError(t, store.CreatePayment(ctx, cash, 1, sales.Paid))
+
+	// nothing more is paid once a sale has been paid
+	require.ErrorIs(t, store.CreatePayment(ctx, cash, 2, sales.Paid), sales.ErrPaymentConflict)
+
+	found, err := store.GetSale(ctx, sale.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, sales.Paid, found.Status)
+	require.Len(t, found.Payments, 2)
+	require.Equal(t, "local-1", *found.Payments[0].Reference)
+	require.Equal(t, "5.50 NZD", found.Payments[1].Change.String())
+	due, err := found.Due()
+	require.NoError(t, err)
+	require.True(t, due.IsZero())
+
+	require.NoError(t, store.VoidSale(ctx, sale.ID.ID, &sales.Void{At: time.Now(), Actor: "till-1", Reason: "mistake"}))
+	found, err = store.GetSale(ctx, sale.ID.ID)
+	require.NoError(t, err)
+	require.Equal(t, sales.Voided, found.Status)
+
+	// the card failed to reverse and is left to retry, the cash went back over the counter
+	var (
+		reversedAt = time.Now()
+		failure    = "terminal offline"
+	)
+	card, till := found.Payments[0], found.Payments[1]
+	card.ReversalFailure, till.ReversedAt = &failure, &reversedAt
+	require.NoError(t, store.RecordReversals(ctx, []*payments.Payment{card, till}))
+
+	found, err = store.GetSale(ctx, sale.ID.ID)
+	require.NoError(t, err)
+	require.Nil(t, found.Payments[0].ReversedAt)
+	require.Equal(t, failure, *found.Payments[0].ReversalFailure)
+	require.NotNil(t, found.Payments[1].ReversedAt)
+
+	card.ReversedAt, card.ReversalFailure = &reversedAt, nil
+	require.NoError(t, store.RecordReversals(ctx, []*payments.Payment{card}))
+	found, err = store.GetSale(ctx, sale.ID.ID)
+	require.NoError(t, err)
+	for _, payment := range found.Payments {
+		require.NotNil(t, payment.ReversedAt)
+		require.Nil(t, payment.ReversalFailure)
+	}
+
+	require.ErrorIs(t, store.CreatePayment(ctx, cash, 2, sales.Paid), sales.ErrPaymentConflict)
+
+	cash.SaleID = &keys.OpaqueID{ID: -1}
+	require.ErrorIs(t, store.CreatePayment(ctx, cash, 0, sales.Paid), sales.ErrSaleNotFound)
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type Taxes struct {
+	Conn *Pool
+}
+
+const rateColumns = `id, retailer_id, jurisdiction, tax_class, name, rate, effective_from, effective_to`
+
+func (t *Taxes) CreateRate(ctx context.Context, rate *tax.Rate) error {
+	tx, err := t.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// serialises rate changes so that concurrent changes can't both pass the overlap check
+	_, err = tx.Exec(ctx, `LOCK TABLE tax_rates IN SHARE ROW EXCLUSIVE MODE`)
+	if err != nil {
+		return fmt.Errorf("failed to lock tax_rates: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE tax_rates SET effective_to = $5
+		WHERE retailer_id = $1 AND jurisdiction = $2 AND tax_class = $3 AND name = $4 AND effective_to IS NULL AND effective_from < $5`,
+		rate.RetailerID.ID, rate.Jurisdiction, rate.Class, rate.Name, rate.EffectiveFrom)
+	if err != nil {
+		return fmt.Errorf("failed to end open rates of tax_rates: %w", err)
+	}
+
+	var overlaps bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (
+			SELECT 1 FROM tax_rates
+			WHERE retailer_id = $1 AND jurisdiction = $2 AND tax_class = $3 AND name = $4
+			AND effective_from < COALESCE($6, 'infinity'::timestamp)
+			AND COALESCE(effective_to, 'infinity'::timestamp) > $5
+		)`, rate.RetailerID.ID, rate.Jurisdiction, rate.Class, rate.Name, rate.EffectiveFrom, rate.EffectiveTo).Scan(&overlaps)
+	if err != nil {
+		return fmt.Errorf("failed to select overlaps from tax_rates: %w", err)
+	}
+	if overlaps {
+		return tax.ErrRateOverlap
+	}
+
+	var assigned int
+	err = tx.QueryRow(ctx, `INSERT INTO tax_rates (retailer_id, jurisdiction, tax_class, name, rate, effective_from, effective_to) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		rate.RetailerID.ID, rate.Jurisdiction, rate.Class, rate.Name, (*money.Decimal)(rate.Rate), rate.EffectiveFrom, rate.EffectiveTo).Scan(&assigned)
+	if isForeignKeyViolation(err) {
+		return tax.ErrRetailerNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to insert into tax_rates: %w", err)
+	}
+	rate.ID = &keys.OpaqueID{ID: assigned}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (t *Taxes) GetRate(ctx context.Context, id int) (*tax.Rate, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := t.Conn.Query(ctx, `SELECT `+rateColumns+` FROM tax_rates WHERE id = $1 AND retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from tax_rates: %w", err)
+	}
+
+	rates, err := scanRates(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(rates) == 0 {
+		return nil, tax.ErrRateNotFound
+	}
+	return rates[0], nil
+}
+
+func (t *Taxes) ActiveRates(ctx context.Context, retailer int, jurisdiction string, at time.Time) ([]*tax.Rate, error) {
+	rows, err := t.Conn.Query(ctx, `SELECT `+rateColumns+` FROM tax_rates
+		WHERE retailer_id = $1 AND jurisdiction = $2 AND effective_from <= $3 AND (effective_to IS NULL OR effective_to > $3)
+		ORDER BY tax_class, name`, retailer, jurisdiction, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select active from tax_rates: %w", err)
+	}
+	return scanRates(rows)
+}
+
+func (t *Taxes) GetRates(ctx context.Context, retailer int, jurisdiction string) ([]*tax.Rate, error) {
+	rows, err := t.Conn.Query(ctx, `SELECT `+rateColumns+` FROM tax_rates
+		WHERE retailer_id = $1 AND jurisdiction = $2 ORDER BY effective_from DESC, tax_class, name`, retailer, jurisdiction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from tax_rates: %w", err)
+	}
+	return scanRates(rows)
+}
+
+func scanRates(rows pgx.Rows) ([]*tax.Rate, error) {
+	defer rows.Close()
+
+	var results []*tax.Rate
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			retailer keys.OpaqueID
+			rate     money.Decimal
+			read     tax.Rate
+		)
+		err := rows.Scan(&id, &retailer, &read.Jurisdiction, &read.Class, &read.Name, &rate, &read.EffectiveFrom, &read.EffectiveTo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from tax_rates: %w", err)
+		}
+
+		read.ID, read.RetailerID, read.Rate = &id, &retailer, new(big.Rat).Set(rate.Rat())
+		results = append(results, &read)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows result from tax_rates: %w", err)
+	}
+	return results, nil
+}
+
+func (t *Taxes) SetSettings(ctx context.Context, settings *tax.Settings) error {
+	_, err := t.Conn.Exec(ctx, `INSERT INTO tax_settings (retailer_id, jurisdiction, prices_include_tax, rounding) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (retailer_id) DO UPDATE SET jurisdiction = EXCLUDED.jurisdiction, prices_include_tax = EXCLUDED.prices_include_tax, rounding = EXCLUDED.rounding, updated_at = CURRENT_TIMESTAMP`,
+		settings.RetailerID.ID, settings.Jurisdiction, settings.PricesIncludeTax, settings.Rounding)
+	if isForeignKeyViolation(err) {
+		return tax.ErrRetailerNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to upsert into tax_settings: %w", err)
+	}
+	return nil
+}
+
+func (t *Taxes) GetSettings(ctx context.Context, retailer int) (*tax.Settings, error) {
+	var settings = tax.Settings{RetailerID: &keys.OpaqueID{ID: retailer}}
+	err := t.Conn.QueryRow(ctx, `SELECT jurisdiction, prices_include_tax, rounding FROM tax_settings WHERE retailer_id = $1`, retailer).
+		Scan(&settings.Jurisdiction, &settings.PricesIncludeTax, &settings.Rounding)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, tax.ErrNoSettings
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to select from tax_settings: %w", err)
+	}
+	return &settings, nil
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/sales"
+	"github.com/suessflorian/pedlar/sales/internal/tax"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestTaxRatesAndSaleTaxes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := godotenv.Load()
+	require.NoError(t, err)
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	require.NotEmpty(t, url)
+
+	conn, err := Conn(ctx, url, t.Name())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, retailer := scope(t, ctx, conn, "pedlar.test")
+
+	store := &Taxes{Conn: conn}
+
+	_, err = store.GetSettings(ctx, retailer)
+	require.ErrorIs(t, err, tax.ErrNoSettings)
+
+	settings := &tax.Settings{RetailerID: &keys.OpaqueID{ID: retailer}, Jurisdiction: "NZ", PricesIncludeTax: true, Rounding: tax.PerLine}
+	require.NoError(t, store.SetSettings(ctx, settings))
+	settings.Rounding = tax.PerInvoice
+	require.NoError(t, store.SetSettings(ctx, settings))
+
+	found, err := store.GetSettings(ctx, retailer)
+	require.NoError(t, err)
+	require.Equal(t, tax.PerInvoice, found.Rounding)
+	require.True(t, found.PricesIncludeTax)
+
+	var (
+		y2010 = time.Date(2010, time.October, 1, 0, 0, 0, 0, time.UTC)
+		y2011 = time.Date(2011, time.October, 1, 0, 0, 0, 0, time.UTC)
+	)
+	var rate = func(fraction *big.Rat, from time.Time, to *time.Time) *tax.Rate {
+		return &tax.Rate{RetailerID: &keys.OpaqueID{ID: retailer}, Jurisdiction: "NZ", Class: items.DefaultTaxClass, Name: "GST", Rate: fraction, EffectiveFrom: from, EffectiveTo: to}
+	}
+
+	require.NoError(t, store.CreateRate(ctx, rate(big.NewRat(125, 1000), time.Date(1989, time.July, 1, 0, 0, 0, 0, time.UTC), nil)))
+	// an open ended rate is ended where the next one starts
+	require.NoError(t, store.CreateRate(ctx, rate(big.NewRat(15, 100), y2010, nil)))
+	require.ErrorIs(t, store.CreateRate(ctx, rate(big.NewRat(16, 100), y2011, &y2011)), tax.ErrRateOverlap)
+
+	active, err := store.ActiveRates(ctx, retailer, "NZ", y2010.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	require.Equal(t, "0.125", tax.FormatRate(active[0].Rate))
+
+	active, err = store.ActiveRates(ctx, retailer, "NZ", y2011)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	require.Equal(t, "0.15", tax.FormatRate(active[0].Rate))
+
+	history, err := store.GetRates(ctx, retailer, "NZ")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, y2010, history[1].EffectiveTo.UTC())
+
+	// rates belong to one retailer, another retailer's rate never ends ours
+	theirs, other := scope(t, context.Background(), conn, "theirs.test")
+	require.NoError(t, store.CreateRate(theirs, &tax.Rate{RetailerID: &keys.OpaqueID{ID: other}, Jurisdiction: "NZ", Class: items.DefaultTaxClass, Name: "GST", Rate: big.NewRat(1, 10), EffectiveFrom: y2011}))
+	active, err = store.ActiveRates(ctx, retailer, "NZ", y2011.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	require.Equal(t, "0.15", tax.FormatRate(active[0].Rate))
+	_, err = store.GetRate(theirs, active[0].ID.ID)
+	require.ErrorIs(t, err, tax.ErrRateNotFound)
+
+	coffee, err := (&Items{Conn: conn}).CreateItem(ctx, items.Details{Name: "flat white", UnitScale: items.Unit})
+	require.NoError(t, err)
+	require.Equal(t, items.DefaultTaxClass, coffee.TaxClass)
+
+	price, err := money.Parse("4.60", "NZD")
+	require.NoError(t, err)
+	taxable, err := money.Parse("4.00", "NZD")
+	require.NoError(t, err)
+	charged, err := money.Parse("0.60", "NZD")
+	require.NoError(t, err)
+
+	sale := &sales.Sale{
+		RetailerID:       &keys.OpaqueID{ID: retailer},
+		SaleDate:         y2011,
+		Currency:         "NZD",
+		PricesIncludeTax: true,
+		Status:           sales.Paid,
+		LineItems: []*sales.LineItem{
+			{ItemID: &keys.OpaqueID{ID: coffee.ID.ID}, Quantity: big.NewRat(1, 1), UnitScale: items.Unit, UnitPrice: price},
+		},
+		Taxes: []*tax.Line{
+			{RateID: active[0].ID, Name: "GST", Class: items.DefaultTaxClass, Rate: active[0].Rate, Taxable: taxable, Amount: charged},
+		},
+	}
+	require.NoError(t, (&Sales{Conn: conn}).CreateSale(ctx, sale))
+
+	read, err := (&Sales{Conn: conn}).GetSale(ctx, sale.ID.ID)
+	require.NoError(t, err)
+	require.True(t, read.PricesIncludeTax)
+	require.Len(t, read.Taxes, 1)
+	require.Equal(t, "0.60 NZD", read.Taxes[0].Amount.String())
+	require.Equal(t, "4.00 NZD", read.Taxes[0].Taxable.String())
+	require.Equal(t, active[0].ID.ID, read.Taxes[0].RateID.ID)
+
+	total, err := read.Total()
+	require.NoError(t, err)
+	require.Equal(t, "4.60 NZD", total.String())
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/gtin"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func (i *Items) CreateVariant(ctx context.Context, item int, deets items.VariantDetails) (*items.Variant, error) {
+	var options = make(map[string]string, len(deets.Options))
+	for _, option := range deets.Options {
+		options[option.Axis] = option.Value
+	}
+
+	var currency *money.Currency
+	if deets.PriceOverride != nil {
+		code := deets.PriceOverride.Currency()
+		currency = &code
+	}
+
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := i.Conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var assigned int
+	err = tx.QueryRow(ctx, `INSERT INTO item_variants (retailer_id, item_id, options, sku, price_override, price_override_currency) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		retailer, item, options, deets.SKU, deets.PriceOverride, currency).Scan(&assigned)
+	if isForeignKeyViolation(err) {
+		return nil, items.ErrItemNotFound
+	} else if isUniqueViolation(err) {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "item_variants_sku_key" {
+			return nil, items.ErrDuplicateSKU
+		}
+		return nil, items.ErrDuplicateVariant
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to insert into item_variants: %w", err)
+	}
+
+	if deets.Barcode != nil {
+		normalised, err := gtin.Normalise(*deets.Barcode)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec(ctx, `INSERT INTO item_barcodes (retailer_id, gtin, code, item_id, variant_id) VALUES ($1, $2, $3, $4, $5)`, retailer, normalised, *deets.Barcode, item, assigned)
+		if isUniqueViolation(err) {
+			return nil, items.ErrDuplicateBarcode
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to insert into item_barcodes: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &items.Variant{
+		ID:             &keys.OpaqueID{ID: assigned},
+		ItemID:         &keys.OpaqueID{ID: item},
+		VariantDetails: deets,
+	}, nil
+}
+
+func (i *Items) GetVariant(ctx context.Context, id int) (*items.Variant, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT v.id, v.item_id, v.options, v.sku, b.code, v.price_override, v.price_override_currency FROM item_variants v LEFT JOIN item_barcodes b ON b.variant_id = v.id WHERE v.id = $1 AND v.retailer_id = $2`, id, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select from item_variants: %w", err)
+	}
+
+	variants, err := scanVariants(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(variants) == 0 {
+		return nil, items.ErrVariantNotFound
+	}
+	return variants[0], nil
+}
+
+func (i *Items) GetVariants(ctx context.Context, item int) ([]*items.Variant, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.Conn.Query(ctx, `SELECT v.id, v.item_id, v.options, v.sku, b.code, v.price_override, v.price_override_currency FROM item_variants v LEFT JOIN item_barcodes b ON b.variant_id = v.id WHERE v.item_id = $1 AND v.retailer_id = $2 ORDER BY v.id`, item, retailer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select many from item_variants: %w", err)
+	}
+
+	return scanVariants(rows)
+}
+
+func scanVariants(rows pgx.Rows) ([]*items.Variant, error) {
+	defer rows.Close()
+
+	var results []*items.Variant
+	for rows.Next() {
+		var (
+			id       keys.OpaqueID
+			itemID   keys.OpaqueID
+			options  map[string]string
+			variant  items.Variant
+			price    *money.Decimal
+			currency *money.Currency
+		)
+		err := rows.Scan(&id, &itemID, &options, &variant.SKU, &variant.Barcode, &price, &currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan from rows result when selecting from item_variants: %w", err)
+		}
+
+		variant.ID, variant.ItemID = &id, &itemID
+		for axis, value := range options {
+			variant.Options = append(variant.Options, items.Option{Axis: axis, Value: value})
+		}
+		sort.Slice(variant.Options, func(a, b int) bool { return variant.Options[a].Axis < variant.Options[b].Axis })
+
+		if price != nil && currency != nil {
+			override, err := money.FromRat(price.Rat(), *currency)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read price override of variant %d: %w", id.ID, err)
+			}
+			variant.PriceOverride = &override
+		}
+
+		results = append(results, &variant)
+	}
+
+	return results, rows.Err()
+}
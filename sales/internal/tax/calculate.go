@@ -0,0 +1,121 @@
+package tax
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// mode rounds tax to the nearest minor unit with ties away from zero, which is what the tax
+// authorities of our retailers expect.
+const mode = money.HalfUp
+
+// charged accumulates the tax of the taxable amounts of a single tax class.
+type charged struct {
+	// gross sums the taxable amounts as given, including tax when prices include tax.
+	gross *big.Rat
+	// taxes hold the tax per rate of the class, exact when rounding per invoice.
+	taxes []*big.Rat
+}
+
+// Calculate charges the rates on the taxable amounts of a sale in the currency and breaks the
+// tax out per rate, in the order of the rates. Amounts of a tax class without rates aren't taxed.
+func Calculate(currency money.Currency, taxables []Taxable, rates []*Rate, settings Settings) ([]*Line, error) {
+	if settings.Rounding != PerLine && settings.Rounding != PerInvoice {
+		return nil, fmt.Errorf("%w: %q", ErrRounding, settings.Rounding)
+	}
+
+	var byClass = make(map[string][]*Rate)
+	for _, rate := range rates {
+		byClass[rate.Class] = append(byClass[rate.Class], rate)
+	}
+
+	var classes = make(map[string]*charged)
+	for _, taxable := range taxables {
+		if taxable.Amount.Currency() != currency {
+			return nil, fmt.Errorf("%w: %s taxed in %s", money.ErrCurrencyMismatch, taxable.Amount.Currency(), currency)
+		}
+
+		applicable := byClass[taxable.Class]
+		if len(applicable) == 0 {
+			continue
+		}
+
+		class, ok := classes[taxable.Class]
+		if !ok {
+			class = &charged{gross: new(big.Rat), taxes: make([]*big.Rat, len(applicable))}
+			for i := range class.taxes {
+				class.taxes[i] = new(big.Rat)
+			}
+			classes[taxable.Class] = class
+		}
+
+		// base is the amount excluding tax, inclusive amounts are divided by one plus all rates
+		var base = taxable.Amount.Rat()
+		if settings.PricesIncludeTax {
+			var combined = big.NewRat(1, 1)
+			for _, rate := range applicable {
+				combined.Add(combined, rate.Rate)
+			}
+			base.Quo(base, combined)
+		}
+
+		for i, rate := range applicable {
+			tax := new(big.Rat).Mul(base, rate.Rate)
+			if settings.Rounding == PerLine {
+				rounded, err := money.Round(tax, currency, mode)
+				if err != nil {
+					return nil, err
+				}
+				tax = rounded.Rat()
+			}
+			class.taxes[i].Add(class.taxes[i], tax)
+		}
+		class.gross.Add(class.gross, taxable.Amount.Rat())
+	}
+
+	var (
+		lines = []*Line{}
+		done  = make(map[string]bool)
+	)
+	for _, rate := range rates {
+		class, ok := classes[rate.Class]
+		if !ok || done[rate.Class] {
+			continue
+		}
+		done[rate.Class] = true
+
+		var (
+			applicable = byClass[rate.Class]
+			amounts    = make([]money.Money, len(applicable))
+			net        = new(big.Rat).Set(class.gross)
+		)
+		for i := range applicable {
+			var err error
+			if amounts[i], err = money.Round(class.taxes[i], currency, mode); err != nil {
+				return nil, err
+			}
+			if settings.PricesIncludeTax {
+				net.Sub(net, amounts[i].Rat())
+			}
+		}
+
+		taxable, err := money.FromRat(net, currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to total taxable amount of %s: %w", rate.Class, err)
+		}
+
+		for i, applied := range applicable {
+			lines = append(lines, &Line{
+				RateID:  applied.ID,
+				Name:    applied.Name,
+				Class:   applied.Class,
+				Rate:    applied.Rate,
+				Taxable: taxable,
+				Amount:  amounts[i],
+			})
+		}
+	}
+	return lines, nil
+}
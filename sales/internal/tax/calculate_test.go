@@ -0,0 +1,181 @@
+package tax
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+func TestCalculate(t *testing.T) {
+	var rate = func(class, name, fraction string) *Rate {
+		r, ok := new(big.Rat).SetString(fraction)
+		require.True(t, ok)
+		return &Rate{Class: class, Name: name, Rate: r}
+	}
+
+	var (
+		gst      = rate("STANDARD", "GST", "0.15")
+		vat      = rate("STANDARD", "VAT", "0.2")
+		reduced  = rate("REDUCED", "VAT", "0.05")
+		federal  = rate("STANDARD", "GST", "0.05")
+		province = rate("STANDARD", "PST", "0.07")
+		consumer = rate("STANDARD", "JCT", "0.1")
+	)
+
+	// want is a tax line as "name class taxable amount"
+	type want struct {
+		name, class, taxable, amount string
+	}
+
+	tests := []struct {
+		name      string
+		currency  money.Currency
+		amounts   []string
+		classes   []string
+		rates     []*Rate
+		inclusive bool
+		rounding  Rounding
+		want      []want
+		err       error
+	}{
+		{
+			name: "exclusive", currency: "NZD", amounts: []string{"10.00"}, rates: []*Rate{gst}, rounding: PerLine,
+			want: []want{{"GST", "STANDARD", "10.00", "1.50"}},
+		},
+		{
+			name: "inclusive", currency: "NZD", amounts: []string{"11.50"}, rates: []*Rate{gst}, inclusive: true, rounding: PerLine,
+			want: []want{{"GST", "STANDARD", "10.00", "1.50"}},
+		},
+		{
+			name: "exclusive line rounding rounds every line up", currency: "NZD", amounts: []string{"0.10", "0.10", "0.10"}, rates: []*Rate{gst}, rounding: PerLine,
+			want: []want{{"GST", "STANDARD", "0.30", "0.06"}},
+		},
+		{
+			name: "exclusive invoice rounding rounds once", currency: "NZD", amounts: []string{"0.10", "0.10", "0.10"}, rates: []*Rate{gst}, rounding: PerInvoice,
+			want: []want{{"GST", "STANDARD", "0.30", "0.05"}},
+		},
+		{
+			name: "inclusive line rounding", currency: "NZD", amounts: []string{"0.05", "0.05", "0.05"}, rates: []*Rate{gst}, inclusive: true, rounding: PerLine,
+			want: []want{{"GST", "STANDARD", "0.12", "0.03"}},
+		},
+		{
+			name: "inclusive invoice rounding", currency: "NZD", amounts: []string{"0.05", "0.05", "0.05"}, rates: []*Rate{gst}, inclusive: true, rounding: PerInvoice,
+			want: []want{{"GST", "STANDARD", "0.13", "0.02"}},
+		},
+		{
+			name: "ties round away from zero", currency: "GBP", amounts: []string{"0.10"}, classes: []string{"REDUCED"}, rates: []*Rate{vat, reduced}, rounding: PerLine,
+			want: []want{{"VAT", "REDUCED", "0.10", "0.01"}},
+		},
+		{
+			name: "negative ties round away from zero", currency: "GBP", amounts: []string{"-0.10"}, classes: []string{"REDUCED"}, rates: []*Rate{vat, reduced}, rounding: PerInvoice,
+			want: []want{{"VAT", "REDUCED", "-0.10", "-0.01"}},
+		},
+		{
+			name: "just below a tie rounds down", currency: "NZD", amounts: []string{"0.03"}, rates: []*Rate{gst}, rounding: PerLine,
+			want: []want{{"GST", "STANDARD", "0.03", "0.00"}},
+		},
+		{
+			name: "classes are broken out separately", currency: "GBP", amounts: []string{"10.00", "10.00", "0.99"}, classes: []string{"STANDARD", "REDUCED", "STANDARD"}, rates: []*Rate{reduced, vat}, rounding: PerInvoice,
+			want: []want{{"VAT", "REDUCED", "10.00", "0.50"}, {"VAT", "STANDARD", "10.99", "2.20"}},
+		},
+		{
+			name: "class without rates is not taxed", currency: "NZD", amounts: []string{"10.00", "5.00"}, classes: []string{"STANDARD", "ZERO_RATED"}, rates: []*Rate{gst}, rounding: PerLine,
+			want: []want{{"GST", "STANDARD", "10.00", "1.50"}},
+		},
+		{
+			name: "nothing taxable", currency: "NZD", amounts: []string{"5.00"}, classes: []string{"EXEMPT"}, rates: []*Rate{gst}, rounding: PerLine,
+		},
+		{
+			name: "several rates of a class exclusive", currency: "CAD", amounts: []string{"10.00"}, rates: []*Rate{federal, province}, rounding: PerLine,
+			want: []want{{"GST", "STANDARD", "10.00", "0.50"}, {"PST", "STANDARD", "10.00", "0.70"}},
+		},
+		{
+			name: "several rates of a class inclusive", currency: "CAD", amounts: []string{"11.20"}, rates: []*Rate{federal, province}, inclusive: true, rounding: PerInvoice,
+			want: []want{{"GST", "STANDARD", "10.00", "0.50"}, {"PST", "STANDARD", "10.00", "0.70"}},
+		},
+		{
+			name: "inclusive taxable absorbs rounding of every rate", currency: "CAD", amounts: []string{"1.00"}, rates: []*Rate{federal, province}, inclusive: true, rounding: PerLine,
+			want: []want{{"GST", "STANDARD", "0.90", "0.04"}, {"PST", "STANDARD", "0.90", "0.06"}},
+		},
+		{
+			name: "currency without minor units", currency: "JPY", amounts: []string{"1100", "55"}, rates: []*Rate{consumer}, inclusive: true, rounding: PerLine,
+			want: []want{{"JCT", "STANDARD", "1050", "105"}},
+		},
+		{
+			name: "currency without minor units invoice rounding", currency: "JPY", amounts: []string{"55", "55"}, rates: []*Rate{consumer}, inclusive: true, rounding: PerInvoice,
+			want: []want{{"JCT", "STANDARD", "100", "10"}},
+		},
+		{
+			name: "unknown rounding", currency: "NZD", amounts: []string{"10.00"}, rates: []*Rate{gst}, rounding: "NEAREST", err: ErrRounding,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var taxables = make([]Taxable, 0, len(tt.amounts))
+			for i, amount := range tt.amounts {
+				m, err := money.Parse(amount, tt.currency)
+				require.NoError(t, err)
+
+				var class = "STANDARD"
+				if tt.classes != nil {
+					class = tt.classes[i]
+				}
+				taxables = append(taxables, Taxable{Class: class, Amount: m})
+			}
+
+			lines, err := Calculate(tt.currency, taxables, tt.rates, Settings{PricesIncludeTax: tt.inclusive, Rounding: tt.rounding})
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			var got = make([]want, 0, len(lines))
+			for _, line := range lines {
+				got = append(got, want{line.Name, line.Class, line.Taxable.Decimal(), line.Amount.Decimal()})
+			}
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestCalculateCurrencyMismatch(t *testing.T) {
+	amount, err := money.Parse("10.00", "AUD")
+	require.NoError(t, err)
+
+	_, err = Calculate("NZD", []Taxable{{Class: "STANDARD", Amount: amount}}, nil, Settings{Rounding: PerLine})
+	require.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate string
+		want string
+		err  error
+	}{
+		{rate: "0.15", want: "0.15"},
+		{rate: " 0.125 ", want: "0.125"},
+		{rate: "0", want: "0"},
+		{rate: "1", want: "1"},
+		{rate: "0.0000001", err: ErrRate},
+		{rate: "1.5", err: ErrRate},
+		{rate: "-0.1", err: ErrRate},
+		{rate: "15%", err: ErrRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rate, func(t *testing.T) {
+			got, err := ParseRate(tt.rate)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, FormatRate(got))
+		})
+	}
+}
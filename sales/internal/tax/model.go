@@ -0,0 +1,69 @@
+package tax
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+// Rounding decides where tax is rounded to the minor unit of the currency of a sale.
+type Rounding string
+
+const (
+	// PerLine rounds the tax of every line item before adding them up.
+	PerLine Rounding = "LINE"
+	// PerInvoice adds up the exact tax of all line items and rounds once per tax rate.
+	PerInvoice Rounding = "INVOICE"
+)
+
+// Rate is a tax a retailer charges in a jurisdiction on items of a tax class within [EffectiveFrom,
+// EffectiveTo), an open ended rate has no EffectiveTo. A tax class may have several rates in a
+// jurisdiction that are charged alongside each other, ie a federal and a provincial sales tax.
+type Rate struct {
+	ID           *keys.OpaqueID
+	RetailerID   *keys.OpaqueID
+	Jurisdiction string
+	Class        string
+	Name         string
+	// Rate is the fraction of the price excluding tax that is charged, ie 0.15 for 15%.
+	Rate          *big.Rat
+	EffectiveFrom time.Time
+	EffectiveTo   *time.Time
+}
+
+// Settings decide how sales of a retailer are taxed.
+type Settings struct {
+	RetailerID   *keys.OpaqueID
+	Jurisdiction string
+	// PricesIncludeTax is whether prices of the retailer already include tax, in which case tax is
+	// backed out of them rather than added on top.
+	PricesIncludeTax bool
+	Rounding         Rounding
+}
+
+// Taxable is an amount charged for items of a tax class, ie the total of a line item.
+type Taxable struct {
+	Class  string
+	Amount money.Money
+}
+
+// Line is the tax charged at a single rate on a sale, Taxable is the amount excluding tax that the
+// rate was charged on.
+type Line struct {
+	RateID  *keys.OpaqueID
+	Name    string
+	Class   string
+	Rate    *big.Rat
+	Taxable money.Money
+	Amount  money.Money
+}
+
+// FormatRate formats a rate as a decimal without trailing zeros, ie "0.15".
+func FormatRate(r *big.Rat) string {
+	formatted := r.FloatString(6)
+	formatted = strings.TrimRight(formatted, "0")
+	return strings.TrimSuffix(formatted, ".")
+}
@@ -0,0 +1,249 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/suessflorian/pedlar/sales/internal/items"
+	"github.com/suessflorian/pedlar/sales/internal/retailers"
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+	"github.com/suessflorian/pedlar/sales/pkg/money"
+)
+
+type store interface {
+	// CreateRate adds the rate with its id assigned in place. An open ended rate of the retailer of
+	// the same name, jurisdiction and tax class that starts before the new rate is ended where the
+	// new rate starts, any other overlap in effective dates is refused with ErrRateOverlap.
+	CreateRate(context.Context, *Rate) error
+	GetRate(context.Context, int) (*Rate, error)
+	// ActiveRates are the rates the retailer charges for every tax class of the jurisdiction
+	// effective at the time, ordered by tax class and name.
+	ActiveRates(ctx context.Context, retailer int, jurisdiction string, at time.Time) ([]*Rate, error)
+	// GetRates lists every rate the retailer has had in the jurisdiction, latest first.
+	GetRates(ctx context.Context, retailer int, jurisdiction string) ([]*Rate, error)
+	SetSettings(context.Context, *Settings) error
+	GetSettings(ctx context.Context, retailer int) (*Settings, error)
+}
+
+type TaxManager struct {
+	Store store
+}
+
+var (
+	ErrRateNotFound     = errors.New("tax rate not found")
+	ErrNoSettings       = errors.New("retailer has no tax settings")
+	ErrRetailerNotFound = errors.New("retailer not found")
+	ErrNoNameRate       = errors.New("tax rate must have a name")
+	ErrRate             = errors.New("tax rate must be a decimal fraction from 0 to 1, ie 0.15")
+	ErrRateOverlap      = errors.New("tax rate overlaps the effective dates of another rate of the same name")
+	ErrEffectiveDates   = errors.New("tax rate must be effective to a time after it is effective from")
+	ErrRounding         = errors.New("unknown tax rounding")
+	// ErrJurisdiction is returned for jurisdictions that aren't an ISO-3166 country code with an
+	// optional subdivision, ie NZ or CA-BC.
+	ErrJurisdiction = errors.New("jurisdiction must be a country code with an optional subdivision, ie NZ or CA-BC")
+)
+
+var jurisdictionPattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// NewRate is a tax rate as presented by a client, rates are effective from now unless told
+// otherwise.
+type NewRate struct {
+	Jurisdiction  string
+	Class         string
+	Name          string
+	Rate          string
+	EffectiveFrom *time.Time
+	EffectiveTo   *time.Time
+}
+
+type NewSettings struct {
+	Retailer         *keys.OpaqueID
+	Jurisdiction     string
+	PricesIncludeTax bool
+	Rounding         Rounding
+}
+
+// Assessment is the tax of a sale along with how it was worked out.
+type Assessment struct {
+	PricesIncludeTax bool
+	Lines            []*Line
+}
+
+// NormaliseJurisdiction upper cases the jurisdiction, ie ca-bc is CA-BC.
+func NormaliseJurisdiction(jurisdiction string) (string, error) {
+	jurisdiction = strings.ToUpper(strings.TrimSpace(jurisdiction))
+	if !jurisdictionPattern.MatchString(jurisdiction) {
+		return "", fmt.Errorf("%w: %q", ErrJurisdiction, jurisdiction)
+	}
+	return jurisdiction, nil
+}
+
+// ParseRate reads a decimal fraction such as "0.15" exactly.
+func ParseRate(rate string) (*big.Rat, error) {
+	parsed, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+	if !ok || parsed.Sign() < 0 || parsed.Cmp(big.NewRat(1, 1)) > 0 {
+		return nil, fmt.Errorf("%w: %q", ErrRate, rate)
+	}
+	// rates are held to six decimal places
+	if !new(big.Rat).Mul(parsed, big.NewRat(1_000_000, 1)).IsInt() {
+		return nil, fmt.Errorf("%w: %q has more than six decimal places", ErrRate, rate)
+	}
+	return parsed, nil
+}
+
+// CreateRate adds a rate the retailer of the request charges.
+func (t *TaxManager) CreateRate(ctx context.Context, input NewRate) (*Rate, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jurisdiction, err := NormaliseJurisdiction(input.Jurisdiction)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := items.NormaliseTaxClass(input.Class)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, ErrNoNameRate
+	}
+
+	rate, err := ParseRate(input.Rate)
+	if err != nil {
+		return nil, err
+	}
+
+	var from = time.Now()
+	if input.EffectiveFrom != nil {
+		from = *input.EffectiveFrom
+	}
+	if input.EffectiveTo != nil && !input.EffectiveTo.After(from) {
+		return nil, ErrEffectiveDates
+	}
+
+	var created = &Rate{
+		RetailerID:    &keys.OpaqueID{ID: retailer},
+		Jurisdiction:  jurisdiction,
+		Class:         class,
+		Name:          name,
+		Rate:          rate,
+		EffectiveFrom: from,
+		EffectiveTo:   input.EffectiveTo,
+	}
+	if err := t.Store.CreateRate(ctx, created); err != nil {
+		return nil, fmt.Errorf("failed to create tax rate: %w", err)
+	}
+	return created, nil
+}
+
+// Rates lists the rates the retailer of the request charges in the jurisdiction effective at the
+// time, or every rate it has had there when no time is given.
+func (t *TaxManager) Rates(ctx context.Context, jurisdiction string, at *time.Time) ([]*Rate, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jurisdiction, err = NormaliseJurisdiction(jurisdiction)
+	if err != nil {
+		return nil, err
+	}
+
+	if at == nil {
+		return t.Store.GetRates(ctx, retailer, jurisdiction)
+	}
+	return t.Store.ActiveRates(ctx, retailer, jurisdiction, *at)
+}
+
+// RateByID gets the rate by its internal id, for records that reference tax rates.
+func (t *TaxManager) RateByID(ctx context.Context, id int) (*Rate, error) {
+	return t.Store.GetRate(ctx, id)
+}
+
+func (t *TaxManager) SetSettings(ctx context.Context, input NewSettings) (*Settings, error) {
+	retailer, err := scope(ctx, input.Retailer)
+	if err != nil {
+		return nil, err
+	}
+
+	jurisdiction, err := NormaliseJurisdiction(input.Jurisdiction)
+	if err != nil {
+		return nil, err
+	}
+
+	switch input.Rounding {
+	case PerLine, PerInvoice:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrRounding, input.Rounding)
+	}
+
+	var settings = &Settings{
+		RetailerID:       &keys.OpaqueID{ID: retailer},
+		Jurisdiction:     jurisdiction,
+		PricesIncludeTax: input.PricesIncludeTax,
+		Rounding:         input.Rounding,
+	}
+	if err := t.Store.SetSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to set tax settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (t *TaxManager) GetSettings(ctx context.Context, retailerID *keys.OpaqueID) (*Settings, error) {
+	retailer, err := scope(ctx, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	return t.Store.GetSettings(ctx, retailer)
+}
+
+// scope is the retailer the request is scoped to, a retailer named by the request must be that one.
+func scope(ctx context.Context, named *keys.OpaqueID) (int, error) {
+	retailer, err := retailers.From(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	if named != nil {
+		id, err := named.Decode(ctx)
+		if err != nil {
+			return -1, fmt.Errorf("failed to decode retailer id: %w", err)
+		}
+		if id != retailer {
+			return -1, ErrRetailerNotFound
+		}
+	}
+	return retailer, nil
+}
+
+// Assess works out the tax of a sale of the retailer at the time. Retailers without tax settings
+// don't charge tax and nil is returned.
+func (t *TaxManager) Assess(ctx context.Context, retailer int, at time.Time, currency money.Currency, taxables []Taxable) (*Assessment, error) {
+	settings, err := t.Store.GetSettings(ctx, retailer)
+	if errors.Is(err, ErrNoSettings) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get tax settings: %w", err)
+	}
+
+	rates, err := t.Store.ActiveRates(ctx, retailer, settings.Jurisdiction, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tax rates: %w", err)
+	}
+
+	lines, err := Calculate(currency, taxables, rates, *settings)
+	if err != nil {
+		return nil, err
+	}
+	return &Assessment{PricesIncludeTax: settings.PricesIncludeTax, Lines: lines}, nil
+}
@@ -0,0 +1,60 @@
+// Package gtin validates Global Trade Item Numbers, the family of barcodes that EAN-8, UPC-A,
+// EAN-13 and GTIN-14 belong to.
+package gtin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrLength     = errors.New("gtin must be 8, 12, 13 or 14 digits")
+	ErrNonDigit   = errors.New("gtin must only contain digits")
+	ErrCheckDigit = errors.New("gtin check digit does not match")
+)
+
+// Validate checks the length, characters and check digit of the code.
+func Validate(code string) error {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return fmt.Errorf("%w: got %d", ErrLength, len(code))
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return ErrNonDigit
+		}
+	}
+
+	payload, check := code[:len(code)-1], int(code[len(code)-1]-'0')
+	if CheckDigit(payload) != check {
+		return ErrCheckDigit
+	}
+	return nil
+}
+
+// CheckDigit computes the modulo 10 check digit of a payload, weighting digits 3 and 1
+// alternately from the rightmost digit.
+func CheckDigit(payload string) int {
+	var sum int
+	for i := 0; i < len(payload); i++ {
+		digit := int(payload[len(payload)-1-i] - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return (10 - sum%10) % 10
+}
+
+// Normalise validates the code and pads it to the 14 digit form, this lets the same product
+// scanned as a UPC-A or an EAN-13 resolve to the same GTIN.
+func Normalise(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if err := Validate(code); err != nil {
+		return "", err
+	}
+	return strings.Repeat("0", 14-len(code)) + code, nil
+}
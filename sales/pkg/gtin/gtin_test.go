@@ -0,0 +1,47 @@
+package gtin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		err  error
+	}{
+		{"ean-8", "96385074", nil},
+		{"upc-a", "036000291452", nil},
+		{"ean-13", "4006381333931", nil},
+		{"gtin-14", "10012345678902", nil},
+		{"bad check digit", "4006381333932", ErrCheckDigit},
+		{"letters", "40063813339A1", ErrNonDigit},
+		{"too short", "1234567", ErrLength},
+		{"isbn-10 length", "0306406152", ErrLength},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.code)
+			if test.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestNormalise(t *testing.T) {
+	upc, err := Normalise("036000291452")
+	require.NoError(t, err)
+
+	ean, err := Normalise(" 0036000291452 ")
+	require.NoError(t, err)
+
+	assert.Equal(t, "00036000291452", upc)
+	assert.Equal(t, upc, ean)
+}
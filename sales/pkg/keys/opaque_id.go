@@ -82,6 +82,18 @@ func (k *OpaqueID) Decode(ctx context.Context) (int, error) {
 	return k.codec.Decode(ctx, k.external)
 }
 
+// DecodeOptional decodes the external id when there is one, leaving it nil otherwise.
+func DecodeOptional(ctx context.Context, externalID *OpaqueID) (*OpaqueID, error) {
+	if externalID == nil {
+		return nil, nil
+	}
+	id, err := externalID.Decode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &OpaqueID{ID: id}, nil
+}
+
 func (k *OpaqueID) UnmarshalJSONContext(ctx context.Context, v interface{}) error {
 	s, ok := v.(string)
 	if !ok {
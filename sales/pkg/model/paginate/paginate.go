@@ -1,8 +1,34 @@
 package paginate
 
-import "github.com/suessflorian/pedlar/sales/pkg/keys"
+import (
+	"context"
+	"fmt"
+
+	"github.com/suessflorian/pedlar/sales/pkg/keys"
+)
 
 type Paginate struct {
 	Cursor *keys.OpaqueID `json:"cursor"`
 	Limit  int            `json:"limit"`
 }
+
+// Decode decodes the cursor of the page, the first 20 are paged through without a page at all.
+func Decode(ctx context.Context, page *Paginate) (Paginate, error) {
+	if page == nil {
+		return Paginate{Limit: 20}, nil
+	}
+
+	if page.Cursor == nil {
+		return *page, nil
+	}
+
+	cursor, err := page.Cursor.Decode(ctx)
+	if err != nil {
+		return Paginate{}, fmt.Errorf("failed to decode cursor: %w", err)
+	}
+
+	return Paginate{
+		Cursor: &keys.OpaqueID{ID: cursor},
+		Limit:  page.Limit,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO-4217 alphabetic currency code.
+type Currency string
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// exponents holds the number of minor unit digits of every supported currency, ie cents.
+var exponents = map[Currency]int{
+	"AUD": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "DKK": 2, "EUR": 2, "FJD": 2,
+	"GBP": 2, "HKD": 2, "IDR": 2, "INR": 2, "MXN": 2, "MYR": 2, "NOK": 2, "NZD": 2,
+	"PHP": 2, "SEK": 2, "SGD": 2, "THB": 2, "TOP": 2, "TWD": 2, "USD": 2, "WST": 2,
+	"ZAR": 2,
+	"CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "VND": 0, "XPF": 0,
+	"BHD": 3, "JOD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+}
+
+func ParseCurrency(code string) (Currency, error) {
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if _, ok := exponents[currency]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return currency, nil
+}
+
+// Exponent is the number of minor unit digits of the currency, it is 0 for unknown currencies.
+func (c Currency) Exponent() int {
+	return exponents[c]
+}
+
+func (c Currency) valid() error {
+	if _, ok := exponents[c]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return nil
+}
+
+func (c *Currency) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("must be a string")
+	}
+
+	currency, err := ParseCurrency(str)
+	if err != nil {
+		return err
+	}
+
+	*c = currency
+	return nil
+}
+
+func (c Currency) MarshalGQL(w io.Writer) {
+	_, _ = io.WriteString(w, strconv.Quote(string(c)))
+}
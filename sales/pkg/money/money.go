@@ -0,0 +1,281 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+	// ErrPrecision is returned for exact amounts that have more decimal places than the minor
+	// units of their currency, ie 1.005 NZD.
+	ErrPrecision = errors.New("amount is more precise than the minor unit of its currency")
+	ErrAmount    = errors.New("amount is not a decimal number")
+	// ErrRange is returned for amounts too large to hold in minor units, ie a price multiplied by
+	// an absurd quantity.
+	ErrRange = errors.New("amount is out of range")
+)
+
+// Money is an exact amount of a currency held in minor units, ie cents. Arithmetic between
+// amounts of different currencies is refused rather than silently mixing them.
+type Money struct {
+	minor    int64
+	currency Currency
+}
+
+// New is the money of minor units of the currency.
+func New(minor int64, currency Currency) (Money, error) {
+	if err := currency.valid(); err != nil {
+		return Money{}, err
+	}
+	return Money{minor: minor, currency: currency}, nil
+}
+
+// Zero is no money of the currency.
+func Zero(currency Currency) Money {
+	return Money{currency: currency}
+}
+
+// Parse reads a decimal amount such as "4.50" exactly, amounts that don't fit the minor unit of
+// the currency are rejected with ErrPrecision.
+func Parse(amount string, currency Currency) (Money, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(amount))
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrAmount, amount)
+	}
+	return FromRat(rat, currency)
+}
+
+// FromRat converts an exact amount, amounts that don't fit the minor unit of the currency are
+// rejected with ErrPrecision.
+func FromRat(r *big.Rat, currency Currency) (Money, error) {
+	if err := currency.valid(); err != nil {
+		return Money{}, err
+	}
+
+	scaled := new(big.Rat).Mul(r, scale(currency))
+	if !scaled.IsInt() {
+		return Money{}, fmt.Errorf("%w: %s %s", ErrPrecision, r.RatString(), currency)
+	}
+	if !scaled.Num().IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s %s", ErrRange, r.RatString(), currency)
+	}
+	return Money{minor: scaled.Num().Int64(), currency: currency}, nil
+}
+
+// Round converts an amount of any precision, rounding it to the minor unit of the currency.
+func Round(r *big.Rat, currency Currency, mode RoundingMode) (Money, error) {
+	if err := currency.valid(); err != nil {
+		return Money{}, err
+	}
+
+	minor := round(new(big.Rat).Mul(r, scale(currency)), mode)
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s %s", ErrRange, r.RatString(), currency)
+	}
+	return Money{minor: minor.Int64(), currency: currency}, nil
+}
+
+func scale(currency Currency) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(currency.Exponent())), nil))
+}
+
+func (m Money) Minor() int64 {
+	return m.minor
+}
+
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+// Rat is the exact amount in major units.
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).Quo(new(big.Rat).SetInt64(m.minor), scale(m.currency))
+}
+
+// Decimal formats the amount with exactly the digits of the minor unit, ie "4.50".
+func (m Money) Decimal() string {
+	return m.Rat().FloatString(m.currency.Exponent())
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + string(m.currency)
+}
+
+func (m Money) IsZero() bool {
+	return m.minor == 0
+}
+
+func (m Money) Sign() int {
+	switch {
+	case m.minor < 0:
+		return -1
+	case m.minor > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) same(o Money) error {
+	if m.currency != o.currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, o.currency)
+	}
+	return nil
+}
+
+// Add sums two amounts of the same currency, sums too large for minor units are refused with
+// ErrRange.
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.same(o); err != nil {
+		return Money{}, err
+	}
+	sum := m.minor + o.minor
+	if (o.minor > 0 && sum < m.minor) || (o.minor < 0 && sum > m.minor) {
+		return Money{}, fmt.Errorf("%w: %s plus %s", ErrRange, m, o)
+	}
+	return Money{minor: sum, currency: m.currency}, nil
+}
+
+// Sub takes an amount of the same currency away, differences too large for minor units are
+// refused with ErrRange.
+func (m Money) Sub(o Money) (Money, error) {
+	if err := m.same(o); err != nil {
+		return Money{}, err
+	}
+	difference := m.minor - o.minor
+	if (o.minor > 0 && difference > m.minor) || (o.minor < 0 && difference < m.minor) {
+		return Money{}, fmt.Errorf("%w: %s minus %s", ErrRange, m, o)
+	}
+	return Money{minor: difference, currency: m.currency}, nil
+}
+
+// Cmp compares two amounts of the same currency, -1 when m is less than o.
+func (m Money) Cmp(o Money) (int, error) {
+	if err := m.same(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.minor < o.minor:
+		return -1, nil
+	case m.minor > o.minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (m Money) Neg() Money {
+	return Money{minor: -m.minor, currency: m.currency}
+}
+
+// Times multiplies by a whole quantity, which never needs rounding. Products too large for minor
+// units are refused with ErrRange.
+func (m Money) Times(n int64) (Money, error) {
+	product := new(big.Int).Mul(big.NewInt(m.minor), big.NewInt(n))
+	if !product.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s times %d", ErrRange, m, n)
+	}
+	return Money{minor: product.Int64(), currency: m.currency}, nil
+}
+
+// Mul multiplies by a rate such as a tax rate or discount, rounding the result. Products too large
+// for minor units are refused with ErrRange.
+func (m Money) Mul(rate *big.Rat, mode RoundingMode) (Money, error) {
+	minor := round(new(big.Rat).Mul(new(big.Rat).SetInt64(m.minor), rate), mode)
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s times %s", ErrRange, m, rate.RatString())
+	}
+	return Money{minor: minor.Int64(), currency: m.currency}, nil
+}
+
+// Allocate splits the amount in proportion to the weights without losing or creating minor
+// units, remainders go to the earliest parts. Allocating 10.00 by 1, 1, 1 gives 3.34, 3.33, 3.33.
+func (m Money) Allocate(weights ...int64) ([]Money, error) {
+	var total int64
+	for _, weight := range weights {
+		if weight < 0 {
+			return nil, errors.New("allocation weights must not be negative")
+		}
+		if total > math.MaxInt64-weight {
+			return nil, fmt.Errorf("%w: allocation weights total more than %d", ErrRange, int64(math.MaxInt64))
+		}
+		total += weight
+	}
+	if total == 0 {
+		return nil, errors.New("allocation weights must not all be zero")
+	}
+
+	var (
+		parts     = make([]Money, len(weights))
+		allocated int64
+	)
+	for i, weight := range weights {
+		share := new(big.Int).Quo(new(big.Int).Mul(big.NewInt(m.minor), big.NewInt(weight)), big.NewInt(total))
+		parts[i] = Money{minor: share.Int64(), currency: m.currency}
+		allocated += parts[i].minor
+	}
+
+	var step int64 = 1
+	if m.minor < 0 {
+		step = -1
+	}
+	for i := 0; allocated != m.minor; i = (i + 1) % len(parts) {
+		if weights[i] == 0 {
+			continue
+		}
+		parts[i].minor += step
+		allocated += step
+	}
+	return parts, nil
+}
+
+// Sum adds up amounts of the currency, the sum of nothing is zero. Totals too large for minor
+// units are refused with ErrRange.
+func Sum(currency Currency, amounts ...Money) (Money, error) {
+	var total = Zero(currency)
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(amount); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+type jsonMoney struct {
+	Amount   string   `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.Decimal(), Currency: m.currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var decoded jsonMoney
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	parsed, err := Parse(decoded.Amount, decoded.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Input is an amount as presented by a client, it is only money once parsed.
+type Input struct {
+	Amount   string   `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+func (i Input) Money() (Money, error) {
+	return Parse(i.Amount, i.Currency)
+}
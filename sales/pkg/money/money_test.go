@@ -0,0 +1,225 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency Currency
+		want     string
+		err      error
+	}{
+		{amount: "4.50", currency: "NZD", want: "4.50 NZD"},
+		{amount: " 12 ", currency: "NZD", want: "12.00 NZD"},
+		{amount: "-0.5", currency: "USD", want: "-0.50 USD"},
+		{amount: "1500", currency: "JPY", want: "1500 JPY"},
+		{amount: "1.234", currency: "KWD", want: "1.234 KWD"},
+		{amount: "0.125", currency: "NZD", err: ErrPrecision},
+		{amount: "1.5", currency: "JPY", err: ErrPrecision},
+		{amount: "four", currency: "NZD", err: ErrAmount},
+		{amount: "1", currency: "XXX", err: ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+string(tt.currency), func(t *testing.T) {
+			m, err := Parse(tt.amount, tt.currency)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.String())
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		amount string
+		mode   RoundingMode
+		want   string
+	}{
+		{amount: "0.125", mode: HalfEven, want: "0.12"},
+		{amount: "0.135", mode: HalfEven, want: "0.14"},
+		{amount: "-0.125", mode: HalfEven, want: "-0.12"},
+		{amount: "0.125", mode: HalfUp, want: "0.13"},
+		{amount: "-0.125", mode: HalfUp, want: "-0.13"},
+		{amount: "0.125", mode: HalfDown, want: "0.12"},
+		{amount: "0.1251", mode: HalfDown, want: "0.13"},
+		{amount: "0.129", mode: Down, want: "0.12"},
+		{amount: "-0.129", mode: Down, want: "-0.12"},
+		{amount: "0.121", mode: Up, want: "0.13"},
+		{amount: "-0.121", mode: Up, want: "-0.13"},
+		{amount: "-0.121", mode: Floor, want: "-0.13"},
+		{amount: "0.129", mode: Floor, want: "0.12"},
+		{amount: "-0.129", mode: Ceiling, want: "-0.12"},
+		{amount: "0.121", mode: Ceiling, want: "0.13"},
+		{amount: "0.12", mode: Up, want: "0.12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			r, _ := new(big.Rat).SetString(tt.amount)
+			m, err := Round(r, "NZD", tt.mode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Decimal())
+		})
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	var (
+		nzd = must(t, "4.50", "NZD")
+		usd = must(t, "1.00", "USD")
+	)
+
+	sum, err := nzd.Add(must(t, "0.55", "NZD"))
+	require.NoError(t, err)
+	assert.Equal(t, "5.05 NZD", sum.String())
+
+	_, err = nzd.Add(usd)
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = nzd.Sub(usd)
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = nzd.Cmp(usd)
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = Sum("NZD", nzd, usd)
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	doubled, err := nzd.Times(2)
+	require.NoError(t, err)
+	total, err := Sum("NZD", nzd, doubled, nzd.Neg())
+	require.NoError(t, err)
+	assert.Equal(t, "9.00 NZD", total.String())
+
+	// 15% GST on 4.50 is 0.675
+	gst, err := nzd.Mul(big.NewRat(15, 100), HalfEven)
+	require.NoError(t, err)
+	assert.Equal(t, "0.68", gst.Decimal())
+	gst, err = nzd.Mul(big.NewRat(15, 100), Down)
+	require.NoError(t, err)
+	assert.Equal(t, "0.67", gst.Decimal())
+
+	// products beyond minor units are refused rather than wrapping around
+	_, err = nzd.Times(math.MaxInt64)
+	assert.ErrorIs(t, err, ErrRange)
+	_, err = nzd.Mul(new(big.Rat).SetInt64(math.MaxInt64), HalfEven)
+	assert.ErrorIs(t, err, ErrRange)
+
+	// as are sums and differences
+	var (
+		largest, _  = New(math.MaxInt64, "NZD")
+		smallest, _ = New(math.MinInt64, "NZD")
+		cent, _     = New(1, "NZD")
+	)
+	_, err = largest.Add(cent)
+	assert.ErrorIs(t, err, ErrRange)
+	_, err = smallest.Add(cent.Neg())
+	assert.ErrorIs(t, err, ErrRange)
+	_, err = smallest.Sub(cent)
+	assert.ErrorIs(t, err, ErrRange)
+	_, err = largest.Sub(cent.Neg())
+	assert.ErrorIs(t, err, ErrRange)
+	_, err = Sum("NZD", largest, cent)
+	assert.ErrorIs(t, err, ErrRange)
+
+	// right up to the edges of minor units
+	edge, err := largest.Sub(cent)
+	require.NoError(t, err)
+	edge, err = edge.Add(cent)
+	require.NoError(t, err)
+	assert.Equal(t, largest, edge)
+	edge, err = smallest.Sub(smallest)
+	require.NoError(t, err)
+	assert.True(t, edge.IsZero())
+	edge, err = Sum("NZD", largest, smallest, cent)
+	require.NoError(t, err)
+	assert.True(t, edge.IsZero())
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		weights []int64
+		want    []string
+	}{
+		{name: "even thirds", amount: "10.00", weights: []int64{1, 1, 1}, want: []string{"3.34", "3.33", "3.33"}},
+		{name: "weighted", amount: "0.05", weights: []int64{3, 7}, want: []string{"0.02", "0.03"}},
+		{name: "skips zero weights", amount: "0.02", weights: []int64{0, 1, 1, 1}, want: []string{"0.00", "0.01", "0.01", "0.00"}},
+		{name: "negative", amount: "-10.00", weights: []int64{1, 1, 1}, want: []string{"-3.34", "-3.33", "-3.33"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := must(t, tt.amount, "NZD").Allocate(tt.weights...)
+			require.NoError(t, err)
+
+			var got []string
+			for _, part := range parts {
+				got = append(got, part.Decimal())
+			}
+			assert.Equal(t, tt.want, got)
+
+			total, err := Sum("NZD", parts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, total.Decimal())
+		})
+	}
+
+	_, err := must(t, "1", "NZD").Allocate(0, 0)
+	assert.Error(t, err)
+
+	// weights totalling beyond an int64 are refused rather than wrapping around
+	_, err = must(t, "1", "NZD").Allocate(math.MaxInt64, 1)
+	assert.ErrorIs(t, err, ErrRange)
+	parts, err := must(t, "1", "NZD").Allocate(math.MaxInt64-1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.00", "0.00"}, []string{parts[0].Decimal(), parts[1].Decimal()})
+}
+
+func TestJSON(t *testing.T) {
+	encoded, err := json.Marshal(must(t, "4.5", "NZD"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount": "4.50", "currency": "NZD"}`, string(encoded))
+
+	var decoded Money
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, must(t, "4.50", "NZD"), decoded)
+}
+
+func TestNumeric(t *testing.T) {
+	encoded, err := must(t, "-4.50", "NZD").NumericValue()
+	require.NoError(t, err)
+	assert.Equal(t, pgtype.Numeric{Int: big.NewInt(-450), Exp: -2, Valid: true}, encoded)
+
+	var d Decimal
+	require.NoError(t, d.ScanNumeric(pgtype.Numeric{Int: big.NewInt(12345), Exp: -4, Valid: true}))
+	assert.Equal(t, "1.2345", d.Rat().FloatString(4))
+	require.NoError(t, d.ScanNumeric(pgtype.Numeric{Int: big.NewInt(12), Exp: 2, Valid: true}))
+	assert.Equal(t, "1200", d.Rat().RatString())
+	assert.Error(t, d.ScanNumeric(pgtype.Numeric{}))
+
+	roundtrip, err := d.NumericValue()
+	require.NoError(t, err)
+	assert.Equal(t, pgtype.Numeric{Int: big.NewInt(1200), Valid: true}, roundtrip)
+
+	_, err = (*Decimal)(big.NewRat(1, 3)).NumericValue()
+	assert.Error(t, err)
+}
+
+func must(t *testing.T, amount string, currency Currency) Money {
+	t.Helper()
+	m, err := Parse(amount, currency)
+	require.NoError(t, err)
+	return m
+}
@@ -0,0 +1,77 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Decimal scans and encodes postgres NUMERIC columns exactly, amounts are held in NUMERIC
+// columns next to a currency column and combined with FromRat once read.
+type Decimal big.Rat
+
+func (d *Decimal) Rat() *big.Rat {
+	return (*big.Rat)(d)
+}
+
+func (d *Decimal) ScanNumeric(n pgtype.Numeric) error {
+	if !n.Valid {
+		return errors.New("cannot scan NULL into money.Decimal")
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("cannot scan non finite numeric into money.Decimal")
+	}
+
+	var (
+		r   = new(big.Rat).SetInt(n.Int)
+		exp = new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(n.Exp))), nil)
+	)
+	if n.Exp < 0 {
+		r.Quo(r, new(big.Rat).SetInt(exp))
+	} else {
+		r.Mul(r, new(big.Rat).SetInt(exp))
+	}
+
+	(*big.Rat)(d).Set(r)
+	return nil
+}
+
+func (d *Decimal) NumericValue() (pgtype.Numeric, error) {
+	return numeric((*big.Rat)(d))
+}
+
+// NumericValue lets money be passed straight into NUMERIC columns.
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(m.minor), Exp: -int32(m.currency.Exponent()), Valid: true}, nil
+}
+
+// numeric encodes terminating decimals only, the denominator must be made of twos and fives.
+func numeric(r *big.Rat) (pgtype.Numeric, error) {
+	var (
+		num = new(big.Int).Set(r.Num())
+		den = new(big.Int).Set(r.Denom())
+		exp int32
+		ten = big.NewInt(10)
+	)
+	for den.Cmp(big.NewInt(1)) != 0 {
+		if exp < -1000 {
+			return pgtype.Numeric{}, fmt.Errorf("%s is not a terminating decimal", r.RatString())
+		}
+		num.Mul(num, ten)
+		exp--
+
+		var g = new(big.Int).GCD(nil, nil, num, den)
+		num.Quo(num, g)
+		den.Quo(den, g)
+	}
+	return pgtype.Numeric{Int: num, Exp: exp, Valid: true}, nil
+}
+
+func abs(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
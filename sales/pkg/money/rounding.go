@@ -0,0 +1,59 @@
+package money
+
+import "math/big"
+
+// RoundingMode decides what happens to amounts that fall between two minor units.
+type RoundingMode int
+
+const (
+	// HalfEven rounds to the nearest minor unit and ties to the even one, also known as
+	// bankers rounding. It doesn't bias sums of many rounded amounts in either direction.
+	HalfEven RoundingMode = iota
+	// HalfUp rounds to the nearest minor unit and ties away from zero.
+	HalfUp
+	// HalfDown rounds to the nearest minor unit and ties towards zero.
+	HalfDown
+	// Down truncates towards zero.
+	Down
+	// Up rounds away from zero.
+	Up
+	Floor
+	Ceiling
+)
+
+// round rounds the rational to an integer.
+func round(r *big.Rat, mode RoundingMode) *big.Int {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() == 0 {
+		return quo
+	}
+
+	var (
+		sign = big.NewInt(int64(r.Sign()))
+		// half compares the discarded remainder against one half
+		half = new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom())
+	)
+
+	var away bool
+	switch mode {
+	case HalfEven:
+		away = half > 0 || (half == 0 && quo.Bit(0) == 1)
+	case HalfUp:
+		away = half >= 0
+	case HalfDown:
+		away = half > 0
+	case Down:
+		away = false
+	case Up:
+		away = true
+	case Floor:
+		away = r.Sign() < 0
+	case Ceiling:
+		away = r.Sign() > 0
+	}
+
+	if away {
+		quo.Add(quo, sign)
+	}
+	return quo
+}